@@ -12,6 +12,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/remotefile"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
@@ -58,15 +59,17 @@ type ostreeOptions struct {
 }
 
 type crBlueprint struct {
-	Name           string                    `json:"name,omitempty"`
-	Description    string                    `json:"description,omitempty"`
-	Version        string                    `json:"version,omitempty"`
-	Packages       []blueprint.Package       `json:"packages,omitempty"`
-	Modules        []blueprint.Package       `json:"modules,omitempty"`
-	Groups         []blueprint.Group         `json:"groups,omitempty"`
-	Containers     []blueprint.Container     `json:"containers,omitempty"`
-	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
-	Distro         string                    `json:"distro,omitempty"`
+	Name            string                    `json:"name,omitempty"`
+	Description     string                    `json:"description,omitempty"`
+	Version         string                    `json:"version,omitempty"`
+	Packages        []blueprint.Package       `json:"packages,omitempty"`
+	Modules         []blueprint.Package       `json:"modules,omitempty"`
+	Groups          []blueprint.Group         `json:"groups,omitempty"`
+	PackagePins     []string                  `json:"package_pins,omitempty"`
+	ExcludePackages []string                  `json:"exclude_packages,omitempty"`
+	Containers      []blueprint.Container     `json:"containers,omitempty"`
+	Customizations  *blueprint.Customizations `json:"customizations,omitempty"`
+	Distro          string                    `json:"distro,omitempty"`
 }
 
 type buildConfig struct {
@@ -95,7 +98,7 @@ func loadConfig(filepath string) buildConfig {
 	return config
 }
 
-func makeManifest(imgType distro.ImageType, config buildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string) (manifest.OSBuildManifest, error) {
+func makeManifest(imgType distro.ImageType, config buildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string, lock map[string][]rpmmd.PackageSpec) (manifest.OSBuildManifest, error) {
 	cacheDir := filepath.Join(cacheRoot, archName+distribution.Name())
 
 	options := distro.ImageOptions{Size: 0}
@@ -126,7 +129,7 @@ func makeManifest(imgType distro.ImageType, config buildConfig, distribution dis
 		fmt.Fprintf(os.Stderr, "[WARNING]\n%s", strings.Join(warnings, "\n"))
 	}
 
-	packageSpecs, err := depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
+	packageSpecs, err := depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName, lock)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] depsolve failed: %s", err.Error())
 	}
@@ -148,6 +151,16 @@ func makeManifest(imgType distro.ImageType, config buildConfig, distribution dis
 		return nil, fmt.Errorf("[ERROR] ostree commit resolution failed: %s\n", err.Error())
 	}
 
+	if err := resolvePipelineRemoteFiles(manifest.GetRemoteFileSourceSpecs()); err != nil {
+		return nil, fmt.Errorf("[ERROR] remote file resolution failed: %s", err.Error())
+	}
+
+	for _, usage := range manifest.GrowToFit(packageSpecs, containerSpecs) {
+		for _, u := range usage {
+			fmt.Fprintf(os.Stderr, "[WARNING] %s was too small for its estimated content (%d < %d bytes) and has been grown\n", u.Mountpoint, u.Size, u.Used)
+		}
+	}
+
 	mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] manifest serialization failed: %s", err.Error())
@@ -250,20 +263,59 @@ func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) (map[s
 	return commits, nil
 }
 
-func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
+// resolvePipelineRemoteFiles fetches the remote files of all pipelines and
+// verifies their content against the checksums the manifest sources expect
+func resolvePipelineRemoteFiles(fileSources map[string][]remotefile.SourceSpec) error {
+	resolver := remotefile.NewResolver()
+	for _, sourceSpecs := range fileSources {
+		for _, sourceSpec := range sourceSpecs {
+			resolver.AddWithChecksum(sourceSpec.URL, sourceSpec.Checksum)
+		}
+	}
+	for _, spec := range resolver.Finish() {
+		if spec.ResolutionError != nil {
+			return fmt.Errorf("%s", spec.ResolutionError.Reason)
+		}
+	}
+	return nil
+}
+
+// depsolve the package sets of all pipelines; if lock is not nil, the
+// packages of each pipeline must exactly match its entry in the lock
+func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string, lock map[string][]rpmmd.PackageSpec) (map[string][]rpmmd.PackageSpec, error) {
 	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
 	solver.SetDNFJSONPath("./dnf-json")
 	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
 	for name, pkgSet := range packageSets {
-		res, err := solver.Depsolve(pkgSet)
+		var res []rpmmd.PackageSpec
+		var err error
+		if lock != nil {
+			res, err = solver.DepsolveLocked(pkgSet, lock[name])
+		} else {
+			res, err = solver.Depsolve(pkgSet)
+		}
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("pipeline %q: %w", name, err)
 		}
 		depsolvedSets[name] = res
 	}
 	return depsolvedSets, nil
 }
 
+// loadLockfile reads a lockfile with the depsolved packages of each pipeline,
+// as written next to the manifest by a previous build
+func loadLockfile(fpath string) map[string][]rpmmd.PackageSpec {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		fail(fmt.Sprintf("failed to read lockfile %q: %s", fpath, err.Error()))
+	}
+	var lock map[string][]rpmmd.PackageSpec
+	if err := json.Unmarshal(data, &lock); err != nil {
+		fail(fmt.Sprintf("failed to unmarshal lockfile %q: %s", fpath, err.Error()))
+	}
+	return lock
+}
+
 func save(ms manifest.OSBuildManifest, fpath string) error {
 	b, err := json.MarshalIndent(ms, "", "  ")
 	if err != nil {
@@ -315,6 +367,9 @@ func main() {
 	flag.StringVar(&imgTypeName, "image", "", "image type name (required)")
 	flag.StringVar(&configFile, "config", "", "build config file (required)")
 
+	var lockFile string
+	flag.StringVar(&lockFile, "lockfile", "", "JSON file with the exact packages of each pipeline that depsolve must resolve to")
+
 	flag.Parse()
 
 	if distroName == "" || imgTypeName == "" || configFile == "" {
@@ -328,6 +383,11 @@ func main() {
 
 	config := loadConfig(configFile)
 
+	var lock map[string][]rpmmd.PackageSpec
+	if lockFile != "" {
+		lock = loadLockfile(lockFile)
+	}
+
 	if err := os.MkdirAll(outputDir, 0777); err != nil {
 		fail(fmt.Sprintf("failed to create target directory: %s", err.Error()))
 	}
@@ -362,7 +422,7 @@ func main() {
 	}
 
 	fmt.Printf("Generating manifest for %s: ", config.Name)
-	mf, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot)
+	mf, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot, lock)
 	if err != nil {
 		check(err)
 	}
@@ -0,0 +1,180 @@
+// Standalone executable that prints the semantic difference between two
+// manifests, or between all manifests with the same file name in two
+// directories, e.g. two versions of the manifests generated by
+// cmd/gen-manifests. Checksums and the UUIDs generated from the seed are
+// ignored.
+//
+// Exits with 0 if there are no differences, 1 if there are differences and 2
+// on errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// readManifest reads a manifest from a file that contains either a plain
+// manifest or a manifest with metadata as written by cmd/gen-manifests.
+func readManifest(path string) (manifest.OSBuildManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var withMetadata struct {
+		Manifest manifest.OSBuildManifest `json:"manifest"`
+	}
+	if err := json.Unmarshal(data, &withMetadata); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %s", path, err.Error())
+	}
+	if withMetadata.Manifest != nil {
+		return withMetadata.Manifest, nil
+	}
+	return manifest.OSBuildManifest(data), nil
+}
+
+func diffFiles(oldPath, newPath string) (*manifest.ManifestDiff, error) {
+	oldMf, err := readManifest(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newMf, err := readManifest(newPath)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Diff(oldMf, newMf)
+}
+
+func listManifests(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names[entry.Name()] = true
+		}
+	}
+	return names, nil
+}
+
+// diffDirs prints the differences of the manifests in two directories and
+// returns true if there are any.
+func diffDirs(oldDir, newDir string, namesOnly bool) (bool, error) {
+	oldNames, err := listManifests(oldDir)
+	if err != nil {
+		return false, err
+	}
+	newNames, err := listManifests(newDir)
+	if err != nil {
+		return false, err
+	}
+
+	names := make([]string, 0, len(oldNames)+len(newNames))
+	for name := range oldNames {
+		names = append(names, name)
+	}
+	for name := range newNames {
+		if !oldNames[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changed := false
+	for _, name := range names {
+		switch {
+		case !newNames[name]:
+			fmt.Printf("Only in %s: %s\n", oldDir, name)
+			changed = true
+		case !oldNames[name]:
+			fmt.Printf("Only in %s: %s\n", newDir, name)
+			changed = true
+		default:
+			d, err := diffFiles(filepath.Join(oldDir, name), filepath.Join(newDir, name))
+			if err != nil {
+				return changed, fmt.Errorf("%s: %s", name, err.Error())
+			}
+			if d.Empty() {
+				continue
+			}
+			changed = true
+			if namesOnly {
+				fmt.Println(name)
+			} else {
+				fmt.Printf("=== %s\n%s\n", name, d.String())
+			}
+		}
+	}
+	return changed, nil
+}
+
+func main() {
+	var namesOnly, jsonOutput bool
+	flag.BoolVar(&namesOnly, "names-only", false, "only print the names of the manifests that differ (directories only)")
+	flag.BoolVar(&jsonOutput, "json", false, "print the difference as json (files only)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <old> <new>\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Compare two manifest files or two directories of manifests.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	oldPath, newPath := flag.Arg(0), flag.Arg(1)
+
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	var changed bool
+	switch {
+	case oldInfo.IsDir() && newInfo.IsDir():
+		changed, err = diffDirs(oldPath, newPath, namesOnly)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+	case !oldInfo.IsDir() && !newInfo.IsDir():
+		d, err := diffFiles(oldPath, newPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		changed = !d.Empty()
+		if jsonOutput {
+			out, err := json.MarshalIndent(d, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to marshal diff to json: %s\n", err.Error())
+				os.Exit(2)
+			}
+			fmt.Println(string(out))
+		} else {
+			fmt.Print(d.String())
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "cannot compare a file with a directory")
+		os.Exit(2)
+	}
+
+	if changed {
+		os.Exit(1)
+	}
+}
@@ -19,11 +19,13 @@ import (
 
 	"github.com/gobwas/glob"
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/remotefile"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rhsm/facts"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -65,15 +67,17 @@ type ostreeOptions struct {
 }
 
 type crBlueprint struct {
-	Name           string                    `json:"name,omitempty"`
-	Description    string                    `json:"description,omitempty"`
-	Version        string                    `json:"version,omitempty"`
-	Packages       []blueprint.Package       `json:"packages,omitempty"`
-	Modules        []blueprint.Package       `json:"modules,omitempty"`
-	Groups         []blueprint.Group         `json:"groups,omitempty"`
-	Containers     []blueprint.Container     `json:"containers,omitempty"`
-	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
-	Distro         string                    `json:"distro,omitempty"`
+	Name            string                    `json:"name,omitempty"`
+	Description     string                    `json:"description,omitempty"`
+	Version         string                    `json:"version,omitempty"`
+	Packages        []blueprint.Package       `json:"packages,omitempty"`
+	Modules         []blueprint.Package       `json:"modules,omitempty"`
+	Groups          []blueprint.Group         `json:"groups,omitempty"`
+	PackagePins     []string                  `json:"package_pins,omitempty"`
+	ExcludePackages []string                  `json:"exclude_packages,omitempty"`
+	Containers      []blueprint.Container     `json:"containers,omitempty"`
+	Customizations  *blueprint.Customizations `json:"customizations,omitempty"`
+	Distro          string                    `json:"distro,omitempty"`
 }
 
 type buildRequest struct {
@@ -151,6 +155,7 @@ func makeManifestJob(
 	cacheRoot string,
 	content map[string]bool,
 	metadata bool,
+	validator *osbuild.SchemaValidator,
 ) manifestJob {
 	distroName := distribution.Name()
 	filename := fmt.Sprintf("%s-%s-%s-%s.json", u(distroName), u(archName), u(imgType.Name()), u(name))
@@ -231,6 +236,12 @@ func makeManifestJob(
 			commitSpecs = mockResolveCommits(manifest.GetOSTreeSourceSpecs())
 		}
 
+		if content["files"] {
+			if err = resolvePipelineRemoteFiles(manifest.GetRemoteFileSourceSpecs()); err != nil {
+				return fmt.Errorf("[%s] remote file resolution failed: %s", filename, err.Error())
+			}
+		}
+
 		mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
 		if err != nil {
 			return fmt.Errorf("[%s] manifest serialization failed: %s", filename, err.Error())
@@ -244,6 +255,16 @@ func makeManifestJob(
 			Config:       &bc,
 		}
 		err = save(mf, packageSpecs, containerSpecs, commitSpecs, request, path, filename, metadata)
+		if err != nil {
+			return
+		}
+
+		// validate after saving so that invalid manifests can be inspected
+		if validator != nil {
+			if verr := validator.Validate(mf); verr != nil {
+				return fmt.Errorf("[%s] manifest validation failed: %s", filename, verr.Error())
+			}
+		}
 		return
 	}
 	return job
@@ -370,6 +391,23 @@ func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) (map[s
 	return commits, nil
 }
 
+// resolvePipelineRemoteFiles fetches the remote files of all pipelines and
+// verifies their content against the checksums the manifest sources expect
+func resolvePipelineRemoteFiles(fileSources map[string][]remotefile.SourceSpec) error {
+	resolver := remotefile.NewResolver()
+	for _, sourceSpecs := range fileSources {
+		for _, sourceSpec := range sourceSpecs {
+			resolver.AddWithChecksum(sourceSpec.URL, sourceSpec.Checksum)
+		}
+	}
+	for _, spec := range resolver.Finish() {
+		if spec.ResolutionError != nil {
+			return fmt.Errorf("%s", spec.ResolutionError.Reason)
+		}
+	}
+	return nil
+}
+
 func mockResolveCommits(commitSources map[string][]ostree.SourceSpec) map[string][]ostree.CommitSpec {
 	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
 	for name, commitSources := range commitSources {
@@ -520,10 +558,11 @@ func main() {
 	flag.StringVar(&configPath, "config", "test/config-map.json", "configuration file mapping image types to configs")
 
 	// content args
-	var packages, containers, commits bool
+	var packages, containers, commits, files bool
 	flag.BoolVar(&packages, "packages", true, "depsolve package sets")
 	flag.BoolVar(&containers, "containers", true, "resolve container checksums")
 	flag.BoolVar(&commits, "commits", false, "resolve ostree commit IDs")
+	flag.BoolVar(&files, "files", false, "fetch remote files and verify their checksums")
 
 	// manifest selection args
 	var arches, distros, imgTypes multiValue
@@ -531,8 +570,23 @@ func main() {
 	flag.Var(&distros, "distros", "comma-separated list of distributions (globs supported)")
 	flag.Var(&imgTypes, "images", "comma-separated list of image types (globs supported)")
 
+	// validation args
+	var validate bool
+	var schemaDir string
+	flag.BoolVar(&validate, "validate", false, "validate the generated manifests against the osbuild module schemas")
+	flag.StringVar(&schemaDir, "osbuild-libdir", osbuild.DefaultLibDir, "osbuild installation or source directory to load the module schemas from")
+
 	flag.Parse()
 
+	var validator *osbuild.SchemaValidator
+	if validate {
+		var err error
+		validator, err = osbuild.LoadSchemaValidator(schemaDir)
+		if err != nil {
+			panic(fmt.Sprintf("failed to load osbuild schemas from %q: %s", schemaDir, err.Error()))
+		}
+	}
+
 	seedArg := int64(0)
 	darm := readRepos()
 	distroReg := distroregistry.NewDefault()
@@ -542,6 +596,7 @@ func main() {
 		"packages":   packages,
 		"containers": containers,
 		"commits":    commits,
+		"files":      files,
 	}
 
 	configs := loadConfigMap(configPath)
@@ -600,7 +655,7 @@ func main() {
 				}
 
 				for _, itConfig := range imgTypeConfigs {
-					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, cacheRoot, contentResolve, metadata)
+					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, cacheRoot, contentResolve, metadata, validator)
 					jobs = append(jobs, job)
 				}
 			}
@@ -143,11 +143,18 @@ func (r *rpmCache) updateCacheDirInfo(path string) {
 		}
 
 		fname := entry.Name()
-		if len(fname) < 64 {
+		var repoID string
+		switch {
+		case fname == depsolveCacheDirName:
+			// the persistent depsolve results are accounted for as a
+			// single entry and removed together when shrinking
+			repoID = fname
+		case len(fname) < 64:
 			// unknown file in cache; ignore
 			continue
+		default:
+			repoID = fname[:64]
 		}
-		repoID := fname[:64]
 		repo, ok := repos[repoID]
 		if !ok {
 			// new repo ID
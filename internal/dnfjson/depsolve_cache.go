@@ -0,0 +1,390 @@
+package dnfjson
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// name of the directory for the persistent depsolve cache in the distro
+// specific cache directory; the rpmCache accounts for it like for the
+// metadata of a repository and removes it when it is the least recently used
+// entry.
+const depsolveCacheDirName = "depsolve-results"
+
+// DepsolveCacheStats holds the statistics of the persistent depsolve cache.
+type DepsolveCacheStats struct {
+	// Requests answered from the cache
+	Hits uint64
+
+	// Requests that had to be depsolved, including the invalidated ones
+	Misses uint64
+
+	// Cached results that were discarded because the remote metadata of
+	// one of their repositories changed or they expired
+	Invalidations uint64
+}
+
+// depsolveCache is a persistent cache of depsolve results. Results are stored
+// with the checksums of the repomd.xml of all the repositories of the request
+// in the dnf cache, i.e. of the metadata they were computed from. On lookup,
+// the current repomd.xml of every repository is fetched from the remote and
+// results are only used as long as the checksums match and they are not older
+// than maxAge, so publishing new metadata in any repository invalidates them.
+type depsolveCache struct {
+	// maximum age of the results
+	maxAge time.Duration
+
+	// returns the checksum of the current remote repomd.xml of a repository
+	remoteChecksum func(repo repoConfig) (string, error)
+
+	// locker for the statistics
+	mu    sync.Mutex
+	stats DepsolveCacheStats
+}
+
+type depsolveCacheEntry struct {
+	Created time.Time `json:"created"`
+
+	// checksums of the repomd.xml files by repository ID
+	RepoMetadata map[string]string `json:"repo_metadata"`
+
+	Packages []rpmmd.PackageSpec `json:"packages"`
+}
+
+func newDepsolveCache(maxAge time.Duration) *depsolveCache {
+	return &depsolveCache{
+		maxAge:         maxAge,
+		remoteChecksum: remoteRepoMetadataChecksum,
+	}
+}
+
+// depsolveCacheKey returns the key of a depsolve request. Unlike
+// Request.Hash(), it covers the transactions of the request.
+func depsolveCacheKey(req *Request) (string, error) {
+	keyReq := *req
+	// the same request can be made with different cache directories
+	keyReq.CacheDir = ""
+	data, err := json.Marshal(keyReq)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// repoMetadataChecksums returns the sha256 checksums of the repomd.xml files
+// of the repositories in the dnf cache directory. It returns false if the
+// metadata of any repository is not cached.
+func repoMetadataChecksums(cacheDir string, repos []repoConfig) (map[string]string, bool) {
+	checksums := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		// dnf stores the metadata of a repository in <repo ID>-<hash>; use
+		// the most recently downloaded one if there are several
+		paths, err := filepath.Glob(filepath.Join(cacheDir, repo.ID+"-*", "repodata", "repomd.xml"))
+		if err != nil || len(paths) == 0 {
+			return nil, false
+		}
+		var newest string
+		var newestTime time.Time
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, false
+			}
+			if newest == "" || info.ModTime().After(newestTime) {
+				newest, newestTime = path, info.ModTime()
+			}
+		}
+		data, err := os.ReadFile(newest)
+		if err != nil {
+			return nil, false
+		}
+		checksums[repo.ID] = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+	return checksums, true
+}
+
+// metalink is the part of a metalink document that describes the current
+// repomd.xml of a repository
+type metalink struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Hashes []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"verification>hash"`
+	} `xml:"files>file"`
+}
+
+// remoteRepoMetadataChecksum returns the sha256 checksum of the current
+// repomd.xml of the repository. For repositories with a metalink, the
+// checksum is taken from the metalink; otherwise the repomd.xml is fetched
+// from the first base URL or the first mirror of the mirror list.
+func remoteRepoMetadataChecksum(repo repoConfig) (string, error) {
+	client, err := repoHTTPClient(repo)
+	if err != nil {
+		return "", err
+	}
+
+	var baseURL string
+	switch {
+	case len(repo.BaseURLs) > 0:
+		baseURL = repo.BaseURLs[0]
+	case repo.Metalink != "":
+		data, err := fetch(client, repo.Metalink)
+		if err != nil {
+			return "", err
+		}
+		var ml metalink
+		if err := xml.Unmarshal(data, &ml); err != nil {
+			return "", fmt.Errorf("cannot parse metalink %s: %w", repo.Metalink, err)
+		}
+		for _, file := range ml.Files {
+			if file.Name != "repomd.xml" {
+				continue
+			}
+			for _, hash := range file.Hashes {
+				if hash.Type == "sha256" {
+					return strings.TrimSpace(hash.Value), nil
+				}
+			}
+		}
+		return "", fmt.Errorf("metalink %s has no sha256 checksum of repomd.xml", repo.Metalink)
+	case repo.MirrorList != "":
+		data, err := fetch(client, repo.MirrorList)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				baseURL = line
+				break
+			}
+		}
+		if baseURL == "" {
+			return "", fmt.Errorf("mirror list %s has no mirrors", repo.MirrorList)
+		}
+	default:
+		return "", fmt.Errorf("repository %s has no base URL, metalink or mirror list", repo.ID)
+	}
+
+	data, err := fetch(client, strings.TrimSuffix(baseURL, "/")+"/repodata/repomd.xml")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// repoHTTPClient returns an HTTP client with the TLS configuration of the
+// repository.
+func repoHTTPClient(repo repoConfig) (*http.Client, error) {
+	/* #nosec G402 */
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: repo.IgnoreSSL,
+	}
+	if repo.SSLCACert != "" {
+		caCert, err := os.ReadFile(repo.SSLCACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("cannot parse the CA certificate %s of repository %s", repo.SSLCACert, repo.ID)
+		}
+	}
+	if repo.SSLClientCert != "" && repo.SSLClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(repo.SSLClientCert, repo.SSLClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   repoMetadataTimeout,
+	}, nil
+}
+
+// repoMetadataTimeout is the timeout for fetching the remote metadata of a
+// repository on lookup
+const repoMetadataTimeout = 30 * time.Second
+
+func fetch(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// remoteMetadataMatches returns true if the current remote metadata of all
+// the repositories matches the metadata the result was computed from. The
+// second return value is false if the remote metadata of any repository
+// could not be fetched.
+func (c *depsolveCache) remoteMetadataMatches(repos []repoConfig, checksums map[string]string) (bool, bool) {
+	if len(repos) != len(checksums) {
+		return false, true
+	}
+	for _, repo := range repos {
+		remote, err := c.remoteChecksum(repo)
+		if err != nil {
+			return false, false
+		}
+		if checksums[repo.ID] != remote {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+func (c *depsolveCache) count(hit, invalidated bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.stats.Hits++
+		return
+	}
+	c.stats.Misses++
+	if invalidated {
+		c.stats.Invalidations++
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *depsolveCache) Stats() DepsolveCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Get returns the cached result of the request and true, or nil and false if
+// the result is not cached or was invalidated.
+func (c *depsolveCache) Get(cacheDir string, req *Request) ([]rpmmd.PackageSpec, bool) {
+	key, err := depsolveCacheKey(req)
+	if err != nil {
+		c.count(false, false)
+		return nil, false
+	}
+	path := filepath.Join(cacheDir, depsolveCacheDirName, key+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.count(false, false)
+		return nil, false
+	}
+
+	var entry depsolveCacheEntry
+	valid := json.Unmarshal(data, &entry) == nil
+	if valid && time.Since(entry.Created) > c.maxAge {
+		valid = false
+	}
+	if valid {
+		var fetched bool
+		valid, fetched = c.remoteMetadataMatches(req.Arguments.Repos, entry.RepoMetadata)
+		if !fetched {
+			// the result may still be valid, but can't be used without
+			// knowing the current metadata
+			c.count(false, false)
+			return nil, false
+		}
+	}
+	if !valid {
+		// ignore errors; the entry is replaced by the next Store()
+		_ = os.Remove(path)
+		c.count(false, true)
+		return nil, false
+	}
+
+	// mark the results as recently used for the rpmCache; ignore errors
+	now := time.Now()
+	_ = os.Chtimes(filepath.Dir(path), now, now)
+
+	c.count(true, false)
+	return entry.Packages, true
+}
+
+// Store saves the result of the request together with the checksums of the
+// current repository metadata. It must be called right after depsolving, so
+// that the metadata is the one the result was computed from.
+func (c *depsolveCache) Store(cacheDir string, req *Request, pkgs []rpmmd.PackageSpec) error {
+	key, err := depsolveCacheKey(req)
+	if err != nil {
+		return err
+	}
+	checksums, ok := repoMetadataChecksums(cacheDir, req.Arguments.Repos)
+	if !ok {
+		// nothing to validate the result against later
+		return nil
+	}
+	data, err := json.Marshal(depsolveCacheEntry{
+		Created:      time.Now(),
+		RepoMetadata: checksums,
+		Packages:     pkgs,
+	})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(cacheDir, depsolveCacheDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first, so that concurrent readers never see
+	// a partial entry
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, key+".json")); err != nil {
+		return err
+	}
+	c.removeExpired(dir, key+".json")
+	return nil
+}
+
+// removeExpired deletes the results in dir, except for the one that was just
+// stored, that are older than the maximum age, so results of requests that
+// are not made again don't accumulate.
+func (c *depsolveCache) removeExpired(dir, stored string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || entry.Name() == stored {
+			continue
+		}
+		if time.Since(info.ModTime()) > c.maxAge {
+			// ignore errors; the next Store() tries again
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package dnfjson
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// writeRepomd creates the cached repomd.xml of a repository like dnf does
+func writeRepomd(t *testing.T, cacheDir string, repo rpmmd.RepoConfig, revision string) {
+	repodata := filepath.Join(cacheDir, repo.Hash()+"-0123456789abcdef", "repodata")
+	require.NoError(t, os.MkdirAll(repodata, 0755))
+	repomd := fmt.Sprintf("<repomd><revision>%s</revision></repomd>", revision)
+	require.NoError(t, os.WriteFile(filepath.Join(repodata, "repomd.xml"), []byte(repomd), 0600))
+}
+
+// newTestRepoServer serves the repository "baseos" from a directory and
+// returns its config and a function that publishes a new revision of its
+// metadata
+func newTestRepoServer(t *testing.T) (rpmmd.RepoConfig, string, func(revision string)) {
+	root := t.TempDir()
+	server := httptest.NewServer(http.FileServer(http.Dir(root)))
+	t.Cleanup(server.Close)
+
+	repomdPath := filepath.Join(root, "baseos", "repodata", "repomd.xml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(repomdPath), 0755))
+	publish := func(revision string) {
+		repomd := fmt.Sprintf("<repomd><revision>%s</revision></repomd>", revision)
+		require.NoError(t, os.WriteFile(repomdPath, []byte(repomd), 0600))
+	}
+	publish("1")
+
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{server.URL + "/baseos"}}
+	return repo, repomdPath, publish
+}
+
+func TestPersistentDepsolveCache(t *testing.T) {
+	repo, remoteRepomd, publish := newTestRepoServer(t)
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	solver := NewSolver("platform:el9", "9", "x86_64", "rhel9.0", t.TempDir())
+	cacheDir := solver.GetCacheDir()
+	localRepodata := filepath.Join(cacheDir, repo.Hash()+"-0123456789abcdef", "repodata")
+
+	// like dnf, the fake dnf-json downloads the current metadata
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := fmt.Sprintf(`cat > /dev/null
+echo called >> %s
+mkdir -p %s && cp %s %s/repomd.xml
+echo '[{"name": "tmux", "epoch": 0, "version": "3.3a", "release": "3.el9", "arch": "x86_64", "repo_id": "%s", "checksum": "sha256:1111"}]'
+`, calls, localRepodata, remoteRepomd, localRepodata, repo.Hash())
+	numCalls := func() int {
+		data, err := os.ReadFile(calls)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return strings.Count(string(data), "called")
+	}
+
+	path := filepath.Join(t.TempDir(), "dnf-json")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755)) // #nosec G306
+	solver.SetDNFJSONPath(path)
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Hour))
+
+	expected := []rpmmd.PackageSpec{
+		{Name: "tmux", Version: "3.3a", Release: "3.el9", Arch: "x86_64", Checksum: "sha256:1111"},
+	}
+
+	pkgs, err := solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pkgs)
+	assert.Equal(t, 1, numCalls())
+	assert.Equal(t, DepsolveCacheStats{Misses: 1}, solver.DepsolveCacheStats())
+
+	// the same request is answered from the cache
+	pkgs, err = solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pkgs)
+	assert.Equal(t, 1, numCalls())
+	assert.Equal(t, DepsolveCacheStats{Hits: 1, Misses: 1}, solver.DepsolveCacheStats())
+
+	// a new solver with the same cache directory, e.g. after a restart,
+	// uses the cached result
+	restarted := NewSolver("platform:el9", "9", "x86_64", "rhel9.0", solver.cache.root)
+	restarted.SetDNFJSONPath(path)
+	require.NoError(t, restarted.EnablePersistentDepsolveCache(time.Hour))
+	pkgs, err = restarted.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pkgs)
+	assert.Equal(t, 1, numCalls())
+	assert.Equal(t, DepsolveCacheStats{Hits: 1}, restarted.DepsolveCacheStats())
+
+	// a different request is not answered from the cache
+	otherPkgSets := []rpmmd.PackageSet{{Include: []string{"tmux", "zsh"}, Repositories: []rpmmd.RepoConfig{repo}}}
+	_, err = solver.Depsolve(otherPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 2, numCalls())
+
+	// new metadata published by the repository invalidates the result,
+	// although the metadata in the dnf cache is unchanged
+	publish("2")
+	_, err = solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, numCalls())
+	assert.Equal(t, DepsolveCacheStats{Hits: 1, Misses: 3, Invalidations: 1}, solver.DepsolveCacheStats())
+
+	// and the new result is cached again
+	_, err = solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, numCalls())
+	assert.Equal(t, DepsolveCacheStats{Hits: 2, Misses: 3, Invalidations: 1}, solver.DepsolveCacheStats())
+}
+
+func TestPersistentDepsolveCacheUnreachableRepo(t *testing.T) {
+	repo, _, _ := newTestRepoServer(t)
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	solver := fakeDNFJSON(t, fmt.Sprintf("cat > /dev/null\necho '[{\"name\": \"tmux\", \"epoch\": 0, \"repo_id\": \"%s\"}]'\n", repo.Hash()))
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Hour))
+	writeRepomd(t, solver.GetCacheDir(), repo, "1")
+	_, err := solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+
+	// results are not used if the remote metadata can't be fetched, but
+	// they are not invalidated either
+	solver.depsolveCache.remoteChecksum = func(repoConfig) (string, error) {
+		return "", fmt.Errorf("connection refused")
+	}
+	_, err = solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, DepsolveCacheStats{Misses: 2}, solver.DepsolveCacheStats())
+}
+
+func TestRemoteRepoMetadataChecksum(t *testing.T) {
+	repo, remoteRepomd, _ := newTestRepoServer(t)
+	repomd, err := os.ReadFile(remoteRepomd)
+	require.NoError(t, err)
+	expected := fmt.Sprintf("%x", sha256.Sum256(repomd))
+	root := filepath.Dir(filepath.Dir(filepath.Dir(remoteRepomd)))
+	baseURL := repo.BaseURLs[0]
+	serverURL := strings.TrimSuffix(baseURL, "/baseos")
+
+	metalink := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<metalink version="3.0" xmlns="http://www.metalinker.org/" xmlns:mm0="http://fedorahosted.org/mirrormanager">
+ <files>
+  <file name="repomd.xml">
+   <mm0:timestamp>1700000000</mm0:timestamp>
+   <verification>
+    <hash type="md5">d41d8cd98f00b204e9800998ecf8427e</hash>
+    <hash type="sha256">%s</hash>
+   </verification>
+   <resources maxconnections="1">
+    <url protocol="http" type="http" preference="100">%s/repodata/repomd.xml</url>
+   </resources>
+  </file>
+ </files>
+</metalink>
+`, expected, baseURL)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "metalink"), []byte(metalink), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "mirrorlist"), []byte("# mirrors\n"+baseURL+"\n"), 0600))
+
+	for name, config := range map[string]repoConfig{
+		"baseurl":    {ID: "baseos", BaseURLs: []string{baseURL + "/"}},
+		"metalink":   {ID: "baseos", Metalink: serverURL + "/metalink"},
+		"mirrorlist": {ID: "baseos", MirrorList: serverURL + "/mirrorlist"},
+	} {
+		checksum, err := remoteRepoMetadataChecksum(config)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, checksum, name)
+	}
+
+	_, err = remoteRepoMetadataChecksum(repoConfig{ID: "baseos", BaseURLs: []string{serverURL + "/missing"}})
+	assert.EqualError(t, err, fmt.Sprintf("cannot fetch %s/missing/repodata/repomd.xml: 404 Not Found", serverURL))
+	_, err = remoteRepoMetadataChecksum(repoConfig{ID: "baseos"})
+	assert.EqualError(t, err, "repository baseos has no base URL, metalink or mirror list")
+}
+
+func TestPersistentDepsolveCacheMaxAge(t *testing.T) {
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{"https://example.com/baseos"}}
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	solver := fakeDNFJSON(t, fmt.Sprintf("cat > /dev/null\necho '[{\"name\": \"tmux\", \"epoch\": 0, \"repo_id\": \"%s\"}]'\n", repo.Hash()))
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Nanosecond))
+	writeRepomd(t, solver.GetCacheDir(), repo, "1")
+
+	for i := 0; i < 2; i++ {
+		_, err := solver.Depsolve(pkgSets)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, DepsolveCacheStats{Misses: 2, Invalidations: 1}, solver.DepsolveCacheStats())
+}
+
+func TestPersistentDepsolveCacheWithoutMetadata(t *testing.T) {
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{"https://example.com/baseos"}}
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	// results can't be validated without the repository metadata, so they
+	// are not cached
+	solver := fakeDNFJSON(t, fmt.Sprintf("cat > /dev/null\necho '[{\"name\": \"tmux\", \"epoch\": 0, \"repo_id\": \"%s\"}]'\n", repo.Hash()))
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Hour))
+	for i := 0; i < 2; i++ {
+		_, err := solver.Depsolve(pkgSets)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, DepsolveCacheStats{Misses: 2}, solver.DepsolveCacheStats())
+	assert.NoDirExists(t, filepath.Join(solver.GetCacheDir(), depsolveCacheDirName))
+}
+
+func TestPersistentDepsolveCacheRequiresMaxAge(t *testing.T) {
+	solver := NewSolver("platform:el9", "9", "x86_64", "rhel9.0", t.TempDir())
+	assert.EqualError(t, solver.EnablePersistentDepsolveCache(0), "the maximum age of persistent depsolve results must be positive")
+	assert.Nil(t, solver.depsolveCache)
+}
+
+func TestPersistentDepsolveCacheRemovesExpired(t *testing.T) {
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{"https://example.com/baseos"}}
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	solver := fakeDNFJSON(t, fmt.Sprintf("cat > /dev/null\necho '[{\"name\": \"tmux\", \"epoch\": 0, \"repo_id\": \"%s\"}]'\n", repo.Hash()))
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Hour))
+	writeRepomd(t, solver.GetCacheDir(), repo, "1")
+
+	dir := filepath.Join(solver.GetCacheDir(), depsolveCacheDirName)
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	stale := filepath.Join(dir, "stale.json")
+	require.NoError(t, os.WriteFile(stale, []byte("{}"), 0600))
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	_, err := solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+	assert.NoFileExists(t, stale)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPersistentDepsolveCacheSize(t *testing.T) {
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{"https://example.com/baseos"}}
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+
+	solver := fakeDNFJSON(t, fmt.Sprintf("cat > /dev/null\necho '[{\"name\": \"tmux\", \"epoch\": 0, \"repo_id\": \"%s\"}]'\n", repo.Hash()))
+	require.NoError(t, solver.EnablePersistentDepsolveCache(time.Hour))
+	writeRepomd(t, solver.GetCacheDir(), repo, "1")
+	_, err := solver.Depsolve(pkgSets)
+	require.NoError(t, err)
+
+	// the results are accounted for in the size of the cache
+	cache := newRPMCache(filepath.Dir(solver.GetCacheDir()), 0)
+	require.Contains(t, cache.repoElements, depsolveCacheDirName)
+	assert.NotZero(t, cache.repoElements[depsolveCacheDirName].size)
+
+	// and removed when shrinking
+	require.NoError(t, cache.shrink())
+	assert.NoDirExists(t, filepath.Join(solver.GetCacheDir(), depsolveCacheDirName))
+}
+
+func TestDepsolveCacheDisabled(t *testing.T) {
+	solver := NewSolver("platform:el9", "9", "x86_64", "rhel9.0", t.TempDir())
+	assert.Equal(t, DepsolveCacheStats{}, solver.DepsolveCacheStats())
+}
@@ -15,8 +15,10 @@ package dnfjson
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -41,6 +43,9 @@ type BaseSolver struct {
 	dnfJsonCmd []string
 
 	resultCache *dnfCache
+
+	// Persistent cache of depsolve results; nil if disabled
+	depsolveCache *depsolveCache
 }
 
 // Create a new unconfigured BaseSolver (without platform information). It can
@@ -66,6 +71,31 @@ func (s *BaseSolver) SetDNFJSONPath(cmd string, args ...string) {
 	s.dnfJsonCmd = append([]string{cmd}, args...)
 }
 
+// EnablePersistentDepsolveCache enables the persistent cache of depsolve
+// results in the cache directory, which survives restarts. Cached results are
+// used for at most maxAge, or until any of their repositories publishes new
+// metadata: every lookup fetches the current repomd.xml (or its checksum from
+// the metalink) of all the repositories of the request and compares it to the
+// metadata the result was computed from. A hit does not run dnf-json. maxAge
+// must not be zero. It must be called before creating Solvers with
+// NewWithConfig(), which share the cache and its statistics.
+func (s *BaseSolver) EnablePersistentDepsolveCache(maxAge time.Duration) error {
+	if maxAge <= 0 {
+		return fmt.Errorf("the maximum age of persistent depsolve results must be positive")
+	}
+	s.depsolveCache = newDepsolveCache(maxAge)
+	return nil
+}
+
+// DepsolveCacheStats returns the hit and miss statistics of the persistent
+// depsolve cache. All counters are zero if the cache is not enabled.
+func (s *BaseSolver) DepsolveCacheStats() DepsolveCacheStats {
+	if s.depsolveCache == nil {
+		return DepsolveCacheStats{}
+	}
+	return s.depsolveCache.Stats()
+}
+
 // NewWithConfig initialises a Solver with the platform information and the
 // BaseSolver's subscription info, cache directory, and dnf-json path.
 // Also loads system subscription information.
@@ -134,6 +164,13 @@ func (s *Solver) GetCacheDir() string {
 // transactions in a chain.  It returns a list of all packages (with solved
 // dependencies) that will be installed into the system.
 func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+	return s.DepsolveContext(context.Background(), pkgSets)
+}
+
+// DepsolveContext is like Depsolve, but the dnf-json process is killed when
+// ctx is cancelled or its deadline expires. A *TimeoutError is returned in
+// this case.
+func (s *Solver) DepsolveContext(ctx context.Context, pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
 	req, repoMap, err := s.makeDepsolveRequest(pkgSets)
 	if err != nil {
 		return nil, err
@@ -143,7 +180,20 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, erro
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
 
-	output, err := run(s.dnfJsonCmd, req)
+	if s.depsolveCache != nil {
+		if pkgs, ok := s.depsolveCache.Get(req.CacheDir, req); ok {
+			// keep the metadata the result depends on in the cache
+			now := time.Now().Local()
+			for _, r := range repoMap {
+				// ignore errors
+				_ = s.cache.touchRepo(r.Hash(), now)
+			}
+			s.cache.updateInfo()
+			return pkgs, nil
+		}
+	}
+
+	output, err := run(ctx, s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -160,12 +210,24 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, erro
 		return nil, err
 	}
 
-	return result.toRPMMD(repoMap), nil
+	pkgs := result.toRPMMD(repoMap)
+	if s.depsolveCache != nil {
+		// ignore errors; the result is depsolved again next time
+		_ = s.depsolveCache.Store(req.CacheDir, req, pkgs)
+	}
+	return pkgs, nil
 }
 
 // FetchMetadata returns the list of all the available packages in repos and
 // their info.
 func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
+	return s.FetchMetadataContext(context.Background(), repos)
+}
+
+// FetchMetadataContext is like FetchMetadata, but the dnf-json process is
+// killed when ctx is cancelled or its deadline expires. A *TimeoutError is
+// returned in this case.
+func (s *Solver) FetchMetadataContext(ctx context.Context, repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
 	req, err := s.makeDumpRequest(repos)
 	if err != nil {
 		return nil, err
@@ -180,7 +242,7 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req)
+	result, err := run(ctx, s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -212,6 +274,13 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 
 // SearchMetadata searches for packages and returns a list of the info for matches.
 func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rpmmd.PackageList, error) {
+	return s.SearchMetadataContext(context.Background(), repos, packages)
+}
+
+// SearchMetadataContext is like SearchMetadata, but the dnf-json process is
+// killed when ctx is cancelled or its deadline expires. A *TimeoutError is
+// returned in this case.
+func (s *Solver) SearchMetadataContext(ctx context.Context, repos []rpmmd.RepoConfig, packages []string) (rpmmd.PackageList, error) {
 	req, err := s.makeSearchRequest(repos, packages)
 	if err != nil {
 		return nil, err
@@ -226,7 +295,7 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req)
+	result, err := run(ctx, s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -459,6 +528,7 @@ func (pkgs packageSpecs) toRPMMD(repos map[string]rpmmd.RepoConfig) []rpmmd.Pack
 		rpmDependencies[i].Arch = dep.Arch
 		rpmDependencies[i].RemoteLocation = dep.RemoteLocation
 		rpmDependencies[i].Checksum = dep.Checksum
+		rpmDependencies[i].InstalledSize = dep.InstalledSize
 		if repo.CheckGPG != nil {
 			rpmDependencies[i].CheckGPG = *repo.CheckGPG
 		}
@@ -558,6 +628,7 @@ type PackageSpec struct {
 	RemoteLocation string `json:"remote_location,omitempty"`
 	Checksum       string `json:"checksum,omitempty"`
 	Secrets        string `json:"secrets,omitempty"`
+	InstalledSize  uint64 `json:"installed_size,omitempty"`
 }
 
 // dnf-json error structure
@@ -570,6 +641,31 @@ func (err Error) Error() string {
 	return fmt.Sprintf("DNF error occurred: %s: %s", err.Kind, err.Reason)
 }
 
+// TimeoutError is returned when a dnf-json request was aborted because its
+// context was cancelled or its deadline expired. Err is the error of the
+// context.
+type TimeoutError struct {
+	Command string
+	Err     error
+}
+
+func (err *TimeoutError) Error() string {
+	if err.Timeout() {
+		return fmt.Sprintf("dnf-json %s request timed out: %s", err.Command, err.Err.Error())
+	}
+	return fmt.Sprintf("dnf-json %s request aborted: %s", err.Command, err.Err.Error())
+}
+
+func (err *TimeoutError) Unwrap() error {
+	return err.Err
+}
+
+// Timeout returns true if the request was aborted because the deadline of
+// its context expired, and false if the context was cancelled.
+func (err *TimeoutError) Timeout() bool {
+	return errors.Is(err.Err, context.DeadlineExceeded)
+}
+
 // parseError parses the response from dnf-json into the Error type and appends
 // the name and URL of a repository to all detected repository IDs in the
 // message.
@@ -603,6 +699,7 @@ func parseError(data []byte, repos []repoConfig) Error {
 
 	return e
 }
+
 func ParseError(data []byte) Error {
 	var e Error
 	if err := json.Unmarshal(data, &e); err != nil {
@@ -615,16 +712,20 @@ func ParseError(data []byte) Error {
 	return e
 }
 
-func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
+func run(ctx context.Context, dnfJsonCmd []string, req *Request) ([]byte, error) {
 	if len(dnfJsonCmd) == 0 {
 		return nil, fmt.Errorf("dnf-json command undefined")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, &TimeoutError{Command: req.Command, Err: err}
+	}
 	ex := dnfJsonCmd[0]
 	args := make([]string, len(dnfJsonCmd)-1)
 	if len(dnfJsonCmd) > 1 {
 		args = dnfJsonCmd[1:]
 	}
-	cmd := exec.Command(ex, args...)
+	// the process is killed when the context is done
+	cmd := exec.CommandContext(ctx, ex, args...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -639,13 +740,18 @@ func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
 		return nil, err
 	}
 
-	err = json.NewEncoder(stdin).Encode(req)
-	if err != nil {
-		return nil, err
-	}
+	// writing the request fails if the process was killed in the meantime,
+	// which is reported by Wait()
+	encodeErr := json.NewEncoder(stdin).Encode(req)
 	stdin.Close()
 
 	err = cmd.Wait()
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return nil, &TimeoutError{Command: req.Command, Err: ctxErr}
+	}
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
 	output := stdout.Bytes()
 	if runError, ok := err.(*exec.ExitError); ok && runError.ExitCode() != 0 {
 		return nil, parseError(output, req.Arguments.Repos)
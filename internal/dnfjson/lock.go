@@ -0,0 +1,165 @@
+package dnfjson
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// LockMismatchError is returned by DepsolveLocked when the depsolved package
+// set differs from the lockfile.
+type LockMismatchError struct {
+	// Locked packages that are not part of the result
+	Missing []rpmmd.PackageSpec
+
+	// Packages of the result that are not locked
+	Unexpected []rpmmd.PackageSpec
+
+	// Packages that were resolved to a different build than the locked one
+	Changed []LockChange
+}
+
+// LockChange is a locked package that was resolved to a different build.
+type LockChange struct {
+	Locked   rpmmd.PackageSpec
+	Resolved rpmmd.PackageSpec
+}
+
+func (err *LockMismatchError) Error() string {
+	lines := []string{"depsolved packages do not match the lockfile:"}
+	for _, pkg := range err.Missing {
+		lines = append(lines, fmt.Sprintf("- %s", pkg.GetNEVRA()))
+	}
+	for _, pkg := range err.Unexpected {
+		lines = append(lines, fmt.Sprintf("+ %s", pkg.GetNEVRA()))
+	}
+	for _, change := range err.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s -> %s", lockedString(change.Locked), lockedString(change.Resolved)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lockedString(pkg rpmmd.PackageSpec) string {
+	if pkg.Checksum == "" {
+		return pkg.GetNEVRA()
+	}
+	return fmt.Sprintf("%s (%s)", pkg.GetNEVRA(), pkg.Checksum)
+}
+
+// DepsolveLocked depsolves the package sets like Depsolve, but requests the
+// exact builds of the packages in lock and fails with a *LockMismatchError
+// if the result is not exactly the locked package set.
+func (s *Solver) DepsolveLocked(pkgSets []rpmmd.PackageSet, lock []rpmmd.PackageSpec) ([]rpmmd.PackageSpec, error) {
+	return s.DepsolveLockedContext(context.Background(), pkgSets, lock)
+}
+
+// DepsolveLockedContext is like DepsolveLocked, but the dnf-json process is
+// killed when ctx is cancelled or its deadline expires.
+func (s *Solver) DepsolveLockedContext(ctx context.Context, pkgSets []rpmmd.PackageSet, lock []rpmmd.PackageSpec) ([]rpmmd.PackageSpec, error) {
+	pkgs, err := s.DepsolveContext(ctx, lockPackageSets(pkgSets, lock))
+	if err != nil {
+		return nil, err
+	}
+	if err := compareLock(lock, pkgs); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// lockPackageSets returns a copy of pkgSets where the includes that name a
+// locked package are replaced by the NEVRAs of all its locked architectures.
+// The locked packages that are not included by any set are added to the last
+// transaction, so the locked build is selected for the dependencies too.
+func lockPackageSets(pkgSets []rpmmd.PackageSet, lock []rpmmd.PackageSpec) []rpmmd.PackageSet {
+	if len(pkgSets) == 0 || len(lock) == 0 {
+		return pkgSets
+	}
+
+	// the same name can be locked for multiple architectures (e.g. x86_64
+	// and i686), so keep the NEVRAs of each name in lock order
+	nevras := make(map[string][]string, len(lock))
+	for _, pkg := range lock {
+		nevras[pkg.Name] = append(nevras[pkg.Name], pkg.GetNEVRA())
+	}
+
+	used := make(map[string]bool)
+	locked := make([]rpmmd.PackageSet, len(pkgSets))
+	for idx, set := range pkgSets {
+		include := make([]string, 0, len(set.Include))
+		for _, name := range set.Include {
+			if pkgNevras, ok := nevras[name]; ok {
+				include = append(include, pkgNevras...)
+				used[name] = true
+			} else {
+				include = append(include, name)
+			}
+		}
+		locked[idx] = rpmmd.PackageSet{
+			Include:         include,
+			Exclude:         append([]string(nil), set.Exclude...),
+			Repositories:    set.Repositories,
+			InstallWeakDeps: set.InstallWeakDeps,
+		}
+	}
+
+	last := &locked[len(locked)-1]
+	for _, pkg := range lock {
+		if !used[pkg.Name] {
+			last.Include = append(last.Include, nevras[pkg.Name]...)
+			used[pkg.Name] = true
+		}
+	}
+	return locked
+}
+
+// compareLock returns a *LockMismatchError describing the differences
+// between the locked and the resolved packages, or nil if they match.
+// Packages are matched by name and architecture and compared by their EVR
+// and, if both have one, their checksum.
+func compareLock(lock, resolved []rpmmd.PackageSpec) error {
+	key := func(pkg rpmmd.PackageSpec) string {
+		return pkg.Name + "." + pkg.Arch
+	}
+
+	resolvedMap := make(map[string]rpmmd.PackageSpec, len(resolved))
+	for _, pkg := range resolved {
+		resolvedMap[key(pkg)] = pkg
+	}
+
+	mismatch := &LockMismatchError{}
+	lockedKeys := make(map[string]bool, len(lock))
+	for _, locked := range lock {
+		lockedKeys[key(locked)] = true
+		pkg, ok := resolvedMap[key(locked)]
+		if !ok {
+			mismatch.Missing = append(mismatch.Missing, locked)
+			continue
+		}
+		if pkg.GetEVRA() != locked.GetEVRA() ||
+			(locked.Checksum != "" && pkg.Checksum != "" && pkg.Checksum != locked.Checksum) {
+			mismatch.Changed = append(mismatch.Changed, LockChange{Locked: locked, Resolved: pkg})
+		}
+	}
+	for _, pkg := range resolved {
+		if !lockedKeys[key(pkg)] {
+			mismatch.Unexpected = append(mismatch.Unexpected, pkg)
+		}
+	}
+
+	if len(mismatch.Missing) == 0 && len(mismatch.Unexpected) == 0 && len(mismatch.Changed) == 0 {
+		return nil
+	}
+
+	byName := func(pkgs []rpmmd.PackageSpec) {
+		sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].GetNEVRA() < pkgs[j].GetNEVRA() })
+	}
+	byName(mismatch.Missing)
+	byName(mismatch.Unexpected)
+	sort.Slice(mismatch.Changed, func(i, j int) bool {
+		return mismatch.Changed[i].Locked.GetNEVRA() < mismatch.Changed[j].Locked.GetNEVRA()
+	})
+	return mismatch
+}
@@ -0,0 +1,115 @@
+package dnfjson
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestLockPackageSets(t *testing.T) {
+	repos := []rpmmd.RepoConfig{{Name: "baseos"}}
+	pkgSets := []rpmmd.PackageSet{
+		{Include: []string{"kernel", "dnf"}, Exclude: []string{"dracut-config-rescue"}, Repositories: repos},
+		{Include: []string{"tmux"}, Repositories: repos},
+	}
+	lock := []rpmmd.PackageSpec{
+		{Name: "kernel", Epoch: 0, Version: "5.14.0", Release: "284.el9", Arch: "x86_64"},
+		{Name: "tmux", Epoch: 0, Version: "3.3a", Release: "3.el9", Arch: "x86_64"},
+		{Name: "glibc", Epoch: 0, Version: "2.34", Release: "60.el9", Arch: "x86_64"},
+		{Name: "shadow-utils", Epoch: 2, Version: "4.9", Release: "6.el9", Arch: "x86_64"},
+	}
+
+	locked := lockPackageSets(pkgSets, lock)
+	assert.Equal(t, []rpmmd.PackageSet{
+		{
+			Include:      []string{"kernel-5.14.0-284.el9.x86_64", "dnf"},
+			Exclude:      []string{"dracut-config-rescue"},
+			Repositories: repos,
+		},
+		{
+			Include: []string{
+				"tmux-3.3a-3.el9.x86_64",
+				"glibc-2.34-60.el9.x86_64",
+				"shadow-utils-2:4.9-6.el9.x86_64",
+			},
+			Repositories: repos,
+		},
+	}, locked)
+
+	// the original package sets are not modified
+	assert.Equal(t, []string{"kernel", "dnf"}, pkgSets[0].Include)
+	assert.Equal(t, []string{"tmux"}, pkgSets[1].Include)
+
+	assert.Equal(t, pkgSets, lockPackageSets(pkgSets, nil))
+}
+
+func TestLockPackageSetsMultiarch(t *testing.T) {
+	pkgSets := []rpmmd.PackageSet{
+		{Include: []string{"glibc"}},
+		{Include: []string{"tmux"}},
+	}
+	lock := []rpmmd.PackageSpec{
+		{Name: "glibc", Version: "2.34", Release: "60.el9", Arch: "x86_64"},
+		{Name: "glibc", Version: "2.34", Release: "60.el9", Arch: "i686"},
+		{Name: "zlib", Version: "1.2.11", Release: "40.el9", Arch: "x86_64"},
+		{Name: "zlib", Version: "1.2.11", Release: "40.el9", Arch: "i686"},
+	}
+
+	locked := lockPackageSets(pkgSets, lock)
+	assert.Equal(t, []string{"glibc-2.34-60.el9.x86_64", "glibc-2.34-60.el9.i686"}, locked[0].Include)
+	assert.Equal(t, []string{"tmux", "zlib-1.2.11-40.el9.x86_64", "zlib-1.2.11-40.el9.i686"}, locked[1].Include)
+}
+
+func TestCompareLock(t *testing.T) {
+	tmux := rpmmd.PackageSpec{Name: "tmux", Version: "3.3a", Release: "3.el9", Arch: "x86_64", Checksum: "sha256:1111"}
+	bash := rpmmd.PackageSpec{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64", Checksum: "sha256:2222"}
+	zsh := rpmmd.PackageSpec{Name: "zsh", Version: "5.8", Release: "9.el9", Arch: "x86_64"}
+
+	assert.NoError(t, compareLock([]rpmmd.PackageSpec{tmux, bash}, []rpmmd.PackageSpec{bash, tmux}))
+
+	newerBash := bash
+	newerBash.Release = "7.el9"
+	newerBash.Checksum = "sha256:3333"
+	rebuiltTmux := tmux
+	rebuiltTmux.Checksum = "sha256:4444"
+
+	err := compareLock([]rpmmd.PackageSpec{tmux, bash, zsh}, []rpmmd.PackageSpec{newerBash, rebuiltTmux, {Name: "ncurses", Version: "6.2", Release: "8.el9", Arch: "x86_64"}})
+	require.Error(t, err)
+	var mismatch *LockMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, []rpmmd.PackageSpec{zsh}, mismatch.Missing)
+	assert.Len(t, mismatch.Unexpected, 1)
+	assert.Equal(t, []LockChange{
+		{Locked: bash, Resolved: newerBash},
+		{Locked: tmux, Resolved: rebuiltTmux},
+	}, mismatch.Changed)
+	assert.Equal(t, `depsolved packages do not match the lockfile:
+- zsh-5.8-9.el9.x86_64
++ ncurses-6.2-8.el9.x86_64
+~ bash-5.1.8-6.el9.x86_64 (sha256:2222) -> bash-5.1.8-7.el9.x86_64 (sha256:3333)
+~ tmux-3.3a-3.el9.x86_64 (sha256:1111) -> tmux-3.3a-3.el9.x86_64 (sha256:4444)`, err.Error())
+}
+
+func TestDepsolveLocked(t *testing.T) {
+	repo := rpmmd.RepoConfig{Name: "baseos", BaseURLs: []string{"https://example.com/baseos"}}
+	pkgSets := []rpmmd.PackageSet{{Include: []string{"tmux"}, Repositories: []rpmmd.RepoConfig{repo}}}
+	script := fmt.Sprintf(`cat > /dev/null
+echo '[{"name": "tmux", "epoch": 0, "version": "3.3a", "release": "3.el9", "arch": "x86_64", "repo_id": "%s", "checksum": "sha256:1111"}]'
+`, repo.Hash())
+	solver := fakeDNFJSON(t, script)
+
+	lock := []rpmmd.PackageSpec{{Name: "tmux", Version: "3.3a", Release: "3.el9", Arch: "x86_64", Checksum: "sha256:1111"}}
+	pkgs, err := solver.DepsolveLocked(pkgSets, lock)
+	require.NoError(t, err)
+	assert.Equal(t, lock, pkgs)
+
+	lock[0].Release = "2.el9"
+	_, err = solver.DepsolveLocked(pkgSets, lock)
+	var mismatch *LockMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Len(t, mismatch.Changed, 1)
+}
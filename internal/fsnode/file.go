@@ -1,12 +1,22 @@
 package fsnode
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"os"
+	"regexp"
 )
 
+var sha256Regex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 type File struct {
 	baseFsNode
 	data []byte
+
+	// url and sha256 are set for files whose content is fetched when the
+	// image is built
+	url    string
+	sha256 string
 }
 
 func (f *File) IsDir() bool {
@@ -20,6 +30,29 @@ func (f *File) Data() []byte {
 	return f.data
 }
 
+// URL returns the URL the content of the file is fetched from, or an empty
+// string if the content is inline.
+func (f *File) URL() string {
+	if f == nil {
+		return ""
+	}
+	return f.url
+}
+
+// IsRemote returns true if the content of the file is fetched from a URL.
+func (f *File) IsRemote() bool {
+	return f.URL() != ""
+}
+
+// Checksum returns the checksum of the content of the file in the form
+// "sha256:<hex>".
+func (f *File) Checksum() string {
+	if f.IsRemote() {
+		return "sha256:" + f.sha256
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(f.Data()))
+}
+
 // NewFile creates a new file with the given path, data, mode, user and group.
 // user and group can be either a string (user name/group name), an int64 (UID/GID) or nil.
 func NewFile(path string, mode *os.FileMode, user interface{}, group interface{}, data []byte) (*File, error) {
@@ -34,3 +67,26 @@ func NewFile(path string, mode *os.FileMode, user interface{}, group interface{}
 		data:       data,
 	}, nil
 }
+
+// NewRemoteFile creates a new file whose content is fetched from the given
+// URL when the image is built. The content must match the given hex encoded
+// sha256 checksum.
+func NewRemoteFile(path string, mode *os.FileMode, user interface{}, group interface{}, url string, checksum string) (*File, error) {
+	baseNode, err := newBaseFsNode(path, mode, user, group)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("url must not be empty")
+	}
+	if !sha256Regex.MatchString(checksum) {
+		return nil, fmt.Errorf("sha256 checksum %q is invalid", checksum)
+	}
+
+	return &File{
+		baseFsNode: *baseNode,
+		url:        url,
+		sha256:     checksum,
+	}, nil
+}
@@ -79,3 +79,30 @@ func TestNewFile(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRemoteFile(t *testing.T) {
+	checksum := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	file, err := NewRemoteFile("/usr/local/bin/agent", common.ToPtr(os.FileMode(0755)), "root", nil, "https://example.com/agent", checksum)
+	assert.NoError(t, err)
+	assert.True(t, file.IsRemote())
+	assert.Equal(t, "https://example.com/agent", file.URL())
+	assert.Equal(t, "sha256:"+checksum, file.Checksum())
+	assert.Nil(t, file.Data())
+
+	_, err = NewRemoteFile("/etc/file", nil, nil, nil, "", checksum)
+	assert.EqualError(t, err, "url must not be empty")
+
+	_, err = NewRemoteFile("/etc/file", nil, nil, nil, "https://example.com/file", "sha256:"+checksum)
+	assert.EqualError(t, err, `sha256 checksum "sha256:`+checksum+`" is invalid`)
+
+	_, err = NewRemoteFile("etc/file", nil, nil, nil, "https://example.com/file", checksum)
+	assert.Error(t, err)
+}
+
+func TestFileChecksum(t *testing.T) {
+	file, err := NewFile("/etc/file", nil, nil, nil, []byte("hello world"))
+	assert.NoError(t, err)
+	assert.False(t, file.IsRemote())
+	assert.Equal(t, "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", file.Checksum())
+}
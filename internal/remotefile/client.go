@@ -1,6 +1,7 @@
 package remotefile
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
@@ -58,3 +59,18 @@ func (c *Client) Resolve(u string) ([]byte, error) {
 
 	return c.makeRequest(parsedURL)
 }
+
+// resolve the contents of a remote file and verify them
+// against the expected checksum in the form "sha256:<hex>"
+func (c *Client) ResolveWithChecksum(u string, checksum string) ([]byte, error) {
+	content, err := c.Resolve(u)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+	if actual != checksum {
+		return nil, fmt.Errorf("File resolver: checksum mismatch for %s: expected %s, got %s", u, checksum, actual)
+	}
+	return content, nil
+}
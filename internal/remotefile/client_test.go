@@ -1,6 +1,7 @@
 package remotefile
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -71,3 +72,20 @@ func TestInputSpecValidation(t *testing.T) {
 	}
 
 }
+
+func TestClientResolveWithChecksum(t *testing.T) {
+	server := makeTestServer()
+	url := server.URL + "/key1"
+	client := NewClient()
+
+	// sha256 of "key1\n"
+	checksum := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("key1\n")))
+	output, err := client.ResolveWithChecksum(url, checksum)
+	assert.NoError(t, err)
+	assert.Equal(t, "key1\n", string(output))
+
+	wrong := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("key2\n")))
+	output, err = client.ResolveWithChecksum(url, wrong)
+	assert.EqualError(t, err, fmt.Sprintf("File resolver: checksum mismatch for %s: expected %s, got %s", url, wrong, checksum))
+	assert.Nil(t, output)
+}
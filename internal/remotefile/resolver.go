@@ -7,9 +7,10 @@ import (
 )
 
 type resolveResult struct {
-	url     string
-	content []byte
-	err     error
+	url      string
+	checksum string
+	content  []byte
+	err      error
 }
 
 // TODO: could make this more generic
@@ -39,6 +40,17 @@ func (r *Resolver) Add(url string) {
 	}()
 }
 
+// AddWithChecksum resolves the url like Add, but the content must match the
+// given checksum in the form "sha256:<hex>".
+func (r *Resolver) AddWithChecksum(url string, checksum string) {
+	client := NewClient()
+	r.jobs += 1
+	go func() {
+		content, err := client.ResolveWithChecksum(url, checksum)
+		r.queue <- resolveResult{url: url, checksum: checksum, content: content, err: err}
+	}()
+}
+
 func (r *Resolver) Finish() []Spec {
 
 	resultItems := make([]Spec, 0, r.jobs)
@@ -57,6 +69,7 @@ func (r *Resolver) Finish() []Spec {
 
 		resultItems = append(resultItems, Spec{
 			URL:             result.url,
+			Checksum:        result.checksum,
 			Content:         result.content,
 			ResolutionError: resultError,
 		})
@@ -1,6 +1,7 @@
 package remotefile
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"testing"
 
@@ -100,3 +101,30 @@ func TestMultiInvalidInputResolver(t *testing.T) {
 	assert.Contains(t, errs, expectedErrMessageOne)
 	assert.Contains(t, errs, expectedErrMessageTwo)
 }
+
+func TestChecksumResolver(t *testing.T) {
+	server := makeTestServer()
+	urlOne := server.URL + "/key1"
+	urlTwo := server.URL + "/key2"
+
+	checksumOne := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("key1\n")))
+
+	resolver := NewResolver()
+	resolver.AddWithChecksum(urlOne, checksumOne)
+	// the checksum of the wrong file
+	resolver.AddWithChecksum(urlTwo, checksumOne)
+
+	resultItems := resolver.Finish()
+	assert.Len(t, resultItems, 2)
+	for _, item := range resultItems {
+		assert.Equal(t, checksumOne, item.Checksum)
+		if item.URL == urlOne {
+			assert.Nil(t, item.ResolutionError)
+			assert.Equal(t, []byte("key1\n"), item.Content)
+		} else {
+			assert.NotNil(t, item.ResolutionError)
+			assert.Contains(t, item.ResolutionError.Reason, "checksum mismatch")
+			assert.Nil(t, item.Content)
+		}
+	}
+}
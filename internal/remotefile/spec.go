@@ -6,4 +6,14 @@ type Spec struct {
 	URL             string
 	Content         []byte
 	ResolutionError *clienterrors.Error
+
+	// Checksum the content was verified against, if any
+	Checksum string
+}
+
+// SourceSpec describes a remote file that is embedded in an image. Its
+// content must match the checksum in the form "sha256:<hex>".
+type SourceSpec struct {
+	URL      string
+	Checksum string
 }
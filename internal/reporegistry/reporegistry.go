@@ -30,6 +30,20 @@ func NewFromDistrosRepoConfigs(distrosRepoConfigs rpmmd.DistrosRepoConfigs) *Rep
 	return &RepoRegistry{distrosRepoConfigs}
 }
 
+// AddDistroRepos adds the repositories of a distro that is not defined in
+// the loaded repository configuration, e.g. a derivative distro registered at
+// runtime. The repositories are keyed by architecture.
+func (r *RepoRegistry) AddDistroRepos(distro string, repos map[string][]rpmmd.RepoConfig) error {
+	if _, exists := r.repos[distro]; exists {
+		return fmt.Errorf("repositories for distribution '%s' are already defined", distro)
+	}
+	if r.repos == nil {
+		r.repos = rpmmd.DistrosRepoConfigs{}
+	}
+	r.repos[distro] = repos
+	return nil
+}
+
 // ReposByImageType returns a slice of rpmmd.RepoConfig instances, which should be used for building the specific
 // image type. All repositories for the associated distribution and architecture, without any ImageTypeTags set,
 // are always part of the returned slice. In addition, if there are repositories tagged with the specific image
@@ -391,3 +391,23 @@ func TestInvalidReposByArchName(t *testing.T) {
 		})
 	}
 }
+
+func TestAddDistroRepos(t *testing.T) {
+	rr := getTestingRepoRegistry()
+
+	repos := map[string][]rpmmd.RepoConfig{
+		"x86_64": {
+			{
+				Name:     "baseos",
+				BaseURLs: []string{"https://dl.rockylinux.org/pub/rocky/9/BaseOS/x86_64/os/"},
+			},
+		},
+	}
+	assert.NoError(t, rr.AddDistroRepos("rocky-93", repos))
+
+	got, err := rr.ReposByArchName("rocky-93", "x86_64", true)
+	assert.NoError(t, err)
+	assert.Equal(t, repos["x86_64"], got)
+
+	assert.EqualError(t, rr.AddDistroRepos(test_distro.TestDistroName, repos), "repositories for distribution 'test-distro' are already defined")
+}
@@ -12,6 +12,18 @@ type User struct {
 	Groups      []string
 	UID         *int
 	GID         *int
+
+	// Expiry date of the account in days since the epoch
+	ExpireDate         *int
+	ForcePasswordReset *bool
+
+	// Password aging in days; not supported by the users stage
+	PasswordMinDays  *int
+	PasswordMaxDays  *int
+	PasswordWarnDays *int
+
+	// Lock the password of the user; takes precedence over Password
+	Locked bool
 }
 
 type Group struct {
@@ -19,12 +31,60 @@ type Group struct {
 	GID  *int
 }
 
-func UsersFromBP(userCustomizations []blueprint.UserCustomization) []User {
+func UsersFromBP(userCustomizations []blueprint.UserCustomization) ([]User, error) {
 	users := make([]User, len(userCustomizations))
 	for idx := range userCustomizations {
-		// currently, they have the same structure, so we convert directly
-		// this will fail to compile as soon as one of the two changes
-		users[idx] = User(userCustomizations[idx])
+		uc := &userCustomizations[idx]
+		expireDate, err := uc.ExpireDays()
+		if err != nil {
+			return nil, err
+		}
+		users[idx] = User{
+			Name:               uc.Name,
+			Description:        uc.Description,
+			Password:           uc.Password,
+			Key:                uc.Key,
+			Home:               uc.Home,
+			Shell:              uc.Shell,
+			Groups:             uc.Groups,
+			UID:                uc.UID,
+			GID:                uc.GID,
+			ExpireDate:         expireDate,
+			ForcePasswordReset: uc.ForcePasswordReset,
+			PasswordMinDays:    uc.PasswordMinDays,
+			PasswordMaxDays:    uc.PasswordMaxDays,
+			PasswordWarnDays:   uc.PasswordWarnDays,
+		}
+	}
+	return users, nil
+}
+
+// the login shell of disabled accounts
+const nologinShell = "/sbin/nologin"
+
+// WithRootAccount applies the root account policy to the users. The root
+// user is added if it is not in the list.
+func WithRootAccount(users []User, root *blueprint.RootAccountCustomization) []User {
+	if root == nil || !(root.Lock || root.Disable) {
+		return users
+	}
+
+	idx := -1
+	for i := range users {
+		if users[i].Name == "root" {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		users = append(users, User{Name: "root"})
+		idx = len(users) - 1
+	}
+
+	users[idx].Locked = true
+	if root.Disable {
+		shell := nologinShell
+		users[idx].Shell = &shell
 	}
 	return users
 }
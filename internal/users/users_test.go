@@ -0,0 +1,58 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+func TestUsersFromBPAccountPolicy(t *testing.T) {
+	users, err := UsersFromBP([]blueprint.UserCustomization{
+		{
+			Name:               "alice",
+			ExpireDate:         common.ToPtr("2025-01-01"),
+			ForcePasswordReset: common.ToPtr(true),
+			PasswordMinDays:    common.ToPtr(1),
+			PasswordMaxDays:    common.ToPtr(90),
+			PasswordWarnDays:   common.ToPtr(7),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []User{
+		{
+			Name:               "alice",
+			ExpireDate:         common.ToPtr(20089),
+			ForcePasswordReset: common.ToPtr(true),
+			PasswordMinDays:    common.ToPtr(1),
+			PasswordMaxDays:    common.ToPtr(90),
+			PasswordWarnDays:   common.ToPtr(7),
+		},
+	}, users)
+}
+
+func TestUsersFromBPInvalidExpireDate(t *testing.T) {
+	_, err := UsersFromBP([]blueprint.UserCustomization{
+		{Name: "alice", ExpireDate: common.ToPtr("01/01/2025")},
+	})
+	assert.EqualError(t, err, `user "alice": expire date "01/01/2025" is not in the YYYY-MM-DD format`)
+}
+
+func TestWithRootAccount(t *testing.T) {
+	key := "ssh-ed25519 AAAA"
+	users := []User{{Name: "alice"}, {Name: "root", Key: &key}}
+
+	assert.Equal(t, users, WithRootAccount(users, nil))
+	assert.Equal(t, users, WithRootAccount(users, &blueprint.RootAccountCustomization{}))
+
+	// the existing root user is locked and keeps its key
+	locked := WithRootAccount([]User{{Name: "alice"}, {Name: "root", Key: &key}}, &blueprint.RootAccountCustomization{Lock: true})
+	assert.Equal(t, []User{{Name: "alice"}, {Name: "root", Key: &key, Locked: true}}, locked)
+
+	// root is added if it's not in the list
+	disabled := WithRootAccount([]User{{Name: "alice"}}, &blueprint.RootAccountCustomization{Disable: true})
+	assert.Equal(t, []User{{Name: "alice"}, {Name: "root", Shell: common.ToPtr("/sbin/nologin"), Locked: true}}, disabled)
+}
@@ -3,6 +3,7 @@ package workload
 type Custom struct {
 	BaseWorkload
 	Packages         []string
+	ExcludePackages  []string
 	Services         []string
 	DisabledServices []string
 }
@@ -11,6 +12,10 @@ func (p *Custom) GetPackages() []string {
 	return p.Packages
 }
 
+func (p *Custom) GetExcludePackages() []string {
+	return p.ExcludePackages
+}
+
 func (p *Custom) GetServices() []string {
 	return p.Services
 }
@@ -7,6 +7,8 @@ type Workload interface {
 	GetRepos() []rpmmd.RepoConfig
 	GetServices() []string
 	GetDisabledServices() []string
+	// GetExcludePackages returns the packages that must not be installed
+	GetExcludePackages() []string
 }
 
 type BaseWorkload struct {
@@ -28,3 +30,7 @@ func (p BaseWorkload) GetServices() []string {
 func (p BaseWorkload) GetDisabledServices() []string {
 	return []string{}
 }
+
+func (p BaseWorkload) GetExcludePackages() []string {
+	return []string{}
+}
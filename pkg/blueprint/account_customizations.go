@@ -0,0 +1,200 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// SudoersDir is the directory the sudo rules are written to.
+const SudoersDir = "/etc/sudoers.d"
+
+// format of the expiry date of users
+const userExpireDateLayout = "2006-01-02"
+
+var (
+	// sudo ignores files in sudoers.d that contain a "." or end with "~"
+	sudoRuleNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	sudoUserRegex     = regexp.MustCompile(`^%?[a-z_][a-z0-9_.-]*\$?$`)
+	sudoHostRegex     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+)
+
+// AccountsCustomization configures account policies that go beyond the
+// creation of users.
+type AccountsCustomization struct {
+	Root *RootAccountCustomization `json:"root,omitempty" toml:"root,omitempty"`
+	// Rules written to /etc/sudoers.d
+	Sudo []SudoRuleCustomization `json:"sudo,omitempty" toml:"sudo,omitempty"`
+}
+
+type RootAccountCustomization struct {
+	// Lock the password of root. SSH keys of root keep working.
+	Lock bool `json:"lock,omitempty" toml:"lock,omitempty"`
+	// Lock the password of root and disable its login shell
+	Disable bool `json:"disable,omitempty" toml:"disable,omitempty"`
+}
+
+// SudoRuleCustomization is a sudo rule in a file of its own in
+// /etc/sudoers.d, e.g.
+//
+//	%admins ALL=(ALL) NOPASSWD: /usr/bin/systemctl restart httpd
+type SudoRuleCustomization struct {
+	// Name of the file in /etc/sudoers.d
+	Name string `json:"name" toml:"name"`
+	// Users, or groups prefixed with "%", the rule applies to
+	Users []string `json:"users" toml:"users"`
+	// Hosts the rule applies to; defaults to ALL
+	Hosts []string `json:"hosts,omitempty" toml:"hosts,omitempty"`
+	// Users the commands can be run as; defaults to ALL
+	RunAs []string `json:"run_as,omitempty" toml:"run_as,omitempty"`
+	// ALL or commands with absolute paths and optional arguments
+	Commands   []string `json:"commands" toml:"commands"`
+	NoPassword bool     `json:"nopasswd,omitempty" toml:"nopasswd,omitempty"`
+}
+
+// checkAccountPolicy validates the account policy fields of a user.
+func (u *UserCustomization) checkAccountPolicy() error {
+	if u.ExpireDate != nil {
+		if _, err := time.Parse(userExpireDateLayout, *u.ExpireDate); err != nil {
+			return fmt.Errorf("user %q: expire date %q is not in the YYYY-MM-DD format", u.Name, *u.ExpireDate)
+		}
+	}
+	if u.PasswordMinDays != nil && *u.PasswordMinDays < 0 {
+		return fmt.Errorf("user %q: password_min_days must not be negative", u.Name)
+	}
+	if u.PasswordMaxDays != nil && *u.PasswordMaxDays < 1 {
+		return fmt.Errorf("user %q: password_max_days must be positive", u.Name)
+	}
+	if u.PasswordWarnDays != nil && *u.PasswordWarnDays < 0 {
+		return fmt.Errorf("user %q: password_warn_days must not be negative", u.Name)
+	}
+	if u.PasswordMinDays != nil && u.PasswordMaxDays != nil && *u.PasswordMinDays > *u.PasswordMaxDays {
+		return fmt.Errorf("user %q: password_min_days must not be greater than password_max_days", u.Name)
+	}
+	return nil
+}
+
+// ExpireDays returns the expiry date of the user in days since the epoch, or
+// nil if the user doesn't expire.
+func (u *UserCustomization) ExpireDays() (*int, error) {
+	if u.ExpireDate == nil {
+		return nil, nil
+	}
+	date, err := time.Parse(userExpireDateLayout, *u.ExpireDate)
+	if err != nil {
+		return nil, fmt.Errorf("user %q: expire date %q is not in the YYYY-MM-DD format", u.Name, *u.ExpireDate)
+	}
+	return common.ToPtr(int(date.Unix() / (24 * 60 * 60))), nil
+}
+
+func (r *SudoRuleCustomization) validate() error {
+	if !sudoRuleNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("sudo rule name %q is invalid", r.Name)
+	}
+	if len(r.Users) == 0 {
+		return fmt.Errorf("sudo rule %q: at least one user is required", r.Name)
+	}
+	for _, user := range r.Users {
+		if !sudoUserRegex.MatchString(user) {
+			return fmt.Errorf("sudo rule %q: user %q is invalid", r.Name, user)
+		}
+	}
+	for _, host := range r.Hosts {
+		if host != "ALL" && !sudoHostRegex.MatchString(host) {
+			return fmt.Errorf("sudo rule %q: host %q is invalid", r.Name, host)
+		}
+	}
+	for _, user := range r.RunAs {
+		if user != "ALL" && !sudoUserRegex.MatchString(user) {
+			return fmt.Errorf("sudo rule %q: run_as user %q is invalid", r.Name, user)
+		}
+	}
+	if len(r.Commands) == 0 {
+		return fmt.Errorf("sudo rule %q: at least one command is required", r.Name)
+	}
+	for _, cmd := range r.Commands {
+		if strings.ContainsAny(cmd, "\n\r") {
+			return fmt.Errorf("sudo rule %q: commands must not contain newlines", r.Name)
+		}
+		if cmd == "ALL" {
+			continue
+		}
+		// like visudo, require fully qualified commands
+		if path := strings.Fields(cmd); len(path) == 0 || !filepath.IsAbs(path[0]) {
+			return fmt.Errorf("sudo rule %q: command %q must be ALL or an absolute path", r.Name, cmd)
+		}
+	}
+	return nil
+}
+
+func (a *AccountsCustomization) Validate() error {
+	names := make(map[string]bool, len(a.Sudo))
+	for idx := range a.Sudo {
+		rule := &a.Sudo[idx]
+		if err := rule.validate(); err != nil {
+			return err
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate sudo rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// sudoEscaper escapes the characters that are special in the arguments of
+// sudoers commands
+var sudoEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `:`, `\:`, `=`, `\=`)
+
+// sudoers renders the rule in the sudoers format.
+func (r *SudoRuleCustomization) sudoers() string {
+	hosts := []string{"ALL"}
+	if len(r.Hosts) > 0 {
+		hosts = r.Hosts
+	}
+	runAs := []string{"ALL"}
+	if len(r.RunAs) > 0 {
+		runAs = r.RunAs
+	}
+	tag := ""
+	if r.NoPassword {
+		tag = "NOPASSWD: "
+	}
+
+	commands := make([]string, len(r.Commands))
+	for idx, cmd := range r.Commands {
+		commands[idx] = sudoEscaper.Replace(cmd)
+	}
+
+	return fmt.Sprintf("%s %s=(%s) %s%s\n",
+		strings.Join(r.Users, ","),
+		strings.Join(hosts, ","),
+		strings.Join(runAs, ","),
+		tag,
+		strings.Join(commands, ", "))
+}
+
+// SudoRulesToFsNodeFiles returns the sudoers files of the sudo rules.
+func SudoRulesToFsNodeFiles(accounts *AccountsCustomization) ([]*fsnode.File, error) {
+	if accounts == nil || len(accounts.Sudo) == 0 {
+		return nil, nil
+	}
+
+	files := make([]*fsnode.File, 0, len(accounts.Sudo))
+	for idx := range accounts.Sudo {
+		rule := &accounts.Sudo[idx]
+		path := filepath.Join(SudoersDir, rule.Name)
+		file, err := fsnode.NewFile(path, common.ToPtr(os.FileMode(0440)), nil, nil, []byte(rule.sudoers()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
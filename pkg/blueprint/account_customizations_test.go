@@ -0,0 +1,168 @@
+package blueprint
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestGetAccounts(t *testing.T) {
+	testCases := map[string]struct {
+		customizations Customizations
+		wantErr        string
+	}{
+		"valid": {
+			customizations: Customizations{
+				User: []UserCustomization{
+					{Name: "root", Key: common.ToPtr("ssh-ed25519 AAAA")},
+					{Name: "alice", ExpireDate: common.ToPtr("2030-12-31"), PasswordMinDays: common.ToPtr(1), PasswordMaxDays: common.ToPtr(90), PasswordWarnDays: common.ToPtr(7)},
+				},
+				Accounts: &AccountsCustomization{
+					Root: &RootAccountCustomization{Lock: true},
+					Sudo: []SudoRuleCustomization{
+						{Name: "admins", Users: []string{"%admins", "alice"}, Commands: []string{"ALL"}},
+						{Name: "web-ops", Users: []string{"bob"}, RunAs: []string{"apache"}, Commands: []string{"/usr/bin/systemctl restart httpd"}, NoPassword: true},
+					},
+				},
+			},
+		},
+		"invalid-expire-date": {
+			customizations: Customizations{User: []UserCustomization{{Name: "alice", ExpireDate: common.ToPtr("31.12.2030")}}},
+			wantErr:        `user "alice": expire date "31.12.2030" is not in the YYYY-MM-DD format`,
+		},
+		"negative-min-days": {
+			customizations: Customizations{User: []UserCustomization{{Name: "alice", PasswordMinDays: common.ToPtr(-1)}}},
+			wantErr:        `user "alice": password_min_days must not be negative`,
+		},
+		"zero-max-days": {
+			customizations: Customizations{User: []UserCustomization{{Name: "alice", PasswordMaxDays: common.ToPtr(0)}}},
+			wantErr:        `user "alice": password_max_days must be positive`,
+		},
+		"min-greater-than-max": {
+			customizations: Customizations{User: []UserCustomization{{Name: "alice", PasswordMinDays: common.ToPtr(30), PasswordMaxDays: common.ToPtr(7)}}},
+			wantErr:        `user "alice": password_min_days must not be greater than password_max_days`,
+		},
+		"lock-root-with-password": {
+			customizations: Customizations{
+				User:     []UserCustomization{{Name: "root", Password: common.ToPtr("secret")}},
+				Accounts: &AccountsCustomization{Root: &RootAccountCustomization{Lock: true}},
+			},
+			wantErr: "the root account cannot be locked when a password is set for it",
+		},
+		"disable-root-with-shell": {
+			customizations: Customizations{
+				User:     []UserCustomization{{Name: "root", Shell: common.ToPtr("/bin/zsh")}},
+				Accounts: &AccountsCustomization{Root: &RootAccountCustomization{Disable: true}},
+			},
+			wantErr: "the root account cannot be disabled when a shell is set for it",
+		},
+		"invalid-sudo-rule-name": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins.conf", Users: []string{"alice"}, Commands: []string{"ALL"}}}}},
+			wantErr:        `sudo rule name "admins.conf" is invalid`,
+		},
+		"duplicate-sudo-rule": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{
+				{Name: "admins", Users: []string{"alice"}, Commands: []string{"ALL"}},
+				{Name: "admins", Users: []string{"bob"}, Commands: []string{"ALL"}},
+			}}},
+			wantErr: `duplicate sudo rule "admins"`,
+		},
+		"sudo-rule-without-users": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Commands: []string{"ALL"}}}}},
+			wantErr:        `sudo rule "admins": at least one user is required`,
+		},
+		"invalid-sudo-user": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice ALL=(ALL) ALL"}, Commands: []string{"ALL"}}}}},
+			wantErr:        `sudo rule "admins": user "alice ALL=(ALL) ALL" is invalid`,
+		},
+		"invalid-sudo-host": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice"}, Hosts: []string{"-host"}, Commands: []string{"ALL"}}}}},
+			wantErr:        `sudo rule "admins": host "-host" is invalid`,
+		},
+		"invalid-sudo-run-as": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice"}, RunAs: []string{"root)"}, Commands: []string{"ALL"}}}}},
+			wantErr:        `sudo rule "admins": run_as user "root)" is invalid`,
+		},
+		"sudo-rule-without-commands": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice"}}}}},
+			wantErr:        `sudo rule "admins": at least one command is required`,
+		},
+		"relative-sudo-command": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice"}, Commands: []string{"systemctl restart httpd"}}}}},
+			wantErr:        `sudo rule "admins": command "systemctl restart httpd" must be ALL or an absolute path`,
+		},
+		"multiline-sudo-command": {
+			customizations: Customizations{Accounts: &AccountsCustomization{Sudo: []SudoRuleCustomization{{Name: "admins", Users: []string{"alice"}, Commands: []string{"/usr/bin/true\nbob ALL=(ALL) ALL"}}}}},
+			wantErr:        `sudo rule "admins": commands must not contain newlines`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			accounts, err := tc.customizations.GetAccounts()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.customizations.Accounts, accounts)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetAccountsEmpty(t *testing.T) {
+	var c *Customizations
+	accounts, err := c.GetAccounts()
+	assert.NoError(t, err)
+	assert.Nil(t, accounts)
+}
+
+func TestUserExpireDays(t *testing.T) {
+	u := UserCustomization{Name: "alice"}
+	days, err := u.ExpireDays()
+	assert.NoError(t, err)
+	assert.Nil(t, days)
+
+	u.ExpireDate = common.ToPtr("1970-01-11")
+	days, err = u.ExpireDays()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, *days)
+}
+
+func TestSudoRulesToFsNodeFiles(t *testing.T) {
+	accounts := &AccountsCustomization{
+		Sudo: []SudoRuleCustomization{
+			{Name: "admins", Users: []string{"%admins", "alice"}, Commands: []string{"ALL"}},
+			{
+				Name:       "web-ops",
+				Users:      []string{"bob"},
+				Hosts:      []string{"web01"},
+				RunAs:      []string{"root", "apache"},
+				Commands:   []string{"/usr/bin/systemctl restart httpd", "/usr/bin/journalctl -u httpd --since=today"},
+				NoPassword: true,
+			},
+		},
+	}
+	require.NoError(t, accounts.Validate())
+
+	files, err := SudoRulesToFsNodeFiles(accounts)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/sudoers.d/admins", files[0].Path())
+	assert.Equal(t, os.FileMode(0440), *files[0].Mode())
+	assert.Equal(t, "%admins,alice ALL=(ALL) ALL\n", string(files[0].Data()))
+
+	assert.Equal(t, "/etc/sudoers.d/web-ops", files[1].Path())
+	assert.Equal(t, `bob web01=(root,apache) NOPASSWD: /usr/bin/systemctl restart httpd, /usr/bin/journalctl -u httpd --since\=today`+"\n", string(files[1].Data()))
+}
+
+func TestSudoRulesToFsNodeFilesEmpty(t *testing.T) {
+	files, err := SudoRulesToFsNodeFiles(&AccountsCustomization{Root: &RootAccountCustomization{Lock: true}})
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
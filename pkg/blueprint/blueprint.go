@@ -2,16 +2,22 @@
 package blueprint
 
 // A Blueprint is a high-level description of an image.
+//
+// PackagePins are exact name-[epoch:]version-release.arch of packages to
+// install. ExcludePackages are packages that must not be installed, not even
+// as dependencies.
 type Blueprint struct {
-	Name           string          `json:"name" toml:"name"`
-	Description    string          `json:"description" toml:"description"`
-	Version        string          `json:"version,omitempty" toml:"version,omitempty"`
-	Packages       []Package       `json:"packages" toml:"packages"`
-	Modules        []Package       `json:"modules" toml:"modules"`
-	Groups         []Group         `json:"groups" toml:"groups"`
-	Containers     []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
-	Customizations *Customizations `json:"customizations,omitempty" toml:"customizations"`
-	Distro         string          `json:"distro" toml:"distro"`
+	Name            string          `json:"name" toml:"name"`
+	Description     string          `json:"description" toml:"description"`
+	Version         string          `json:"version,omitempty" toml:"version,omitempty"`
+	Packages        []Package       `json:"packages" toml:"packages"`
+	Modules         []Package       `json:"modules" toml:"modules"`
+	Groups          []Group         `json:"groups" toml:"groups"`
+	PackagePins     []string        `json:"package_pins,omitempty" toml:"package_pins,omitempty"`
+	ExcludePackages []string        `json:"exclude_packages,omitempty" toml:"exclude_packages,omitempty"`
+	Containers      []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
+	Customizations  *Customizations `json:"customizations,omitempty" toml:"customizations"`
+	Distro          string          `json:"distro" toml:"distro"`
 }
 
 type Change struct {
@@ -57,6 +63,14 @@ func (b *Blueprint) GetPackagesEx(bootable bool) []string {
 	for _, group := range b.Groups {
 		packages = append(packages, "@"+group.Name)
 	}
+	for _, nevra := range b.PackagePins {
+		// pins are validated with the rest of the blueprint; pass invalid
+		// ones on as they are and let the depsolver reject them
+		if pin, err := ParsePackagePin(nevra); err == nil {
+			nevra = pin.String()
+		}
+		packages = append(packages, nevra)
+	}
 
 	if bootable {
 		kc := b.Customizations.GetKernel()
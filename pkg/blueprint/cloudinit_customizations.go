@@ -0,0 +1,263 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// CloudInitConfigDir is the directory of the cloud-init configuration files.
+const CloudInitConfigDir = "/etc/cloud/cloud.cfg.d"
+
+// CloudInitConfigFile is the cloud-init configuration file with the
+// datasources, the default user and the module lists of the blueprint. The
+// configuration files are read in lexical order, so it overrides the defaults
+// of the image type.
+const CloudInitConfigFile = CloudInitConfigDir + "/95-blueprint.cfg"
+
+var (
+	cloudInitDatasourceRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+	cloudInitModuleRegex     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	cloudInitSnippetRegex    = regexp.MustCompile(`^[\w.-]{1,250}\.cfg$`)
+	// user and group names
+	cloudInitAccountRegex = regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,31}$`)
+)
+
+// CloudInitCustomization configures cloud-init in addition to the
+// configuration of the image type.
+type CloudInitCustomization struct {
+	// Datasources cloud-init looks for, in order, e.g. OpenStack. They
+	// replace the datasources of the image type.
+	Datasources []string `json:"datasources,omitempty" toml:"datasources,omitempty"`
+	// User created by cloud-init that the instance credentials are added to
+	DefaultUser *CloudInitDefaultUserCustomization `json:"default_user,omitempty" toml:"default_user,omitempty"`
+	// Modules that cloud-init doesn't run, e.g. growpart
+	DisabledModules []string `json:"disabled_modules,omitempty" toml:"disabled_modules,omitempty"`
+	// Additional configuration files written to /etc/cloud/cloud.cfg.d
+	Snippets []CloudInitSnippetCustomization `json:"snippets,omitempty" toml:"snippets,omitempty"`
+}
+
+// CloudInitModules are the modules cloud-init runs in each of its stages, as
+// listed in /etc/cloud/cloud.cfg of the cloud-init package of a distro.
+type CloudInitModules struct {
+	Init   []string `json:"cloud_init_modules" yaml:"cloud_init_modules"`
+	Config []string `json:"cloud_config_modules" yaml:"cloud_config_modules"`
+	Final  []string `json:"cloud_final_modules" yaml:"cloud_final_modules"`
+}
+
+type CloudInitDefaultUserCustomization struct {
+	Name   string   `json:"name" toml:"name"`
+	Groups []string `json:"groups,omitempty" toml:"groups,omitempty"`
+}
+
+type CloudInitSnippetCustomization struct {
+	// Name of the file, e.g. 50-network.cfg
+	Filename string `json:"filename" toml:"filename"`
+	// YAML configuration
+	Contents string `json:"contents" toml:"contents"`
+}
+
+func (c *CloudInitCustomization) Validate() error {
+	if len(c.Datasources) == 0 && c.DefaultUser == nil && len(c.DisabledModules) == 0 && len(c.Snippets) == 0 {
+		return fmt.Errorf("cloud-init customization must not be empty")
+	}
+
+	for _, ds := range c.Datasources {
+		if !cloudInitDatasourceRegex.MatchString(ds) {
+			return fmt.Errorf("cloud-init datasource name %q is invalid", ds)
+		}
+	}
+
+	if du := c.DefaultUser; du != nil {
+		if !cloudInitAccountRegex.MatchString(du.Name) {
+			return fmt.Errorf("cloud-init default user name %q is invalid", du.Name)
+		}
+		for _, group := range du.Groups {
+			if !cloudInitAccountRegex.MatchString(group) {
+				return fmt.Errorf("cloud-init default user group name %q is invalid", group)
+			}
+		}
+	}
+
+	for _, module := range c.DisabledModules {
+		if !cloudInitModuleRegex.MatchString(module) {
+			return fmt.Errorf("cloud-init module name %q is invalid", module)
+		}
+	}
+
+	filenames := make(map[string]bool, len(c.Snippets))
+	for _, snippet := range c.Snippets {
+		if !cloudInitSnippetRegex.MatchString(snippet.Filename) {
+			return fmt.Errorf("cloud-init snippet filename %q is invalid", snippet.Filename)
+		}
+		if path.Join(CloudInitConfigDir, snippet.Filename) == CloudInitConfigFile {
+			return fmt.Errorf("cloud-init snippet filename %q is reserved", snippet.Filename)
+		}
+		if filenames[snippet.Filename] {
+			return fmt.Errorf("duplicate cloud-init snippet %q", snippet.Filename)
+		}
+		filenames[snippet.Filename] = true
+
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(snippet.Contents), &config); err != nil {
+			return fmt.Errorf("cloud-init snippet %q is not a valid YAML mapping: %w", snippet.Filename, err)
+		}
+		if len(config) == 0 {
+			return fmt.Errorf("cloud-init snippet %q must not be empty", snippet.Filename)
+		}
+	}
+
+	return nil
+}
+
+// checkFileConflicts returns an error if a file or directory customization
+// would replace a configuration file of the customization.
+func (c *CloudInitCustomization) checkFileConflicts(files []FileCustomization, dirs []DirectoryCustomization) error {
+	paths := map[string]bool{}
+	if c.hasConfigFile() {
+		paths[CloudInitConfigFile] = true
+	}
+	for _, snippet := range c.Snippets {
+		paths[path.Join(CloudInitConfigDir, snippet.Filename)] = true
+	}
+
+	for _, file := range files {
+		if paths[path.Clean(file.Path)] {
+			return fmt.Errorf("cloud-init configuration file %q conflicts with a file customization", file.Path)
+		}
+	}
+	for _, dir := range dirs {
+		if paths[path.Clean(dir.Path)] {
+			return fmt.Errorf("cloud-init configuration file %q conflicts with a directory customization", dir.Path)
+		}
+	}
+	return nil
+}
+
+// hasConfigFile returns true if the customization is written to
+// CloudInitConfigFile.
+func (c *CloudInitCustomization) hasConfigFile() bool {
+	return len(c.Datasources) > 0 || c.DefaultUser != nil || len(c.DisabledModules) > 0
+}
+
+// cloudInitModuleName normalizes a module name, cloud-init treats "-" and "_"
+// in module names the same.
+func cloudInitModuleName(module string) string {
+	return strings.ReplaceAll(module, "-", "_")
+}
+
+// withoutModules returns the module lists without the disabled modules. Each
+// of the disabled modules must be in one of the lists.
+func (m *CloudInitModules) withoutModules(disabled []string) (*CloudInitModules, error) {
+	remove := make(map[string]bool, len(disabled))
+	for _, module := range disabled {
+		remove[cloudInitModuleName(module)] = true
+	}
+
+	found := make(map[string]bool, len(disabled))
+	filter := func(modules []string) []string {
+		filtered := []string{}
+		for _, module := range modules {
+			if name := cloudInitModuleName(module); remove[name] {
+				found[name] = true
+				continue
+			}
+			filtered = append(filtered, module)
+		}
+		return filtered
+	}
+	result := &CloudInitModules{
+		Init:   filter(m.Init),
+		Config: filter(m.Config),
+		Final:  filter(m.Final),
+	}
+
+	for _, module := range disabled {
+		if !found[cloudInitModuleName(module)] {
+			return nil, fmt.Errorf("cloud-init module %q is not run by the image", module)
+		}
+	}
+	return result, nil
+}
+
+// ValidateDisabledModules checks that the disabled modules can be removed from
+// the module lists of the image.
+func (c *CloudInitCustomization) ValidateDisabledModules(modules *CloudInitModules) error {
+	if c == nil || len(c.DisabledModules) == 0 {
+		return nil
+	}
+	if modules == nil {
+		return fmt.Errorf("cloud-init modules cannot be disabled, the module lists of the image are unknown")
+	}
+	_, err := modules.withoutModules(c.DisabledModules)
+	return err
+}
+
+// CloudInitCustomizationToFsNodeFiles returns the cloud-init configuration
+// files of the customization. cloud-init can only disable modules by not
+// listing them and the lists in the configuration files replace the ones of
+// /etc/cloud/cloud.cfg, so the disabled modules are removed from the complete
+// module lists of the image, which are required to disable modules.
+func CloudInitCustomizationToFsNodeFiles(c *CloudInitCustomization, modules *CloudInitModules) ([]*fsnode.File, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	var files []*fsnode.File
+	if c.hasConfigFile() {
+		type defaultUser struct {
+			Name   string   `yaml:"name"`
+			Groups []string `yaml:"groups,omitempty"`
+		}
+		type systemInfo struct {
+			DefaultUser defaultUser `yaml:"default_user"`
+		}
+		config := struct {
+			DatasourceList    []string    `yaml:"datasource_list,omitempty"`
+			SystemInfo        *systemInfo `yaml:"system_info,omitempty"`
+			*CloudInitModules `yaml:",inline"`
+		}{
+			DatasourceList: c.Datasources,
+		}
+		if du := c.DefaultUser; du != nil {
+			config.SystemInfo = &systemInfo{DefaultUser: defaultUser{Name: du.Name, Groups: du.Groups}}
+		}
+		if len(c.DisabledModules) > 0 {
+			if err := c.ValidateDisabledModules(modules); err != nil {
+				return nil, err
+			}
+			enabled, err := modules.withoutModules(c.DisabledModules)
+			if err != nil {
+				return nil, err
+			}
+			config.CloudInitModules = enabled
+		}
+
+		data, err := yaml.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		file, err := fsnode.NewFile(CloudInitConfigFile, common.ToPtr(os.FileMode(0644)), nil, nil, data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	for _, snippet := range c.Snippets {
+		file, err := fsnode.NewFile(path.Join(CloudInitConfigDir, snippet.Filename), common.ToPtr(os.FileMode(0644)), nil, nil, []byte(snippet.Contents))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,156 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCloudInit(t *testing.T) {
+	testCases := map[string]struct {
+		cloudInit *CloudInitCustomization
+		files     []FileCustomization
+		wantErr   string
+	}{
+		"valid": {
+			cloudInit: &CloudInitCustomization{
+				Datasources:     []string{"OpenStack", "ConfigDrive", "None"},
+				DefaultUser:     &CloudInitDefaultUserCustomization{Name: "cloud-user", Groups: []string{"wheel", "adm"}},
+				DisabledModules: []string{"growpart", "ssh-authkey-fingerprints"},
+				Snippets:        []CloudInitSnippetCustomization{{Filename: "50-network.cfg", Contents: "network:\n  config: disabled\n"}},
+			},
+		},
+		"empty": {
+			cloudInit: &CloudInitCustomization{},
+			wantErr:   "cloud-init customization must not be empty",
+		},
+		"invalid-datasource": {
+			cloudInit: &CloudInitCustomization{Datasources: []string{"Open Stack"}},
+			wantErr:   `cloud-init datasource name "Open Stack" is invalid`,
+		},
+		"invalid-default-user": {
+			cloudInit: &CloudInitCustomization{DefaultUser: &CloudInitDefaultUserCustomization{Name: "Cloud User"}},
+			wantErr:   `cloud-init default user name "Cloud User" is invalid`,
+		},
+		"invalid-default-user-group": {
+			cloudInit: &CloudInitCustomization{DefaultUser: &CloudInitDefaultUserCustomization{Name: "cloud-user", Groups: []string{"wheel,adm"}}},
+			wantErr:   `cloud-init default user group name "wheel,adm" is invalid`,
+		},
+		"invalid-module": {
+			cloudInit: &CloudInitCustomization{DisabledModules: []string{"growpart|.*"}},
+			wantErr:   `cloud-init module name "growpart|.*" is invalid`,
+		},
+		"invalid-snippet-filename": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "../cloud.cfg", Contents: "a: b"}}},
+			wantErr:   `cloud-init snippet filename "../cloud.cfg" is invalid`,
+		},
+		"reserved-snippet-filename": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "95-blueprint.cfg", Contents: "a: b"}}},
+			wantErr:   `cloud-init snippet filename "95-blueprint.cfg" is reserved`,
+		},
+		"duplicate-snippet": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "50-a.cfg", Contents: "a: b"}, {Filename: "50-a.cfg", Contents: "c: d"}}},
+			wantErr:   `duplicate cloud-init snippet "50-a.cfg"`,
+		},
+		"invalid-snippet-yaml": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "50-a.cfg", Contents: "- a\n- b\n"}}},
+			wantErr:   "cloud-init snippet \"50-a.cfg\" is not a valid YAML mapping: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into map[string]interface {}",
+		},
+		"empty-snippet": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "50-a.cfg", Contents: "# nothing\n"}}},
+			wantErr:   `cloud-init snippet "50-a.cfg" must not be empty`,
+		},
+		"file-conflict": {
+			cloudInit: &CloudInitCustomization{Datasources: []string{"NoCloud"}},
+			files:     []FileCustomization{{Path: "/etc/cloud/cloud.cfg.d/95-blueprint.cfg"}},
+			wantErr:   `cloud-init configuration file "/etc/cloud/cloud.cfg.d/95-blueprint.cfg" conflicts with a file customization`,
+		},
+		"snippet-file-conflict": {
+			cloudInit: &CloudInitCustomization{Snippets: []CloudInitSnippetCustomization{{Filename: "50-a.cfg", Contents: "a: b"}}},
+			files:     []FileCustomization{{Path: "/etc/cloud/cloud.cfg.d/50-a.cfg"}},
+			wantErr:   `cloud-init configuration file "/etc/cloud/cloud.cfg.d/50-a.cfg" conflicts with a file customization`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{CloudInit: tc.cloudInit, Files: tc.files}
+			cloudInit, err := c.GetCloudInit()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.cloudInit, cloudInit)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetCloudInitEmpty(t *testing.T) {
+	var c *Customizations
+	cloudInit, err := c.GetCloudInit()
+	assert.NoError(t, err)
+	assert.Nil(t, cloudInit)
+}
+
+func TestCloudInitCustomizationToFsNodeFiles(t *testing.T) {
+	files, err := CloudInitCustomizationToFsNodeFiles(&CloudInitCustomization{
+		Datasources: []string{"OpenStack", "None"},
+		DefaultUser: &CloudInitDefaultUserCustomization{Name: "cloud-user", Groups: []string{"wheel"}},
+		Snippets:    []CloudInitSnippetCustomization{{Filename: "50-network.cfg", Contents: "network:\n  config: disabled\n"}},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/cloud/cloud.cfg.d/95-blueprint.cfg", files[0].Path())
+	assert.Equal(t, `datasource_list:
+    - OpenStack
+    - None
+system_info:
+    default_user:
+        name: cloud-user
+        groups:
+            - wheel
+`, string(files[0].Data()))
+
+	assert.Equal(t, "/etc/cloud/cloud.cfg.d/50-network.cfg", files[1].Path())
+	assert.Equal(t, "network:\n  config: disabled\n", string(files[1].Data()))
+
+	files, err = CloudInitCustomizationToFsNodeFiles(nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCloudInitCustomizationDisabledModules(t *testing.T) {
+	modules := &CloudInitModules{
+		Init:   []string{"migrator", "growpart", "resizefs"},
+		Config: []string{"runcmd"},
+		Final:  []string{"ssh_authkey_fingerprints", "final-message"},
+	}
+
+	// the complete module lists without the disabled modules replace the
+	// ones of cloud.cfg
+	files, err := CloudInitCustomizationToFsNodeFiles(&CloudInitCustomization{
+		DisabledModules: []string{"growpart", "ssh-authkey-fingerprints", "runcmd"},
+	}, modules)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "/etc/cloud/cloud.cfg.d/95-blueprint.cfg", files[0].Path())
+	assert.Equal(t, `cloud_init_modules:
+    - migrator
+    - resizefs
+cloud_config_modules: []
+cloud_final_modules:
+    - final-message
+`, string(files[0].Data()))
+
+	// the module lists of the image are not modified
+	assert.Equal(t, []string{"migrator", "growpart", "resizefs"}, modules.Init)
+
+	_, err = CloudInitCustomizationToFsNodeFiles(&CloudInitCustomization{DisabledModules: []string{"puppet"}}, modules)
+	assert.EqualError(t, err, `cloud-init module "puppet" is not run by the image`)
+
+	_, err = CloudInitCustomizationToFsNodeFiles(&CloudInitCustomization{DisabledModules: []string{"growpart"}}, nil)
+	assert.EqualError(t, err, "cloud-init modules cannot be disabled, the module lists of the image are unknown")
+}
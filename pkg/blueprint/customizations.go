@@ -7,23 +7,34 @@ import (
 )
 
 type Customizations struct {
-	Hostname           *string                   `json:"hostname,omitempty" toml:"hostname,omitempty"`
-	Kernel             *KernelCustomization      `json:"kernel,omitempty" toml:"kernel,omitempty"`
-	SSHKey             []SSHKeyCustomization     `json:"sshkey,omitempty" toml:"sshkey,omitempty"`
-	User               []UserCustomization       `json:"user,omitempty" toml:"user,omitempty"`
-	Group              []GroupCustomization      `json:"group,omitempty" toml:"group,omitempty"`
-	Timezone           *TimezoneCustomization    `json:"timezone,omitempty" toml:"timezone,omitempty"`
-	Locale             *LocaleCustomization      `json:"locale,omitempty" toml:"locale,omitempty"`
-	Firewall           *FirewallCustomization    `json:"firewall,omitempty" toml:"firewall,omitempty"`
-	Services           *ServicesCustomization    `json:"services,omitempty" toml:"services,omitempty"`
-	Filesystem         []FilesystemCustomization `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
-	InstallationDevice string                    `json:"installation_device,omitempty" toml:"installation_device,omitempty"`
-	FDO                *FDOCustomization         `json:"fdo,omitempty" toml:"fdo,omitempty"`
-	OpenSCAP           *OpenSCAPCustomization    `json:"openscap,omitempty" toml:"openscap,omitempty"`
-	Ignition           *IgnitionCustomization    `json:"ignition,omitempty" toml:"ignition,omitempty"`
-	Directories        []DirectoryCustomization  `json:"directories,omitempty" toml:"directories,omitempty"`
-	Files              []FileCustomization       `json:"files,omitempty" toml:"files,omitempty"`
-	Repositories       []RepositoryCustomization `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	Hostname           *string                     `json:"hostname,omitempty" toml:"hostname,omitempty"`
+	Kernel             *KernelCustomization        `json:"kernel,omitempty" toml:"kernel,omitempty"`
+	SSHKey             []SSHKeyCustomization       `json:"sshkey,omitempty" toml:"sshkey,omitempty"`
+	User               []UserCustomization         `json:"user,omitempty" toml:"user,omitempty"`
+	Group              []GroupCustomization        `json:"group,omitempty" toml:"group,omitempty"`
+	Timezone           *TimezoneCustomization      `json:"timezone,omitempty" toml:"timezone,omitempty"`
+	Locale             *LocaleCustomization        `json:"locale,omitempty" toml:"locale,omitempty"`
+	Firewall           *FirewallCustomization      `json:"firewall,omitempty" toml:"firewall,omitempty"`
+	Services           *ServicesCustomization      `json:"services,omitempty" toml:"services,omitempty"`
+	Filesystem         []FilesystemCustomization   `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
+	Partitioning       *PartitioningCustomization  `json:"partitioning,omitempty" toml:"partitioning,omitempty"`
+	InstallationDevice string                      `json:"installation_device,omitempty" toml:"installation_device,omitempty"`
+	FDO                *FDOCustomization           `json:"fdo,omitempty" toml:"fdo,omitempty"`
+	OpenSCAP           *OpenSCAPCustomization      `json:"openscap,omitempty" toml:"openscap,omitempty"`
+	Ignition           *IgnitionCustomization      `json:"ignition,omitempty" toml:"ignition,omitempty"`
+	Directories        []DirectoryCustomization    `json:"directories,omitempty" toml:"directories,omitempty"`
+	Files              []FileCustomization         `json:"files,omitempty" toml:"files,omitempty"`
+	Repositories       []RepositoryCustomization   `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	Network            *NetworkCustomization       `json:"network,omitempty" toml:"network,omitempty"`
+	Sysctl             []SysctlCustomization       `json:"sysctl,omitempty" toml:"sysctl,omitempty"`
+	KernelModules      *KernelModulesCustomization `json:"kernel_modules,omitempty" toml:"kernel_modules,omitempty"`
+	Dracut             *DracutCustomization        `json:"dracut,omitempty" toml:"dracut,omitempty"`
+	Tuned              *TunedCustomization         `json:"tuned,omitempty" toml:"tuned,omitempty"`
+	Systemd            *SystemdCustomization       `json:"systemd,omitempty" toml:"systemd,omitempty"`
+	SELinux            *SELinuxCustomization       `json:"selinux,omitempty" toml:"selinux,omitempty"`
+	Accounts           *AccountsCustomization      `json:"accounts,omitempty" toml:"accounts,omitempty"`
+	Installer          *InstallerCustomization     `json:"installer,omitempty" toml:"installer,omitempty"`
+	CloudInit          *CloudInitCustomization     `json:"cloud_init,omitempty" toml:"cloud_init,omitempty"`
 }
 
 type IgnitionCustomization struct {
@@ -68,6 +79,14 @@ type UserCustomization struct {
 	Groups      []string `json:"groups,omitempty" toml:"groups,omitempty"`
 	UID         *int     `json:"uid,omitempty" toml:"uid,omitempty"`
 	GID         *int     `json:"gid,omitempty" toml:"gid,omitempty"`
+
+	// Date the account expires on, in the YYYY-MM-DD format
+	ExpireDate *string `json:"expiredate,omitempty" toml:"expiredate,omitempty"`
+	// Force the user to change the password on the first login
+	ForcePasswordReset *bool `json:"force_password_reset,omitempty" toml:"force_password_reset,omitempty"`
+	PasswordMinDays    *int  `json:"password_min_days,omitempty" toml:"password_min_days,omitempty"`
+	PasswordMaxDays    *int  `json:"password_max_days,omitempty" toml:"password_max_days,omitempty"`
+	PasswordWarnDays   *int  `json:"password_warn_days,omitempty" toml:"password_warn_days,omitempty"`
 }
 
 type GroupCustomization struct {
@@ -277,6 +296,13 @@ func (c *Customizations) GetFilesystems() []FilesystemCustomization {
 	return c.Filesystem
 }
 
+func (c *Customizations) GetPartitioning() *PartitioningCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Partitioning
+}
+
 func (c *Customizations) GetFilesystemsMinSize() uint64 {
 	if c == nil {
 		return 0
@@ -349,3 +375,169 @@ func (c *Customizations) GetRepositories() ([]RepositoryCustomization, error) {
 
 	return c.Repositories, nil
 }
+
+// GetNetwork returns the validated network customization. The keyfiles of
+// the connection profiles must not conflict with the file and directory
+// customizations.
+func (c *Customizations) GetNetwork() (*NetworkCustomization, error) {
+	if c == nil || c.Network == nil {
+		return nil, nil
+	}
+
+	if err := c.Network.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.Network.checkKeyfileConflicts(c.Files, c.Directories); err != nil {
+		return nil, err
+	}
+
+	return c.Network, nil
+}
+
+// GetSysctl returns the validated sysctl customizations.
+func (c *Customizations) GetSysctl() ([]SysctlCustomization, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if err := validateSysctl(c.Sysctl); err != nil {
+		return nil, err
+	}
+
+	return c.Sysctl, nil
+}
+
+// GetKernelModules returns the validated kernel modules customization.
+func (c *Customizations) GetKernelModules() (*KernelModulesCustomization, error) {
+	if c == nil || c.KernelModules == nil {
+		return nil, nil
+	}
+
+	if err := c.KernelModules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.KernelModules, nil
+}
+
+// GetDracut returns the validated dracut customization.
+func (c *Customizations) GetDracut() (*DracutCustomization, error) {
+	if c == nil || c.Dracut == nil {
+		return nil, nil
+	}
+
+	if err := c.Dracut.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Dracut, nil
+}
+
+// GetTuned returns the validated tuned customization.
+func (c *Customizations) GetTuned() (*TunedCustomization, error) {
+	if c == nil || c.Tuned == nil {
+		return nil, nil
+	}
+
+	if err := c.Tuned.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Tuned, nil
+}
+
+// GetSystemd returns the validated systemd customization.
+func (c *Customizations) GetSystemd() (*SystemdCustomization, error) {
+	if c == nil || c.Systemd == nil {
+		return nil, nil
+	}
+
+	if err := c.Systemd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Systemd, nil
+}
+
+// GetCloudInit returns the validated cloud-init customization.
+func (c *Customizations) GetCloudInit() (*CloudInitCustomization, error) {
+	if c == nil || c.CloudInit == nil {
+		return nil, nil
+	}
+
+	if err := c.CloudInit.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.CloudInit.checkFileConflicts(c.Files, c.Directories); err != nil {
+		return nil, err
+	}
+
+	return c.CloudInit, nil
+}
+
+// GetSELinux returns the validated SELinux customization.
+func (c *Customizations) GetSELinux() (*SELinuxCustomization, error) {
+	if c == nil || c.SELinux == nil {
+		return nil, nil
+	}
+
+	if err := c.SELinux.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.SELinux, nil
+}
+
+// GetAccounts returns the validated accounts customization. The account
+// policy fields of the users are validated as well, even if there is no
+// accounts customization.
+func (c *Customizations) GetAccounts() (*AccountsCustomization, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	for idx := range c.User {
+		if err := c.User[idx].checkAccountPolicy(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Accounts == nil {
+		return nil, nil
+	}
+
+	if err := c.Accounts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if root := c.Accounts.Root; root != nil && (root.Lock || root.Disable) {
+		for _, user := range c.User {
+			if user.Name != "root" {
+				continue
+			}
+			if user.Password != nil && *user.Password != "" {
+				return nil, fmt.Errorf("the root account cannot be locked when a password is set for it")
+			}
+			if root.Disable && user.Shell != nil {
+				return nil, fmt.Errorf("the root account cannot be disabled when a shell is set for it")
+			}
+		}
+	}
+
+	return c.Accounts, nil
+}
+
+// GetInstaller returns the validated installer customization.
+func (c *Customizations) GetInstaller() (*InstallerCustomization, error) {
+	if c == nil || c.Installer == nil {
+		return nil, nil
+	}
+
+	if err := c.Installer.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Installer, nil
+}
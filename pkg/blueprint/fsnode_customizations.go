@@ -3,6 +3,7 @@ package blueprint
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path"
 	"regexp"
@@ -190,6 +191,11 @@ type FileCustomization struct {
 	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
 	// Data is the file content in plain text
 	Data string `json:"data,omitempty" toml:"data,omitempty"`
+	// URL is an https URL the file content is fetched from when the image
+	// is built, as an alternative to Data
+	URL string `json:"url,omitempty" toml:"url,omitempty"`
+	// SHA256 is the hex encoded checksum of the content fetched from URL
+	SHA256 string `json:"sha256,omitempty" toml:"sha256,omitempty"`
 }
 
 // Custom TOML unmarshalling for FileCustomization with validation
@@ -245,6 +251,24 @@ func (f *FileCustomization) UnmarshalTOML(data interface{}) error {
 		return fmt.Errorf("UnmarshalTOML: data must be a string")
 	}
 
+	switch fileURL := dataMap["url"].(type) {
+	case string:
+		file.URL = fileURL
+	case nil:
+		break
+	default:
+		return fmt.Errorf("UnmarshalTOML: url must be a string")
+	}
+
+	switch checksum := dataMap["sha256"].(type) {
+	case string:
+		file.SHA256 = checksum
+	case nil:
+		break
+	default:
+		return fmt.Errorf("UnmarshalTOML: sha256 must be a string")
+	}
+
 	// try converting to fsnode.File to validate all values
 	_, err := file.ToFsNodeFile()
 	if err != nil {
@@ -309,9 +333,41 @@ func (f FileCustomization) ToFsNodeFile() (*fsnode.File, error) {
 		mode = common.ToPtr(os.FileMode(modeNum))
 	}
 
+	if f.URL != "" {
+		if data != nil {
+			return nil, fmt.Errorf("file %q: data and url are mutually exclusive", f.Path)
+		}
+		if err := validateFileURL(f.URL); err != nil {
+			return nil, fmt.Errorf("file %q: %w", f.Path, err)
+		}
+		if f.SHA256 == "" {
+			return nil, fmt.Errorf("file %q: sha256 is required for files with a url", f.Path)
+		}
+		return fsnode.NewRemoteFile(f.Path, mode, f.User, f.Group, f.URL, strings.ToLower(f.SHA256))
+	}
+	if f.SHA256 != "" {
+		return nil, fmt.Errorf("file %q: sha256 is only supported for files with a url", f.Path)
+	}
+
 	return fsnode.NewFile(f.Path, mode, f.User, f.Group, data)
 }
 
+// validateFileURL checks that the content of a file is fetched from an https
+// URL.
+func validateFileURL(fileURL string) error {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return fmt.Errorf("url %q is invalid", fileURL)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("url %q must be an https url", fileURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("url %q must not contain credentials", fileURL)
+	}
+	return nil
+}
+
 // FileCustomizationsToFsNodeFiles converts a slice of FileCustomization to a slice of *fsnode.File
 func FileCustomizationsToFsNodeFiles(files []FileCustomization) ([]*fsnode.File, error) {
 	if len(files) == 0 {
@@ -0,0 +1,220 @@
+package blueprint
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// plain names like sda or paths under /dev like disk/by-id/wwn-0x5000
+	installerDiskRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9/_.:-]*$`)
+	// interface names, MAC addresses or one of "link" and "bootif"
+	installerDeviceRegex   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+	installerHostnameRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+	installerTimezoneRegex = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+)
+
+// InstallerCustomization configures the kickstart file of installer image
+// types to make the installation unattended.
+type InstallerCustomization struct {
+	// Disks the installer may use, e.g. sda or disk/by-id/wwn-0x5000; all
+	// other disks are ignored
+	TargetDisks []string `json:"target_disks,omitempty" toml:"target_disks,omitempty"`
+	// Initialize invalid partition tables without asking
+	ZeroMBR   bool                             `json:"zerombr,omitempty" toml:"zerombr,omitempty"`
+	ClearPart *InstallerClearPartCustomization `json:"clearpart,omitempty" toml:"clearpart,omitempty"`
+	AutoPart  *InstallerAutoPartCustomization  `json:"autopart,omitempty" toml:"autopart,omitempty"`
+	Network   []InstallerNetworkCustomization  `json:"network,omitempty" toml:"network,omitempty"`
+	// Timezone of the installed system, e.g. Europe/Berlin
+	Timezone string `json:"timezone,omitempty" toml:"timezone,omitempty"`
+	// Reboot once the installation is done
+	Reboot bool `json:"reboot,omitempty" toml:"reboot,omitempty"`
+	// Eject the installation media before rebooting
+	EjectMedia bool                               `json:"eject_media,omitempty" toml:"eject_media,omitempty"`
+	Post       []InstallerPostScriptCustomization `json:"post,omitempty" toml:"post,omitempty"`
+}
+
+// InstallerClearPartCustomization removes existing partitions before
+// partitioning.
+type InstallerClearPartCustomization struct {
+	// all removes all partitions, linux only the Linux ones
+	Mode string `json:"mode" toml:"mode"`
+	// Limit the removal to these disks
+	Drives []string `json:"drives,omitempty" toml:"drives,omitempty"`
+	// Create an empty partition table on the disks
+	InitLabel bool `json:"initlabel,omitempty" toml:"initlabel,omitempty"`
+}
+
+// InstallerAutoPartCustomization lets the installer partition the target
+// disks automatically.
+type InstallerAutoPartCustomization struct {
+	// plain, lvm, thinp or btrfs; defaults to lvm
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+	// Filesystem of the partitions; not supported with btrfs
+	FSType string `json:"fstype,omitempty" toml:"fstype,omitempty"`
+	// Don't create a separate /home partition
+	NoHome bool `json:"nohome,omitempty" toml:"nohome,omitempty"`
+}
+
+// InstallerNetworkCustomization configures a network device during and
+// after the installation.
+type InstallerNetworkCustomization struct {
+	// Interface name, MAC address, link or bootif
+	Device string `json:"device,omitempty" toml:"device,omitempty"`
+	// dhcp or static; defaults to dhcp
+	BootProto   string   `json:"bootproto,omitempty" toml:"bootproto,omitempty"`
+	IP          string   `json:"ip,omitempty" toml:"ip,omitempty"`
+	Netmask     string   `json:"netmask,omitempty" toml:"netmask,omitempty"`
+	Gateway     string   `json:"gateway,omitempty" toml:"gateway,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty" toml:"nameservers,omitempty"`
+	Hostname    string   `json:"hostname,omitempty" toml:"hostname,omitempty"`
+	// Activate the device in the installer environment
+	Activate bool `json:"activate,omitempty" toml:"activate,omitempty"`
+}
+
+// InstallerPostScriptCustomization is a script run after the installation.
+type InstallerPostScriptCustomization struct {
+	// Absolute path of the interpreter; defaults to /bin/sh
+	Interpreter string `json:"interpreter,omitempty" toml:"interpreter,omitempty"`
+	// Run the script in the installer environment instead of the installed
+	// system, which is mounted at /mnt/sysroot
+	NoChroot bool `json:"nochroot,omitempty" toml:"nochroot,omitempty"`
+	// Abort the installation if the script fails
+	ErrorOnFail bool   `json:"erroronfail,omitempty" toml:"erroronfail,omitempty"`
+	Script      string `json:"script" toml:"script"`
+}
+
+// GetBootProto returns the boot protocol of the device, defaulting to dhcp.
+func (n *InstallerNetworkCustomization) GetBootProto() string {
+	if n.BootProto == "" {
+		return "dhcp"
+	}
+	return n.BootProto
+}
+
+func validateInstallerDisks(disks []string) error {
+	for _, disk := range disks {
+		if !installerDiskRegex.MatchString(disk) || strings.Contains(disk, "..") {
+			return fmt.Errorf("installer disk name %q is invalid", disk)
+		}
+	}
+	return nil
+}
+
+func (i *InstallerCustomization) Validate() error {
+	if err := validateInstallerDisks(i.TargetDisks); err != nil {
+		return err
+	}
+
+	if cp := i.ClearPart; cp != nil {
+		switch cp.Mode {
+		case "all", "linux":
+		default:
+			return fmt.Errorf("unsupported installer clearpart mode %q", cp.Mode)
+		}
+		if err := validateInstallerDisks(cp.Drives); err != nil {
+			return err
+		}
+	}
+
+	if ap := i.AutoPart; ap != nil {
+		switch ap.Type {
+		case "", "plain", "lvm", "thinp", "btrfs":
+		default:
+			return fmt.Errorf("unsupported installer autopart type %q", ap.Type)
+		}
+		switch ap.FSType {
+		case "", "xfs", "ext4", "ext3", "ext2":
+		default:
+			return fmt.Errorf("unsupported installer autopart filesystem type %q", ap.FSType)
+		}
+		if ap.Type == "btrfs" && ap.FSType != "" {
+			return fmt.Errorf("installer autopart filesystem type cannot be set for btrfs")
+		}
+	}
+
+	devices := make(map[string]bool, len(i.Network))
+	for idx := range i.Network {
+		n := &i.Network[idx]
+		if err := n.validate(); err != nil {
+			return err
+		}
+		if devices[n.Device] {
+			return fmt.Errorf("duplicate installer network device %q", n.Device)
+		}
+		devices[n.Device] = true
+	}
+
+	if i.Timezone != "" && !installerTimezoneRegex.MatchString(i.Timezone) {
+		return fmt.Errorf("installer timezone %q is invalid", i.Timezone)
+	}
+
+	if i.EjectMedia && !i.Reboot {
+		return fmt.Errorf("installer eject_media requires reboot")
+	}
+
+	for idx := range i.Post {
+		if err := i.Post[idx].validate(); err != nil {
+			return fmt.Errorf("installer post script #%d: %w", idx+1, err)
+		}
+	}
+	return nil
+}
+
+func (n *InstallerNetworkCustomization) validate() error {
+	if n.Device != "" && !installerDeviceRegex.MatchString(n.Device) {
+		return fmt.Errorf("installer network device %q is invalid", n.Device)
+	}
+
+	switch n.GetBootProto() {
+	case "dhcp":
+		if n.IP != "" || n.Netmask != "" || n.Gateway != "" {
+			return fmt.Errorf("installer network device %q: ip, netmask and gateway require the static bootproto", n.Device)
+		}
+	case "static":
+		if n.IP == "" || n.Netmask == "" {
+			return fmt.Errorf("installer network device %q: ip and netmask are required for the static bootproto", n.Device)
+		}
+		if net.ParseIP(n.IP) == nil {
+			return fmt.Errorf("installer network device %q: ip %q is invalid", n.Device, n.IP)
+		}
+		if net.ParseIP(n.Netmask) == nil {
+			return fmt.Errorf("installer network device %q: netmask %q is invalid", n.Device, n.Netmask)
+		}
+		if n.Gateway != "" && net.ParseIP(n.Gateway) == nil {
+			return fmt.Errorf("installer network device %q: gateway %q is invalid", n.Device, n.Gateway)
+		}
+	default:
+		return fmt.Errorf("installer network device %q: unsupported bootproto %q", n.Device, n.BootProto)
+	}
+
+	for _, ns := range n.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("installer network device %q: nameserver %q is invalid", n.Device, ns)
+		}
+	}
+
+	if n.Hostname != "" && (len(n.Hostname) > 253 || !installerHostnameRegex.MatchString(n.Hostname)) {
+		return fmt.Errorf("installer network device %q: hostname %q is invalid", n.Device, n.Hostname)
+	}
+	return nil
+}
+
+func (p *InstallerPostScriptCustomization) validate() error {
+	if strings.TrimSpace(p.Script) == "" {
+		return fmt.Errorf("script must not be empty")
+	}
+	for _, line := range strings.Split(p.Script, "\n") {
+		// a line with %end would terminate the section early
+		if strings.TrimSpace(line) == "%end" {
+			return fmt.Errorf("script must not contain %%end")
+		}
+	}
+	if p.Interpreter != "" && (!filepath.IsAbs(p.Interpreter) || strings.ContainsAny(p.Interpreter, " \t\n")) {
+		return fmt.Errorf("interpreter %q must be an absolute path", p.Interpreter)
+	}
+	return nil
+}
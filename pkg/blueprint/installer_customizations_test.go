@@ -0,0 +1,131 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInstaller(t *testing.T) {
+	testCases := map[string]struct {
+		installer InstallerCustomization
+		wantErr   string
+	}{
+		"valid": {
+			installer: InstallerCustomization{
+				TargetDisks: []string{"sda", "disk/by-id/wwn-0x5000c500a0b1c2d3"},
+				ZeroMBR:     true,
+				ClearPart:   &InstallerClearPartCustomization{Mode: "all", InitLabel: true},
+				AutoPart:    &InstallerAutoPartCustomization{Type: "lvm", FSType: "xfs"},
+				Network: []InstallerNetworkCustomization{
+					{Device: "link", Activate: true, Hostname: "node1.example.com"},
+					{Device: "52:54:00:12:34:56", BootProto: "static", IP: "10.0.0.2", Netmask: "255.255.255.0", Gateway: "10.0.0.1", Nameservers: []string{"10.0.0.1", "2001:db8::1"}},
+				},
+				Timezone:   "America/New_York",
+				Reboot:     true,
+				EjectMedia: true,
+				Post:       []InstallerPostScriptCustomization{{Interpreter: "/usr/bin/python3", Script: "print('hi')"}},
+			},
+		},
+		"invalid-target-disk": {
+			installer: InstallerCustomization{TargetDisks: []string{"../sda"}},
+			wantErr:   `installer disk name "../sda" is invalid`,
+		},
+		"invalid-clearpart-mode": {
+			installer: InstallerCustomization{ClearPart: &InstallerClearPartCustomization{}},
+			wantErr:   `unsupported installer clearpart mode ""`,
+		},
+		"invalid-clearpart-drive": {
+			installer: InstallerCustomization{ClearPart: &InstallerClearPartCustomization{Mode: "linux", Drives: []string{"sd a"}}},
+			wantErr:   `installer disk name "sd a" is invalid`,
+		},
+		"invalid-autopart-type": {
+			installer: InstallerCustomization{AutoPart: &InstallerAutoPartCustomization{Type: "zfs"}},
+			wantErr:   `unsupported installer autopart type "zfs"`,
+		},
+		"invalid-autopart-fstype": {
+			installer: InstallerCustomization{AutoPart: &InstallerAutoPartCustomization{FSType: "vfat"}},
+			wantErr:   `unsupported installer autopart filesystem type "vfat"`,
+		},
+		"btrfs-with-fstype": {
+			installer: InstallerCustomization{AutoPart: &InstallerAutoPartCustomization{Type: "btrfs", FSType: "xfs"}},
+			wantErr:   "installer autopart filesystem type cannot be set for btrfs",
+		},
+		"invalid-network-device": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0 --noipv6"}}},
+			wantErr:   `installer network device "eth0 --noipv6" is invalid`,
+		},
+		"duplicate-network-device": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0"}, {Device: "eth0"}}},
+			wantErr:   `duplicate installer network device "eth0"`,
+		},
+		"dhcp-with-ip": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", IP: "10.0.0.2"}}},
+			wantErr:   `installer network device "eth0": ip, netmask and gateway require the static bootproto`,
+		},
+		"static-without-netmask": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", BootProto: "static", IP: "10.0.0.2"}}},
+			wantErr:   `installer network device "eth0": ip and netmask are required for the static bootproto`,
+		},
+		"static-invalid-ip": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", BootProto: "static", IP: "10.0.0.256", Netmask: "255.255.255.0"}}},
+			wantErr:   `installer network device "eth0": ip "10.0.0.256" is invalid`,
+		},
+		"static-invalid-gateway": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", BootProto: "static", IP: "10.0.0.2", Netmask: "255.255.255.0", Gateway: "gw"}}},
+			wantErr:   `installer network device "eth0": gateway "gw" is invalid`,
+		},
+		"unsupported-bootproto": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", BootProto: "ibft"}}},
+			wantErr:   `installer network device "eth0": unsupported bootproto "ibft"`,
+		},
+		"invalid-nameserver": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", Nameservers: []string{"dns.example.com"}}}},
+			wantErr:   `installer network device "eth0": nameserver "dns.example.com" is invalid`,
+		},
+		"invalid-hostname": {
+			installer: InstallerCustomization{Network: []InstallerNetworkCustomization{{Device: "eth0", Hostname: "-node"}}},
+			wantErr:   `installer network device "eth0": hostname "-node" is invalid`,
+		},
+		"invalid-timezone": {
+			installer: InstallerCustomization{Timezone: "Europe/../Berlin "},
+			wantErr:   `installer timezone "Europe/../Berlin " is invalid`,
+		},
+		"eject-without-reboot": {
+			installer: InstallerCustomization{EjectMedia: true},
+			wantErr:   "installer eject_media requires reboot",
+		},
+		"empty-post-script": {
+			installer: InstallerCustomization{Post: []InstallerPostScriptCustomization{{Script: " \n"}}},
+			wantErr:   "installer post script #1: script must not be empty",
+		},
+		"post-script-with-end": {
+			installer: InstallerCustomization{Post: []InstallerPostScriptCustomization{{Script: "true"}, {Script: "true\n%end\n%pre\nfalse"}}},
+			wantErr:   "installer post script #2: script must not contain %end",
+		},
+		"relative-post-interpreter": {
+			installer: InstallerCustomization{Post: []InstallerPostScriptCustomization{{Interpreter: "python3", Script: "print('hi')"}}},
+			wantErr:   `installer post script #1: interpreter "python3" must be an absolute path`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{Installer: &tc.installer}
+			installer, err := c.GetInstaller()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, &tc.installer, installer)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetInstallerEmpty(t *testing.T) {
+	var c *Customizations
+	installer, err := c.GetInstaller()
+	assert.NoError(t, err)
+	assert.Nil(t, installer)
+}
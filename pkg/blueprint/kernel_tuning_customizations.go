@@ -0,0 +1,149 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// KernelModuleOptionsFile is the modprobe.d configuration file with the
+// module options of the blueprint.
+const KernelModuleOptionsFile = "/etc/modprobe.d/blueprint-options.conf"
+
+var (
+	sysctlKeyRegex    = regexp.MustCompile(`^-?[\w*][\w.*/-]*$`)
+	kernelModuleRegex = regexp.MustCompile(`^[\w-]{1,64}$`)
+	dracutModuleRegex = regexp.MustCompile(`^[\w-]+$`)
+	tunedProfileRegex = regexp.MustCompile(`^[\w.-]+$`)
+)
+
+// SysctlCustomization sets a kernel parameter at boot. Parameters are added
+// to the ones configured by the image type.
+type SysctlCustomization struct {
+	// Name of the parameter, e.g. net.ipv4.ip_forward. Globs are supported
+	// and a leading "-" excludes a parameter from a matching glob.
+	Key   string `json:"key" toml:"key"`
+	Value string `json:"value,omitempty" toml:"value,omitempty"`
+}
+
+type KernelModulesCustomization struct {
+	// Modules that must not be loaded automatically
+	Blacklist []string `json:"blacklist,omitempty" toml:"blacklist,omitempty"`
+	// Parameters passed to modules when they are loaded
+	Options []KernelModuleOptionsCustomization `json:"options,omitempty" toml:"options,omitempty"`
+}
+
+type KernelModuleOptionsCustomization struct {
+	Name    string `json:"name" toml:"name"`
+	Options string `json:"options" toml:"options"`
+}
+
+// DracutCustomization configures the modules of the initramfs in addition to
+// the ones configured by the image type.
+type DracutCustomization struct {
+	AddModules  []string `json:"add_modules,omitempty" toml:"add_modules,omitempty"`
+	OmitModules []string `json:"omit_modules,omitempty" toml:"omit_modules,omitempty"`
+	AddDrivers  []string `json:"add_drivers,omitempty" toml:"add_drivers,omitempty"`
+}
+
+// TunedCustomization selects TuneD profiles, which are applied after the
+// profiles of the image type.
+type TunedCustomization struct {
+	Profiles []string `json:"profiles" toml:"profiles"`
+}
+
+func validateSysctl(sysctl []SysctlCustomization) error {
+	keys := make(map[string]bool, len(sysctl))
+	for _, s := range sysctl {
+		if !sysctlKeyRegex.MatchString(s.Key) {
+			return fmt.Errorf("sysctl key %q is invalid", s.Key)
+		}
+		if keys[s.Key] {
+			return fmt.Errorf("duplicate sysctl key %q", s.Key)
+		}
+		keys[s.Key] = true
+
+		if strings.ContainsAny(s.Value, "\n\r") {
+			return fmt.Errorf("sysctl %q: value must not contain newlines", s.Key)
+		}
+		excluded := strings.HasPrefix(s.Key, "-")
+		if excluded && s.Value != "" {
+			return fmt.Errorf("sysctl %q: excluded keys must not have a value", s.Key)
+		}
+		if !excluded && s.Value == "" {
+			return fmt.Errorf("sysctl %q: value is required", s.Key)
+		}
+	}
+	return nil
+}
+
+func (k *KernelModulesCustomization) Validate() error {
+	for _, module := range k.Blacklist {
+		if !kernelModuleRegex.MatchString(module) {
+			return fmt.Errorf("kernel module name %q is invalid", module)
+		}
+	}
+
+	modules := make(map[string]bool, len(k.Options))
+	for _, o := range k.Options {
+		if !kernelModuleRegex.MatchString(o.Name) {
+			return fmt.Errorf("kernel module name %q is invalid", o.Name)
+		}
+		if modules[o.Name] {
+			return fmt.Errorf("duplicate options for kernel module %q", o.Name)
+		}
+		modules[o.Name] = true
+
+		if strings.TrimSpace(o.Options) == "" {
+			return fmt.Errorf("kernel module %q: options must not be empty", o.Name)
+		}
+		if strings.ContainsAny(o.Options, "\n\r") {
+			return fmt.Errorf("kernel module %q: options must not contain newlines", o.Name)
+		}
+	}
+	return nil
+}
+
+func (d *DracutCustomization) Validate() error {
+	for _, module := range append(append([]string{}, d.AddModules...), d.OmitModules...) {
+		if !dracutModuleRegex.MatchString(module) {
+			return fmt.Errorf("dracut module name %q is invalid", module)
+		}
+	}
+	for _, driver := range d.AddDrivers {
+		if !kernelModuleRegex.MatchString(driver) {
+			return fmt.Errorf("dracut driver name %q is invalid", driver)
+		}
+	}
+	return nil
+}
+
+func (t *TunedCustomization) Validate() error {
+	if len(t.Profiles) == 0 {
+		return fmt.Errorf("at least one tuned profile is required")
+	}
+	for _, profile := range t.Profiles {
+		if !tunedProfileRegex.MatchString(profile) {
+			return fmt.Errorf("tuned profile name %q is invalid", profile)
+		}
+	}
+	return nil
+}
+
+// KernelModuleOptionsToFsNodeFile returns the modprobe.d configuration file
+// setting the module options, or nil if there are no options.
+func KernelModuleOptionsToFsNodeFile(modules *KernelModulesCustomization) (*fsnode.File, error) {
+	if modules == nil || len(modules.Options) == 0 {
+		return nil, nil
+	}
+
+	var sb strings.Builder
+	for _, o := range modules.Options {
+		fmt.Fprintf(&sb, "options %s %s\n", o.Name, strings.TrimSpace(o.Options))
+	}
+	return fsnode.NewFile(KernelModuleOptionsFile, common.ToPtr(os.FileMode(0644)), nil, nil, []byte(sb.String()))
+}
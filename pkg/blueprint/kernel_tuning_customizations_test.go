@@ -0,0 +1,174 @@
+package blueprint
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSysctl(t *testing.T) {
+	testCases := map[string]struct {
+		sysctl  []SysctlCustomization
+		wantErr string
+	}{
+		"valid": {
+			sysctl: []SysctlCustomization{
+				{Key: "net.ipv4.ip_forward", Value: "1"},
+				{Key: "net/ipv6/conf/all/disable_ipv6", Value: "1"},
+				{Key: "net.ipv4.conf.*.rp_filter", Value: "2"},
+				{Key: "-net.ipv4.conf.lo.rp_filter"},
+			},
+		},
+		"invalid-key": {
+			sysctl:  []SysctlCustomization{{Key: "net.ipv4.ip_forward = 1", Value: "1"}},
+			wantErr: `sysctl key "net.ipv4.ip_forward = 1" is invalid`,
+		},
+		"duplicate-key": {
+			sysctl:  []SysctlCustomization{{Key: "vm.swappiness", Value: "10"}, {Key: "vm.swappiness", Value: "20"}},
+			wantErr: `duplicate sysctl key "vm.swappiness"`,
+		},
+		"missing-value": {
+			sysctl:  []SysctlCustomization{{Key: "vm.swappiness"}},
+			wantErr: `sysctl "vm.swappiness": value is required`,
+		},
+		"excluded-with-value": {
+			sysctl:  []SysctlCustomization{{Key: "-vm.swappiness", Value: "10"}},
+			wantErr: `sysctl "-vm.swappiness": excluded keys must not have a value`,
+		},
+		"multiline-value": {
+			sysctl:  []SysctlCustomization{{Key: "vm.swappiness", Value: "10\nkernel.panic = 1"}},
+			wantErr: `sysctl "vm.swappiness": value must not contain newlines`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{Sysctl: tc.sysctl}
+			sysctl, err := c.GetSysctl()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.sysctl, sysctl)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetKernelModules(t *testing.T) {
+	testCases := map[string]struct {
+		modules *KernelModulesCustomization
+		wantErr string
+	}{
+		"valid": {
+			modules: &KernelModulesCustomization{
+				Blacklist: []string{"nouveau", "pcspkr"},
+				Options:   []KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+			},
+		},
+		"invalid-blacklist": {
+			modules: &KernelModulesCustomization{Blacklist: []string{"../nouveau"}},
+			wantErr: `kernel module name "../nouveau" is invalid`,
+		},
+		"invalid-options-module": {
+			modules: &KernelModulesCustomization{Options: []KernelModuleOptionsCustomization{{Name: "kvm intel", Options: "nested=1"}}},
+			wantErr: `kernel module name "kvm intel" is invalid`,
+		},
+		"duplicate-options": {
+			modules: &KernelModulesCustomization{Options: []KernelModuleOptionsCustomization{{Name: "kvm", Options: "a=1"}, {Name: "kvm", Options: "b=1"}}},
+			wantErr: `duplicate options for kernel module "kvm"`,
+		},
+		"empty-options": {
+			modules: &KernelModulesCustomization{Options: []KernelModuleOptionsCustomization{{Name: "kvm", Options: " "}}},
+			wantErr: `kernel module "kvm": options must not be empty`,
+		},
+		"multiline-options": {
+			modules: &KernelModulesCustomization{Options: []KernelModuleOptionsCustomization{{Name: "kvm", Options: "a=1\ninstall kvm /bin/false"}}},
+			wantErr: `kernel module "kvm": options must not contain newlines`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{KernelModules: tc.modules}
+			modules, err := c.GetKernelModules()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.modules, modules)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetDracut(t *testing.T) {
+	c := &Customizations{Dracut: &DracutCustomization{AddModules: []string{"fips"}, OmitModules: []string{"plymouth"}, AddDrivers: []string{"virtio_blk"}}}
+	dracut, err := c.GetDracut()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Dracut, dracut)
+
+	c.Dracut.OmitModules = []string{"ply mouth"}
+	_, err = c.GetDracut()
+	assert.EqualError(t, err, `dracut module name "ply mouth" is invalid`)
+
+	c.Dracut = &DracutCustomization{AddDrivers: []string{"virtio/blk"}}
+	_, err = c.GetDracut()
+	assert.EqualError(t, err, `dracut driver name "virtio/blk" is invalid`)
+}
+
+func TestGetTuned(t *testing.T) {
+	c := &Customizations{Tuned: &TunedCustomization{Profiles: []string{"throughput-performance"}}}
+	tuned, err := c.GetTuned()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Tuned, tuned)
+
+	c.Tuned.Profiles = nil
+	_, err = c.GetTuned()
+	assert.EqualError(t, err, "at least one tuned profile is required")
+
+	c.Tuned.Profiles = []string{"../balanced"}
+	_, err = c.GetTuned()
+	assert.EqualError(t, err, `tuned profile name "../balanced" is invalid`)
+}
+
+func TestGetKernelTuningEmpty(t *testing.T) {
+	var c *Customizations
+
+	sysctl, err := c.GetSysctl()
+	assert.NoError(t, err)
+	assert.Nil(t, sysctl)
+
+	modules, err := c.GetKernelModules()
+	assert.NoError(t, err)
+	assert.Nil(t, modules)
+
+	dracut, err := c.GetDracut()
+	assert.NoError(t, err)
+	assert.Nil(t, dracut)
+
+	tuned, err := c.GetTuned()
+	assert.NoError(t, err)
+	assert.Nil(t, tuned)
+}
+
+func TestKernelModuleOptionsToFsNodeFile(t *testing.T) {
+	file, err := KernelModuleOptionsToFsNodeFile(&KernelModulesCustomization{
+		Blacklist: []string{"nouveau"},
+		Options: []KernelModuleOptionsCustomization{
+			{Name: "kvm_intel", Options: "nested=1"},
+			{Name: "bonding", Options: " max_bonds=2 miimon=100 "},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, file)
+	assert.Equal(t, "/etc/modprobe.d/blueprint-options.conf", file.Path())
+	assert.Equal(t, os.FileMode(0644), *file.Mode())
+	assert.Equal(t, "options kvm_intel nested=1\noptions bonding max_bonds=2 miimon=100\n", string(file.Data()))
+
+	file, err = KernelModuleOptionsToFsNodeFile(&KernelModulesCustomization{Blacklist: []string{"nouveau"}})
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+}
@@ -0,0 +1,459 @@
+package blueprint
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// NetworkCustomization defines NetworkManager connection profiles, which are
+// written as keyfiles to /etc/NetworkManager/system-connections.
+type NetworkCustomization struct {
+	Connections []NetworkConnectionCustomization `json:"connections,omitempty" toml:"connections,omitempty"`
+}
+
+type NetworkConnectionCustomization struct {
+	// Name of the connection profile, also used as the file name
+	Name string `json:"name" toml:"name"`
+	// ethernet (default), bond, vlan or bridge
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+	// Name of the network interface the connection applies to
+	Interface   string `json:"interface,omitempty" toml:"interface,omitempty"`
+	MACAddress  string `json:"mac_address,omitempty" toml:"mac_address,omitempty"`
+	MTU         uint   `json:"mtu,omitempty" toml:"mtu,omitempty"`
+	Autoconnect *bool  `json:"autoconnect,omitempty" toml:"autoconnect,omitempty"`
+
+	// Name of the bond or bridge connection this connection is a port of.
+	// Ports have no IP configuration of their own.
+	Controller string `json:"controller,omitempty" toml:"controller,omitempty"`
+
+	IPv4 *NetworkIPCustomization `json:"ipv4,omitempty" toml:"ipv4,omitempty"`
+	IPv6 *NetworkIPCustomization `json:"ipv6,omitempty" toml:"ipv6,omitempty"`
+
+	Bond   *NetworkBondCustomization   `json:"bond,omitempty" toml:"bond,omitempty"`
+	VLAN   *NetworkVLANCustomization   `json:"vlan,omitempty" toml:"vlan,omitempty"`
+	Bridge *NetworkBridgeCustomization `json:"bridge,omitempty" toml:"bridge,omitempty"`
+}
+
+type NetworkIPCustomization struct {
+	// auto (DHCP or SLAAC), manual or disabled; defaults to manual if
+	// addresses are set and auto otherwise. IPv6 also supports dhcp and
+	// ignore.
+	Method string `json:"method,omitempty" toml:"method,omitempty"`
+	// Addresses with prefix length, e.g. 192.168.1.10/24
+	Addresses []string                    `json:"addresses,omitempty" toml:"addresses,omitempty"`
+	Gateway   string                      `json:"gateway,omitempty" toml:"gateway,omitempty"`
+	DNS       []string                    `json:"dns,omitempty" toml:"dns,omitempty"`
+	DNSSearch []string                    `json:"dns_search,omitempty" toml:"dns_search,omitempty"`
+	Routes    []NetworkRouteCustomization `json:"routes,omitempty" toml:"routes,omitempty"`
+}
+
+type NetworkRouteCustomization struct {
+	// Destination network with prefix length, e.g. 10.0.0.0/8
+	Destination string `json:"destination" toml:"destination"`
+	Gateway     string `json:"gateway,omitempty" toml:"gateway,omitempty"`
+	Metric      *uint  `json:"metric,omitempty" toml:"metric,omitempty"`
+}
+
+type NetworkBondCustomization struct {
+	// Bonding mode, e.g. active-backup or 802.3ad; defaults to balance-rr
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+	// Additional bonding options, e.g. miimon
+	Options map[string]string `json:"options,omitempty" toml:"options,omitempty"`
+}
+
+type NetworkVLANCustomization struct {
+	ID uint `json:"id" toml:"id"`
+	// Name of the parent interface
+	Parent string `json:"parent" toml:"parent"`
+}
+
+type NetworkBridgeCustomization struct {
+	STP *bool `json:"stp,omitempty" toml:"stp,omitempty"`
+}
+
+const (
+	NetworkConnectionTypeEthernet = "ethernet"
+	NetworkConnectionTypeBond     = "bond"
+	NetworkConnectionTypeVLAN     = "vlan"
+	NetworkConnectionTypeBridge   = "bridge"
+)
+
+// NetworkManagerConnectionsDir is the directory the connection profiles are
+// written to.
+const NetworkManagerConnectionsDir = "/etc/NetworkManager/system-connections"
+
+const networkConnectionNameRegex = `^[\w.-]{1,200}$`
+
+// supported values, sorted for common.IsStringInSortedSlice()
+var (
+	bondModes   = []string{"802.3ad", "active-backup", "balance-alb", "balance-rr", "balance-tlb", "balance-xor", "broadcast"}
+	ipv4Methods = []string{"auto", "disabled", "manual"}
+	ipv6Methods = []string{"auto", "dhcp", "disabled", "ignore", "manual"}
+)
+
+// keyfilePath returns the path of the keyfile of the connection profile.
+func (c *NetworkConnectionCustomization) keyfilePath() string {
+	return filepath.Join(NetworkManagerConnectionsDir, c.Name+".nmconnection")
+}
+
+func (c *NetworkConnectionCustomization) getType() string {
+	if c.Type == "" {
+		return NetworkConnectionTypeEthernet
+	}
+	return c.Type
+}
+
+// interfaceName returns the name of the interface of the connection
+func (c *NetworkConnectionCustomization) interfaceName() string {
+	if c.Interface == "" && c.getType() == NetworkConnectionTypeVLAN && c.VLAN != nil {
+		return fmt.Sprintf("%s.%d", c.VLAN.Parent, c.VLAN.ID)
+	}
+	return c.Interface
+}
+
+func validateInterfaceName(name string) error {
+	if len(name) > 15 || strings.ContainsAny(name, "/: \t\n") || name == "." || name == ".." {
+		return fmt.Errorf("invalid interface name %q", name)
+	}
+	return nil
+}
+
+// Validate checks that the connection profiles are complete and consistent.
+func (n *NetworkCustomization) Validate() error {
+	nameRegex := regexp.MustCompile(networkConnectionNameRegex)
+
+	connections := make(map[string]*NetworkConnectionCustomization, len(n.Connections))
+	for idx := range n.Connections {
+		conn := &n.Connections[idx]
+		if !nameRegex.MatchString(conn.Name) {
+			return fmt.Errorf("network connection name %q is invalid", conn.Name)
+		}
+		if _, exists := connections[conn.Name]; exists {
+			return fmt.Errorf("duplicate network connection %q", conn.Name)
+		}
+		connections[conn.Name] = conn
+	}
+
+	for idx := range n.Connections {
+		if err := n.Connections[idx].validate(connections); err != nil {
+			return fmt.Errorf("network connection %q: %w", n.Connections[idx].Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *NetworkConnectionCustomization) validate(connections map[string]*NetworkConnectionCustomization) error {
+	connType := c.getType()
+	switch connType {
+	case NetworkConnectionTypeEthernet:
+		if c.Interface == "" && c.MACAddress == "" {
+			return fmt.Errorf("ethernet connections require an interface or a MAC address")
+		}
+	case NetworkConnectionTypeBond, NetworkConnectionTypeBridge:
+		if c.Interface == "" {
+			return fmt.Errorf("%s connections require an interface", connType)
+		}
+	case NetworkConnectionTypeVLAN:
+		if c.VLAN == nil {
+			return fmt.Errorf("vlan connections require vlan settings")
+		}
+		if c.VLAN.ID < 1 || c.VLAN.ID > 4094 {
+			return fmt.Errorf("vlan id must be between 1 and 4094")
+		}
+		if err := validateInterfaceName(c.VLAN.Parent); err != nil || c.VLAN.Parent == "" {
+			return fmt.Errorf("vlan connections require a valid parent interface")
+		}
+	default:
+		return fmt.Errorf("unsupported connection type %q", c.Type)
+	}
+
+	if c.Bond != nil && connType != NetworkConnectionTypeBond {
+		return fmt.Errorf("bond settings are only supported for bond connections")
+	}
+	if c.VLAN != nil && connType != NetworkConnectionTypeVLAN {
+		return fmt.Errorf("vlan settings are only supported for vlan connections")
+	}
+	if c.Bridge != nil && connType != NetworkConnectionTypeBridge {
+		return fmt.Errorf("bridge settings are only supported for bridge connections")
+	}
+	if c.Bond != nil && c.Bond.Mode != "" && !common.IsStringInSortedSlice(bondModes, c.Bond.Mode) {
+		return fmt.Errorf("unsupported bond mode %q", c.Bond.Mode)
+	}
+	if c.Bond != nil {
+		for key, value := range c.Bond.Options {
+			if key == "" || key == "mode" || strings.ContainsAny(key, "=,[]\n") || strings.Contains(value, "\n") {
+				return fmt.Errorf("invalid bond option %q", key)
+			}
+		}
+	}
+
+	if iface := c.interfaceName(); iface != "" {
+		if err := validateInterfaceName(iface); err != nil {
+			return err
+		}
+	}
+	if c.MACAddress != "" {
+		if _, err := net.ParseMAC(c.MACAddress); err != nil {
+			return fmt.Errorf("invalid MAC address %q", c.MACAddress)
+		}
+	}
+
+	if c.Controller != "" {
+		controller, exists := connections[c.Controller]
+		if !exists {
+			return fmt.Errorf("controller %q is not defined", c.Controller)
+		}
+		if t := controller.getType(); t != NetworkConnectionTypeBond && t != NetworkConnectionTypeBridge {
+			return fmt.Errorf("controller %q must be a bond or bridge connection", c.Controller)
+		}
+		if c.Controller == c.Name {
+			return fmt.Errorf("a connection cannot be its own controller")
+		}
+		if c.IPv4 != nil || c.IPv6 != nil {
+			return fmt.Errorf("ports of a %s cannot have an IP configuration", controller.getType())
+		}
+	}
+
+	if c.IPv4 != nil {
+		if err := c.IPv4.validate(false); err != nil {
+			return fmt.Errorf("ipv4: %w", err)
+		}
+	}
+	if c.IPv6 != nil {
+		if err := c.IPv6.validate(true); err != nil {
+			return fmt.Errorf("ipv6: %w", err)
+		}
+	}
+	return nil
+}
+
+func (ip *NetworkIPCustomization) getMethod() string {
+	if ip.Method != "" {
+		return ip.Method
+	}
+	if len(ip.Addresses) > 0 {
+		return "manual"
+	}
+	return "auto"
+}
+
+// ipFamilyMatches returns true if the IP address belongs to the expected family
+func ipFamilyMatches(ip net.IP, ipv6 bool) bool {
+	return (ip.To4() == nil) == ipv6
+}
+
+func (ip *NetworkIPCustomization) validate(ipv6 bool) error {
+	methods := ipv4Methods
+	if ipv6 {
+		methods = ipv6Methods
+	}
+	method := ip.getMethod()
+	if !common.IsStringInSortedSlice(methods, method) {
+		return fmt.Errorf("unsupported method %q", method)
+	}
+	if method == "manual" && len(ip.Addresses) == 0 {
+		return fmt.Errorf("the manual method requires addresses")
+	}
+	if (method == "disabled" || method == "ignore") &&
+		(len(ip.Addresses) > 0 || ip.Gateway != "" || len(ip.DNS) > 0 || len(ip.Routes) > 0) {
+		return fmt.Errorf("the %s method does not support addresses, gateways, DNS or routes", method)
+	}
+
+	for _, addr := range ip.Addresses {
+		ipAddr, _, err := net.ParseCIDR(addr)
+		if err != nil || !ipFamilyMatches(ipAddr, ipv6) {
+			return fmt.Errorf("invalid address %q", addr)
+		}
+	}
+	if ip.Gateway != "" {
+		if gw := net.ParseIP(ip.Gateway); gw == nil || !ipFamilyMatches(gw, ipv6) {
+			return fmt.Errorf("invalid gateway %q", ip.Gateway)
+		}
+	}
+	for _, dns := range ip.DNS {
+		if server := net.ParseIP(dns); server == nil || !ipFamilyMatches(server, ipv6) {
+			return fmt.Errorf("invalid DNS server %q", dns)
+		}
+	}
+	for _, domain := range ip.DNSSearch {
+		if domain == "" || strings.ContainsAny(domain, "; \t\n") {
+			return fmt.Errorf("invalid DNS search domain %q", domain)
+		}
+	}
+	for _, route := range ip.Routes {
+		dst, _, err := net.ParseCIDR(route.Destination)
+		if err != nil || !ipFamilyMatches(dst, ipv6) {
+			return fmt.Errorf("invalid route destination %q", route.Destination)
+		}
+		if route.Gateway != "" {
+			if gw := net.ParseIP(route.Gateway); gw == nil || !ipFamilyMatches(gw, ipv6) {
+				return fmt.Errorf("invalid route gateway %q", route.Gateway)
+			}
+		}
+	}
+	return nil
+}
+
+// keyfile returns the connection profile in the NetworkManager keyfile
+// format.
+func (c *NetworkConnectionCustomization) keyfile(connections map[string]*NetworkConnectionCustomization) string {
+	var b strings.Builder
+	section := func(name string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", name)
+	}
+	key := func(name string, value interface{}) {
+		fmt.Fprintf(&b, "%s=%v\n", name, value)
+	}
+
+	connType := c.getType()
+	section("connection")
+	key("id", c.Name)
+	key("type", connType)
+	if iface := c.interfaceName(); iface != "" {
+		key("interface-name", iface)
+	}
+	if c.Autoconnect != nil {
+		key("autoconnect", *c.Autoconnect)
+	}
+	if c.Controller != "" {
+		// master and slave-type are understood by all NetworkManager
+		// versions, unlike their newer aliases
+		controller := connections[c.Controller]
+		key("master", controller.interfaceName())
+		key("slave-type", controller.getType())
+	}
+
+	if connType == NetworkConnectionTypeEthernet && (c.MACAddress != "" || c.MTU != 0) {
+		section("ethernet")
+		if c.MACAddress != "" {
+			key("mac-address", strings.ToUpper(c.MACAddress))
+		}
+		if c.MTU != 0 {
+			key("mtu", c.MTU)
+		}
+	}
+
+	switch connType {
+	case NetworkConnectionTypeBond:
+		section("bond")
+		mode := "balance-rr"
+		if c.Bond != nil && c.Bond.Mode != "" {
+			mode = c.Bond.Mode
+		}
+		key("mode", mode)
+		if c.Bond != nil {
+			options := make([]string, 0, len(c.Bond.Options))
+			for option := range c.Bond.Options {
+				options = append(options, option)
+			}
+			sort.Strings(options)
+			for _, option := range options {
+				key(option, c.Bond.Options[option])
+			}
+		}
+	case NetworkConnectionTypeVLAN:
+		section("vlan")
+		key("id", c.VLAN.ID)
+		key("parent", c.VLAN.Parent)
+	case NetworkConnectionTypeBridge:
+		section("bridge")
+		if c.Bridge != nil && c.Bridge.STP != nil {
+			key("stp", *c.Bridge.STP)
+		}
+	}
+
+	if c.Controller != "" {
+		// ports have no IP configuration
+		return b.String()
+	}
+
+	writeIP := func(name string, ip *NetworkIPCustomization) {
+		section(name)
+		if ip == nil {
+			key("method", "auto")
+			return
+		}
+		key("method", ip.getMethod())
+		for idx, addr := range ip.Addresses {
+			key(fmt.Sprintf("address%d", idx+1), addr)
+		}
+		if ip.Gateway != "" {
+			key("gateway", ip.Gateway)
+		}
+		if len(ip.DNS) > 0 {
+			key("dns", strings.Join(ip.DNS, ";")+";")
+		}
+		if len(ip.DNSSearch) > 0 {
+			key("dns-search", strings.Join(ip.DNSSearch, ";")+";")
+		}
+		for idx, route := range ip.Routes {
+			value := route.Destination
+			if route.Gateway != "" || route.Metric != nil {
+				value += "," + route.Gateway
+			}
+			if route.Metric != nil {
+				value += fmt.Sprintf(",%d", *route.Metric)
+			}
+			key(fmt.Sprintf("route%d", idx+1), value)
+		}
+	}
+	writeIP("ipv4", c.IPv4)
+	writeIP("ipv6", c.IPv6)
+
+	return b.String()
+}
+
+// checkKeyfileConflicts returns an error if a keyfile of the connection
+// profiles would be created at the same path as one of the custom files or
+// directories.
+func (n *NetworkCustomization) checkKeyfileConflicts(files []FileCustomization, dirs []DirectoryCustomization) error {
+	paths := make(map[string]bool, len(files)+len(dirs))
+	for _, file := range files {
+		paths[filepath.Clean(file.Path)] = true
+	}
+	for _, dir := range dirs {
+		paths[filepath.Clean(dir.Path)] = true
+	}
+	for idx := range n.Connections {
+		if path := n.Connections[idx].keyfilePath(); paths[path] {
+			return fmt.Errorf("network connection %q: keyfile %q conflicts with a file or directory customization", n.Connections[idx].Name, path)
+		}
+	}
+	return nil
+}
+
+// NetworkCustomizationToFsNodeFiles renders the connection profiles into
+// NetworkManager keyfiles. NetworkManager ignores keyfiles that are readable
+// by other users than root, so they are created with mode 0600.
+func NetworkCustomizationToFsNodeFiles(network *NetworkCustomization) ([]*fsnode.File, error) {
+	if network == nil || len(network.Connections) == 0 {
+		return nil, nil
+	}
+
+	connections := make(map[string]*NetworkConnectionCustomization, len(network.Connections))
+	for idx := range network.Connections {
+		connections[network.Connections[idx].Name] = &network.Connections[idx]
+	}
+
+	files := make([]*fsnode.File, 0, len(network.Connections))
+	for idx := range network.Connections {
+		conn := &network.Connections[idx]
+		file, err := fsnode.NewFile(conn.keyfilePath(), common.ToPtr(os.FileMode(0600)), nil, nil, []byte(conn.keyfile(connections)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
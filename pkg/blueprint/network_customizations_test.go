@@ -0,0 +1,289 @@
+package blueprint
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestNetworkCustomizationValidate(t *testing.T) {
+	testCases := map[string]struct {
+		connections []NetworkConnectionCustomization
+		wantErr     string
+	}{
+		"dhcp": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0"}},
+		},
+		"static-bond-with-vlan": {
+			connections: []NetworkConnectionCustomization{
+				{Name: "bond0", Type: "bond", Interface: "bond0", Bond: &NetworkBondCustomization{Mode: "active-backup"}, IPv4: &NetworkIPCustomization{Method: "disabled"}},
+				{Name: "bond0-port1", Interface: "eth0", Controller: "bond0"},
+				{Name: "bond0-port2", MACAddress: "52:54:00:12:34:56", Controller: "bond0"},
+				{Name: "vlan10", Type: "vlan", VLAN: &NetworkVLANCustomization{ID: 10, Parent: "bond0"}, IPv4: &NetworkIPCustomization{Addresses: []string{"192.168.10.5/24"}, Gateway: "192.168.10.1"}},
+			},
+		},
+		"invalid-name": {
+			connections: []NetworkConnectionCustomization{{Name: "../eth0", Interface: "eth0"}},
+			wantErr:     `network connection name "../eth0" is invalid`,
+		},
+		"duplicate-name": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0"}, {Name: "eth0", Interface: "eth1"}},
+			wantErr:     `duplicate network connection "eth0"`,
+		},
+		"unknown-type": {
+			connections: []NetworkConnectionCustomization{{Name: "wifi", Type: "wifi", Interface: "wlan0"}},
+			wantErr:     `network connection "wifi": unsupported connection type "wifi"`,
+		},
+		"ethernet-without-interface": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0"}},
+			wantErr:     `network connection "eth0": ethernet connections require an interface or a MAC address`,
+		},
+		"long-interface-name": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "averylonginterfacename"}},
+			wantErr:     `network connection "eth0": invalid interface name "averylonginterfacename"`,
+		},
+		"invalid-mac": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", MACAddress: "52:54:00"}},
+			wantErr:     `network connection "eth0": invalid MAC address "52:54:00"`,
+		},
+		"vlan-without-settings": {
+			connections: []NetworkConnectionCustomization{{Name: "vlan10", Type: "vlan"}},
+			wantErr:     `network connection "vlan10": vlan connections require vlan settings`,
+		},
+		"vlan-invalid-id": {
+			connections: []NetworkConnectionCustomization{{Name: "vlan0", Type: "vlan", VLAN: &NetworkVLANCustomization{ID: 4095, Parent: "eth0"}}},
+			wantErr:     `network connection "vlan0": vlan id must be between 1 and 4094`,
+		},
+		"bond-settings-on-ethernet": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", Bond: &NetworkBondCustomization{}}},
+			wantErr:     `network connection "eth0": bond settings are only supported for bond connections`,
+		},
+		"bad-bond-mode": {
+			connections: []NetworkConnectionCustomization{{Name: "bond0", Type: "bond", Interface: "bond0", Bond: &NetworkBondCustomization{Mode: "fastest"}}},
+			wantErr:     `network connection "bond0": unsupported bond mode "fastest"`,
+		},
+		"bad-bond-option": {
+			connections: []NetworkConnectionCustomization{{Name: "bond0", Type: "bond", Interface: "bond0", Bond: &NetworkBondCustomization{Options: map[string]string{"miimon": "100\n[ipv4]"}}}},
+			wantErr:     `network connection "bond0": invalid bond option "miimon"`,
+		},
+		"undefined-controller": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", Controller: "br0"}},
+			wantErr:     `network connection "eth0": controller "br0" is not defined`,
+		},
+		"ethernet-controller": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0"}, {Name: "eth1", Interface: "eth1", Controller: "eth0"}},
+			wantErr:     `network connection "eth1": controller "eth0" must be a bond or bridge connection`,
+		},
+		"port-with-ip": {
+			connections: []NetworkConnectionCustomization{
+				{Name: "br0", Type: "bridge", Interface: "br0"},
+				{Name: "eth0", Interface: "eth0", Controller: "br0", IPv4: &NetworkIPCustomization{}},
+			},
+			wantErr: `network connection "eth0": ports of a bridge cannot have an IP configuration`,
+		},
+		"manual-without-addresses": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv4: &NetworkIPCustomization{Method: "manual"}}},
+			wantErr:     `network connection "eth0": ipv4: the manual method requires addresses`,
+		},
+		"ipv4-dhcp-method": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv4: &NetworkIPCustomization{Method: "dhcp"}}},
+			wantErr:     `network connection "eth0": ipv4: unsupported method "dhcp"`,
+		},
+		"ipv6-address-in-ipv4": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv4: &NetworkIPCustomization{Addresses: []string{"fd00::5/64"}}}},
+			wantErr:     `network connection "eth0": ipv4: invalid address "fd00::5/64"`,
+		},
+		"address-without-prefix": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv6: &NetworkIPCustomization{Addresses: []string{"fd00::5"}}}},
+			wantErr:     `network connection "eth0": ipv6: invalid address "fd00::5"`,
+		},
+		"invalid-dns": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv4: &NetworkIPCustomization{DNS: []string{"dns.example.com"}}}},
+			wantErr:     `network connection "eth0": ipv4: invalid DNS server "dns.example.com"`,
+		},
+		"disabled-with-addresses": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv6: &NetworkIPCustomization{Method: "disabled", Addresses: []string{"fd00::5/64"}}}},
+			wantErr:     `network connection "eth0": ipv6: the disabled method does not support addresses, gateways, DNS or routes`,
+		},
+		"invalid-route": {
+			connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0", IPv4: &NetworkIPCustomization{Routes: []NetworkRouteCustomization{{Destination: "10.0.0.0"}}}}},
+			wantErr:     `network connection "eth0": ipv4: invalid route destination "10.0.0.0"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{Network: &NetworkCustomization{Connections: tc.connections}}
+			network, err := c.GetNetwork()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, c.Network, network)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetNetworkFileConflict(t *testing.T) {
+	network := &NetworkCustomization{Connections: []NetworkConnectionCustomization{{Name: "eth0", Interface: "eth0"}}}
+
+	c := &Customizations{
+		Network: network,
+		Files: []FileCustomization{
+			{Path: "/etc/NetworkManager/system-connections/eth1.nmconnection", Data: "[connection]\n"},
+		},
+	}
+	_, err := c.GetNetwork()
+	assert.NoError(t, err)
+
+	c.Files = append(c.Files, FileCustomization{Path: "/etc/NetworkManager/system-connections/eth0.nmconnection", Data: "[connection]\n"})
+	_, err = c.GetNetwork()
+	assert.EqualError(t, err, `network connection "eth0": keyfile "/etc/NetworkManager/system-connections/eth0.nmconnection" conflicts with a file or directory customization`)
+
+	c.Files = nil
+	c.Directories = []DirectoryCustomization{{Path: "/etc/NetworkManager/system-connections/eth0.nmconnection/"}}
+	_, err = c.GetNetwork()
+	assert.EqualError(t, err, `network connection "eth0": keyfile "/etc/NetworkManager/system-connections/eth0.nmconnection" conflicts with a file or directory customization`)
+}
+
+func TestGetNetworkEmpty(t *testing.T) {
+	var c *Customizations
+	network, err := c.GetNetwork()
+	assert.NoError(t, err)
+	assert.Nil(t, network)
+}
+
+func TestNetworkCustomizationToFsNodeFiles(t *testing.T) {
+	network := &NetworkCustomization{
+		Connections: []NetworkConnectionCustomization{
+			{
+				Name:      "uplink",
+				Interface: "eth2",
+				MTU:       9000,
+				IPv4: &NetworkIPCustomization{
+					Addresses: []string{"192.168.1.10/24", "192.168.1.11/24"},
+					Gateway:   "192.168.1.1",
+					DNS:       []string{"192.168.1.2", "192.168.1.3"},
+					DNSSearch: []string{"example.com"},
+					Routes: []NetworkRouteCustomization{
+						{Destination: "10.0.0.0/8", Gateway: "192.168.1.254", Metric: common.ToPtr(uint(100))},
+						{Destination: "172.16.0.0/12"},
+					},
+				},
+				IPv6: &NetworkIPCustomization{Method: "disabled"},
+			},
+			{
+				Name:      "bond0",
+				Type:      "bond",
+				Interface: "bond0",
+				Bond: &NetworkBondCustomization{
+					Mode:    "802.3ad",
+					Options: map[string]string{"miimon": "100", "lacp_rate": "fast"},
+				},
+			},
+			{Name: "bond0-port", MACAddress: "52:54:00:ab:cd:ef", Controller: "bond0", Autoconnect: common.ToPtr(true)},
+			{Name: "br0", Type: "bridge", Interface: "br0", Bridge: &NetworkBridgeCustomization{STP: common.ToPtr(false)}},
+			{Name: "vlan10", Type: "vlan", VLAN: &NetworkVLANCustomization{ID: 10, Parent: "bond0"}, Controller: "br0"},
+		},
+	}
+	require.NoError(t, network.Validate())
+
+	files, err := NetworkCustomizationToFsNodeFiles(network)
+	require.NoError(t, err)
+	require.Len(t, files, 5)
+
+	for _, file := range files {
+		assert.Equal(t, os.FileMode(0600), *file.Mode())
+		assert.Nil(t, file.User())
+		assert.Nil(t, file.Group())
+	}
+
+	assert.Equal(t, "/etc/NetworkManager/system-connections/uplink.nmconnection", files[0].Path())
+	assert.Equal(t, `[connection]
+id=uplink
+type=ethernet
+interface-name=eth2
+
+[ethernet]
+mtu=9000
+
+[ipv4]
+method=manual
+address1=192.168.1.10/24
+address2=192.168.1.11/24
+gateway=192.168.1.1
+dns=192.168.1.2;192.168.1.3;
+dns-search=example.com;
+route1=10.0.0.0/8,192.168.1.254,100
+route2=172.16.0.0/12
+
+[ipv6]
+method=disabled
+`, string(files[0].Data()))
+
+	assert.Equal(t, `[connection]
+id=bond0
+type=bond
+interface-name=bond0
+
+[bond]
+mode=802.3ad
+lacp_rate=fast
+miimon=100
+
+[ipv4]
+method=auto
+
+[ipv6]
+method=auto
+`, string(files[1].Data()))
+
+	assert.Equal(t, `[connection]
+id=bond0-port
+type=ethernet
+autoconnect=true
+master=bond0
+slave-type=bond
+
+[ethernet]
+mac-address=52:54:00:AB:CD:EF
+`, string(files[2].Data()))
+
+	assert.Equal(t, `[connection]
+id=br0
+type=bridge
+interface-name=br0
+
+[bridge]
+stp=false
+
+[ipv4]
+method=auto
+
+[ipv6]
+method=auto
+`, string(files[3].Data()))
+
+	assert.Equal(t, `[connection]
+id=vlan10
+type=vlan
+interface-name=bond0.10
+master=br0
+slave-type=bridge
+
+[vlan]
+id=10
+parent=bond0
+`, string(files[4].Data()))
+}
+
+func TestNetworkCustomizationToFsNodeFilesEmpty(t *testing.T) {
+	files, err := NetworkCustomizationToFsNodeFiles(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -0,0 +1,127 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	packageArchRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	// name, version and release of pins must not contain globs
+	packagePinFieldRegex = regexp.MustCompile(`^[A-Za-z0-9._+~^-]+$`)
+	// exclude specs are passed to dnf and may contain globs
+	packageExcludeRegex = regexp.MustCompile(`^[A-Za-z0-9._+~^:*?\[\]-]+$`)
+)
+
+// A PackagePin is an exact name-[epoch:]version-release.arch of a package.
+type PackagePin struct {
+	Name    string
+	Epoch   uint
+	Version string
+	Release string
+	Arch    string
+}
+
+// String returns the pin as a dnf package spec, which always includes the
+// epoch so that it matches a single package.
+func (p PackagePin) String() string {
+	return fmt.Sprintf("%s-%d:%s-%s.%s", p.Name, p.Epoch, p.Version, p.Release, p.Arch)
+}
+
+// ParsePackagePin parses a name-[epoch:]version-release.arch string, e.g.
+// bash-0:5.1.8-6.el9.x86_64.
+func ParsePackagePin(nevra string) (PackagePin, error) {
+	invalid := fmt.Errorf("package pin %q is not in the name-[epoch:]version-release.arch format", nevra)
+
+	dot := strings.LastIndex(nevra, ".")
+	if dot == -1 {
+		return PackagePin{}, invalid
+	}
+	pin := PackagePin{Arch: nevra[dot+1:]}
+	nevr := nevra[:dot]
+
+	dash := strings.LastIndex(nevr, "-")
+	if dash == -1 {
+		return PackagePin{}, invalid
+	}
+	pin.Release = nevr[dash+1:]
+	nev := nevr[:dash]
+
+	dash = strings.LastIndex(nev, "-")
+	if dash == -1 {
+		return PackagePin{}, invalid
+	}
+	pin.Name = nev[:dash]
+	ev := nev[dash+1:]
+
+	if epoch, version, found := strings.Cut(ev, ":"); found {
+		e, err := strconv.ParseUint(epoch, 10, 32)
+		if err != nil {
+			return PackagePin{}, invalid
+		}
+		pin.Epoch = uint(e)
+		ev = version
+	}
+	pin.Version = ev
+
+	if !packageArchRegex.MatchString(pin.Arch) {
+		return PackagePin{}, invalid
+	}
+	for _, field := range []string{pin.Name, pin.Version, pin.Release} {
+		if !packagePinFieldRegex.MatchString(field) {
+			return PackagePin{}, invalid
+		}
+	}
+	return pin, nil
+}
+
+// GetPackagePins returns the parsed package pins of the blueprint.
+func (b *Blueprint) GetPackagePins() ([]PackagePin, error) {
+	var pins []PackagePin
+	for _, nevra := range b.PackagePins {
+		pin, err := ParsePackagePin(nevra)
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// ValidatePackageSelection checks that the package pins and excludes of the
+// blueprint are valid and don't contradict each other or the packages of the
+// blueprint.
+func (b *Blueprint) ValidatePackageSelection() error {
+	pins, err := b.GetPackagePins()
+	if err != nil {
+		return err
+	}
+
+	requested := make(map[string]bool)
+	for _, pkg := range append(append([]Package{}, b.Packages...), b.Modules...) {
+		requested[pkg.Name] = true
+	}
+
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		if pinned[pin.Name] {
+			return fmt.Errorf("package %q is pinned more than once", pin.Name)
+		}
+		pinned[pin.Name] = true
+		if requested[pin.Name] {
+			return fmt.Errorf("package %q is both pinned and listed in packages", pin.Name)
+		}
+	}
+
+	for _, exclude := range b.ExcludePackages {
+		if !packageExcludeRegex.MatchString(exclude) {
+			return fmt.Errorf("package exclude %q is invalid", exclude)
+		}
+		if requested[exclude] || pinned[exclude] {
+			return fmt.Errorf("package %q is both excluded and requested", exclude)
+		}
+	}
+	return nil
+}
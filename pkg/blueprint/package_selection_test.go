@@ -0,0 +1,125 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackagePin(t *testing.T) {
+	tests := []struct {
+		nevra    string
+		expected PackagePin
+	}{
+		{"bash-5.1.8-6.el9.x86_64", PackagePin{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"}},
+		{"shadow-utils-2:4.9-6.el9.x86_64", PackagePin{Name: "shadow-utils", Epoch: 2, Version: "4.9", Release: "6.el9", Arch: "x86_64"}},
+		{"python3-dnf-plugins-core-0:4.3.0-5.el9.noarch", PackagePin{Name: "python3-dnf-plugins-core", Version: "4.3.0", Release: "5.el9", Arch: "noarch"}},
+		{"libstdc++-11.3.1-4.3.el9.aarch64", PackagePin{Name: "libstdc++", Version: "11.3.1", Release: "4.3.el9", Arch: "aarch64"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nevra, func(t *testing.T) {
+			pin, err := ParsePackagePin(tt.nevra)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pin)
+		})
+	}
+
+	invalid := []string{
+		"bash",
+		"bash-5.1.8",
+		"bash-5.1.8.x86_64",
+		"bash-5.1.8-6.el9.",
+		"bash-x:5.1.8-6.el9.x86_64",
+		"bash-5.1.*-6.el9.x86_64",
+		"-5.1.8-6.el9.x86_64",
+	}
+	for _, nevra := range invalid {
+		t.Run(nevra, func(t *testing.T) {
+			_, err := ParsePackagePin(nevra)
+			assert.EqualError(t, err, "package pin \""+nevra+"\" is not in the name-[epoch:]version-release.arch format")
+		})
+	}
+}
+
+func TestPackagePinString(t *testing.T) {
+	pin := PackagePin{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"}
+	assert.Equal(t, "bash-0:5.1.8-6.el9.x86_64", pin.String())
+}
+
+func TestGetPackagesExWithPins(t *testing.T) {
+	bp := Blueprint{
+		Packages:    []Package{{Name: "tmux"}},
+		PackagePins: []string{"bash-5.1.8-6.el9.x86_64"},
+	}
+	assert.ElementsMatch(t, []string{"tmux", "bash-0:5.1.8-6.el9.x86_64"}, bp.GetPackagesEx(false))
+}
+
+func TestValidatePackageSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		bp   Blueprint
+		err  string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			bp: Blueprint{
+				Packages:        []Package{{Name: "tmux"}},
+				PackagePins:     []string{"bash-5.1.8-6.el9.x86_64"},
+				ExcludePackages: []string{"dracut-config-rescue", "iwl*-firmware"},
+			},
+		},
+		{
+			name: "invalid-pin",
+			bp:   Blueprint{PackagePins: []string{"bash"}},
+			err:  `package pin "bash" is not in the name-[epoch:]version-release.arch format`,
+		},
+		{
+			name: "pinned-twice",
+			bp:   Blueprint{PackagePins: []string{"bash-5.1.8-6.el9.x86_64", "bash-5.1.8-7.el9.x86_64"}},
+			err:  `package "bash" is pinned more than once`,
+		},
+		{
+			name: "pinned-and-requested",
+			bp: Blueprint{
+				Packages:    []Package{{Name: "bash"}},
+				PackagePins: []string{"bash-5.1.8-6.el9.x86_64"},
+			},
+			err: `package "bash" is both pinned and listed in packages`,
+		},
+		{
+			name: "invalid-exclude",
+			bp:   Blueprint{ExcludePackages: []string{"bash; rm -rf /"}},
+			err:  `package exclude "bash; rm -rf /" is invalid`,
+		},
+		{
+			name: "excluded-and-requested",
+			bp: Blueprint{
+				Modules:         []Package{{Name: "tmux"}},
+				ExcludePackages: []string{"tmux"},
+			},
+			err: `package "tmux" is both excluded and requested`,
+		},
+		{
+			name: "excluded-and-pinned",
+			bp: Blueprint{
+				PackagePins:     []string{"bash-5.1.8-6.el9.x86_64"},
+				ExcludePackages: []string{"bash"},
+			},
+			err: `package "bash" is both excluded and requested`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.bp.ValidatePackageSelection()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
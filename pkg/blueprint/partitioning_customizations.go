@@ -0,0 +1,309 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/osbuild/images/internal/pathpolicy"
+)
+
+// PartitioningCustomization describes a complete disk layout. When set, it
+// replaces the base partition table of the image type. Partitions that are
+// required for booting, e.g. the BIOS boot partition or the ESP, are added
+// from the base partition table if they are not part of the layout.
+type PartitioningCustomization struct {
+	// Partition table type: "gpt" or "dos". Defaults to the type of the
+	// partition table of the image type.
+	Type       string                   `json:"type,omitempty" toml:"type,omitempty"`
+	Partitions []PartitionCustomization `json:"partitions,omitempty" toml:"partitions,omitempty"`
+}
+
+// PartitionCustomization describes a single partition. At most one of
+// Filesystem, VolumeGroup and Btrfs can be set; a partition without any
+// payload is a raw partition and must specify its PartType.
+type PartitionCustomization struct {
+	// Partition type: a GUID for gpt or a hex id (e.g. "83") for dos
+	// partition tables. Derived from the payload if empty.
+	PartType string `json:"part_type,omitempty" toml:"part_type,omitempty"`
+	MinSize  uint64 `json:"minsize,omitempty" toml:"minsize,omitempty"`
+	Bootable bool   `json:"bootable,omitempty" toml:"bootable,omitempty"`
+
+	// If set, the payload of the partition is placed inside a LUKS2
+	// container.
+	LUKS *LUKSCustomization `json:"luks,omitempty" toml:"luks,omitempty"`
+
+	Filesystem  *FilesystemTypedCustomization `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
+	VolumeGroup *VolumeGroupCustomization     `json:"lvm,omitempty" toml:"lvm,omitempty"`
+	Btrfs       *BtrfsCustomization           `json:"btrfs,omitempty" toml:"btrfs,omitempty"`
+}
+
+type FilesystemTypedCustomization struct {
+	Type         string `json:"type" toml:"type"`
+	Mountpoint   string `json:"mountpoint" toml:"mountpoint"`
+	Label        string `json:"label,omitempty" toml:"label,omitempty"`
+	FSTabOptions string `json:"fstab_options,omitempty" toml:"fstab_options,omitempty"`
+}
+
+type VolumeGroupCustomization struct {
+	Name           string                       `json:"name" toml:"name"`
+	LogicalVolumes []LogicalVolumeCustomization `json:"logical_volumes,omitempty" toml:"logical_volumes,omitempty"`
+}
+
+type LogicalVolumeCustomization struct {
+	Name       string                       `json:"name,omitempty" toml:"name,omitempty"`
+	MinSize    uint64                       `json:"minsize,omitempty" toml:"minsize,omitempty"`
+	Filesystem FilesystemTypedCustomization `json:"filesystem" toml:"filesystem"`
+}
+
+type BtrfsCustomization struct {
+	Label      string                        `json:"label,omitempty" toml:"label,omitempty"`
+	Subvolumes []BtrfsSubvolumeCustomization `json:"subvolumes,omitempty" toml:"subvolumes,omitempty"`
+}
+
+type BtrfsSubvolumeCustomization struct {
+	Name       string `json:"name" toml:"name"`
+	Mountpoint string `json:"mountpoint" toml:"mountpoint"`
+	MinSize    uint64 `json:"minsize,omitempty" toml:"minsize,omitempty"`
+}
+
+type LUKSCustomization struct {
+	Passphrase string `json:"passphrase" toml:"passphrase"`
+	Label      string `json:"label,omitempty" toml:"label,omitempty"`
+	Cipher     string `json:"cipher,omitempty" toml:"cipher,omitempty"`
+}
+
+// ESPMountpoint is the mountpoint of the EFI system partition, which is
+// always allowed in a partitioning customization.
+const ESPMountpoint = "/boot/efi"
+
+// LVM names may only contain alphanumeric characters and "+_.-"; see lvm(8)
+var lvmNameRegex = regexp.MustCompile(`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`)
+
+var supportedFilesystemTypes = map[string]bool{
+	"xfs":  true,
+	"ext4": true,
+	"vfat": true,
+}
+
+// Mountpoints returns all the mountpoints defined in the layout
+func (pc *PartitioningCustomization) Mountpoints() []string {
+	if pc == nil {
+		return nil
+	}
+
+	mountpoints := []string{}
+	for _, part := range pc.Partitions {
+		if part.Filesystem != nil {
+			mountpoints = append(mountpoints, part.Filesystem.Mountpoint)
+		}
+		if part.VolumeGroup != nil {
+			for _, lv := range part.VolumeGroup.LogicalVolumes {
+				mountpoints = append(mountpoints, lv.Filesystem.Mountpoint)
+			}
+		}
+		if part.Btrfs != nil {
+			for _, subvol := range part.Btrfs.Subvolumes {
+				mountpoints = append(mountpoints, subvol.Mountpoint)
+			}
+		}
+	}
+	return mountpoints
+}
+
+// HasBtrfs returns true if any of the partitions contains a btrfs volume
+func (pc *PartitioningCustomization) HasBtrfs() bool {
+	if pc == nil {
+		return false
+	}
+	for _, part := range pc.Partitions {
+		if part.Btrfs != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the partitioning customization describes a consistent
+// layout, i.e. that every partition has a valid payload, that volume group,
+// logical volume and subvolume names are valid and unique and that every
+// mountpoint is used only once, including a root mountpoint.
+func (pc *PartitioningCustomization) Validate() error {
+	if pc == nil {
+		return nil
+	}
+
+	switch pc.Type {
+	case "", "gpt":
+	case "dos":
+		if len(pc.Partitions) > 4 {
+			return fmt.Errorf("partitioning: dos partition tables support at most 4 partitions, got %d", len(pc.Partitions))
+		}
+	default:
+		return fmt.Errorf("partitioning: unsupported partition table type %q", pc.Type)
+	}
+
+	if len(pc.Partitions) == 0 {
+		return fmt.Errorf("partitioning: at least one partition is required")
+	}
+
+	vgnames := make(map[string]bool)
+	for idx, part := range pc.Partitions {
+		payloads := 0
+		if part.Filesystem != nil {
+			payloads++
+			if err := part.Filesystem.validate(); err != nil {
+				return fmt.Errorf("partitioning: partition %d: %w", idx, err)
+			}
+		}
+		if part.VolumeGroup != nil {
+			payloads++
+			if err := part.VolumeGroup.validate(); err != nil {
+				return fmt.Errorf("partitioning: partition %d: %w", idx, err)
+			}
+			if vgnames[part.VolumeGroup.Name] {
+				return fmt.Errorf("partitioning: partition %d: duplicate volume group name %q", idx, part.VolumeGroup.Name)
+			}
+			vgnames[part.VolumeGroup.Name] = true
+		}
+		if part.Btrfs != nil {
+			payloads++
+			if err := part.Btrfs.validate(); err != nil {
+				return fmt.Errorf("partitioning: partition %d: %w", idx, err)
+			}
+		}
+
+		switch payloads {
+		case 0:
+			if part.PartType == "" {
+				return fmt.Errorf("partitioning: partition %d: raw partitions require a partition type", idx)
+			}
+			if part.LUKS != nil {
+				return fmt.Errorf("partitioning: partition %d: encryption requires a payload", idx)
+			}
+		case 1:
+		default:
+			return fmt.Errorf("partitioning: partition %d: only one of filesystem, lvm or btrfs can be specified", idx)
+		}
+
+		if part.LUKS != nil {
+			if part.LUKS.Passphrase == "" {
+				return fmt.Errorf("partitioning: partition %d: encryption requires a passphrase", idx)
+			}
+			if part.Filesystem != nil && (part.Filesystem.Mountpoint == ESPMountpoint || part.Filesystem.Mountpoint == "/boot") {
+				return fmt.Errorf("partitioning: partition %d: %q cannot be encrypted", idx, part.Filesystem.Mountpoint)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, mountpoint := range pc.Mountpoints() {
+		if seen[mountpoint] {
+			return fmt.Errorf("partitioning: duplicate mountpoint %q", mountpoint)
+		}
+		seen[mountpoint] = true
+	}
+	if !seen["/"] {
+		return fmt.Errorf("partitioning: no root (\"/\") mountpoint defined")
+	}
+
+	return nil
+}
+
+func validateMountpoint(mountpoint string) error {
+	if mountpoint == "" {
+		return fmt.Errorf("mountpoint is required")
+	}
+	if !filepath.IsAbs(mountpoint) || filepath.Clean(mountpoint) != mountpoint {
+		return fmt.Errorf("mountpoint %q must be a clean, absolute path", mountpoint)
+	}
+	return nil
+}
+
+func (fs *FilesystemTypedCustomization) validate() error {
+	if err := validateMountpoint(fs.Mountpoint); err != nil {
+		return err
+	}
+	if !supportedFilesystemTypes[fs.Type] {
+		return fmt.Errorf("unsupported filesystem type %q for %q", fs.Type, fs.Mountpoint)
+	}
+	if fs.Mountpoint == ESPMountpoint && fs.Type != "vfat" {
+		return fmt.Errorf("%q must be a vfat filesystem, got %q", ESPMountpoint, fs.Type)
+	}
+	return nil
+}
+
+func (vg *VolumeGroupCustomization) validate() error {
+	if !lvmNameRegex.MatchString(vg.Name) {
+		return fmt.Errorf("invalid volume group name %q", vg.Name)
+	}
+	if len(vg.LogicalVolumes) == 0 {
+		return fmt.Errorf("volume group %q has no logical volumes", vg.Name)
+	}
+
+	names := make(map[string]bool)
+	for _, lv := range vg.LogicalVolumes {
+		if lv.Name != "" {
+			if !lvmNameRegex.MatchString(lv.Name) {
+				return fmt.Errorf("invalid logical volume name %q", lv.Name)
+			}
+			if names[lv.Name] {
+				return fmt.Errorf("duplicate logical volume name %q in volume group %q", lv.Name, vg.Name)
+			}
+			names[lv.Name] = true
+		}
+		if err := lv.Filesystem.validate(); err != nil {
+			return err
+		}
+		if lv.Filesystem.Mountpoint == ESPMountpoint || lv.Filesystem.Mountpoint == "/boot" {
+			return fmt.Errorf("%q cannot be placed on a logical volume", lv.Filesystem.Mountpoint)
+		}
+	}
+	return nil
+}
+
+func (b *BtrfsCustomization) validate() error {
+	if len(b.Subvolumes) == 0 {
+		return fmt.Errorf("btrfs volume has no subvolumes")
+	}
+
+	names := make(map[string]bool)
+	for _, subvol := range b.Subvolumes {
+		if subvol.Name == "" {
+			return fmt.Errorf("btrfs subvolume for %q requires a name", subvol.Mountpoint)
+		}
+		if names[subvol.Name] {
+			return fmt.Errorf("duplicate btrfs subvolume name %q", subvol.Name)
+		}
+		names[subvol.Name] = true
+		if err := validateMountpoint(subvol.Mountpoint); err != nil {
+			return err
+		}
+		if subvol.Mountpoint == ESPMountpoint || subvol.Mountpoint == "/boot" {
+			return fmt.Errorf("%q cannot be placed on a btrfs subvolume", subvol.Mountpoint)
+		}
+	}
+	return nil
+}
+
+// CheckPartitioningPolicy checks if the mountpoints of the partitioning
+// customization are allowed by the policy. The EFI system partition is always
+// allowed.
+func CheckPartitioningPolicy(pc *PartitioningCustomization, mountpointAllowList *pathpolicy.PathPolicies) error {
+	invalidMountpoints := []string{}
+	for _, m := range pc.Mountpoints() {
+		if m == ESPMountpoint {
+			continue
+		}
+		err := mountpointAllowList.Check(m)
+		if err != nil {
+			invalidMountpoints = append(invalidMountpoints, m)
+		}
+	}
+
+	if len(invalidMountpoints) > 0 {
+		return fmt.Errorf("The following custom mountpoints are not supported %+q", invalidMountpoints)
+	}
+
+	return nil
+}
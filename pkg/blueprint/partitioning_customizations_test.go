@@ -0,0 +1,272 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/internal/pathpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitioningCustomizationParse(t *testing.T) {
+	blueprint := `
+name = "test"
+
+[customizations.partitioning]
+type = "gpt"
+
+[[customizations.partitioning.partitions]]
+minsize = 1073741824
+[customizations.partitioning.partitions.filesystem]
+type = "xfs"
+mountpoint = "/boot"
+
+[[customizations.partitioning.partitions]]
+[customizations.partitioning.partitions.lvm]
+name = "rootvg"
+
+[[customizations.partitioning.partitions.lvm.logical_volumes]]
+name = "rootlv"
+minsize = 2147483648
+filesystem = { type = "xfs", mountpoint = "/" }
+
+[[customizations.partitioning.partitions.lvm.logical_volumes]]
+filesystem = { type = "ext4", mountpoint = "/var/log", label = "logs" }
+`
+
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	pc := bp.Customizations.GetPartitioning()
+	require.NotNil(t, pc)
+	assert.NoError(t, pc.Validate())
+	assert.Equal(t, "gpt", pc.Type)
+	require.Len(t, pc.Partitions, 2)
+	assert.Equal(t, "/boot", pc.Partitions[0].Filesystem.Mountpoint)
+	assert.Equal(t, uint64(1073741824), pc.Partitions[0].MinSize)
+	require.NotNil(t, pc.Partitions[1].VolumeGroup)
+	assert.Equal(t, "rootvg", pc.Partitions[1].VolumeGroup.Name)
+	require.Len(t, pc.Partitions[1].VolumeGroup.LogicalVolumes, 2)
+	assert.Equal(t, "ext4", pc.Partitions[1].VolumeGroup.LogicalVolumes[1].Filesystem.Type)
+	assert.Equal(t, []string{"/boot", "/", "/var/log"}, pc.Mountpoints())
+}
+
+func TestPartitioningCustomizationValidate(t *testing.T) {
+	root := &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/"}
+
+	testCases := []struct {
+		name    string
+		pc      PartitioningCustomization
+		wantErr string
+	}{
+		{
+			name: "plain",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: &FilesystemTypedCustomization{Type: "vfat", Mountpoint: "/boot/efi"}},
+					{Filesystem: root},
+				},
+			},
+		},
+		{
+			name: "encrypted-btrfs",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						LUKS: &LUKSCustomization{Passphrase: "secret"},
+						Btrfs: &BtrfsCustomization{
+							Subvolumes: []BtrfsSubvolumeCustomization{
+								{Name: "root", Mountpoint: "/"},
+								{Name: "home", Mountpoint: "/home"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "no-partitions",
+			pc:      PartitioningCustomization{},
+			wantErr: "partitioning: at least one partition is required",
+		},
+		{
+			name: "bad-type",
+			pc: PartitioningCustomization{
+				Type:       "apm",
+				Partitions: []PartitionCustomization{{Filesystem: root}},
+			},
+			wantErr: `partitioning: unsupported partition table type "apm"`,
+		},
+		{
+			name: "no-root",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/data"}},
+				},
+			},
+			wantErr: `partitioning: no root ("/") mountpoint defined`,
+		},
+		{
+			name: "duplicate-mountpoint",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: root},
+					{
+						VolumeGroup: &VolumeGroupCustomization{
+							Name: "vg",
+							LogicalVolumes: []LogicalVolumeCustomization{
+								{Filesystem: *root},
+							},
+						},
+					},
+				},
+			},
+			wantErr: `partitioning: duplicate mountpoint "/"`,
+		},
+		{
+			name: "multiple-payloads",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						Filesystem: root,
+						Btrfs: &BtrfsCustomization{
+							Subvolumes: []BtrfsSubvolumeCustomization{
+								{Name: "data", Mountpoint: "/data"},
+							},
+						},
+					},
+				},
+			},
+			wantErr: "partitioning: partition 0: only one of filesystem, lvm or btrfs can be specified",
+		},
+		{
+			name: "raw-without-type",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{MinSize: 1024},
+					{Filesystem: root},
+				},
+			},
+			wantErr: "partitioning: partition 0: raw partitions require a partition type",
+		},
+		{
+			name: "unsupported-fs",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: &FilesystemTypedCustomization{Type: "zfs", Mountpoint: "/"}},
+				},
+			},
+			wantErr: `partitioning: partition 0: unsupported filesystem type "zfs" for "/"`,
+		},
+		{
+			name: "relative-mountpoint",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "var/"}},
+				},
+			},
+			wantErr: `partitioning: partition 0: mountpoint "var/" must be a clean, absolute path`,
+		},
+		{
+			name: "bad-vg-name",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						VolumeGroup: &VolumeGroupCustomization{
+							Name: "-vg",
+							LogicalVolumes: []LogicalVolumeCustomization{
+								{Filesystem: *root},
+							},
+						},
+					},
+				},
+			},
+			wantErr: `partitioning: partition 0: invalid volume group name "-vg"`,
+		},
+		{
+			name: "boot-on-lv",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						VolumeGroup: &VolumeGroupCustomization{
+							Name: "vg",
+							LogicalVolumes: []LogicalVolumeCustomization{
+								{Filesystem: *root},
+								{Filesystem: FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/boot"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: `partitioning: partition 0: "/boot" cannot be placed on a logical volume`,
+		},
+		{
+			name: "duplicate-lv-name",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						VolumeGroup: &VolumeGroupCustomization{
+							Name: "vg",
+							LogicalVolumes: []LogicalVolumeCustomization{
+								{Name: "data", Filesystem: *root},
+								{Name: "data", Filesystem: FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/var"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: `partitioning: partition 0: duplicate logical volume name "data" in volume group "vg"`,
+		},
+		{
+			name: "luks-without-passphrase",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						LUKS:       &LUKSCustomization{},
+						Filesystem: root,
+					},
+				},
+			},
+			wantErr: "partitioning: partition 0: encryption requires a passphrase",
+		},
+		{
+			name: "esp-not-vfat",
+			pc: PartitioningCustomization{
+				Partitions: []PartitionCustomization{
+					{Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/boot/efi"}},
+					{Filesystem: root},
+				},
+			},
+			wantErr: `partitioning: partition 0: "/boot/efi" must be a vfat filesystem, got "xfs"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.pc.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPartitioningPolicy(t *testing.T) {
+	pc := &PartitioningCustomization{
+		Partitions: []PartitionCustomization{
+			{Filesystem: &FilesystemTypedCustomization{Type: "vfat", Mountpoint: "/boot/efi"}},
+			{Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/"}},
+			{Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/data"}},
+		},
+	}
+	assert.NoError(t, CheckPartitioningPolicy(pc, pathpolicy.MountpointPolicies))
+
+	pc.Partitions = append(pc.Partitions, PartitionCustomization{
+		Filesystem: &FilesystemTypedCustomization{Type: "xfs", Mountpoint: "/etc"},
+	})
+	assert.EqualError(t, CheckPartitioningPolicy(pc, pathpolicy.MountpointPolicies), `The following custom mountpoints are not supported ["/etc"]`)
+}
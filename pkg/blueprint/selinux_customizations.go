@@ -0,0 +1,145 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	selinuxBooleanRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	selinuxTypeRegex    = regexp.MustCompile(`^[a-z0-9_]+_t$`)
+)
+
+// SELinuxCustomization sets the SELinux mode and modifies the SELinux policy
+// of the image. The distros reject the policy modifications, since osbuild
+// has no stage that applies them to the tree at build time.
+type SELinuxCustomization struct {
+	// enforcing, permissive or disabled; replaces the mode of the image type
+	Mode     string                        `json:"mode,omitempty" toml:"mode,omitempty"`
+	Booleans []SELinuxBooleanCustomization `json:"booleans,omitempty" toml:"booleans,omitempty"`
+	// File context rules labeling the files matching a path regular
+	// expression, e.g. /srv/www(/.*)?
+	FileContexts []SELinuxFileContextCustomization `json:"fcontexts,omitempty" toml:"fcontexts,omitempty"`
+	// Directories labeled like other directories, e.g. /srv/www like
+	// /var/www
+	FileContextEquivalences []SELinuxFileContextEquivalenceCustomization `json:"fcontext_equivalences,omitempty" toml:"fcontext_equivalences,omitempty"`
+	Ports                   []SELinuxPortCustomization                   `json:"ports,omitempty" toml:"ports,omitempty"`
+}
+
+type SELinuxBooleanCustomization struct {
+	Name  string `json:"name" toml:"name"`
+	Value bool   `json:"value" toml:"value"`
+}
+
+type SELinuxFileContextCustomization struct {
+	Path string `json:"path" toml:"path"`
+	Type string `json:"type" toml:"type"`
+}
+
+type SELinuxFileContextEquivalenceCustomization struct {
+	Path  string `json:"path" toml:"path"`
+	Equal string `json:"equal" toml:"equal"`
+}
+
+type SELinuxPortCustomization struct {
+	// tcp, udp, sctp or dccp
+	Protocol string `json:"protocol" toml:"protocol"`
+	// A port or a range of ports, e.g. 8080 or 8080-8090
+	Port string `json:"port" toml:"port"`
+	Type string `json:"type" toml:"type"`
+}
+
+// HasPolicyModifications returns true if the customization modifies the
+// SELinux policy, as opposed to only setting the mode.
+func (s *SELinuxCustomization) HasPolicyModifications() bool {
+	return len(s.Booleans) > 0 || len(s.FileContexts) > 0 || len(s.FileContextEquivalences) > 0 || len(s.Ports) > 0
+}
+
+func (s *SELinuxCustomization) Validate() error {
+	switch s.Mode {
+	case "", "enforcing", "permissive", "disabled":
+	default:
+		return fmt.Errorf("unsupported SELinux mode %q", s.Mode)
+	}
+
+	booleans := make(map[string]bool, len(s.Booleans))
+	for _, b := range s.Booleans {
+		if !selinuxBooleanRegex.MatchString(b.Name) {
+			return fmt.Errorf("SELinux boolean name %q is invalid", b.Name)
+		}
+		if booleans[b.Name] {
+			return fmt.Errorf("duplicate SELinux boolean %q", b.Name)
+		}
+		booleans[b.Name] = true
+	}
+
+	for _, fc := range s.FileContexts {
+		if !strings.HasPrefix(fc.Path, "/") {
+			return fmt.Errorf("SELinux file context path %q must be absolute", fc.Path)
+		}
+		if strings.ContainsAny(fc.Path, "\n\r") {
+			return fmt.Errorf("SELinux file context path %q must be a single line", fc.Path)
+		}
+		if _, err := regexp.Compile(fc.Path); err != nil {
+			return fmt.Errorf("SELinux file context path %q is not a valid regular expression", fc.Path)
+		}
+		if !selinuxTypeRegex.MatchString(fc.Type) {
+			return fmt.Errorf("SELinux file context %q: type %q is invalid", fc.Path, fc.Type)
+		}
+	}
+
+	for _, eq := range s.FileContextEquivalences {
+		for _, path := range []string{eq.Path, eq.Equal} {
+			if !strings.HasPrefix(path, "/") || path == "/" {
+				return fmt.Errorf("SELinux file context equivalence path %q must be absolute and not /", path)
+			}
+			if strings.ContainsAny(path, "\n\r") {
+				return fmt.Errorf("SELinux file context equivalence path %q must be a single line", path)
+			}
+		}
+		if eq.Path == eq.Equal {
+			return fmt.Errorf("SELinux file context equivalence %q must not refer to itself", eq.Path)
+		}
+	}
+
+	ports := make(map[string]bool, len(s.Ports))
+	for _, p := range s.Ports {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		key := p.Protocol + "/" + p.Port
+		if ports[key] {
+			return fmt.Errorf("duplicate SELinux port %q", key)
+		}
+		ports[key] = true
+	}
+	return nil
+}
+
+func (p *SELinuxPortCustomization) validate() error {
+	switch p.Protocol {
+	case "tcp", "udp", "sctp", "dccp":
+	default:
+		return fmt.Errorf("SELinux port %q: unsupported protocol %q", p.Port, p.Protocol)
+	}
+
+	bounds := strings.SplitN(p.Port, "-", 2)
+	var ports []int
+	for _, bound := range bounds {
+		port, err := strconv.Atoi(bound)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("SELinux port %q is invalid", p.Port)
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) == 2 && ports[0] > ports[1] {
+		return fmt.Errorf("SELinux port range %q is invalid", p.Port)
+	}
+
+	if !selinuxTypeRegex.MatchString(p.Type) {
+		return fmt.Errorf("SELinux port %q: type %q is invalid", p.Port, p.Type)
+	}
+	return nil
+}
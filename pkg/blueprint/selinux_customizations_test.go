@@ -0,0 +1,108 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSELinux(t *testing.T) {
+	testCases := map[string]struct {
+		selinux SELinuxCustomization
+		wantErr string
+	}{
+		"valid": {
+			selinux: SELinuxCustomization{
+				Mode:                    "permissive",
+				Booleans:                []SELinuxBooleanCustomization{{Name: "httpd_can_network_connect", Value: true}},
+				FileContexts:            []SELinuxFileContextCustomization{{Path: "/srv/app(/.*)?", Type: "httpd_sys_content_t"}},
+				FileContextEquivalences: []SELinuxFileContextEquivalenceCustomization{{Path: "/srv/www", Equal: "/var/www"}},
+				Ports: []SELinuxPortCustomization{
+					{Protocol: "tcp", Port: "8080", Type: "http_port_t"},
+					{Protocol: "udp", Port: "8080", Type: "http_port_t"},
+				},
+			},
+		},
+		"mode-only": {
+			selinux: SELinuxCustomization{Mode: "enforcing"},
+		},
+		"invalid-mode": {
+			selinux: SELinuxCustomization{Mode: "strict"},
+			wantErr: `unsupported SELinux mode "strict"`,
+		},
+		"invalid-boolean": {
+			selinux: SELinuxCustomization{Booleans: []SELinuxBooleanCustomization{{Name: "httpd-can"}}},
+			wantErr: `SELinux boolean name "httpd-can" is invalid`,
+		},
+		"duplicate-boolean": {
+			selinux: SELinuxCustomization{Booleans: []SELinuxBooleanCustomization{{Name: "httpd_can_network_connect"}, {Name: "httpd_can_network_connect", Value: true}}},
+			wantErr: `duplicate SELinux boolean "httpd_can_network_connect"`,
+		},
+		"relative-fcontext": {
+			selinux: SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "srv/app", Type: "httpd_sys_content_t"}}},
+			wantErr: `SELinux file context path "srv/app" must be absolute`,
+		},
+		"multiline-fcontext": {
+			selinux: SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/app\n/etc", Type: "httpd_sys_content_t"}}},
+			wantErr: `SELinux file context path "/srv/app\n/etc" must be a single line`,
+		},
+		"invalid-fcontext-regex": {
+			selinux: SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/app(/.*", Type: "httpd_sys_content_t"}}},
+			wantErr: `SELinux file context path "/srv/app(/.*" is not a valid regular expression`,
+		},
+		"invalid-fcontext-type": {
+			selinux: SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/app", Type: "system_u:object_r:httpd_sys_content_t:s0"}}},
+			wantErr: `SELinux file context "/srv/app": type "system_u:object_r:httpd_sys_content_t:s0" is invalid`,
+		},
+		"equivalence-to-root": {
+			selinux: SELinuxCustomization{FileContextEquivalences: []SELinuxFileContextEquivalenceCustomization{{Path: "/", Equal: "/var/www"}}},
+			wantErr: `SELinux file context equivalence path "/" must be absolute and not /`,
+		},
+		"equivalence-to-itself": {
+			selinux: SELinuxCustomization{FileContextEquivalences: []SELinuxFileContextEquivalenceCustomization{{Path: "/srv/www", Equal: "/srv/www"}}},
+			wantErr: `SELinux file context equivalence "/srv/www" must not refer to itself`,
+		},
+		"invalid-port-protocol": {
+			selinux: SELinuxCustomization{Ports: []SELinuxPortCustomization{{Protocol: "icmp", Port: "8080", Type: "http_port_t"}}},
+			wantErr: `SELinux port "8080": unsupported protocol "icmp"`,
+		},
+		"invalid-port": {
+			selinux: SELinuxCustomization{Ports: []SELinuxPortCustomization{{Protocol: "tcp", Port: "http", Type: "http_port_t"}}},
+			wantErr: `SELinux port "http" is invalid`,
+		},
+		"invalid-port-range": {
+			selinux: SELinuxCustomization{Ports: []SELinuxPortCustomization{{Protocol: "tcp", Port: "8090-8080", Type: "http_port_t"}}},
+			wantErr: `SELinux port range "8090-8080" is invalid`,
+		},
+		"duplicate-port": {
+			selinux: SELinuxCustomization{Ports: []SELinuxPortCustomization{{Protocol: "tcp", Port: "8080", Type: "http_port_t"}, {Protocol: "tcp", Port: "8080", Type: "http_cache_port_t"}}},
+			wantErr: `duplicate SELinux port "tcp/8080"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{SELinux: &tc.selinux}
+			selinux, err := c.GetSELinux()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, c.SELinux, selinux)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetSELinuxEmpty(t *testing.T) {
+	var c *Customizations
+	selinux, err := c.GetSELinux()
+	assert.NoError(t, err)
+	assert.Nil(t, selinux)
+}
+
+func TestSELinuxHasPolicyModifications(t *testing.T) {
+	assert.False(t, (&SELinuxCustomization{Mode: "permissive"}).HasPolicyModifications())
+	assert.True(t, (&SELinuxCustomization{Booleans: []SELinuxBooleanCustomization{{Name: "httpd_can_network_connect"}}}).HasPolicyModifications())
+	assert.True(t, (&SELinuxCustomization{Ports: []SELinuxPortCustomization{{Protocol: "tcp", Port: "8080", Type: "http_port_t"}}}).HasPolicyModifications())
+}
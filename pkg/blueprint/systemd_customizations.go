@@ -0,0 +1,417 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// SystemdUnitsDir is the directory the custom unit files are written to.
+const SystemdUnitsDir = "/etc/systemd/system"
+
+var (
+	systemdUnitNameRegex    = regexp.MustCompile(`^[\w:.\\-]+(@[\w:.\\-]*)?\.([a-z]+)$`)
+	systemdDropinNameRegex  = regexp.MustCompile(`^[\w.-]{1,250}\.conf$`)
+	systemdEnvironmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=\S*$`)
+)
+
+// unit types that custom unit files can be created for, sorted
+var systemdUnitFileTypes = []string{"service", "target", "timer"}
+
+// unit types drop-ins can be created for, sorted
+var systemdDropinUnitTypes = []string{"mount", "path", "service", "slice", "socket", "target", "timer"}
+
+// process start-up types of services, sorted
+var systemdServiceTypes = []string{"dbus", "exec", "forking", "idle", "notify", "notify-reload", "oneshot", "simple"}
+
+// SystemdCustomization defines custom systemd units, drop-in overrides for
+// existing units and the default target.
+type SystemdCustomization struct {
+	// Target the system boots into, e.g. multi-user.target. It replaces the
+	// default target of the image type.
+	DefaultTarget string `json:"default_target,omitempty" toml:"default_target,omitempty"`
+	// Unit files created in /etc/systemd/system. Units with an install
+	// section are enabled.
+	Units []SystemdUnitCustomization `json:"units,omitempty" toml:"units,omitempty"`
+	// Drop-in files overriding the configuration of existing units
+	Dropins []SystemdDropinCustomization `json:"dropins,omitempty" toml:"dropins,omitempty"`
+}
+
+type SystemdUnitCustomization struct {
+	// Name of the unit, e.g. backup.service or backup.timer
+	Name   string                         `json:"name" toml:"name"`
+	Config SystemdUnitConfigCustomization `json:"config" toml:"config"`
+}
+
+type SystemdDropinCustomization struct {
+	// Name of the unit the drop-in applies to
+	Unit string `json:"unit" toml:"unit"`
+	// Name of the drop-in file, e.g. 10-restart.conf
+	Filename string                         `json:"filename" toml:"filename"`
+	Config   SystemdUnitConfigCustomization `json:"config" toml:"config"`
+}
+
+// SystemdUnitConfigCustomization holds the sections of a unit file or
+// drop-in. Drop-ins don't support the timer section.
+type SystemdUnitConfigCustomization struct {
+	Unit    *SystemdUnitSectionCustomization    `json:"unit,omitempty" toml:"unit,omitempty"`
+	Service *SystemdServiceSectionCustomization `json:"service,omitempty" toml:"service,omitempty"`
+	Timer   *SystemdTimerSectionCustomization   `json:"timer,omitempty" toml:"timer,omitempty"`
+	Install *SystemdInstallSectionCustomization `json:"install,omitempty" toml:"install,omitempty"`
+}
+
+type SystemdUnitSectionCustomization struct {
+	Description              string   `json:"description,omitempty" toml:"description,omitempty"`
+	DefaultDependencies      *bool    `json:"default_dependencies,omitempty" toml:"default_dependencies,omitempty"`
+	ConditionPathExists      []string `json:"condition_path_exists,omitempty" toml:"condition_path_exists,omitempty"`
+	ConditionPathIsDirectory []string `json:"condition_path_is_directory,omitempty" toml:"condition_path_is_directory,omitempty"`
+	Requires                 []string `json:"requires,omitempty" toml:"requires,omitempty"`
+	Wants                    []string `json:"wants,omitempty" toml:"wants,omitempty"`
+	After                    []string `json:"after,omitempty" toml:"after,omitempty"`
+	Before                   []string `json:"before,omitempty" toml:"before,omitempty"`
+}
+
+type SystemdServiceSectionCustomization struct {
+	Type            string   `json:"type,omitempty" toml:"type,omitempty"`
+	RemainAfterExit bool     `json:"remain_after_exit,omitempty" toml:"remain_after_exit,omitempty"`
+	ExecStartPre    []string `json:"exec_start_pre,omitempty" toml:"exec_start_pre,omitempty"`
+	ExecStart       []string `json:"exec_start,omitempty" toml:"exec_start,omitempty"`
+	ExecStopPost    []string `json:"exec_stop_post,omitempty" toml:"exec_stop_post,omitempty"`
+	// Variable assignments, e.g. OPTIONS=-4
+	Environment     []string `json:"environment,omitempty" toml:"environment,omitempty"`
+	EnvironmentFile []string `json:"environment_file,omitempty" toml:"environment_file,omitempty"`
+}
+
+type SystemdTimerSectionCustomization struct {
+	OnCalendar         []string `json:"on_calendar,omitempty" toml:"on_calendar,omitempty"`
+	OnBootSec          string   `json:"on_boot_sec,omitempty" toml:"on_boot_sec,omitempty"`
+	OnUnitActiveSec    string   `json:"on_unit_active_sec,omitempty" toml:"on_unit_active_sec,omitempty"`
+	RandomizedDelaySec string   `json:"randomized_delay_sec,omitempty" toml:"randomized_delay_sec,omitempty"`
+	Persistent         *bool    `json:"persistent,omitempty" toml:"persistent,omitempty"`
+	// Unit activated by the timer; defaults to the service with the same name
+	Unit string `json:"unit,omitempty" toml:"unit,omitempty"`
+}
+
+type SystemdInstallSectionCustomization struct {
+	WantedBy   []string `json:"wanted_by,omitempty" toml:"wanted_by,omitempty"`
+	RequiredBy []string `json:"required_by,omitempty" toml:"required_by,omitempty"`
+}
+
+// systemdUnitType returns the type of a unit, e.g. "service", or an error if
+// the unit name is invalid.
+func systemdUnitType(name string) (string, error) {
+	match := systemdUnitNameRegex.FindStringSubmatch(name)
+	if match == nil || len(name) > 255 {
+		return "", fmt.Errorf("systemd unit name %q is invalid", name)
+	}
+	return match[2], nil
+}
+
+func validateSystemdValues(key string, values ...string) error {
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s must not be empty", key)
+		}
+		if strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("%s must not contain newlines", key)
+		}
+	}
+	return nil
+}
+
+func validateSystemdUnitNames(key string, names ...string) error {
+	for _, name := range names {
+		if _, err := systemdUnitType(name); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *SystemdUnitSectionCustomization) validate() error {
+	if s.Description != "" {
+		if err := validateSystemdValues("description", s.Description); err != nil {
+			return err
+		}
+	}
+	if err := validateSystemdValues("condition_path_exists", s.ConditionPathExists...); err != nil {
+		return err
+	}
+	if err := validateSystemdValues("condition_path_is_directory", s.ConditionPathIsDirectory...); err != nil {
+		return err
+	}
+	if err := validateSystemdUnitNames("requires", s.Requires...); err != nil {
+		return err
+	}
+	if err := validateSystemdUnitNames("wants", s.Wants...); err != nil {
+		return err
+	}
+	if err := validateSystemdUnitNames("after", s.After...); err != nil {
+		return err
+	}
+	return validateSystemdUnitNames("before", s.Before...)
+}
+
+func (s *SystemdServiceSectionCustomization) validate() error {
+	if s.Type != "" && !common.IsStringInSortedSlice(systemdServiceTypes, s.Type) {
+		return fmt.Errorf("unsupported service type %q", s.Type)
+	}
+	if len(s.ExecStart) > 1 && s.Type != "oneshot" {
+		return fmt.Errorf("only oneshot services support more than one exec_start command")
+	}
+	if err := validateSystemdValues("exec_start_pre", s.ExecStartPre...); err != nil {
+		return err
+	}
+	if err := validateSystemdValues("exec_start", s.ExecStart...); err != nil {
+		return err
+	}
+	if err := validateSystemdValues("exec_stop_post", s.ExecStopPost...); err != nil {
+		return err
+	}
+	for _, env := range s.Environment {
+		if !systemdEnvironmentRegex.MatchString(env) {
+			return fmt.Errorf("environment assignment %q is invalid", env)
+		}
+	}
+	for _, file := range s.EnvironmentFile {
+		// a leading "-" ignores a missing file
+		if !filepath.IsAbs(strings.TrimPrefix(file, "-")) {
+			return fmt.Errorf("environment_file %q must be an absolute path", file)
+		}
+	}
+	return validateSystemdValues("environment_file", s.EnvironmentFile...)
+}
+
+func (s *SystemdTimerSectionCustomization) validate() error {
+	if len(s.OnCalendar) == 0 && s.OnBootSec == "" && s.OnUnitActiveSec == "" {
+		return fmt.Errorf("timers require on_calendar, on_boot_sec or on_unit_active_sec")
+	}
+	if err := validateSystemdValues("on_calendar", s.OnCalendar...); err != nil {
+		return err
+	}
+	for _, kv := range [][2]string{
+		{"on_boot_sec", s.OnBootSec},
+		{"on_unit_active_sec", s.OnUnitActiveSec},
+		{"randomized_delay_sec", s.RandomizedDelaySec},
+	} {
+		if kv[1] != "" {
+			if err := validateSystemdValues(kv[0], kv[1]); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Unit != "" {
+		return validateSystemdUnitNames("unit", s.Unit)
+	}
+	return nil
+}
+
+func (s *SystemdInstallSectionCustomization) validate() error {
+	if len(s.WantedBy) == 0 && len(s.RequiredBy) == 0 {
+		return fmt.Errorf("the install section requires wanted_by or required_by")
+	}
+	if err := validateSystemdUnitNames("wanted_by", s.WantedBy...); err != nil {
+		return err
+	}
+	return validateSystemdUnitNames("required_by", s.RequiredBy...)
+}
+
+// validate checks the sections of the configuration of a unit of the given
+// type.
+func (c *SystemdUnitConfigCustomization) validate(unitType string) error {
+	if c.Service != nil && unitType != "service" {
+		return fmt.Errorf("the service section is only supported for service units")
+	}
+	if c.Timer != nil && unitType != "timer" {
+		return fmt.Errorf("the timer section is only supported for timer units")
+	}
+
+	if c.Unit != nil {
+		if err := c.Unit.validate(); err != nil {
+			return fmt.Errorf("unit section: %w", err)
+		}
+	}
+	if c.Service != nil {
+		if err := c.Service.validate(); err != nil {
+			return fmt.Errorf("service section: %w", err)
+		}
+	}
+	if c.Timer != nil {
+		if err := c.Timer.validate(); err != nil {
+			return fmt.Errorf("timer section: %w", err)
+		}
+	}
+	if c.Install != nil {
+		if err := c.Install.validate(); err != nil {
+			return fmt.Errorf("install section: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *SystemdCustomization) Validate() error {
+	if s.DefaultTarget != "" {
+		if unitType, err := systemdUnitType(s.DefaultTarget); err != nil || unitType != "target" {
+			return fmt.Errorf("default target %q is not a valid target unit name", s.DefaultTarget)
+		}
+	}
+
+	units := make(map[string]bool, len(s.Units))
+	for _, unit := range s.Units {
+		unitType, err := systemdUnitType(unit.Name)
+		if err != nil {
+			return err
+		}
+		if !common.IsStringInSortedSlice(systemdUnitFileTypes, unitType) {
+			return fmt.Errorf("systemd unit %q: unsupported unit type %q", unit.Name, unitType)
+		}
+		if strings.Contains(unit.Name, "@") {
+			return fmt.Errorf("systemd unit %q: template units are not supported", unit.Name)
+		}
+		if units[unit.Name] {
+			return fmt.Errorf("duplicate systemd unit %q", unit.Name)
+		}
+		units[unit.Name] = true
+
+		if err := unit.Config.validate(unitType); err != nil {
+			return fmt.Errorf("systemd unit %q: %w", unit.Name, err)
+		}
+		switch {
+		case unitType == "service" && (unit.Config.Service == nil || len(unit.Config.Service.ExecStart) == 0):
+			return fmt.Errorf("systemd unit %q: services require exec_start", unit.Name)
+		case unitType == "timer" && unit.Config.Timer == nil:
+			return fmt.Errorf("systemd unit %q: timers require a timer section", unit.Name)
+		}
+	}
+
+	dropins := make(map[string]bool, len(s.Dropins))
+	for _, dropin := range s.Dropins {
+		unitType, err := systemdUnitType(dropin.Unit)
+		if err != nil {
+			return err
+		}
+		if !common.IsStringInSortedSlice(systemdDropinUnitTypes, unitType) {
+			return fmt.Errorf("systemd drop-in for %q: unsupported unit type %q", dropin.Unit, unitType)
+		}
+		if !systemdDropinNameRegex.MatchString(dropin.Filename) {
+			return fmt.Errorf("systemd drop-in for %q: filename %q is invalid", dropin.Unit, dropin.Filename)
+		}
+		key := dropin.Unit + "/" + dropin.Filename
+		if dropins[key] {
+			return fmt.Errorf("duplicate systemd drop-in %q for %q", dropin.Filename, dropin.Unit)
+		}
+		dropins[key] = true
+
+		if dropin.Config.Timer != nil {
+			return fmt.Errorf("systemd drop-in for %q: the timer section is not supported in drop-ins", dropin.Unit)
+		}
+		if dropin.Config.Unit == nil && dropin.Config.Service == nil && dropin.Config.Install == nil {
+			return fmt.Errorf("systemd drop-in for %q: at least one section is required", dropin.Unit)
+		}
+		if err := dropin.Config.validate(unitType); err != nil {
+			return fmt.Errorf("systemd drop-in for %q: %w", dropin.Unit, err)
+		}
+	}
+	return nil
+}
+
+// EnabledUnits returns the names of the custom units that are enabled, which
+// are the ones with an install section.
+func (s *SystemdCustomization) EnabledUnits() []string {
+	var enabled []string
+	for _, unit := range s.Units {
+		if unit.Config.Install != nil {
+			enabled = append(enabled, unit.Name)
+		}
+	}
+	return enabled
+}
+
+// unitFile renders the unit file of a custom unit.
+func (u *SystemdUnitCustomization) unitFile() string {
+	var sb strings.Builder
+	section := func(name string) {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "[%s]\n", name)
+	}
+	keys := func(key string, values ...string) {
+		for _, value := range values {
+			fmt.Fprintf(&sb, "%s=%s\n", key, value)
+		}
+	}
+	boolKey := func(key string, value *bool) {
+		if value != nil {
+			fmt.Fprintf(&sb, "%s=%t\n", key, *value)
+		}
+	}
+	optKey := func(key, value string) {
+		if value != "" {
+			keys(key, value)
+		}
+	}
+
+	if s := u.Config.Unit; s != nil {
+		section("Unit")
+		optKey("Description", s.Description)
+		boolKey("DefaultDependencies", s.DefaultDependencies)
+		keys("ConditionPathExists", s.ConditionPathExists...)
+		keys("ConditionPathIsDirectory", s.ConditionPathIsDirectory...)
+		keys("Requires", s.Requires...)
+		keys("Wants", s.Wants...)
+		keys("After", s.After...)
+		keys("Before", s.Before...)
+	}
+	if s := u.Config.Service; s != nil {
+		section("Service")
+		optKey("Type", s.Type)
+		if s.RemainAfterExit {
+			keys("RemainAfterExit", "yes")
+		}
+		keys("Environment", s.Environment...)
+		keys("EnvironmentFile", s.EnvironmentFile...)
+		keys("ExecStartPre", s.ExecStartPre...)
+		keys("ExecStart", s.ExecStart...)
+		keys("ExecStopPost", s.ExecStopPost...)
+	}
+	if s := u.Config.Timer; s != nil {
+		section("Timer")
+		keys("OnCalendar", s.OnCalendar...)
+		optKey("OnBootSec", s.OnBootSec)
+		optKey("OnUnitActiveSec", s.OnUnitActiveSec)
+		optKey("RandomizedDelaySec", s.RandomizedDelaySec)
+		boolKey("Persistent", s.Persistent)
+		optKey("Unit", s.Unit)
+	}
+	if s := u.Config.Install; s != nil {
+		section("Install")
+		keys("WantedBy", s.WantedBy...)
+		keys("RequiredBy", s.RequiredBy...)
+	}
+	return sb.String()
+}
+
+// SystemdUnitsToFsNodeFiles returns the unit files of the custom units.
+func SystemdUnitsToFsNodeFiles(systemd *SystemdCustomization) ([]*fsnode.File, error) {
+	if systemd == nil || len(systemd.Units) == 0 {
+		return nil, nil
+	}
+
+	files := make([]*fsnode.File, 0, len(systemd.Units))
+	for idx := range systemd.Units {
+		unit := &systemd.Units[idx]
+		path := filepath.Join(SystemdUnitsDir, unit.Name)
+		file, err := fsnode.NewFile(path, common.ToPtr(os.FileMode(0644)), nil, nil, []byte(unit.unitFile()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
@@ -0,0 +1,227 @@
+package blueprint
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestSystemdCustomizationValidate(t *testing.T) {
+	backupService := SystemdUnitCustomization{
+		Name: "backup.service",
+		Config: SystemdUnitConfigCustomization{
+			Service: &SystemdServiceSectionCustomization{Type: "oneshot", ExecStart: []string{"/usr/bin/backup"}},
+		},
+	}
+	backupTimer := SystemdUnitCustomization{
+		Name: "backup.timer",
+		Config: SystemdUnitConfigCustomization{
+			Timer:   &SystemdTimerSectionCustomization{OnCalendar: []string{"daily"}},
+			Install: &SystemdInstallSectionCustomization{WantedBy: []string{"timers.target"}},
+		},
+	}
+
+	testCases := map[string]struct {
+		systemd SystemdCustomization
+		wantErr string
+	}{
+		"valid": {
+			systemd: SystemdCustomization{
+				DefaultTarget: "multi-user.target",
+				Units:         []SystemdUnitCustomization{backupService, backupTimer},
+				Dropins: []SystemdDropinCustomization{
+					{
+						Unit:     "getty@tty1.service",
+						Filename: "10-autologin.conf",
+						Config: SystemdUnitConfigCustomization{
+							Service: &SystemdServiceSectionCustomization{Environment: []string{"TERM=linux"}},
+						},
+					},
+				},
+			},
+		},
+		"invalid-default-target": {
+			systemd: SystemdCustomization{DefaultTarget: "multi-user.service"},
+			wantErr: `default target "multi-user.service" is not a valid target unit name`,
+		},
+		"invalid-unit-name": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "../backup.service"}}},
+			wantErr: `systemd unit name "../backup.service" is invalid`,
+		},
+		"unsupported-unit-type": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.socket"}}},
+			wantErr: `systemd unit "backup.socket": unsupported unit type "socket"`,
+		},
+		"template-unit": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup@.service"}}},
+			wantErr: `systemd unit "backup@.service": template units are not supported`,
+		},
+		"duplicate-unit": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{backupService, backupService}},
+			wantErr: `duplicate systemd unit "backup.service"`,
+		},
+		"service-without-exec-start": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.service"}}},
+			wantErr: `systemd unit "backup.service": services require exec_start`,
+		},
+		"timer-without-timer-section": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.timer"}}},
+			wantErr: `systemd unit "backup.timer": timers require a timer section`,
+		},
+		"timer-without-trigger": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.timer", Config: SystemdUnitConfigCustomization{Timer: &SystemdTimerSectionCustomization{Persistent: common.ToPtr(true)}}}}},
+			wantErr: `systemd unit "backup.timer": timer section: timers require on_calendar, on_boot_sec or on_unit_active_sec`,
+		},
+		"service-section-in-timer": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.timer", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{}}}}},
+			wantErr: `systemd unit "backup.timer": the service section is only supported for service units`,
+		},
+		"multiple-exec-start": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.service", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{ExecStart: []string{"/bin/a", "/bin/b"}}}}}},
+			wantErr: `systemd unit "backup.service": service section: only oneshot services support more than one exec_start command`,
+		},
+		"unknown-service-type": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.service", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{Type: "daemon", ExecStart: []string{"/bin/a"}}}}}},
+			wantErr: `systemd unit "backup.service": service section: unsupported service type "daemon"`,
+		},
+		"multiline-exec-start": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "backup.service", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{ExecStart: []string{"/bin/a\n[Install]"}}}}}},
+			wantErr: `systemd unit "backup.service": service section: exec_start must not contain newlines`,
+		},
+		"invalid-dependency": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "app.target", Config: SystemdUnitConfigCustomization{Unit: &SystemdUnitSectionCustomization{After: []string{"network online"}}}}}},
+			wantErr: `systemd unit "app.target": unit section: after: systemd unit name "network online" is invalid`,
+		},
+		"empty-install-section": {
+			systemd: SystemdCustomization{Units: []SystemdUnitCustomization{{Name: "app.target", Config: SystemdUnitConfigCustomization{Install: &SystemdInstallSectionCustomization{}}}}},
+			wantErr: `systemd unit "app.target": install section: the install section requires wanted_by or required_by`,
+		},
+		"invalid-environment": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "sshd.service", Filename: "env.conf", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{Environment: []string{"OPTIONS=-4 -6"}}}}}},
+			wantErr: `systemd drop-in for "sshd.service": service section: environment assignment "OPTIONS=-4 -6" is invalid`,
+		},
+		"relative-environment-file": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "sshd.service", Filename: "env.conf", Config: SystemdUnitConfigCustomization{Service: &SystemdServiceSectionCustomization{EnvironmentFile: []string{"-etc/sysconfig/sshd"}}}}}},
+			wantErr: `systemd drop-in for "sshd.service": service section: environment_file "-etc/sysconfig/sshd" must be an absolute path`,
+		},
+		"invalid-dropin-filename": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "sshd.service", Filename: "override"}}},
+			wantErr: `systemd drop-in for "sshd.service": filename "override" is invalid`,
+		},
+		"duplicate-dropin": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{
+				{Unit: "sshd.service", Filename: "override.conf", Config: SystemdUnitConfigCustomization{Unit: &SystemdUnitSectionCustomization{Description: "a"}}},
+				{Unit: "sshd.service", Filename: "override.conf", Config: SystemdUnitConfigCustomization{Unit: &SystemdUnitSectionCustomization{Description: "b"}}},
+			}},
+			wantErr: `duplicate systemd drop-in "override.conf" for "sshd.service"`,
+		},
+		"empty-dropin": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "sshd.service", Filename: "override.conf"}}},
+			wantErr: `systemd drop-in for "sshd.service": at least one section is required`,
+		},
+		"timer-dropin": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "fstrim.timer", Filename: "override.conf", Config: SystemdUnitConfigCustomization{Timer: &SystemdTimerSectionCustomization{OnCalendar: []string{"daily"}}}}}},
+			wantErr: `systemd drop-in for "fstrim.timer": the timer section is not supported in drop-ins`,
+		},
+		"unsupported-dropin-unit-type": {
+			systemd: SystemdCustomization{Dropins: []SystemdDropinCustomization{{Unit: "sshd.scope", Filename: "override.conf"}}},
+			wantErr: `systemd drop-in for "sshd.scope": unsupported unit type "scope"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Customizations{Systemd: &tc.systemd}
+			systemd, err := c.GetSystemd()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, c.Systemd, systemd)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetSystemdEmpty(t *testing.T) {
+	var c *Customizations
+	systemd, err := c.GetSystemd()
+	assert.NoError(t, err)
+	assert.Nil(t, systemd)
+}
+
+func TestSystemdUnitsToFsNodeFiles(t *testing.T) {
+	systemd := &SystemdCustomization{
+		Units: []SystemdUnitCustomization{
+			{
+				Name: "backup.service",
+				Config: SystemdUnitConfigCustomization{
+					Unit: &SystemdUnitSectionCustomization{
+						Description: "Back up the data",
+						Wants:       []string{"network-online.target"},
+						After:       []string{"network-online.target"},
+					},
+					Service: &SystemdServiceSectionCustomization{
+						Type:            "oneshot",
+						Environment:     []string{"TARGET=/srv/backup"},
+						EnvironmentFile: []string{"-/etc/sysconfig/backup"},
+						ExecStart:       []string{"/usr/bin/backup --full", "/usr/bin/backup --verify"},
+					},
+				},
+			},
+			{
+				Name: "backup.timer",
+				Config: SystemdUnitConfigCustomization{
+					Timer: &SystemdTimerSectionCustomization{
+						OnCalendar:         []string{"Mon *-*-* 02:00:00", "Thu *-*-* 02:00:00"},
+						RandomizedDelaySec: "30min",
+						Persistent:         common.ToPtr(true),
+					},
+					Install: &SystemdInstallSectionCustomization{WantedBy: []string{"timers.target"}},
+				},
+			},
+		},
+	}
+	require.NoError(t, systemd.Validate())
+	assert.Equal(t, []string{"backup.timer"}, systemd.EnabledUnits())
+
+	files, err := SystemdUnitsToFsNodeFiles(systemd)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/systemd/system/backup.service", files[0].Path())
+	assert.Equal(t, os.FileMode(0644), *files[0].Mode())
+	assert.Equal(t, `[Unit]
+Description=Back up the data
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+Environment=TARGET=/srv/backup
+EnvironmentFile=-/etc/sysconfig/backup
+ExecStart=/usr/bin/backup --full
+ExecStart=/usr/bin/backup --verify
+`, string(files[0].Data()))
+
+	assert.Equal(t, "/etc/systemd/system/backup.timer", files[1].Path())
+	assert.Equal(t, `[Timer]
+OnCalendar=Mon *-*-* 02:00:00
+OnCalendar=Thu *-*-* 02:00:00
+RandomizedDelaySec=30min
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, string(files[1].Data()))
+}
+
+func TestSystemdUnitsToFsNodeFilesEmpty(t *testing.T) {
+	files, err := SystemdUnitsToFsNodeFiles(&SystemdCustomization{DefaultTarget: "graphical.target"})
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -394,6 +394,7 @@ type resolvedIds struct {
 	Manifest     digest.Digest
 	Config       digest.Digest
 	ListManifest digest.Digest
+	LayersSize   uint64
 }
 
 func (cl *Client) resolveManifestList(ctx context.Context, list manifestList) (resolvedIds, error) {
@@ -419,6 +420,7 @@ func (cl *Client) resolveManifestList(ctx context.Context, list manifestList) (r
 func (cl *Client) resolveRawManifest(ctx context.Context, rm RawManifest) (resolvedIds, error) {
 
 	var imageID digest.Digest
+	var layers []manifest.LayerInfo
 
 	switch rm.MimeType {
 	case manifest.DockerV2ListMediaType:
@@ -457,6 +459,7 @@ func (cl *Client) resolveRawManifest(ctx context.Context, rm RawManifest) (resol
 			return resolvedIds{}, nil
 		}
 		imageID = m.ConfigInfo().Digest
+		layers = m.LayerInfos()
 
 	case manifest.DockerV2Schema2MediaType:
 		m, err := manifest.Schema2FromManifest(rm.Data)
@@ -466,6 +469,7 @@ func (cl *Client) resolveRawManifest(ctx context.Context, rm RawManifest) (resol
 		}
 
 		imageID = m.ConfigInfo().Digest
+		layers = m.LayerInfos()
 
 	default:
 		return resolvedIds{}, fmt.Errorf("unsupported manifest format '%s'", rm.MimeType)
@@ -477,9 +481,17 @@ func (cl *Client) resolveRawManifest(ctx context.Context, rm RawManifest) (resol
 		return resolvedIds{}, err
 	}
 
+	var layersSize uint64
+	for _, layer := range layers {
+		if layer.Size > 0 {
+			layersSize += uint64(layer.Size)
+		}
+	}
+
 	return resolvedIds{
-		Manifest: dg,
-		Config:   imageID,
+		Manifest:   dg,
+		Config:     imageID,
+		LayersSize: layersSize,
 	}, nil
 }
 
@@ -501,6 +513,7 @@ func (cl *Client) Resolve(ctx context.Context, name string) (Spec, error) {
 	}
 
 	spec := NewSpec(cl.Target, ids.Manifest, ids.Config, cl.GetTLSVerify(), ids.ListManifest.String(), name)
+	spec.Size = ids.LayersSize
 
 	return spec, nil
 }
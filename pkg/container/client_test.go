@@ -18,8 +18,9 @@ func TestClientResolve(t *testing.T) {
 	defer registry.Close()
 
 	repo := registry.AddRepo("library/osbuild")
+	layer := NewDataBlobFromBase64(rootLayer)
 	listDigest := repo.AddImage(
-		[]Blob{NewDataBlobFromBase64(rootLayer)},
+		[]Blob{layer},
 		[]string{"amd64", "ppc64le"},
 		"cool container",
 		time.Time{})
@@ -45,6 +46,7 @@ func TestClientResolve(t *testing.T) {
 		TLSVerify:  client.GetTLSVerify(),
 		LocalName:  client.Target.String(),
 		ListDigest: listDigest,
+		Size:       uint64(layer.GetSize()),
 	}, spec)
 
 	client.SetArchitectureChoice("ppc64le")
@@ -58,6 +60,7 @@ func TestClientResolve(t *testing.T) {
 		TLSVerify:  client.GetTLSVerify(),
 		LocalName:  client.Target.String(),
 		ListDigest: listDigest,
+		Size:       uint64(layer.GetSize()),
 	}, spec)
 
 	// don't have that architecture
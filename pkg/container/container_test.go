@@ -370,6 +370,11 @@ func (reg *Registry) Resolve(target, arch string) (container.Spec, error) {
 		return container.Spec{}, fmt.Errorf("unknown digest")
 	}
 
+	var size uint64
+	for _, layer := range mf.LayersDescriptors {
+		size += uint64(layer.Size)
+	}
+
 	return container.Spec{
 		Source:     ref.String(),
 		Digest:     checksum,
@@ -377,6 +382,7 @@ func (reg *Registry) Resolve(target, arch string) (container.Spec, error) {
 		LocalName:  target,
 		TLSVerify:  common.ToPtr(false),
 		ListDigest: listDigest,
+		Size:       size,
 	}, nil
 }
 
@@ -17,6 +17,7 @@ type Spec struct {
 	ImageID    string // container image identifier
 	LocalName  string // name to use inside the image
 	ListDigest string // digest of the list manifest at the Source (optional)
+	Size       uint64 // size of the compressed layers of the image (optional)
 }
 
 // NewSpec creates a new Spec from the essential information.
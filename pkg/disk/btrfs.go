@@ -9,10 +9,10 @@ import (
 )
 
 type Btrfs struct {
-	UUID       string
-	Label      string
-	Mountpoint string
-	Subvolumes []BtrfsSubvolume
+	UUID       string           `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	Label      string           `json:"label,omitempty" yaml:"label,omitempty"`
+	Mountpoint string           `json:"mountpoint,omitempty" yaml:"mountpoint,omitempty"`
+	Subvolumes []BtrfsSubvolume `json:"subvolumes,omitempty" yaml:"subvolumes,omitempty"`
 }
 
 func (b *Btrfs) IsContainer() bool {
@@ -75,18 +75,23 @@ func (b *Btrfs) GenUUID(rng *rand.Rand) {
 	if b.UUID == "" {
 		b.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
 	}
+
+	// subvolumes inherit UUID of main volume
+	for idx := range b.Subvolumes {
+		b.Subvolumes[idx].UUID = b.UUID
+	}
 }
 
 type BtrfsSubvolume struct {
-	Name       string
-	Size       uint64
-	Mountpoint string
-	GroupID    uint64
+	Name       string `json:"name" yaml:"name"`
+	Size       uint64 `json:"size,omitempty" yaml:"size,omitempty"`
+	Mountpoint string `json:"mountpoint,omitempty" yaml:"mountpoint,omitempty"`
+	GroupID    uint64 `json:"group_id,omitempty" yaml:"group_id,omitempty"`
 
-	MntOps string
+	MntOps string `json:"mntops,omitempty" yaml:"mntops,omitempty"`
 
 	// UUID of the parent volume
-	UUID string
+	UUID string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
 }
 
 func (subvol *BtrfsSubvolume) IsContainer() bool {
@@ -149,7 +154,10 @@ func (bs *BtrfsSubvolume) GetFSTabOptions() FSTabOptions {
 		return FSTabOptions{}
 	}
 
-	ops := strings.Join([]string{bs.MntOps, fmt.Sprintf("subvol=%s", bs.Name)}, ",")
+	ops := fmt.Sprintf("subvol=%s", bs.Name)
+	if bs.MntOps != "" {
+		ops = strings.Join([]string{bs.MntOps, ops}, ",")
+	}
 	return FSTabOptions{
 		MntOps: ops,
 		Freq:   0,
@@ -0,0 +1,265 @@
+package disk
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+// Default key derivation parameters for LUKS2 containers created from
+// blueprint customizations; these match the cryptsetup defaults.
+var defaultCustomLUKSPBKDF = Argon2id{
+	Iterations:  4,
+	Memory:      1048576,
+	Parallelism: 4,
+}
+
+// Size of the EFI system partition if none is given in the customization
+const defaultESPSize = 200 * 1024 * 1024
+
+// NewCustomPartitionTable creates a base partition table from the given
+// partitioning customization that can be passed to NewPartitionTable in place
+// of the base partition table of an image type. Partitions of basePT that are
+// needed for booting, i.e. the BIOS boot and PReP partitions and the EFI
+// system partition, are prepended if the customization does not define them.
+// If the root filesystem ends up on a logical volume, a btrfs subvolume or
+// inside a LUKS container and no /boot is defined, a /boot partition is
+// created.
+func NewCustomPartitionTable(pc *blueprint.PartitioningCustomization, basePT *PartitionTable) (*PartitionTable, error) {
+	if err := pc.Validate(); err != nil {
+		return nil, err
+	}
+
+	ptType := pc.Type
+	if ptType == "" {
+		ptType = basePT.Type
+	}
+	if ptType != basePT.Type {
+		return nil, fmt.Errorf("partitioning: partition table type %q is not supported for this image type, expected %q", ptType, basePT.Type)
+	}
+
+	pt := &PartitionTable{
+		Type:         ptType,
+		SectorSize:   basePT.SectorSize,
+		ExtraPadding: basePT.ExtraPadding,
+	}
+
+	customTypes := make(map[string]bool)
+	for _, part := range pc.Partitions {
+		customTypes[part.PartType] = true
+	}
+	customMountpoints := make(map[string]bool)
+	for _, mountpoint := range pc.Mountpoints() {
+		customMountpoints[mountpoint] = true
+	}
+
+	for idx := range basePT.Partitions {
+		part := &basePT.Partitions[idx]
+		switch {
+		case part.IsBIOSBoot(), part.IsPReP():
+			if customTypes[part.Type] {
+				continue
+			}
+		case len(entityPath(part, blueprint.ESPMountpoint)) > 0:
+			if customMountpoints[blueprint.ESPMountpoint] {
+				continue
+			}
+		default:
+			continue
+		}
+		pt.Partitions = append(pt.Partitions, *part.Clone().(*Partition))
+	}
+
+	for idx := range pc.Partitions {
+		partition, err := newCustomPartition(ptType, &pc.Partitions[idx])
+		if err != nil {
+			return nil, err
+		}
+		pt.Partitions = append(pt.Partitions, partition)
+	}
+
+	// NB: entityPath has reversed order
+	rootPath := entityPath(pt, "/")
+	if _, onPartition := rootPath[1].(*Partition); !onPartition && !customMountpoints["/boot"] {
+		// the root filesystem is not directly on a partition; the boot
+		// loader needs a separate /boot partition to find the kernel
+		if _, err := pt.CreateMountpoint("/boot", 0); err != nil {
+			return nil, fmt.Errorf("partitioning: %w", err)
+		}
+		bootPath := entityPath(pt, "/boot")
+		resizeEntityBranch(bootPath, clampFSSize("/boot", 0))
+	}
+
+	if ptType == "dos" && len(pt.Partitions) > 4 {
+		return nil, fmt.Errorf("partitioning: dos partition tables support at most 4 partitions, layout requires %d", len(pt.Partitions))
+	}
+
+	return pt, nil
+}
+
+func newCustomPartition(ptType string, pc *blueprint.PartitionCustomization) (Partition, error) {
+	partition := Partition{
+		Type:     pc.PartType,
+		Bootable: pc.Bootable,
+	}
+
+	var partType string
+	switch {
+	case pc.Filesystem != nil:
+		partition.Payload = newCustomFilesystem(pc.Filesystem)
+		switch {
+		case pc.Filesystem.Mountpoint == blueprint.ESPMountpoint:
+			partType = pickPartType(ptType, EFISystemPartitionGUID, "ef")
+		case pc.Filesystem.Mountpoint == "/boot":
+			partType = pickPartType(ptType, XBootLDRPartitionGUID, "83")
+		default:
+			partType = pickPartType(ptType, FilesystemDataGUID, "83")
+		}
+	case pc.VolumeGroup != nil:
+		vg, err := newCustomVolumeGroup(pc.VolumeGroup)
+		if err != nil {
+			return Partition{}, err
+		}
+		partition.Payload = vg
+		partType = pickPartType(ptType, LVMPartitionGUID, "8e")
+	case pc.Btrfs != nil:
+		partition.Payload = newCustomBtrfs(pc.Btrfs)
+		partType = pickPartType(ptType, FilesystemDataGUID, "83")
+	}
+
+	if pc.LUKS != nil {
+		partition.Payload = &LUKSContainer{
+			Passphrase: pc.LUKS.Passphrase,
+			Label:      pc.LUKS.Label,
+			Cipher:     pc.LUKS.Cipher,
+			PBKDF:      defaultCustomLUKSPBKDF,
+			Payload:    partition.Payload,
+		}
+	}
+
+	if partition.Type == "" {
+		partition.Type = partType
+	}
+
+	partition.Size = pc.MinSize
+	if fs := pc.Filesystem; fs != nil {
+		if fs.Mountpoint != blueprint.ESPMountpoint {
+			partition.Size = clampFSSize(fs.Mountpoint, partition.Size)
+		} else if partition.Size == 0 {
+			partition.Size = defaultESPSize
+		}
+	}
+	if payloadSize := minPayloadSize(partition.Payload); payloadSize > partition.Size {
+		partition.Size = payloadSize
+	}
+
+	return partition, nil
+}
+
+func pickPartType(ptType, gptType, dosType string) string {
+	if ptType == "dos" {
+		return dosType
+	}
+	return gptType
+}
+
+func newCustomFilesystem(fc *blueprint.FilesystemTypedCustomization) *Filesystem {
+	fs := &Filesystem{
+		Type:         fc.Type,
+		Label:        fc.Label,
+		Mountpoint:   fc.Mountpoint,
+		FSTabOptions: fc.FSTabOptions,
+	}
+
+	if fs.FSTabOptions == "" {
+		fs.FSTabOptions = "defaults"
+		if fs.Type == "vfat" {
+			fs.FSTabOptions = "defaults,uid=0,gid=0,umask=077,shortname=winnt"
+		}
+	}
+	if fs.Type == "vfat" {
+		fs.FSTabPassNo = 2
+	}
+
+	return fs
+}
+
+func newCustomVolumeGroup(vgc *blueprint.VolumeGroupCustomization) (*LVMVolumeGroup, error) {
+	vg := &LVMVolumeGroup{
+		Name:        vgc.Name,
+		Description: "created via lvm2 and osbuild",
+	}
+
+	// the explicitly named logical volumes are created first, so that the
+	// names derived from mountpoints cannot collide with any of them; the
+	// order of the customization is restored afterwards
+	lvs := make([]LVMLogicalVolume, len(vgc.LogicalVolumes))
+	for idx := range vgc.LogicalVolumes {
+		lvc := &vgc.LogicalVolumes[idx]
+		if lvc.Name == "" {
+			continue
+		}
+		lvs[idx] = LVMLogicalVolume{
+			Name:    lvc.Name,
+			Size:    vg.AlignUp(clampFSSize(lvc.Filesystem.Mountpoint, lvc.MinSize)),
+			Payload: newCustomFilesystem(&lvc.Filesystem),
+		}
+		vg.LogicalVolumes = append(vg.LogicalVolumes, lvs[idx])
+	}
+
+	for idx := range vgc.LogicalVolumes {
+		lvc := &vgc.LogicalVolumes[idx]
+		if lvc.Name != "" {
+			continue
+		}
+		fs := newCustomFilesystem(&lvc.Filesystem)
+		size := clampFSSize(fs.Mountpoint, lvc.MinSize)
+		lv, err := vg.CreateLogicalVolume(fs.Mountpoint, size, fs)
+		if err != nil {
+			return nil, fmt.Errorf("partitioning: volume group %q: logical volume for %q: %w", vg.Name, fs.Mountpoint, err)
+		}
+		lvs[idx] = *lv.(*LVMLogicalVolume)
+	}
+
+	vg.LogicalVolumes = lvs
+	return vg, nil
+}
+
+func newCustomBtrfs(bc *blueprint.BtrfsCustomization) *Btrfs {
+	btrfs := &Btrfs{
+		Label: bc.Label,
+	}
+
+	for _, svc := range bc.Subvolumes {
+		btrfs.Subvolumes = append(btrfs.Subvolumes, BtrfsSubvolume{
+			Name:       svc.Name,
+			Size:       clampFSSize(svc.Mountpoint, svc.MinSize),
+			Mountpoint: svc.Mountpoint,
+		})
+	}
+
+	return btrfs
+}
+
+// minPayloadSize returns the space needed to hold the given entity and all
+// its sized children, including the metadata of volume containers.
+func minPayloadSize(ent Entity) uint64 {
+	var size uint64
+
+	if c, ok := ent.(Container); ok {
+		for idx := uint(0); idx < c.GetItemCount(); idx++ {
+			child := c.GetChild(idx)
+			if s, ok := child.(Sizeable); ok {
+				size += s.GetSize()
+			} else {
+				size += minPayloadSize(child)
+			}
+		}
+	}
+
+	if vc, ok := ent.(VolumeContainer); ok {
+		size += vc.MetadataSize()
+	}
+
+	return size
+}
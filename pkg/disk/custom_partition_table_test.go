@@ -0,0 +1,163 @@
+package disk
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCustomPartitionTable(t *testing.T) {
+	pc := &blueprint.PartitioningCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 1 * GiB,
+				Filesystem: &blueprint.FilesystemTypedCustomization{
+					Type:       "ext4",
+					Mountpoint: "/boot",
+				},
+			},
+			{
+				VolumeGroup: &blueprint.VolumeGroupCustomization{
+					Name: "vg00",
+					LogicalVolumes: []blueprint.LogicalVolumeCustomization{
+						{
+							Name:    "root",
+							MinSize: 3 * GiB,
+							Filesystem: blueprint.FilesystemTypedCustomization{
+								Type:       "xfs",
+								Mountpoint: "/",
+							},
+						},
+						{
+							MinSize: 2 * GiB,
+							Filesystem: blueprint.FilesystemTypedCustomization{
+								Type:       "xfs",
+								Mountpoint: "/var/log",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	basePT := testPartitionTables["plain"]
+	customPT, err := NewCustomPartitionTable(pc, &basePT)
+	require.NoError(t, err)
+
+	// BIOS boot and ESP are taken from the base partition table, /boot and
+	// the LVM partition are from the customization
+	require.Len(t, customPT.Partitions, 4)
+	assert.True(t, customPT.Partitions[0].IsBIOSBoot())
+	assert.Equal(t, EFISystemPartitionGUID, customPT.Partitions[1].Type)
+	assert.Equal(t, XBootLDRPartitionGUID, customPT.Partitions[2].Type)
+	assert.Equal(t, LVMPartitionGUID, customPT.Partitions[3].Type)
+	assert.Equal(t, uint64(5*GiB+1*MiB), customPT.Partitions[3].Size)
+
+	vg := customPT.Partitions[3].Payload.(*LVMVolumeGroup)
+	assert.Equal(t, "vg00", vg.Name)
+	assert.Equal(t, "root", vg.LogicalVolumes[0].Name)
+	assert.Equal(t, "var_loglv", vg.LogicalVolumes[1].Name)
+
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+	pt, err := NewPartitionTable(customPT, nil, 0, false, nil, rng)
+	require.NoError(t, err)
+	for _, mnt := range []string{"/", "/boot", "/boot/efi", "/var/log"} {
+		assert.True(t, pt.ContainsMountpoint(mnt))
+	}
+	assert.Equal(t, "ext4", pt.FindMountable("/boot").GetFSType())
+	assert.Contains(t, pt.GetBuildPackages(), "lvm2")
+	assert.Contains(t, pt.GetBuildPackages(), "e2fsprogs")
+}
+
+func TestNewCustomPartitionTableLVNameCollision(t *testing.T) {
+	// the name derived from /var/log is taken by a later logical volume
+	pc := &blueprint.PartitioningCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				VolumeGroup: &blueprint.VolumeGroupCustomization{
+					Name: "vg00",
+					LogicalVolumes: []blueprint.LogicalVolumeCustomization{
+						{
+							Filesystem: blueprint.FilesystemTypedCustomization{
+								Type:       "xfs",
+								Mountpoint: "/var/log",
+							},
+						},
+						{
+							Name: "var_loglv",
+							Filesystem: blueprint.FilesystemTypedCustomization{
+								Type:       "xfs",
+								Mountpoint: "/",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	basePT := testPartitionTables["plain"]
+	customPT, err := NewCustomPartitionTable(pc, &basePT)
+	require.NoError(t, err)
+
+	var vg *LVMVolumeGroup
+	for _, part := range customPT.Partitions {
+		if part.Type == LVMPartitionGUID {
+			vg = part.Payload.(*LVMVolumeGroup)
+		}
+	}
+	require.NotNil(t, vg)
+	require.Len(t, vg.LogicalVolumes, 2)
+	assert.Equal(t, "var_loglv00", vg.LogicalVolumes[0].Name)
+	assert.Equal(t, "/var/log", vg.LogicalVolumes[0].Payload.(*Filesystem).Mountpoint)
+	assert.Equal(t, "var_loglv", vg.LogicalVolumes[1].Name)
+	assert.Equal(t, "/", vg.LogicalVolumes[1].Payload.(*Filesystem).Mountpoint)
+}
+
+func TestNewCustomPartitionTableAddsBoot(t *testing.T) {
+	pc := &blueprint.PartitioningCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				LUKS: &blueprint.LUKSCustomization{
+					Passphrase: "secret",
+				},
+				Filesystem: &blueprint.FilesystemTypedCustomization{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+
+	basePT := testPartitionTables["plain"]
+	customPT, err := NewCustomPartitionTable(pc, &basePT)
+	require.NoError(t, err)
+
+	assert.True(t, customPT.ContainsMountpoint("/boot"))
+	luks := customPT.Partitions[2].Payload.(*LUKSContainer)
+	assert.Equal(t, "secret", luks.Passphrase)
+	assert.Equal(t, uint64(1*GiB), customPT.Partitions[2].Size)
+}
+
+func TestNewCustomPartitionTableTypeMismatch(t *testing.T) {
+	pc := &blueprint.PartitioningCustomization{
+		Type: "dos",
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Filesystem: &blueprint.FilesystemTypedCustomization{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+
+	basePT := testPartitionTables["plain"]
+	_, err := NewCustomPartitionTable(pc, &basePT)
+	assert.EqualError(t, err, `partitioning: partition table type "dos" is not supported for this image type, expected "gpt"`)
+}
@@ -972,6 +972,80 @@ func TestEnsureDirectorySizes(t *testing.T) {
 
 }
 
+func TestEstimateUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+	basePT := testPartitionTables["plain"]
+	mountpoints := []blueprint.FilesystemCustomization{{Mountpoint: "/var", MinSize: 1 * GiB}}
+	pt, err := NewPartitionTable(&basePT, mountpoints, 5*GiB, false, map[string]uint64{}, rng)
+	assert.NoError(err)
+
+	rootPart := pt.Partitions[3]
+	assert.Equal("/", rootPart.Payload.(*Filesystem).Mountpoint)
+	varPart := pt.Partitions[4]
+	assert.Equal("/var", varPart.Payload.(*Filesystem).Mountpoint)
+
+	usage := pt.EstimateUsage(map[string]uint64{
+		"/usr":                        3 * GiB,
+		"/etc/hosts":                  1 * KiB,
+		"/var/lib/containers/storage": 2 * GiB,
+		"/boot/efi/EFI/BOOT":          1 * MiB,
+	})
+	assert.Equal([]MountpointUsage{
+		{Mountpoint: "/", Size: rootPart.Size, Used: 3*GiB + 1*KiB},
+		{Mountpoint: "/boot/efi", Size: 200 * MiB, Used: 1 * MiB},
+		{Mountpoint: "/var", Size: 1 * GiB, Used: 2 * GiB},
+	}, usage)
+	assert.True(usage[0].Fits())
+	assert.True(usage[1].Fits())
+	assert.False(usage[2].Fits())
+
+	assert.Panics(func() { pt.EstimateUsage(map[string]uint64{"invalid": 300}) })
+}
+
+func TestGrowToFit(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+	basePT := testPartitionTables["plain"]
+	mountpoints := []blueprint.FilesystemCustomization{{Mountpoint: "/var", MinSize: 1 * GiB}}
+	pt, err := NewPartitionTable(&basePT, mountpoints, 5*GiB, false, map[string]uint64{}, rng)
+	assert.NoError(err)
+	assert.Equal(uint64(5*GiB), pt.Size)
+
+	// nothing to do if everything fits
+	sizes := map[string]uint64{"/usr": 2 * GiB, "/var/lib": 512 * MiB}
+	assert.Empty(pt.GrowToFit(sizes))
+	assert.Equal(uint64(5*GiB), pt.Size)
+
+	sizes = map[string]uint64{"/usr": 6 * GiB, "/var/lib": 2 * GiB}
+	grown := pt.GrowToFit(sizes)
+	assert.Len(grown, 2)
+	assert.Equal("/", grown[0].Mountpoint)
+	assert.Equal("/var", grown[1].Mountpoint)
+
+	for _, u := range pt.EstimateUsage(sizes) {
+		assert.True(u.Fits(), u.Mountpoint)
+	}
+	assert.Equal(uint64(2*GiB), pt.Partitions[4].Size)
+	assert.GreaterOrEqual(pt.Partitions[3].Size, uint64(6*GiB))
+
+	// the partitions are laid out again and the disk grew to fit them
+	start := pt.Partitions[0].Start
+	for _, idx := range []int{0, 1, 2, 4, 3} {
+		part := pt.Partitions[idx]
+		assert.Equal(start, part.Start)
+		start += part.Size
+	}
+	assert.LessOrEqual(start+pt.HeaderSize(), pt.Size)
+	assert.Greater(pt.Size, uint64(8*GiB))
+}
+
 func TestMinimumSizesWithRequiredSizes(t *testing.T) {
 	assert := assert.New(t)
 
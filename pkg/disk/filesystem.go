@@ -8,18 +8,18 @@ import (
 
 // Filesystem related functions
 type Filesystem struct {
-	Type string
+	Type string `json:"type" yaml:"type"`
 	// ID of the filesystem, vfat doesn't use traditional UUIDs, therefore this
 	// is just a string.
-	UUID       string
-	Label      string
-	Mountpoint string
+	UUID       string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	Label      string `json:"label,omitempty" yaml:"label,omitempty"`
+	Mountpoint string `json:"mountpoint,omitempty" yaml:"mountpoint,omitempty"`
 	// The fourth field of fstab(5); fs_mntops
-	FSTabOptions string
+	FSTabOptions string `json:"fstab_options,omitempty" yaml:"fstab_options,omitempty"`
 	// The fifth field of fstab(5); fs_freq
-	FSTabFreq uint64
+	FSTabFreq uint64 `json:"fstab_freq,omitempty" yaml:"fstab_freq,omitempty"`
 	// The sixth field of fstab(5); fs_passno
-	FSTabPassNo uint64
+	FSTabPassNo uint64 `json:"fstab_passno,omitempty" yaml:"fstab_passno,omitempty"`
 }
 
 func (fs *Filesystem) IsContainer() bool {
@@ -0,0 +1,140 @@
+package inspect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+// MismatchError lists the differences between a disk image and the partition
+// table it was defined with.
+type MismatchError struct {
+	Mismatches []string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("disk does not match the partition table: %s", strings.Join(e.Mismatches, "; "))
+}
+
+type comparison struct {
+	mismatches []string
+}
+
+func (c *comparison) check(what string, expected, actual interface{}) {
+	if expected != actual {
+		c.mismatches = append(c.mismatches, fmt.Sprintf("%s is %v instead of %v", what, actual, expected))
+	}
+}
+
+// normalizeID returns an identifier in the form it is compared in: GUIDs are
+// case insensitive, the dashes of FAT volume IDs are optional and MBR
+// partition types and disk identifiers are hexadecimal numbers.
+func normalizeID(id string) string {
+	id = strings.ToLower(id)
+	if len(id) == 9 && id[4] == '-' {
+		// FAT volume ID
+		return id[:4] + id[5:]
+	}
+	return id
+}
+
+// normalizeMBRType returns the hexadecimal MBR partition type without prefix
+// and with two digits.
+func normalizeMBRType(t string) string {
+	t = strings.TrimPrefix(strings.ToLower(t), "0x")
+	if len(t) == 1 {
+		t = "0" + t
+	}
+	return t
+}
+
+// Compare checks that a disk matches the partition table it was defined with:
+// the partition table type and identifier, the sizes, types, UUIDs and
+// bootable flags of the partitions and the types, UUIDs and labels of their
+// filesystems. Empty identifiers and labels of the partition table are not
+// compared. The error is a *MismatchError that lists all the differences.
+func Compare(pt *disk.PartitionTable, d *Disk) error {
+	c := &comparison{}
+
+	c.check("partition table type", pt.Type, d.Type)
+	if pt.UUID != "" {
+		c.check("partition table UUID", normalizeID(pt.UUID), normalizeID(d.UUID))
+	}
+	if pt.Size != 0 {
+		c.check("disk size", pt.Size, d.Size)
+	}
+
+	if len(pt.Partitions) != len(d.Partitions) {
+		c.check("number of partitions", len(pt.Partitions), len(d.Partitions))
+		return &MismatchError{Mismatches: c.mismatches}
+	}
+
+	for idx := range pt.Partitions {
+		expected := &pt.Partitions[idx]
+		actual := &d.Partitions[idx]
+		name := fmt.Sprintf("partition %d", idx+1)
+
+		c.check(name+" start", expected.Start, actual.Start)
+		c.check(name+" size", expected.Size, actual.Size)
+		c.check(name+" bootable flag", expected.Bootable, actual.Bootable)
+
+		// partitions without a type are created with the type of Linux
+		// filesystems
+		if pt.Type == "dos" {
+			partType := expected.Type
+			if partType == "" {
+				partType = "83"
+			}
+			c.check(name+" type", normalizeMBRType(partType), normalizeMBRType(actual.Type))
+		} else {
+			partType := expected.Type
+			if partType == "" {
+				partType = disk.FilesystemDataGUID
+			}
+			c.check(name+" type", normalizeID(partType), normalizeID(actual.Type))
+			if expected.UUID != "" {
+				c.check(name+" UUID", normalizeID(expected.UUID), normalizeID(actual.UUID))
+			}
+		}
+
+		c.comparePayload(name, expected.Payload, actual.Filesystem)
+	}
+
+	if len(c.mismatches) > 0 {
+		return &MismatchError{Mismatches: c.mismatches}
+	}
+	return nil
+}
+
+func (c *comparison) comparePayload(name string, payload disk.Entity, fs *Filesystem) {
+	var fsType, uuid, label string
+	switch p := payload.(type) {
+	case nil:
+		// the contents of raw partitions are not checked
+		return
+	case *disk.Filesystem:
+		fsType, uuid, label = p.Type, p.UUID, p.Label
+	case *disk.Btrfs:
+		fsType, uuid, label = "btrfs", p.UUID, p.Label
+	case *disk.LUKSContainer:
+		fsType, uuid, label = "crypto_LUKS", p.UUID, p.Label
+	case *disk.LVMVolumeGroup:
+		fsType = "LVM2_member"
+	default:
+		c.mismatches = append(c.mismatches, fmt.Sprintf("%s payload of type %T cannot be compared", name, payload))
+		return
+	}
+
+	if fs == nil {
+		c.mismatches = append(c.mismatches, fmt.Sprintf("%s contains no known filesystem instead of %s", name, fsType))
+		return
+	}
+	c.check(name+" filesystem type", fsType, fs.Type)
+	if uuid != "" {
+		c.check(name+" filesystem UUID", normalizeID(uuid), normalizeID(fs.UUID))
+	}
+	if label != "" {
+		c.check(name+" filesystem label", label, fs.Label)
+	}
+}
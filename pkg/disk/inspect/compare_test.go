@@ -0,0 +1,85 @@
+package inspect
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func TestCompare(t *testing.T) {
+	img, pt := testGPTImage(t)
+	d, err := Inspect(bytes.NewReader(img), uint64(len(img)))
+	require.NoError(t, err)
+
+	assert.NoError(t, Compare(pt, d))
+
+	// identifiers are compared case insensitively and empty ones are ignored
+	pt.UUID = ""
+	pt.Partitions[0].UUID = "68b2905b-df3e-4fb3-80fa-49d1e773aa33"
+	pt.Partitions[1].Payload.(*disk.Filesystem).Label = ""
+	assert.NoError(t, Compare(pt, d))
+
+	pt.Size = 16 * MiB
+	pt.Partitions[0].Bootable = true
+	pt.Partitions[1].Size = 1 * MiB
+	pt.Partitions[1].Type = disk.FilesystemDataGUID
+	pt.Partitions[1].Payload.(*disk.Filesystem).UUID = "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75"
+	pt.Partitions[2].Payload = &disk.Filesystem{Type: "ext4"}
+
+	err = Compare(pt, d)
+	assert.IsType(t, &MismatchError{}, err)
+	assert.Equal(t, []string{
+		"disk size is 8388608 instead of 16777216",
+		"partition 1 bootable flag is false instead of true",
+		"partition 2 size is 2097152 instead of 1048576",
+		"partition 2 type is bc13c2ff-59e6-4262-a352-b275fd6f7172 instead of 0fc63daf-8483-4772-8e79-3d69d8477de4",
+		"partition 2 filesystem UUID is 0194fdc2-fa2f-4cc0-81d3-ff12045b73c8 instead of 6e4ff95f-f662-45ee-a82a-bdf44a2d0b75",
+		"partition 3 filesystem type is crypto_LUKS instead of ext4",
+	}, err.(*MismatchError).Mismatches)
+
+	pt.Partitions = pt.Partitions[:2]
+	assert.EqualError(t, Compare(pt, d), "disk does not match the partition table: disk size is 8388608 instead of 16777216; number of partitions is 3 instead of 2")
+}
+
+func TestCompareMBR(t *testing.T) {
+	img := make([]byte, 4*MiB)
+	writeMBR(img, 0, []mbrPartitionEntry{
+		{Status: 0x80, Type: 0x83, FirstSector: 2048, Sectors: 2048},
+		{Type: 0x8e, FirstSector: 4096, Sectors: 4096},
+	})
+	writeExt4(t, img[1*MiB:], testBootFSUUID, "boot")
+
+	d, err := Inspect(bytes.NewReader(img), uint64(len(img)))
+	require.NoError(t, err)
+
+	pt := &disk.PartitionTable{
+		UUID: "0x14FC63D2",
+		Type: "dos",
+		Partitions: []disk.Partition{
+			{
+				Start:    1 * MiB,
+				Size:     1 * MiB,
+				Bootable: true,
+				Payload: &disk.Filesystem{
+					Type:       "ext4",
+					UUID:       testBootFSUUID,
+					Mountpoint: "/boot",
+				},
+			},
+			{
+				Start: 2 * MiB,
+				Size:  2 * MiB,
+				Type:  "0x8e",
+			},
+		},
+	}
+	assert.NoError(t, Compare(pt, d))
+
+	pt.Partitions[1].Type = "8E"
+	pt.Partitions[1].Payload = &disk.LVMVolumeGroup{Name: "rootvg"}
+	assert.EqualError(t, Compare(pt, d), "disk does not match the partition table: partition 2 contains no known filesystem instead of LVM2_member")
+}
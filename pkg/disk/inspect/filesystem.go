@@ -0,0 +1,162 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// probeFilesystem identifies the filesystem or container at the start of a
+// partition from its superblock or header. It returns nil if the contents
+// are not recognised.
+func probeFilesystem(r io.ReaderAt) (*Filesystem, error) {
+	for _, probe := range []func(io.ReaderAt) (*Filesystem, error){probeLUKS, probeXFS, probeExt4, probeBtrfs, probeLVM, probeVFAT} {
+		fs, err := probe(r)
+		if err != nil {
+			return nil, err
+		}
+		if fs != nil {
+			return fs, nil
+		}
+	}
+	return nil, nil
+}
+
+// readBlock reads size bytes at the offset and returns nil if they are not
+// available, e.g. because the partition is too small.
+func readBlock(r io.ReaderAt, offset int64, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := r.ReadAt(buf, offset)
+	if err == io.EOF && n < size {
+		return nil, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func cString(data []byte) string {
+	if idx := bytes.IndexByte(data, 0); idx != -1 {
+		data = data[:idx]
+	}
+	return string(data)
+}
+
+func uuidString(data []byte) string {
+	u, _ := uuid.FromBytes(data)
+	return u.String()
+}
+
+func probeExt4(r io.ReaderAt) (*Filesystem, error) {
+	sb, err := readBlock(r, 1024, 256)
+	if sb == nil || err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(sb[56:]) != 0xef53 {
+		return nil, nil
+	}
+	return &Filesystem{
+		Type:  "ext4",
+		UUID:  uuidString(sb[104:120]),
+		Label: cString(sb[120:136]),
+	}, nil
+}
+
+func probeXFS(r io.ReaderAt) (*Filesystem, error) {
+	sb, err := readBlock(r, 0, 120)
+	if sb == nil || err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sb[:4], []byte("XFSB")) {
+		return nil, nil
+	}
+	return &Filesystem{
+		Type:  "xfs",
+		UUID:  uuidString(sb[32:48]),
+		Label: cString(sb[108:120]),
+	}, nil
+}
+
+func probeBtrfs(r io.ReaderAt) (*Filesystem, error) {
+	sb, err := readBlock(r, 0x10000, 0x1000)
+	if sb == nil || err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sb[0x40:0x48], []byte("_BHRfS_M")) {
+		return nil, nil
+	}
+	return &Filesystem{
+		Type:  "btrfs",
+		UUID:  uuidString(sb[0x20:0x30]),
+		Label: cString(sb[0x12b : 0x12b+256]),
+	}, nil
+}
+
+func probeVFAT(r io.ReaderAt) (*Filesystem, error) {
+	bs, err := readBlock(r, 0, 512)
+	if bs == nil || err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(bs[510:]) != 0xaa55 {
+		return nil, nil
+	}
+
+	// the extended boot record is at a different offset for FAT32
+	var ebr []byte
+	switch {
+	case bytes.HasPrefix(bs[82:], []byte("FAT32")):
+		ebr = bs[64:90]
+	case bytes.HasPrefix(bs[54:], []byte("FAT")):
+		ebr = bs[36:62]
+	default:
+		return nil, nil
+	}
+
+	// the volume ID is shown like the UUID of other filesystems
+	volID := binary.LittleEndian.Uint32(ebr[3:])
+	return &Filesystem{
+		Type:  "vfat",
+		UUID:  fmt.Sprintf("%04X-%04X", volID>>16, volID&0xffff),
+		Label: strings.TrimRight(string(ebr[7:18]), " "),
+	}, nil
+}
+
+func probeLUKS(r io.ReaderAt) (*Filesystem, error) {
+	hdr, err := readBlock(r, 0, 208)
+	if hdr == nil || err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hdr[:6], []byte{'L', 'U', 'K', 'S', 0xba, 0xbe}) {
+		return nil, nil
+	}
+	fs := &Filesystem{
+		Type: "crypto_LUKS",
+		UUID: cString(hdr[168:208]),
+	}
+	// only LUKS2 headers have a label
+	if binary.BigEndian.Uint16(hdr[6:]) == 2 {
+		fs.Label = cString(hdr[24:72])
+	}
+	return fs, nil
+}
+
+func probeLVM(r io.ReaderAt) (*Filesystem, error) {
+	// the label can be in any of the first four sectors
+	for sector := int64(0); sector < 4; sector++ {
+		label, err := readBlock(r, sector*512, 40)
+		if label == nil || err != nil {
+			return nil, err
+		}
+		if bytes.Equal(label[:8], []byte("LABELONE")) && bytes.Equal(label[24:32], []byte("LVM2 001")) {
+			// the UUID of the physical volume is formatted differently
+			// from other UUIDs by LVM and not compared
+			return &Filesystem{Type: "LVM2_member"}, nil
+		}
+	}
+	return nil, nil
+}
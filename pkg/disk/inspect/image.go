@@ -0,0 +1,83 @@
+package inspect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Image formats that can be read
+const (
+	FormatRaw   = "raw"
+	FormatQCOW2 = "qcow2"
+)
+
+// Image is a disk image opened for reading its virtual disk, independently of
+// the format it is stored in.
+type Image struct {
+	io.ReaderAt
+	format string
+	size   uint64
+	file   *os.File
+}
+
+// Open opens a disk image. The format of the image is detected from its
+// contents: qcow2 images are recognised by their header and any other file
+// is read as a raw image.
+func Open(path string) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := newImage(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot read image %q: %w", path, err)
+	}
+	img.file = file
+	return img, nil
+}
+
+func newImage(file *os.File) (*Image, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(magic, qcow2Magic):
+		qcow, err := newQCOW2Reader(file)
+		if err != nil {
+			return nil, err
+		}
+		return &Image{ReaderAt: qcow, format: FormatQCOW2, size: qcow.size}, nil
+	case bytes.Equal(magic, []byte("KDMV")):
+		return nil, fmt.Errorf("vmdk images are not supported")
+	default:
+		return &Image{ReaderAt: file, format: FormatRaw, size: uint64(info.Size())}, nil
+	}
+}
+
+// Format returns the format of the image file.
+func (img *Image) Format() string {
+	return img.format
+}
+
+// Size returns the size of the virtual disk in bytes.
+func (img *Image) Size() uint64 {
+	return img.size
+}
+
+func (img *Image) Close() error {
+	if img.file == nil {
+		return nil
+	}
+	return img.file.Close()
+}
@@ -0,0 +1,87 @@
+// Package inspect reads the partition table and the filesystems of built disk
+// images, so they can be compared with the disk.PartitionTable the image was
+// defined with.
+//
+// Raw and qcow2 images are supported. The partitions are read from GPT or
+// MBR partition tables and the filesystems are identified by their
+// superblocks. The contents of LUKS containers and LVM volume groups are not
+// read.
+package inspect
+
+import (
+	"fmt"
+	"io"
+)
+
+// Disk is the partition table of a disk image.
+type Disk struct {
+	// Size of the disk in bytes
+	Size uint64
+	// Partition table type, dos or gpt
+	Type string
+	// Disk GUID of GPT disks, or the disk identifier of MBR disks in the
+	// form 0x14fc63d2
+	UUID       string
+	SectorSize uint64
+	Partitions []Partition
+}
+
+type Partition struct {
+	// Start and size of the partition in bytes
+	Start uint64
+	Size  uint64
+	// Type GUID for GPT or the hexadecimal type byte for MBR, e.g. 83
+	Type string
+	// Unique partition GUID, GPT only
+	UUID string
+	// Name of the partition, GPT only
+	Name     string
+	Bootable bool
+	// Filesystem or container in the partition, nil if it is unknown
+	Filesystem *Filesystem
+}
+
+type Filesystem struct {
+	// Type as reported by blkid, e.g. xfs, vfat, crypto_LUKS or LVM2_member
+	Type  string
+	UUID  string
+	Label string
+}
+
+// Inspect reads the partition table of a disk and identifies the filesystems
+// of the partitions.
+func Inspect(r io.ReaderAt, size uint64) (*Disk, error) {
+	d, err := readPartitionTable(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range d.Partitions {
+		part := &d.Partitions[idx]
+		if part.Start+part.Size > size {
+			return nil, fmt.Errorf("partition %d ends after the end of the disk", idx+1)
+		}
+		part.Filesystem, err = probeFilesystem(io.NewSectionReader(r, int64(part.Start), int64(part.Size)))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read partition %d: %w", idx+1, err)
+		}
+	}
+
+	return d, nil
+}
+
+// InspectImage reads the partition table and filesystems of a disk image
+// file.
+func InspectImage(path string) (*Disk, error) {
+	img, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	d, err := Inspect(img, img.Size())
+	if err != nil {
+		return nil, fmt.Errorf("cannot inspect image %q: %w", path, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,300 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+const MiB = 1024 * 1024
+
+const (
+	testDiskUUID   = "D209C89E-EA5E-4FBD-B161-B461CCE297E0"
+	testBootFSUUID = "0194fdc2-fa2f-4cc0-81d3-ff12045b73c8"
+	testLUKSUUID   = "fb180daf-48a7-4ee0-b10d-394651850fd4"
+)
+
+// guidBytes encodes a GUID in the mixed endian format of GPT.
+func guidBytes(t *testing.T, s string) [16]byte {
+	u, err := uuid.Parse(s)
+	require.NoError(t, err)
+	var guid [16]byte
+	copy(guid[:], u[:])
+	guid[0], guid[1], guid[2], guid[3] = u[3], u[2], u[1], u[0]
+	guid[4], guid[5] = u[5], u[4]
+	guid[6], guid[7] = u[7], u[6]
+	return guid
+}
+
+type testPartition struct {
+	start    uint64
+	size     uint64
+	typ      string
+	uuid     string
+	name     string
+	bootable bool
+}
+
+// writeMBR writes an MBR with the given partitions, or with a single partition
+// of mbrType that spans the disk.
+func writeMBR(img []byte, mbrType uint8, parts []mbrPartitionEntry) {
+	var m mbr
+	m.DiskID = 0x14fc63d2
+	m.Signature = 0xaa55
+	if mbrType != 0 {
+		m.Partitions[0] = mbrPartitionEntry{Type: mbrType, FirstSector: 1, Sectors: uint32(len(img)/512 - 1)}
+	}
+	copy(m.Partitions[:], parts)
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, &m)
+	copy(img, buf.Bytes())
+}
+
+// writeGPT writes a protective MBR and a GPT header with 128 partition
+// entries to a disk with 512 byte sectors.
+func writeGPT(t *testing.T, img []byte, parts []testPartition) {
+	writeMBR(img, mbrProtectiveType, nil)
+
+	entries := &bytes.Buffer{}
+	for idx := 0; idx < 128; idx++ {
+		var entry gptPartitionEntry
+		if idx < len(parts) {
+			p := parts[idx]
+			entry.TypeGUID = guidBytes(t, p.typ)
+			entry.UniqueGUID = guidBytes(t, p.uuid)
+			entry.FirstLBA = p.start / 512
+			entry.LastLBA = (p.start+p.size)/512 - 1
+			if p.bootable {
+				entry.Attributes = gptBootableAttribute
+			}
+			copy(entry.Name[:], utf16.Encode([]rune(p.name)))
+		}
+		require.NoError(t, binary.Write(entries, binary.LittleEndian, &entry))
+	}
+	copy(img[2*512:], entries.Bytes())
+
+	header := gptHeader{
+		Revision:              0x00010000,
+		HeaderSize:            92,
+		CurrentLBA:            1,
+		BackupLBA:             uint64(len(img)/512 - 1),
+		FirstUsableLBA:        34,
+		LastUsableLBA:         uint64(len(img)/512 - 34),
+		DiskGUID:              guidBytes(t, testDiskUUID),
+		PartitionEntriesLBA:   2,
+		NumPartitionEntries:   128,
+		PartitionEntrySize:    128,
+		PartitionEntriesCRC32: crc32.ChecksumIEEE(entries.Bytes()),
+	}
+	copy(header.Signature[:], "EFI PART")
+	buf := &bytes.Buffer{}
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, &header))
+	header.HeaderCRC32 = crc32.ChecksumIEEE(buf.Bytes())
+	buf.Reset()
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, &header))
+	copy(img[512:], buf.Bytes())
+}
+
+func writeVFAT(t *testing.T, part []byte, volumeID, label string) {
+	u, err := uuid.Parse("00000000-0000-0000-0000-0000" + volumeID[:4] + volumeID[5:])
+	require.NoError(t, err)
+	copy(part[82:], "FAT32   ")
+	binary.LittleEndian.PutUint32(part[67:], binary.BigEndian.Uint32(u[12:]))
+	copy(part[71:82], label+"           ")
+	binary.LittleEndian.PutUint16(part[510:], 0xaa55)
+}
+
+func writeExt4(t *testing.T, part []byte, fsUUID, label string) {
+	u, err := uuid.Parse(fsUUID)
+	require.NoError(t, err)
+	binary.LittleEndian.PutUint16(part[1024+56:], 0xef53)
+	copy(part[1024+104:], u[:])
+	copy(part[1024+120:], label)
+}
+
+func writeXFS(t *testing.T, part []byte, fsUUID, label string) {
+	u, err := uuid.Parse(fsUUID)
+	require.NoError(t, err)
+	copy(part, "XFSB")
+	copy(part[32:], u[:])
+	copy(part[108:], label)
+}
+
+func writeLUKS2(part []byte, luksUUID, label string) {
+	copy(part, []byte{'L', 'U', 'K', 'S', 0xba, 0xbe})
+	binary.BigEndian.PutUint16(part[6:], 2)
+	copy(part[24:], label)
+	copy(part[168:], luksUUID)
+}
+
+// testGPTImage returns a disk image with an ESP, an xfs /boot partition and a
+// LUKS2 root partition, and the partition table it matches.
+func testGPTImage(t *testing.T) ([]byte, *disk.PartitionTable) {
+	img := make([]byte, 8*MiB)
+	writeGPT(t, img, []testPartition{
+		{start: 1 * MiB, size: 1 * MiB, typ: disk.EFISystemPartitionGUID, uuid: disk.EFISystemPartitionUUID, name: "EFI System Partition"},
+		{start: 2 * MiB, size: 2 * MiB, typ: disk.XBootLDRPartitionGUID, uuid: disk.FilesystemDataUUID, name: "boot", bootable: true},
+		{start: 4 * MiB, size: 4*MiB - 34*512, typ: disk.FilesystemDataGUID, uuid: disk.RootPartitionUUID},
+	})
+	writeVFAT(t, img[1*MiB:], disk.EFIFilesystemUUID, "ESP")
+	writeXFS(t, img[2*MiB:], testBootFSUUID, "boot")
+	writeLUKS2(img[4*MiB:], testLUKSUUID, "root")
+
+	pt := &disk.PartitionTable{
+		Size: 8 * MiB,
+		UUID: testDiskUUID,
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * MiB,
+				Size:  1 * MiB,
+				Type:  disk.EFISystemPartitionGUID,
+				UUID:  disk.EFISystemPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:       "vfat",
+					UUID:       "7B7795E7",
+					Mountpoint: "/boot/efi",
+					Label:      "ESP",
+				},
+			},
+			{
+				Start:    2 * MiB,
+				Size:     2 * MiB,
+				Type:     disk.XBootLDRPartitionGUID,
+				UUID:     disk.FilesystemDataUUID,
+				Bootable: true,
+				Payload: &disk.Filesystem{
+					Type:       "xfs",
+					UUID:       testBootFSUUID,
+					Mountpoint: "/boot",
+					Label:      "boot",
+				},
+			},
+			{
+				Start: 4 * MiB,
+				Size:  4*MiB - 34*512,
+				UUID:  disk.RootPartitionUUID,
+				Payload: &disk.LUKSContainer{
+					UUID:  testLUKSUUID,
+					Label: "root",
+				},
+			},
+		},
+	}
+	return img, pt
+}
+
+func TestInspectGPT(t *testing.T) {
+	img, _ := testGPTImage(t)
+
+	d, err := Inspect(bytes.NewReader(img), uint64(len(img)))
+	require.NoError(t, err)
+
+	assert.Equal(t, &Disk{
+		Size:       8 * MiB,
+		Type:       "gpt",
+		UUID:       testDiskUUID,
+		SectorSize: 512,
+		Partitions: []Partition{
+			{
+				Start:      1 * MiB,
+				Size:       1 * MiB,
+				Type:       disk.EFISystemPartitionGUID,
+				UUID:       disk.EFISystemPartitionUUID,
+				Name:       "EFI System Partition",
+				Filesystem: &Filesystem{Type: "vfat", UUID: disk.EFIFilesystemUUID, Label: "ESP"},
+			},
+			{
+				Start:      2 * MiB,
+				Size:       2 * MiB,
+				Type:       disk.XBootLDRPartitionGUID,
+				UUID:       disk.FilesystemDataUUID,
+				Name:       "boot",
+				Bootable:   true,
+				Filesystem: &Filesystem{Type: "xfs", UUID: testBootFSUUID, Label: "boot"},
+			},
+			{
+				Start:      4 * MiB,
+				Size:       4*MiB - 34*512,
+				Type:       disk.FilesystemDataGUID,
+				UUID:       disk.RootPartitionUUID,
+				Filesystem: &Filesystem{Type: "crypto_LUKS", UUID: testLUKSUUID, Label: "root"},
+			},
+		},
+	}, d)
+}
+
+func TestInspectMBR(t *testing.T) {
+	img := make([]byte, 4*MiB)
+	writeMBR(img, 0, []mbrPartitionEntry{
+		{Status: 0x80, Type: 0x83, FirstSector: 2048, Sectors: 2048},
+		{Type: 0x8e, FirstSector: 4096, Sectors: 4096},
+	})
+	writeExt4(t, img[1*MiB:], testBootFSUUID, "boot")
+	copy(img[2*MiB+512:], "LABELONE")
+	copy(img[2*MiB+512+24:], "LVM2 001")
+
+	d, err := Inspect(bytes.NewReader(img), uint64(len(img)))
+	require.NoError(t, err)
+
+	assert.Equal(t, &Disk{
+		Size:       4 * MiB,
+		Type:       "dos",
+		UUID:       "0x14fc63d2",
+		SectorSize: 512,
+		Partitions: []Partition{
+			{
+				Start:      1 * MiB,
+				Size:       1 * MiB,
+				Type:       "83",
+				Bootable:   true,
+				Filesystem: &Filesystem{Type: "ext4", UUID: testBootFSUUID, Label: "boot"},
+			},
+			{
+				Start:      2 * MiB,
+				Size:       2 * MiB,
+				Type:       "8e",
+				Filesystem: &Filesystem{Type: "LVM2_member"},
+			},
+		},
+	}, d)
+}
+
+func TestInspectErrors(t *testing.T) {
+	img := make([]byte, 4*MiB)
+	_, err := Inspect(bytes.NewReader(img), uint64(len(img)))
+	assert.EqualError(t, err, "no partition table found")
+
+	img, _ = testGPTImage(t)
+	img[2*512] ^= 0xff
+	_, err = Inspect(bytes.NewReader(img), uint64(len(img)))
+	assert.EqualError(t, err, "GPT partition entries checksum mismatch")
+
+	img = make([]byte, 4*MiB)
+	writeMBR(img, 0, []mbrPartitionEntry{{Type: 0x83, FirstSector: 2048, Sectors: 8192}})
+	_, err = Inspect(bytes.NewReader(img), uint64(len(img)))
+	assert.EqualError(t, err, "partition 1 ends after the end of the disk")
+}
+
+func TestInspectImage(t *testing.T) {
+	img, pt := testGPTImage(t)
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(path, img, 0600))
+
+	d, err := InspectImage(path)
+	require.NoError(t, err)
+	assert.NoError(t, Compare(pt, d))
+
+	_, err = InspectImage(filepath.Join(t.TempDir(), "missing.raw"))
+	assert.Error(t, err)
+}
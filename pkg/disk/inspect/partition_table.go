@@ -0,0 +1,179 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+)
+
+// MBR partition type of the protective partition of GPT disks
+const mbrProtectiveType = 0xee
+
+// legacy BIOS bootable attribute of GPT partitions
+const gptBootableAttribute = 1 << 2
+
+// sector sizes that GPT headers are looked for with, in order
+var gptSectorSizes = []uint64{512, 4096}
+
+type mbrPartitionEntry struct {
+	Status      uint8
+	CHSFirst    [3]byte
+	Type        uint8
+	CHSLast     [3]byte
+	FirstSector uint32
+	Sectors     uint32
+}
+
+type mbr struct {
+	Bootstrap  [440]byte
+	DiskID     uint32
+	Reserved   uint16
+	Partitions [4]mbrPartitionEntry
+	Signature  uint16
+}
+
+type gptHeader struct {
+	Signature             [8]byte
+	Revision              uint32
+	HeaderSize            uint32
+	HeaderCRC32           uint32
+	Reserved              uint32
+	CurrentLBA            uint64
+	BackupLBA             uint64
+	FirstUsableLBA        uint64
+	LastUsableLBA         uint64
+	DiskGUID              [16]byte
+	PartitionEntriesLBA   uint64
+	NumPartitionEntries   uint32
+	PartitionEntrySize    uint32
+	PartitionEntriesCRC32 uint32
+}
+
+type gptPartitionEntry struct {
+	TypeGUID   [16]byte
+	UniqueGUID [16]byte
+	FirstLBA   uint64
+	LastLBA    uint64
+	Attributes uint64
+	Name       [36]uint16
+}
+
+// guidString formats a GUID that is stored in the mixed endian format of GPT.
+func guidString(guid [16]byte) string {
+	var u uuid.UUID
+	copy(u[:], guid[:])
+	// the first three fields are little endian
+	u[0], u[1], u[2], u[3] = guid[3], guid[2], guid[1], guid[0]
+	u[4], u[5] = guid[5], guid[4]
+	u[6], u[7] = guid[7], guid[6]
+	return strings.ToUpper(u.String())
+}
+
+// readPartitionTable reads the GPT or MBR partition table of a disk.
+func readPartitionTable(r io.ReaderAt, size uint64) (*Disk, error) {
+	var m mbr
+	if err := binary.Read(io.NewSectionReader(r, 0, 512), binary.LittleEndian, &m); err != nil {
+		return nil, fmt.Errorf("cannot read MBR: %w", err)
+	}
+	if m.Signature != 0xaa55 {
+		return nil, fmt.Errorf("no partition table found")
+	}
+
+	for _, p := range m.Partitions {
+		if p.Type == mbrProtectiveType {
+			return readGPT(r, size)
+		}
+	}
+
+	d := &Disk{
+		Type:       "dos",
+		UUID:       fmt.Sprintf("0x%08x", m.DiskID),
+		Size:       size,
+		SectorSize: 512,
+	}
+	for _, p := range m.Partitions {
+		if p.Type == 0 {
+			continue
+		}
+		d.Partitions = append(d.Partitions, Partition{
+			Start:    uint64(p.FirstSector) * d.SectorSize,
+			Size:     uint64(p.Sectors) * d.SectorSize,
+			Type:     fmt.Sprintf("%02x", p.Type),
+			Bootable: p.Status == 0x80,
+		})
+	}
+	return d, nil
+}
+
+func readGPT(r io.ReaderAt, size uint64) (*Disk, error) {
+	for _, sectorSize := range gptSectorSizes {
+		headerData := make([]byte, 92)
+		if _, err := r.ReadAt(headerData, int64(sectorSize)); err != nil {
+			return nil, fmt.Errorf("cannot read GPT header: %w", err)
+		}
+		if !bytes.Equal(headerData[:8], []byte("EFI PART")) {
+			continue
+		}
+
+		var header gptHeader
+		if err := binary.Read(bytes.NewReader(headerData), binary.LittleEndian, &header); err != nil {
+			return nil, fmt.Errorf("cannot read GPT header: %w", err)
+		}
+		if header.HeaderSize < 92 || uint64(header.HeaderSize) > sectorSize {
+			return nil, fmt.Errorf("invalid GPT header size %d", header.HeaderSize)
+		}
+		headerData = make([]byte, header.HeaderSize)
+		if _, err := r.ReadAt(headerData, int64(sectorSize)); err != nil {
+			return nil, fmt.Errorf("cannot read GPT header: %w", err)
+		}
+		// the checksum is calculated with the checksum field zeroed
+		binary.LittleEndian.PutUint32(headerData[16:], 0)
+		if crc32.ChecksumIEEE(headerData) != header.HeaderCRC32 {
+			return nil, fmt.Errorf("GPT header checksum mismatch")
+		}
+
+		if header.PartitionEntrySize < 128 || header.NumPartitionEntries > 1024 {
+			return nil, fmt.Errorf("invalid GPT partition entries")
+		}
+		entries := make([]byte, header.NumPartitionEntries*header.PartitionEntrySize)
+		if _, err := r.ReadAt(entries, int64(header.PartitionEntriesLBA*sectorSize)); err != nil {
+			return nil, fmt.Errorf("cannot read GPT partition entries: %w", err)
+		}
+		if crc32.ChecksumIEEE(entries) != header.PartitionEntriesCRC32 {
+			return nil, fmt.Errorf("GPT partition entries checksum mismatch")
+		}
+
+		d := &Disk{
+			Type:       "gpt",
+			UUID:       guidString(header.DiskGUID),
+			Size:       size,
+			SectorSize: sectorSize,
+		}
+		for idx := uint32(0); idx < header.NumPartitionEntries; idx++ {
+			var entry gptPartitionEntry
+			data := entries[idx*header.PartitionEntrySize:]
+			if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &entry); err != nil {
+				return nil, fmt.Errorf("cannot read GPT partition entry: %w", err)
+			}
+			if entry.TypeGUID == [16]byte{} {
+				continue
+			}
+			d.Partitions = append(d.Partitions, Partition{
+				Start:    entry.FirstLBA * sectorSize,
+				Size:     (entry.LastLBA - entry.FirstLBA + 1) * sectorSize,
+				Type:     guidString(entry.TypeGUID),
+				UUID:     guidString(entry.UniqueGUID),
+				Bootable: entry.Attributes&gptBootableAttribute != 0,
+				Name:     strings.TrimRight(string(utf16.Decode(entry.Name[:])), "\x00"),
+			})
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("protective MBR without a GPT header")
+}
@@ -0,0 +1,203 @@
+package inspect
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// incompatible feature of qcow2 version 3 images that is set if the
+// refcounts may be inconsistent, which doesn't matter for reading
+const qcow2FeatureDirty = 1 << 0
+
+const (
+	qcow2OffsetMask     = 0x00fffffffffffe00
+	qcow2CompressedFlag = 1 << 62
+	qcow2ZeroFlag       = 1 << 0
+)
+
+// qcow2Header holds the fields of the qcow2 header that are common to all
+// versions.
+type qcow2Header struct {
+	Magic                 [4]byte
+	Version               uint32
+	BackingFileOffset     uint64
+	BackingFileSize       uint32
+	ClusterBits           uint32
+	Size                  uint64
+	CryptMethod           uint32
+	L1Size                uint32
+	L1TableOffset         uint64
+	RefcountTableOffset   uint64
+	RefcountTableClusters uint32
+	NbSnapshots           uint32
+	SnapshotsOffset       uint64
+}
+
+// qcow2Reader reads the virtual disk of a qcow2 image. Images with a backing
+// file, encryption or an external data file are not supported.
+type qcow2Reader struct {
+	file        io.ReaderAt
+	size        uint64
+	clusterBits uint32
+	l1          []uint64
+	l2Cache     map[uint64][]uint64
+}
+
+func newQCOW2Reader(file io.ReaderAt) (*qcow2Reader, error) {
+	var header qcow2Header
+	if err := binary.Read(io.NewSectionReader(file, 0, 72), binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("cannot read qcow2 header: %w", err)
+	}
+
+	switch header.Version {
+	case 2:
+	case 3:
+		var incompatible uint64
+		if err := binary.Read(io.NewSectionReader(file, 72, 8), binary.BigEndian, &incompatible); err != nil {
+			return nil, fmt.Errorf("cannot read qcow2 header: %w", err)
+		}
+		if unsupported := incompatible &^ qcow2FeatureDirty; unsupported != 0 {
+			return nil, fmt.Errorf("unsupported qcow2 features %#x", unsupported)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported qcow2 version %d", header.Version)
+	}
+
+	if header.BackingFileOffset != 0 {
+		return nil, fmt.Errorf("qcow2 images with a backing file are not supported")
+	}
+	if header.CryptMethod != 0 {
+		return nil, fmt.Errorf("encrypted qcow2 images are not supported")
+	}
+	if header.ClusterBits < 9 || header.ClusterBits > 21 {
+		return nil, fmt.Errorf("invalid qcow2 cluster size 2^%d", header.ClusterBits)
+	}
+
+	// the L1 table must cover the virtual disk
+	clusterSize := uint64(1) << header.ClusterBits
+	l2Size := clusterSize / 8
+	if needed := (header.Size + clusterSize*l2Size - 1) / (clusterSize * l2Size); uint64(header.L1Size) < needed {
+		return nil, fmt.Errorf("qcow2 L1 table is too small for the image size")
+	}
+
+	l1 := make([]uint64, header.L1Size)
+	if err := binary.Read(io.NewSectionReader(file, int64(header.L1TableOffset), int64(header.L1Size)*8), binary.BigEndian, l1); err != nil {
+		return nil, fmt.Errorf("cannot read qcow2 L1 table: %w", err)
+	}
+
+	return &qcow2Reader{
+		file:        file,
+		size:        header.Size,
+		clusterBits: header.ClusterBits,
+		l1:          l1,
+		l2Cache:     make(map[uint64][]uint64),
+	}, nil
+}
+
+func (r *qcow2Reader) clusterSize() uint64 {
+	return 1 << r.clusterBits
+}
+
+// l2Table returns the L2 table with the given index of the L1 table, or nil
+// if it is not allocated.
+func (r *qcow2Reader) l2Table(idx uint64) ([]uint64, error) {
+	offset := r.l1[idx] & qcow2OffsetMask
+	if offset == 0 {
+		return nil, nil
+	}
+	if l2, ok := r.l2Cache[idx]; ok {
+		return l2, nil
+	}
+
+	l2 := make([]uint64, r.clusterSize()/8)
+	if err := binary.Read(io.NewSectionReader(r.file, int64(offset), int64(r.clusterSize())), binary.BigEndian, l2); err != nil {
+		return nil, fmt.Errorf("cannot read qcow2 L2 table: %w", err)
+	}
+	r.l2Cache[idx] = l2
+	return l2, nil
+}
+
+// readCluster reads the virtual cluster with the given index into buf, which
+// is the size of a cluster.
+func (r *qcow2Reader) readCluster(idx uint64, buf []byte) error {
+	l2Size := r.clusterSize() / 8
+	l2, err := r.l2Table(idx / l2Size)
+	if err != nil {
+		return err
+	}
+
+	var entry uint64
+	if l2 != nil {
+		entry = l2[idx%l2Size]
+	}
+
+	if entry&qcow2CompressedFlag != 0 {
+		return r.readCompressedCluster(entry, buf)
+	}
+
+	offset := entry & qcow2OffsetMask
+	if offset == 0 || entry&qcow2ZeroFlag != 0 {
+		// unallocated and zero clusters read as zeros
+		for i := range buf {
+			buf[i] = 0
+		}
+		return nil
+	}
+
+	if _, err := r.file.ReadAt(buf, int64(offset)); err != nil {
+		return fmt.Errorf("cannot read qcow2 cluster: %w", err)
+	}
+	return nil
+}
+
+// readCompressedCluster decompresses a cluster that is stored with deflate.
+func (r *qcow2Reader) readCompressedCluster(entry uint64, buf []byte) error {
+	sectorsShift := 62 - (r.clusterBits - 8)
+	offset := entry & (1<<sectorsShift - 1)
+	// the number of 512 byte sectors after the one with the offset
+	sectors := (entry & (qcow2CompressedFlag - 1)) >> sectorsShift
+	size := (sectors+1)*512 - offset%512
+
+	compressed := make([]byte, size)
+	if n, err := r.file.ReadAt(compressed, int64(offset)); err != nil && !(err == io.EOF && n > 0) {
+		return fmt.Errorf("cannot read compressed qcow2 cluster: %w", err)
+	}
+
+	// the compressed data may be followed by unrelated data, so the
+	// decompression stops after a full cluster
+	if _, err := io.ReadFull(flate.NewReader(bytes.NewReader(compressed)), buf); err != nil {
+		return fmt.Errorf("cannot decompress qcow2 cluster: %w", err)
+	}
+	return nil
+}
+
+func (r *qcow2Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if uint64(off) >= r.size {
+		return 0, io.EOF
+	}
+
+	var err error
+	if remaining := r.size - uint64(off); uint64(len(p)) > remaining {
+		p = p[:remaining]
+		err = io.EOF
+	}
+
+	cluster := make([]byte, r.clusterSize())
+	n := 0
+	for n < len(p) {
+		pos := uint64(off) + uint64(n)
+		if err := r.readCluster(pos>>r.clusterBits, cluster); err != nil {
+			return n, err
+		}
+		n += copy(p[n:], cluster[pos&(r.clusterSize()-1):])
+	}
+	return n, err
+}
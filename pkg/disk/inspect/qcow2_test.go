@@ -0,0 +1,195 @@
+package inspect
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClusterBits = 16
+
+// writeQCOW2 converts a raw image to a version 3 qcow2 image with 64 KiB
+// clusters. Clusters that only contain zeros are left unallocated or marked
+// as zero clusters in turns and the first cluster is compressed.
+func writeQCOW2(t *testing.T, raw []byte) []byte {
+	clusterSize := uint64(1) << testClusterBits
+	clusters := (uint64(len(raw)) + clusterSize - 1) / clusterSize
+	l2Size := clusterSize / 8
+	l1Size := (clusters + l2Size - 1) / l2Size
+	require.LessOrEqual(t, l1Size, l2Size, "test image is too large")
+
+	// the header is followed by the L1 table and the L2 tables, each in
+	// their own cluster
+	img := make([]byte, (2+l1Size)*clusterSize)
+	header := qcow2Header{
+		Version:       3,
+		ClusterBits:   testClusterBits,
+		Size:          uint64(len(raw)),
+		L1Size:        uint32(l1Size),
+		L1TableOffset: clusterSize,
+	}
+	copy(header.Magic[:], qcow2Magic)
+	buf := &bytes.Buffer{}
+	require.NoError(t, binary.Write(buf, binary.BigEndian, &header))
+	copy(img, buf.Bytes())
+	// header length of version 3 images
+	binary.BigEndian.PutUint32(img[100:], 104)
+
+	l2 := make([]uint64, l1Size*l2Size)
+	zeros := make([]byte, clusterSize)
+	zeroClusters := 0
+	for idx := uint64(0); idx < clusters; idx++ {
+		data := make([]byte, clusterSize)
+		copy(data, raw[idx*clusterSize:])
+		offset := uint64(len(img))
+
+		switch {
+		case idx == 0:
+			compressed := &bytes.Buffer{}
+			w, err := flate.NewWriter(compressed, flate.BestCompression)
+			require.NoError(t, err)
+			_, err = w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			// compressed clusters don't have to start at a sector boundary
+			offset += 100
+			img = append(img, make([]byte, 100)...)
+			img = append(img, compressed.Bytes()...)
+			sectors := (offset%512+uint64(compressed.Len())+511)/512 - 1
+			l2[idx] = qcow2CompressedFlag | sectors<<(62-(testClusterBits-8)) | offset
+			// pad to the next cluster
+			img = append(img, make([]byte, clusterSize-uint64(len(img))%clusterSize)...)
+		case bytes.Equal(data, zeros):
+			if zeroClusters%2 == 1 {
+				l2[idx] = qcow2ZeroFlag
+			}
+			zeroClusters++
+		default:
+			l2[idx] = offset
+			img = append(img, data...)
+		}
+	}
+
+	for idx := uint64(0); idx < l1Size; idx++ {
+		l2Offset := (2 + idx) * clusterSize
+		binary.BigEndian.PutUint64(img[clusterSize+idx*8:], l2Offset)
+		for entry := uint64(0); entry < l2Size; entry++ {
+			binary.BigEndian.PutUint64(img[l2Offset+entry*8:], l2[idx*l2Size+entry])
+		}
+	}
+	return img
+}
+
+func TestQCOW2Reader(t *testing.T) {
+	raw, _ := testGPTImage(t)
+	// data that isn't aligned to clusters
+	copy(raw[3*MiB-10:], bytes.Repeat([]byte{0x5a}, 20))
+
+	r, err := newQCOW2Reader(bytes.NewReader(writeQCOW2(t, raw)))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(raw)), r.size)
+
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, int64(r.size)))
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(raw, data))
+
+	// reads across cluster boundaries and past the end of the disk
+	buf := make([]byte, 20)
+	n, err := r.ReadAt(buf, 3*MiB-10)
+	require.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, bytes.Repeat([]byte{0x5a}, 20), buf)
+
+	n, err = r.ReadAt(buf, int64(r.size)-5)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 5, n)
+}
+
+func TestQCOW2ReaderUnsupported(t *testing.T) {
+	raw, _ := testGPTImage(t)
+
+	tests := []struct {
+		name   string
+		modify func(img []byte)
+		err    string
+	}{
+		{
+			name:   "version",
+			modify: func(img []byte) { binary.BigEndian.PutUint32(img[4:], 1) },
+			err:    "unsupported qcow2 version 1",
+		},
+		{
+			name:   "backing-file",
+			modify: func(img []byte) { binary.BigEndian.PutUint64(img[8:], 1024) },
+			err:    "qcow2 images with a backing file are not supported",
+		},
+		{
+			name:   "encryption",
+			modify: func(img []byte) { binary.BigEndian.PutUint32(img[32:], 2) },
+			err:    "encrypted qcow2 images are not supported",
+		},
+		{
+			name:   "features",
+			modify: func(img []byte) { binary.BigEndian.PutUint64(img[72:], 1<<1) },
+			err:    "unsupported qcow2 features 0x2",
+		},
+		{
+			name:   "l1-size",
+			modify: func(img []byte) { binary.BigEndian.PutUint64(img[24:], 1<<40) },
+			err:    "qcow2 L1 table is too small for the image size",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			img := writeQCOW2(t, raw)
+			tc.modify(img)
+			_, err := newQCOW2Reader(bytes.NewReader(img))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	// the dirty flag doesn't prevent reading
+	img := writeQCOW2(t, raw)
+	binary.BigEndian.PutUint64(img[72:], qcow2FeatureDirty)
+	_, err := newQCOW2Reader(bytes.NewReader(img))
+	assert.NoError(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	raw, pt := testGPTImage(t)
+	dir := t.TempDir()
+
+	rawPath := filepath.Join(dir, "disk.raw")
+	require.NoError(t, os.WriteFile(rawPath, raw, 0600))
+	qcow2Path := filepath.Join(dir, "disk.qcow2")
+	require.NoError(t, os.WriteFile(qcow2Path, writeQCOW2(t, raw), 0600))
+	vmdkPath := filepath.Join(dir, "disk.vmdk")
+	require.NoError(t, os.WriteFile(vmdkPath, []byte("KDMV"), 0600))
+
+	img, err := Open(rawPath)
+	require.NoError(t, err)
+	assert.Equal(t, FormatRaw, img.Format())
+	assert.Equal(t, uint64(len(raw)), img.Size())
+	assert.NoError(t, img.Close())
+
+	img, err = Open(qcow2Path)
+	require.NoError(t, err)
+	assert.Equal(t, FormatQCOW2, img.Format())
+	assert.Equal(t, uint64(len(raw)), img.Size())
+	assert.NoError(t, img.Close())
+
+	d, err := InspectImage(qcow2Path)
+	require.NoError(t, err)
+	assert.NoError(t, Compare(pt, d))
+
+	_, err = Open(vmdkPath)
+	assert.EqualError(t, err, `cannot read image "`+vmdkPath+`": vmdk images are not supported`)
+}
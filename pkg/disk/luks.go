@@ -8,30 +8,30 @@ import (
 )
 
 type Argon2id struct {
-	Iterations  uint
-	Memory      uint
-	Parallelism uint
+	Iterations  uint `json:"iterations,omitempty" yaml:"iterations,omitempty"`
+	Memory      uint `json:"memory,omitempty" yaml:"memory,omitempty"`
+	Parallelism uint `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
 }
 
 type ClevisBind struct {
-	Pin              string
-	Policy           string
-	RemovePassphrase bool
+	Pin              string `json:"pin" yaml:"pin"`
+	Policy           string `json:"policy" yaml:"policy"`
+	RemovePassphrase bool   `json:"remove_passphrase,omitempty" yaml:"remove_passphrase,omitempty"`
 }
 type LUKSContainer struct {
-	Passphrase string
-	UUID       string
-	Cipher     string
-	Label      string
-	Subsystem  string
-	SectorSize uint64
+	Passphrase string `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	UUID       string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	Cipher     string `json:"cipher,omitempty" yaml:"cipher,omitempty"`
+	Label      string `json:"label,omitempty" yaml:"label,omitempty"`
+	Subsystem  string `json:"subsystem,omitempty" yaml:"subsystem,omitempty"`
+	SectorSize uint64 `json:"sector_size,omitempty" yaml:"sector_size,omitempty"`
 
 	// password-based key derivation function
-	PBKDF Argon2id
+	PBKDF Argon2id `json:"pbkdf" yaml:"pbkdf"`
 
-	Clevis *ClevisBind
+	Clevis *ClevisBind `json:"clevis,omitempty" yaml:"clevis,omitempty"`
 
-	Payload Entity
+	Payload Entity `json:"payload,omitempty" yaml:"-"`
 }
 
 func (lc *LUKSContainer) IsContainer() bool {
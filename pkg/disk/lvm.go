@@ -12,10 +12,10 @@ import (
 const LVMDefaultExtentSize = 4 * common.MebiByte
 
 type LVMVolumeGroup struct {
-	Name        string
-	Description string
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 
-	LogicalVolumes []LVMLogicalVolume
+	LogicalVolumes []LVMLogicalVolume `json:"logical_volumes,omitempty" yaml:"logical_volumes,omitempty"`
 }
 
 func (vg *LVMVolumeGroup) IsContainer() bool {
@@ -142,9 +142,9 @@ func (vg *LVMVolumeGroup) MetadataSize() uint64 {
 }
 
 type LVMLogicalVolume struct {
-	Name    string
-	Size    uint64
-	Payload Entity
+	Name    string `json:"name" yaml:"name"`
+	Size    uint64 `json:"size,omitempty" yaml:"size,omitempty"`
+	Payload Entity `json:"payload,omitempty" yaml:"-"`
 }
 
 func (lv *LVMLogicalVolume) IsContainer() bool {
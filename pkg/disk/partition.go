@@ -5,17 +5,17 @@ import (
 )
 
 type Partition struct {
-	Start    uint64 // Start of the partition in bytes
-	Size     uint64 // Size of the partition in bytes
-	Type     string // Partition type, e.g. 0x83 for MBR or a UUID for gpt
-	Bootable bool   // `Legacy BIOS bootable` (GPT) or `active` (DOS) flag
+	Start    uint64 `json:"start,omitempty" yaml:"start,omitempty"`       // Start of the partition in bytes
+	Size     uint64 `json:"size,omitempty" yaml:"size,omitempty"`         // Size of the partition in bytes
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`         // Partition type, e.g. 0x83 for MBR or a UUID for gpt
+	Bootable bool   `json:"bootable,omitempty" yaml:"bootable,omitempty"` // `Legacy BIOS bootable` (GPT) or `active` (DOS) flag
 
 	// ID of the partition, dos doesn't use traditional UUIDs, therefore this
 	// is just a string.
-	UUID string
+	UUID string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
 
 	// If nil, the partition is raw; It doesn't contain a payload.
-	Payload Entity
+	Payload Entity `json:"payload,omitempty" yaml:"-"`
 }
 
 func (p *Partition) IsContainer() bool {
@@ -4,19 +4,20 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"sort"
 
 	"github.com/google/uuid"
 	"github.com/osbuild/images/pkg/blueprint"
 )
 
 type PartitionTable struct {
-	Size       uint64 // Size of the disk (in bytes).
-	UUID       string // Unique identifier of the partition table (GPT only).
-	Type       string // Partition table type, e.g. dos, gpt.
-	Partitions []Partition
+	Size       uint64      `json:"size,omitempty" yaml:"size,omitempty"` // Size of the disk (in bytes).
+	UUID       string      `json:"uuid,omitempty" yaml:"uuid,omitempty"` // Unique identifier of the partition table (GPT only).
+	Type       string      `json:"type" yaml:"type"`                     // Partition table type, e.g. dos, gpt.
+	Partitions []Partition `json:"partitions" yaml:"partitions"`
 
-	SectorSize   uint64 // Sector size in bytes
-	ExtraPadding uint64 // Extra space at the end of the partition table (sectors)
+	SectorSize   uint64 `json:"sector_size,omitempty" yaml:"sector_size,omitempty"`     // Sector size in bytes
+	ExtraPadding uint64 `json:"extra_padding,omitempty" yaml:"extra_padding,omitempty"` // Extra space at the end of the partition table (sectors)
 }
 
 func NewPartitionTable(basePT *PartitionTable, mountpoints []blueprint.FilesystemCustomization, imageSize uint64, lvmify bool, requiredSizes map[string]uint64, rng *rand.Rand) (*PartitionTable, error) {
@@ -193,18 +194,15 @@ func (pt *PartitionTable) findDirectoryEntityPath(dir string) []Entity {
 	return pt.findDirectoryEntityPath(parent)
 }
 
-// EnsureDirectorySizes takes a mapping of directory paths to sizes (in bytes)
-// and resizes the appropriate partitions such that they are at least the size
-// of the sum of their subdirectories plus their own sizes.
-// The function will panic if any of the directory paths are invalid.
-func (pt *PartitionTable) EnsureDirectorySizes(dirSizeMap map[string]uint64) {
-
-	type mntSize struct {
-		entPath []Entity
-		newSize uint64
-	}
+type mntSize struct {
+	entPath []Entity
+	newSize uint64
+}
 
-	// add up the required size for each directory grouped by their mountpoints
+// directorySizesByMountpoint adds up the sizes of the directories in
+// dirSizeMap grouped by the mountpoint they are on.
+// The function will panic if any of the directory paths are invalid.
+func (pt *PartitionTable) directorySizesByMountpoint(dirSizeMap map[string]uint64) map[string]*mntSize {
 	mntSizeMap := make(map[string]*mntSize)
 	for dir, size := range dirSizeMap {
 		entPath := pt.findDirectoryEntityPath(dir)
@@ -219,13 +217,82 @@ func (pt *PartitionTable) EnsureDirectorySizes(dirSizeMap map[string]uint64) {
 		es := mntSizeMap[mountpoint]
 		es.newSize += size
 	}
+	return mntSizeMap
+}
 
+// EnsureDirectorySizes takes a mapping of directory paths to sizes (in bytes)
+// and resizes the appropriate partitions such that they are at least the size
+// of the sum of their subdirectories plus their own sizes.
+// The function will panic if any of the directory paths are invalid.
+func (pt *PartitionTable) EnsureDirectorySizes(dirSizeMap map[string]uint64) {
 	// resize all the entities in the map
-	for _, es := range mntSizeMap {
+	for _, es := range pt.directorySizesByMountpoint(dirSizeMap) {
 		resizeEntityBranch(es.entPath, es.newSize)
 	}
 }
 
+// MountpointUsage is the estimated usage of the filesystem at a mountpoint.
+type MountpointUsage struct {
+	Mountpoint string
+	Size       uint64 // size of the volume the filesystem is on (in bytes)
+	Used       uint64 // estimated size of the content (in bytes)
+}
+
+// Fits returns true if the estimated content fits on the volume.
+func (u MountpointUsage) Fits() bool {
+	return u.Used <= u.Size
+}
+
+// EstimateUsage takes a mapping of directory paths to the estimated size of
+// their content (in bytes) and returns the usage of each mountpoint that
+// contains any of the directories, sorted by mountpoint. The size of a
+// mountpoint is the size of the closest sizeable entity, e.g. the partition
+// or logical volume, that the filesystem is on.
+// The function will panic if any of the directory paths are invalid.
+func (pt *PartitionTable) EstimateUsage(dirSizeMap map[string]uint64) []MountpointUsage {
+	usage := make([]MountpointUsage, 0)
+	for mountpoint, es := range pt.directorySizesByMountpoint(dirSizeMap) {
+		u := MountpointUsage{
+			Mountpoint: mountpoint,
+			Used:       es.newSize,
+		}
+		for _, ent := range es.entPath {
+			if sz, ok := ent.(Sizeable); ok {
+				u.Size = sz.GetSize()
+				break
+			}
+		}
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Mountpoint < usage[j].Mountpoint
+	})
+	return usage
+}
+
+// GrowToFit resizes the partitions and volumes such that the estimated
+// content of the directories in dirSizeMap fits on them and recalculates the
+// layout of the partition table, which grows if needed. In contrast to
+// EnsureDirectorySizes it can be used on a partition table that was already
+// laid out. Returns the usage of the mountpoints that were too small before
+// they were resized.
+// The function will panic if any of the directory paths are invalid.
+func (pt *PartitionTable) GrowToFit(dirSizeMap map[string]uint64) []MountpointUsage {
+	grown := make([]MountpointUsage, 0)
+	for _, u := range pt.EstimateUsage(dirSizeMap) {
+		if !u.Fits() {
+			grown = append(grown, u)
+		}
+	}
+	if len(grown) == 0 {
+		return grown
+	}
+
+	pt.EnsureDirectorySizes(dirSizeMap)
+	pt.relayout(pt.Size)
+	return grown
+}
+
 func (pt *PartitionTable) CreateMountpoint(mountpoint string, size uint64) (Entity, error) {
 	filesystem := Filesystem{
 		Type:         "xfs",
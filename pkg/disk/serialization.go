@@ -0,0 +1,405 @@
+package disk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the version of the serialization format for partition
+// tables. It must be increased whenever the format changes in an
+// incompatible way.
+const FormatVersion = 1
+
+// Type tags for the payload entities in the serialization format. Entities
+// that can hold a payload (Partition, LUKSContainer and LVMLogicalVolume)
+// store the tag of their payload in the "payload_type" field.
+const (
+	payloadTypeFilesystem = "filesystem"
+	payloadTypeLUKS       = "luks"
+	payloadTypeLVM        = "lvm"
+	payloadTypeBtrfs      = "btrfs"
+)
+
+// partitionTableDocument is the top level object of serialized partition
+// tables. It contains either a single partition table or a map of partition
+// tables, e.g. keyed by architecture.
+type partitionTableDocument struct {
+	Version         uint                      `json:"version" yaml:"version"`
+	PartitionTable  *PartitionTable           `json:"partition_table,omitempty" yaml:"partition_table,omitempty"`
+	PartitionTables map[string]PartitionTable `json:"partition_tables,omitempty" yaml:"partition_tables,omitempty"`
+}
+
+func payloadType(e Entity) (string, error) {
+	switch e.(type) {
+	case nil:
+		return "", nil
+	case *Filesystem:
+		return payloadTypeFilesystem, nil
+	case *LUKSContainer:
+		return payloadTypeLUKS, nil
+	case *LVMVolumeGroup:
+		return payloadTypeLVM, nil
+	case *Btrfs:
+		return payloadTypeBtrfs, nil
+	default:
+		return "", fmt.Errorf("unsupported payload entity %T", e)
+	}
+}
+
+// newPayload returns a new, empty entity for the given payload_type.
+func newPayload(typ string) (Entity, error) {
+	switch typ {
+	case payloadTypeFilesystem:
+		return &Filesystem{}, nil
+	case payloadTypeLUKS:
+		return &LUKSContainer{}, nil
+	case payloadTypeLVM:
+		return &LVMVolumeGroup{}, nil
+	case payloadTypeBtrfs:
+		return &Btrfs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown payload_type %q", typ)
+	}
+}
+
+func unmarshalPayload(typ string, data json.RawMessage) (Entity, error) {
+	if typ == "" {
+		if len(data) != 0 && string(data) != "null" {
+			return nil, fmt.Errorf("payload without payload_type")
+		}
+		return nil, nil
+	}
+	payload, err := newPayload(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing payload for payload_type %q", typ)
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// yamlPayload holds the payload of an entity while the YAML mapping of the
+// entity is decoded, since the payload can only be decoded once its
+// payload_type is known. It keeps the unmarshal function of the decoder
+// instead of the node, so that the payload is decoded with the options of the
+// decoder, e.g. the rejection of unknown fields.
+type yamlPayload struct {
+	unmarshal func(interface{}) error
+}
+
+func (yp *yamlPayload) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	yp.unmarshal = unmarshal
+	return nil
+}
+
+// unmarshalYAMLPayload decodes the payload of the entity described by what.
+// The errors of the decoder are returned as they are, so that it reports them
+// with their lines.
+func unmarshalYAMLPayload(typ string, data yamlPayload, what string) (Entity, error) {
+	if typ == "" {
+		if data.unmarshal != nil {
+			return nil, fmt.Errorf("%s: payload without payload_type", what)
+		}
+		return nil, nil
+	}
+	payload, err := newPayload(typ)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+
+	if data.unmarshal == nil {
+		return nil, fmt.Errorf("%s: missing payload for payload_type %q", what, typ)
+	}
+	if err := data.unmarshal(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (p *Partition) MarshalJSON() ([]byte, error) {
+	type alias Partition
+	typ, err := payloadType(p.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		*alias
+		PayloadType string `json:"payload_type,omitempty"`
+	}{(*alias)(p), typ})
+}
+
+func (p *Partition) UnmarshalJSON(data []byte) error {
+	type alias Partition
+	aux := struct {
+		*alias
+		PayloadType string          `json:"payload_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}{alias: (*alias)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalPayload(aux.PayloadType, aux.Payload)
+	if err != nil {
+		return fmt.Errorf("partition: %w", err)
+	}
+	p.Payload = payload
+	return nil
+}
+
+func (p *Partition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type alias Partition
+	aux := struct {
+		*alias      `yaml:",inline"`
+		PayloadType string      `yaml:"payload_type"`
+		Payload     yamlPayload `yaml:"payload"`
+	}{alias: (*alias)(p)}
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalYAMLPayload(aux.PayloadType, aux.Payload, "partition")
+	if err != nil {
+		return err
+	}
+	p.Payload = payload
+	return nil
+}
+
+func (lc *LUKSContainer) MarshalJSON() ([]byte, error) {
+	type alias LUKSContainer
+	typ, err := payloadType(lc.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		*alias
+		PayloadType string `json:"payload_type,omitempty"`
+	}{(*alias)(lc), typ})
+}
+
+func (lc *LUKSContainer) UnmarshalJSON(data []byte) error {
+	type alias LUKSContainer
+	aux := struct {
+		*alias
+		PayloadType string          `json:"payload_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}{alias: (*alias)(lc)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalPayload(aux.PayloadType, aux.Payload)
+	if err != nil {
+		return fmt.Errorf("luks container: %w", err)
+	}
+	lc.Payload = payload
+	return nil
+}
+
+func (lc *LUKSContainer) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type alias LUKSContainer
+	aux := struct {
+		*alias      `yaml:",inline"`
+		PayloadType string      `yaml:"payload_type"`
+		Payload     yamlPayload `yaml:"payload"`
+	}{alias: (*alias)(lc)}
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalYAMLPayload(aux.PayloadType, aux.Payload, "luks container")
+	if err != nil {
+		return err
+	}
+	lc.Payload = payload
+	return nil
+}
+
+func (lv *LVMLogicalVolume) MarshalJSON() ([]byte, error) {
+	type alias LVMLogicalVolume
+	typ, err := payloadType(lv.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		*alias
+		PayloadType string `json:"payload_type,omitempty"`
+	}{(*alias)(lv), typ})
+}
+
+func (lv *LVMLogicalVolume) UnmarshalJSON(data []byte) error {
+	type alias LVMLogicalVolume
+	aux := struct {
+		*alias
+		PayloadType string          `json:"payload_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}{alias: (*alias)(lv)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalPayload(aux.PayloadType, aux.Payload)
+	if err != nil {
+		return fmt.Errorf("logical volume %q: %w", lv.Name, err)
+	}
+	lv.Payload = payload
+	return nil
+}
+
+func (lv *LVMLogicalVolume) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type alias LVMLogicalVolume
+	aux := struct {
+		*alias      `yaml:",inline"`
+		PayloadType string      `yaml:"payload_type"`
+		Payload     yamlPayload `yaml:"payload"`
+	}{alias: (*alias)(lv)}
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	payload, err := unmarshalYAMLPayload(aux.PayloadType, aux.Payload, fmt.Sprintf("logical volume %q", lv.Name))
+	if err != nil {
+		return err
+	}
+	lv.Payload = payload
+	return nil
+}
+
+// MarshalPartitionTable serializes the partition table into a versioned JSON
+// document. Since JSON is a subset of YAML, the result can also be read as
+// YAML.
+func MarshalPartitionTable(pt *PartitionTable) ([]byte, error) {
+	doc := partitionTableDocument{
+		Version:        FormatVersion,
+		PartitionTable: pt,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// MarshalPartitionTables serializes a map of partition tables, e.g. the base
+// partition tables of an image type keyed by architecture, into a versioned
+// JSON document.
+func MarshalPartitionTables(pts map[string]PartitionTable) ([]byte, error) {
+	doc := partitionTableDocument{
+		Version:         FormatVersion,
+		PartitionTables: pts,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// UnmarshalPartitionTable reads a partition table from a JSON or YAML
+// document created by MarshalPartitionTable.
+func UnmarshalPartitionTable(data []byte) (*PartitionTable, error) {
+	doc, err := unmarshalDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.PartitionTable == nil {
+		return nil, fmt.Errorf("document does not contain a partition_table")
+	}
+	if err := doc.PartitionTable.Validate(); err != nil {
+		return nil, err
+	}
+	return doc.PartitionTable, nil
+}
+
+// UnmarshalPartitionTables reads a map of partition tables from a JSON or YAML
+// document created by MarshalPartitionTables.
+func UnmarshalPartitionTables(data []byte) (map[string]PartitionTable, error) {
+	doc, err := unmarshalDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.PartitionTables == nil {
+		return nil, fmt.Errorf("document does not contain partition_tables")
+	}
+	for name, pt := range doc.PartitionTables {
+		if err := pt.Validate(); err != nil {
+			return nil, fmt.Errorf("partition table %q: %w", name, err)
+		}
+	}
+	return doc.PartitionTables, nil
+}
+
+// LoadPartitionTable reads a single partition table from the JSON or YAML
+// file at the given path.
+func LoadPartitionTable(path string) (*PartitionTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := UnmarshalPartitionTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partition table from %q: %w", path, err)
+	}
+	return pt, nil
+}
+
+// LoadPartitionTables reads a map of partition tables from the JSON or YAML
+// file at the given path. The result can be used as base partition tables for
+// image types.
+func LoadPartitionTables(path string) (map[string]PartitionTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pts, err := UnmarshalPartitionTables(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partition tables from %q: %w", path, err)
+	}
+	return pts, nil
+}
+
+func unmarshalDocument(data []byte) (*partitionTableDocument, error) {
+	// JSON is a subset of YAML, so both are read by the YAML decoder
+	var doc partitionTableDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported partition table format version %d (expected %d)", doc.Version, FormatVersion)
+	}
+	return &doc, nil
+}
+
+// Validate checks that a deserialized partition table can be used as the
+// base of a new partition table.
+func (pt *PartitionTable) Validate() error {
+	switch pt.Type {
+	case "gpt":
+		if pt.UUID != "" {
+			if _, err := uuid.Parse(pt.UUID); err != nil {
+				return fmt.Errorf("invalid partition table UUID %q: %w", pt.UUID, err)
+			}
+		}
+		for idx, part := range pt.Partitions {
+			if part.UUID == "" {
+				continue
+			}
+			if _, err := uuid.Parse(part.UUID); err != nil {
+				return fmt.Errorf("invalid UUID %q for partition %d: %w", part.UUID, idx, err)
+			}
+		}
+	case "dos":
+		if len(pt.Partitions) > 4 {
+			return fmt.Errorf("dos partition tables support at most 4 partitions, got %d", len(pt.Partitions))
+		}
+	default:
+		return fmt.Errorf("unsupported partition table type %q", pt.Type)
+	}
+
+	if !pt.ContainsMountpoint("/") {
+		return fmt.Errorf("no root (\"/\") mountpoint")
+	}
+	return nil
+}
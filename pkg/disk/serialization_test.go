@@ -0,0 +1,192 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPartitionTableRoundTrip(t *testing.T) {
+	for name := range testPartitionTables {
+		t.Run(name, func(t *testing.T) {
+			pt := testPartitionTables[name]
+			data, err := MarshalPartitionTable(&pt)
+			require.NoError(t, err)
+
+			newPT, err := UnmarshalPartitionTable(data)
+			require.NoError(t, err)
+			assert.Equal(t, &pt, newPT)
+		})
+	}
+
+	data, err := MarshalPartitionTables(testPartitionTables)
+	require.NoError(t, err)
+	pts, err := UnmarshalPartitionTables(data)
+	require.NoError(t, err)
+	assert.Equal(t, testPartitionTables, pts)
+}
+
+func TestPartitionTableYAML(t *testing.T) {
+	doc := `
+version: 1
+partition_table:
+  type: gpt
+  uuid: D209C89E-EA5E-4FBD-B161-B461CCE297E0
+  partitions:
+    - size: 1048576
+      bootable: true
+      type: 21686148-6449-6E6F-744E-656564454649
+    - size: 524288000
+      type: 0FC63DAF-8483-4772-8E79-3D69D8477DE4
+      payload_type: filesystem
+      payload:
+        type: xfs
+        mountpoint: /boot
+        fstab_options: defaults
+    - type: E6D6D379-F507-44C2-A23C-238F2A3DF928
+      payload_type: luks
+      payload:
+        passphrase: osbuild
+        pbkdf:
+          iterations: 4
+          memory: 32
+          parallelism: 1
+        payload_type: lvm
+        payload:
+          name: rootvg
+          logical_volumes:
+            - name: rootlv
+              size: 2147483648
+              payload_type: filesystem
+              payload:
+                type: xfs
+                mountpoint: /
+            - name: loglv
+              size: 1073741824
+              payload_type: filesystem
+              payload:
+                type: ext4
+                label: logs
+                mountpoint: /var/log
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pt.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(doc), 0600))
+
+	pt, err := LoadPartitionTable(path)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt", pt.Type)
+	require.Len(t, pt.Partitions, 3)
+	assert.Nil(t, pt.Partitions[0].Payload)
+	assert.Equal(t, "/boot", pt.FindMountable("/boot").GetMountpoint())
+
+	luks, ok := pt.Partitions[2].Payload.(*LUKSContainer)
+	require.True(t, ok)
+	assert.Equal(t, uint(32), luks.PBKDF.Memory)
+	vg, ok := luks.Payload.(*LVMVolumeGroup)
+	require.True(t, ok)
+	require.Len(t, vg.LogicalVolumes, 2)
+	assert.Equal(t, uint64(1073741824), vg.LogicalVolumes[1].Size)
+	assert.Equal(t, "ext4", pt.FindMountable("/var/log").GetFSType())
+}
+
+func TestPartitionTableUnmarshalYAMLScalars(t *testing.T) {
+	// unquoted scalars that YAML reads as numbers are decoded into string
+	// fields, also in payloads
+	doc := `
+version: 1
+partition_table:
+  type: dos
+  size: 4294967296
+  partitions:
+    - size: 4293918720
+      type: 83
+      bootable: true
+      payload_type: luks
+      payload:
+        passphrase: 1234
+        label: 2023
+        payload_type: filesystem
+        payload:
+          type: xfs
+          label: 42
+          mountpoint: /
+`
+	pt, err := UnmarshalPartitionTable([]byte(doc))
+	require.NoError(t, err)
+	assert.Equal(t, "dos", pt.Type)
+	require.Len(t, pt.Partitions, 1)
+	assert.Equal(t, "83", pt.Partitions[0].Type)
+	assert.True(t, pt.Partitions[0].Bootable)
+	luks := pt.Partitions[0].Payload.(*LUKSContainer)
+	assert.Equal(t, "1234", luks.Passphrase)
+	assert.Equal(t, "2023", luks.Label)
+	assert.Equal(t, "42", luks.Payload.(*Filesystem).Label)
+}
+
+func TestPartitionTableUnmarshalYAMLKnownFields(t *testing.T) {
+	// the payloads are decoded with the options of the decoder
+	doc := `
+type: gpt
+partitions:
+  - payload_type: luks
+    payload:
+      payload_type: filesystem
+      payload: {type: xfs, mountpiont: /}
+`
+	var pt PartitionTable
+	require.NoError(t, yaml.Unmarshal([]byte(doc), &pt))
+
+	dec := yaml.NewDecoder(strings.NewReader(doc))
+	dec.KnownFields(true)
+	err := dec.Decode(&pt)
+	assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 7: field mountpiont not found in type disk.Filesystem")
+}
+
+func TestPartitionTableUnmarshalErrors(t *testing.T) {
+	testCases := map[string]struct {
+		doc     string
+		wantErr string
+	}{
+		"no-version": {
+			doc:     `{"partition_table": {"type": "gpt", "partitions": []}}`,
+			wantErr: "unsupported partition table format version 0 (expected 1)",
+		},
+		"future-version": {
+			doc:     `{"version": 2, "partition_table": {"type": "gpt", "partitions": []}}`,
+			wantErr: "unsupported partition table format version 2 (expected 1)",
+		},
+		"no-table": {
+			doc:     `{"version": 1}`,
+			wantErr: "document does not contain a partition_table",
+		},
+		"bad-payload-type": {
+			doc:     `{"version": 1, "partition_table": {"type": "gpt", "partitions": [{"payload_type": "zfs", "payload": {}}]}}`,
+			wantErr: `partition: unknown payload_type "zfs"`,
+		},
+		"untyped-payload": {
+			doc:     `{"version": 1, "partition_table": {"type": "gpt", "partitions": [{"payload": {"type": "xfs"}}]}}`,
+			wantErr: "partition: payload without payload_type",
+		},
+		"bad-pt-type": {
+			doc:     `{"version": 1, "partition_table": {"type": "apm", "partitions": []}}`,
+			wantErr: `unsupported partition table type "apm"`,
+		},
+		"no-root": {
+			doc:     `{"version": 1, "partition_table": {"type": "gpt", "partitions": [{"payload_type": "filesystem", "payload": {"type": "xfs", "mountpoint": "/boot"}}]}}`,
+			wantErr: `no root ("/") mountpoint`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := UnmarshalPartitionTable([]byte(tc.doc))
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// AddAccounts adds the users and groups of the blueprint, with the root
+// account policy applied, and the files of the sudo rules to the OS
+// customizations. The users and groups are left out with noUsers, e.g. in the
+// payload of an installer that creates them via kickstart instead.
+func AddAccounts(osc *manifest.OSCustomizations, c *blueprint.Customizations, noUsers bool) error {
+	accounts, err := c.GetAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to get accounts customization: %w", err)
+	}
+
+	if !noUsers {
+		osc.Groups = users.GroupsFromBP(c.GetGroups())
+		osc.Users, err = users.UsersFromBP(c.GetUsers())
+		if err != nil {
+			return fmt.Errorf("failed to convert user customizations: %w", err)
+		}
+		if accounts != nil {
+			osc.Users = users.WithRootAccount(osc.Users, accounts.Root)
+		}
+	}
+
+	sudoFiles, err := blueprint.SudoRulesToFsNodeFiles(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to convert sudo rules to fs node files: %w", err)
+	}
+	if len(sudoFiles) > 0 {
+		osc.Files = append(osc.Files, sudoFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "sudo")
+	}
+
+	return nil
+}
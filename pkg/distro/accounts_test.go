@@ -0,0 +1,44 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+func TestAddAccounts(t *testing.T) {
+	c := &blueprint.Customizations{
+		User:  []blueprint.UserCustomization{{Name: "admin"}},
+		Group: []blueprint.GroupCustomization{{Name: "wheel"}},
+		Accounts: &blueprint.AccountsCustomization{
+			Root: &blueprint.RootAccountCustomization{Lock: true},
+			Sudo: []blueprint.SudoRuleCustomization{{Name: "admin", Users: []string{"admin"}, Commands: []string{"ALL"}}},
+		},
+	}
+
+	osc := manifest.OSCustomizations{}
+	require.NoError(t, AddAccounts(&osc, c, false))
+	require.Len(t, osc.Users, 2)
+	assert.Equal(t, "admin", osc.Users[0].Name)
+	assert.Equal(t, "root", osc.Users[1].Name)
+	assert.True(t, osc.Users[1].Locked)
+	require.Len(t, osc.Groups, 1)
+	require.Len(t, osc.Files, 1)
+	assert.Equal(t, "/etc/sudoers.d/admin", osc.Files[0].Path())
+	assert.Equal(t, []string{"sudo"}, osc.ExtraBasePackages)
+
+	// the sudo rules are added without the users
+	osc = manifest.OSCustomizations{}
+	require.NoError(t, AddAccounts(&osc, c, true))
+	assert.Empty(t, osc.Users)
+	assert.Empty(t, osc.Groups)
+	assert.Len(t, osc.Files, 1)
+
+	c.User[0].ExpireDate = common.ToPtr("tomorrow")
+	assert.EqualError(t, AddAccounts(&osc, c, false), `failed to get accounts customization: user "admin": expire date "tomorrow" is not in the YYYY-MM-DD format`)
+}
@@ -0,0 +1,29 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// addCloudInitFiles adds the configuration files of the cloud-init
+// customization to the OS customizations, together with cloud-init. The
+// disabled modules are removed from cloudInitModules, the module lists of the
+// image.
+func addCloudInitFiles(osc *manifest.OSCustomizations, c *blueprint.Customizations, cloudInitModules *blueprint.CloudInitModules) error {
+	cloudInit, err := c.GetCloudInit()
+	if err != nil {
+		return fmt.Errorf("failed to get cloud-init customization: %w", err)
+	}
+	cloudInitFiles, err := blueprint.CloudInitCustomizationToFsNodeFiles(cloudInit, cloudInitModules)
+	if err != nil {
+		return fmt.Errorf("failed to convert cloud-init customization to fs node files: %w", err)
+	}
+	osc.Files = append(osc.Files, cloudInitFiles...)
+	if cloudInit != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "cloud-init")
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+func TestAddCloudInitFiles(t *testing.T) {
+	c := &blueprint.Customizations{
+		CloudInit: &blueprint.CloudInitCustomization{
+			Datasources: []string{"OpenStack"},
+			Snippets:    []blueprint.CloudInitSnippetCustomization{{Filename: "50-network.cfg", Contents: "network: {config: disabled}"}},
+		},
+	}
+	osc := manifest.OSCustomizations{}
+	require.NoError(t, addCloudInitFiles(&osc, c, nil))
+	require.Len(t, osc.Files, 2)
+	assert.Equal(t, blueprint.CloudInitConfigFile, osc.Files[0].Path())
+	assert.Equal(t, "/etc/cloud/cloud.cfg.d/50-network.cfg", osc.Files[1].Path())
+	assert.Equal(t, []string{"cloud-init"}, osc.ExtraBasePackages)
+
+	// the disabled modules are removed from the module lists of the image
+	c.CloudInit.DisabledModules = []string{"growpart"}
+	modules := &blueprint.CloudInitModules{
+		Init:   []string{"growpart", "resizefs"},
+		Config: []string{"runcmd"},
+		Final:  []string{"final-message"},
+	}
+	osc = manifest.OSCustomizations{}
+	require.NoError(t, addCloudInitFiles(&osc, c, modules))
+	assert.Contains(t, string(osc.Files[0].Data()), "cloud_init_modules:\n    - resizefs\n")
+	assert.EqualError(t, addCloudInitFiles(&osc, c, nil), "failed to convert cloud-init customization to fs node files: cloud-init modules cannot be disabled, the module lists of the image are unknown")
+
+	c.CloudInit.Datasources = []string{"Open Stack"}
+	assert.EqualError(t, addCloudInitFiles(&osc, c, modules), `failed to get cloud-init customization: cloud-init datasource name "Open Stack" is invalid`)
+}
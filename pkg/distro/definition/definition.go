@@ -0,0 +1,179 @@
+// Package definition implements distributions whose image types are described
+// by data files instead of Go code. A definition is a versioned JSON or YAML
+// document that contains the distribution metadata, its default image
+// configuration and a list of image types with their package sets, image
+// configuration, platforms and partition tables.
+//
+// Definitions can only describe image types that are fully determined by
+// their data, i.e. disk images and tarballs of the OS tree. Image types that
+// need custom logic (installers, ostree commits, etc.) are still implemented
+// in Go in the respective distro packages.
+package definition
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/distro"
+)
+
+// FormatVersion is the version of the definition format. It must be increased
+// whenever the format changes in an incompatible way.
+const FormatVersion = 1
+
+// Image kinds that can be described by a definition
+const (
+	// Bootable disk image; the image format is defined by the platform
+	KindDisk = "disk"
+
+	// Tarball of the OS tree
+	KindArchive = "archive"
+)
+
+// Definition is the top level object of a distro definition document.
+type Definition struct {
+	Version    uint                  `json:"version" yaml:"version"`
+	Distro     DistroDefinition      `json:"distro" yaml:"distro"`
+	ImageTypes []ImageTypeDefinition `json:"image_types" yaml:"image_types"`
+}
+
+// DistroDefinition holds the distribution metadata and the defaults shared by
+// all image types.
+type DistroDefinition struct {
+	Name             string `json:"name" yaml:"name"`
+	Product          string `json:"product" yaml:"product"`
+	OSVersion        string `json:"os_version" yaml:"os_version"`
+	Releasever       string `json:"releasever" yaml:"releasever"`
+	ModulePlatformID string `json:"module_platform_id" yaml:"module_platform_id"`
+	Vendor           string `json:"vendor" yaml:"vendor"`
+
+	// Template for the default ostree ref; "%s" is replaced by the arch
+	OSTreeRef string `json:"ostree_ref,omitempty" yaml:"ostree_ref,omitempty"`
+
+	// Distribution family the manifests are generated for: el7, el8, el9
+	// or fedora
+	Family string `json:"family" yaml:"family"`
+
+	Runner RunnerDefinition `json:"runner" yaml:"runner"`
+
+	DefaultImageConfig *distro.ImageConfig `json:"default_image_config,omitempty" yaml:"default_image_config,omitempty"`
+
+	// Package sets added to the package sets of the same name of all image
+	// types, e.g. packages every "os" package set needs
+	PackageSets map[string]PackageSetDefinition `json:"package_sets,omitempty" yaml:"package_sets,omitempty"`
+
+	// Architecture specific package sets added to the package sets of the
+	// same name of all image types of the architecture
+	ArchPackageSets map[string]map[string]PackageSetDefinition `json:"arch_package_sets,omitempty" yaml:"arch_package_sets,omitempty"`
+}
+
+// RunnerDefinition selects the osbuild runner used for the build pipeline.
+type RunnerDefinition struct {
+	// One of rhel, centos, fedora or linux
+	Name  string `json:"name" yaml:"name"`
+	Major uint64 `json:"major,omitempty" yaml:"major,omitempty"`
+	Minor uint64 `json:"minor,omitempty" yaml:"minor,omitempty"`
+}
+
+type PackageSetDefinition struct {
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// ImageTypeDefinition describes an image type and the architectures it is
+// available for.
+type ImageTypeDefinition struct {
+	Name          string   `json:"name" yaml:"name"`
+	Aliases       []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Filename      string   `json:"filename" yaml:"filename"`
+	MIMEType      string   `json:"mime_type" yaml:"mime_type"`
+	Kind          string   `json:"kind" yaml:"kind"`
+	Bootable      bool     `json:"bootable,omitempty" yaml:"bootable,omitempty"`
+	DefaultSize   uint64   `json:"default_size,omitempty" yaml:"default_size,omitempty"`
+	KernelOptions string   `json:"kernel_options,omitempty" yaml:"kernel_options,omitempty"`
+	// One of xz, zstd or gzip; the MIME type defaults to the one of the
+	// compressed files and the filename must have the matching extension
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// Compression level of zstd and gzip and number of threads of zstd, the
+	// defaults of the compression are used if not set
+	CompressionLevel   int `json:"compression_level,omitempty" yaml:"compression_level,omitempty"`
+	CompressionThreads int `json:"compression_threads,omitempty" yaml:"compression_threads,omitempty"`
+
+	// Pipeline names; they are derived from the kind, the image format and
+	// the compression if not set
+	BuildPipelines   []string `json:"build_pipelines,omitempty" yaml:"build_pipelines,omitempty"`
+	PayloadPipelines []string `json:"payload_pipelines,omitempty" yaml:"payload_pipelines,omitempty"`
+	Exports          []string `json:"exports,omitempty" yaml:"exports,omitempty"`
+
+	ImageConfig *distro.ImageConfig             `json:"image_config,omitempty" yaml:"image_config,omitempty"`
+	PackageSets map[string]PackageSetDefinition `json:"package_sets,omitempty" yaml:"package_sets,omitempty"`
+
+	Arches map[string]ImageTypeArchDefinition `json:"arches" yaml:"arches"`
+}
+
+// ImageTypeArchDefinition holds the architecture specific parts of an image
+// type.
+type ImageTypeArchDefinition struct {
+	Platform       PlatformDefinition              `json:"platform" yaml:"platform"`
+	PartitionTable *disk.PartitionTable            `json:"partition_table,omitempty" yaml:"partition_table,omitempty"`
+	PackageSets    map[string]PackageSetDefinition `json:"package_sets,omitempty" yaml:"package_sets,omitempty"`
+}
+
+// PlatformDefinition describes the boot and firmware setup of an image type.
+// The boot mode of the image type is derived from it.
+type PlatformDefinition struct {
+	// One of raw, qcow2, vmdk, vhd, vhdx, gce or ova; only used by disk images
+	ImageFormat      string   `json:"image_format,omitempty" yaml:"image_format,omitempty"`
+	QCOW2Compat      string   `json:"qcow2_compat,omitempty" yaml:"qcow2_compat,omitempty"`
+	FirmwarePackages []string `json:"firmware_packages,omitempty" yaml:"firmware_packages,omitempty"`
+
+	// Legacy BIOS boot (x86_64) or Open Firmware boot (ppc64le)
+	BIOS bool `json:"bios,omitempty" yaml:"bios,omitempty"`
+
+	// UEFI boot using the shim and grub of the distro vendor (x86_64 and
+	// aarch64)
+	UEFI bool `json:"uefi,omitempty" yaml:"uefi,omitempty"`
+
+	// zipl boot (s390x)
+	Zipl bool `json:"zipl,omitempty" yaml:"zipl,omitempty"`
+}
+
+// Parse reads a definition from a JSON or YAML document.
+func Parse(data []byte) (*Definition, error) {
+	// JSON is a subset of YAML, so both are read by the YAML decoder.
+	// Unknown fields are rejected at every level, including inside the
+	// image configurations and partition tables.
+	var def Definition
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if def.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported definition format version %d (expected %d)", def.Version, FormatVersion)
+	}
+	return &def, nil
+}
+
+// Load reads the definition at the given path and creates the distribution
+// it describes.
+func Load(path string) (distro.Distro, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	def, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load distro definition from %q: %w", path, err)
+	}
+	d, err := New(def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid distro definition %q: %w", path, err)
+	}
+	return d, nil
+}
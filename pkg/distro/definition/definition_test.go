@@ -0,0 +1,473 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestLoadExample(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "example-9.3", d.Name())
+	assert.Equal(t, "9", d.Releasever())
+	assert.Equal(t, "platform:el9", d.ModulePlatformID())
+	assert.Equal(t, []string{"aarch64", "x86_64"}, d.ListArches())
+
+	x86, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qcow2", "tar"}, x86.ListImageTypes())
+
+	qcow2, err := x86.GetImageType("guest-image")
+	require.NoError(t, err)
+	assert.Equal(t, "qcow2", qcow2.Name())
+	assert.Equal(t, "disk.qcow2", qcow2.Filename())
+	assert.Equal(t, "gpt", qcow2.PartitionType())
+	assert.Equal(t, distro.BOOT_HYBRID, qcow2.BootMode())
+	assert.Equal(t, uint64(10737418240), qcow2.Size(0))
+	assert.Equal(t, []string{"build"}, qcow2.BuildPipelines())
+	assert.Equal(t, []string{"os", "image", "qcow2"}, qcow2.PayloadPipelines())
+	assert.Equal(t, []string{"qcow2"}, qcow2.Exports())
+
+	tar, err := x86.GetImageType("tar")
+	require.NoError(t, err)
+	assert.Equal(t, "", tar.PartitionType())
+	assert.Equal(t, distro.BOOT_NONE, tar.BootMode())
+	assert.Equal(t, []string{"os", "archive"}, tar.PayloadPipelines())
+	assert.Equal(t, []string{"archive"}, tar.Exports())
+
+	aarch64, err := d.GetArch("aarch64")
+	require.NoError(t, err)
+	qcow2, err = aarch64.GetImageType("qcow2")
+	require.NoError(t, err)
+	assert.Equal(t, distro.BOOT_UEFI, qcow2.BootMode())
+}
+
+func TestExampleManifests(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Packages: []blueprint.Package{{Name: "tmux"}},
+		Customizations: &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/var/log", MinSize: 1024 * 1024 * 1024},
+			},
+		},
+	}
+
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, itName := range arch.ListImageTypes() {
+			t.Run(archName+"/"+itName, func(t *testing.T) {
+				it, err := arch.GetImageType(itName)
+				require.NoError(t, err)
+
+				itBP := bp
+				if it.PartitionType() == "" {
+					itBP = &blueprint.Blueprint{Packages: bp.Packages}
+				}
+				mf, _, err := it.Manifest(itBP, distro.ImageOptions{}, nil, 0)
+				require.NoError(t, err)
+				assert.Equal(t, it.Exports(), mf.GetExports())
+
+				chains := mf.GetPackageSetChains()
+				require.Contains(t, chains, "build")
+				require.Contains(t, chains, "os")
+				assert.Contains(t, chains["os"][0].Include, "dnf")
+				if archName == "x86_64" {
+					assert.Contains(t, chains["os"][0].Include, "microcode_ctl")
+				} else {
+					assert.NotContains(t, chains["os"][0].Include, "microcode_ctl")
+				}
+				assert.Contains(t, chains["os"][0].Exclude, "rng-tools")
+				assert.Contains(t, chains["os"][len(chains["os"])-1].Include, "tmux")
+			})
+		}
+	}
+}
+
+func TestImageConfigInheritance(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	it, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	ic := it.(*imageType).getDefaultImageConfig()
+	assert.Equal(t, "UTC", *ic.Timezone)
+	assert.Equal(t, "multi-user.target", *ic.DefaultTarget)
+	assert.Equal(t, []string{"sshd"}, ic.EnabledServices)
+	require.Len(t, ic.Sysconfig, 1)
+	assert.Equal(t, "kernel", ic.Sysconfig[0].Kernel.DefaultKernel)
+
+	osc := osCustomizations(it.(*imageType), rpmmd.PackageSet{}, distro.ImageOptions{}, nil, &blueprint.Customizations{})
+	assert.Equal(t, "C.UTF-8", osc.Language)
+	assert.Equal(t, "targeted", osc.SElinux)
+	assert.Equal(t, []string{"console=tty0 console=ttyS0,115200n8 no_timer_check"}, osc.KernelOptionsAppend)
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	const distroHeader = `
+version: 1
+distro:
+  name: test-1
+  releasever: "1"
+  vendor: test
+  family: fedora
+  runner: {name: fedora, major: 39}
+`
+
+	testCases := map[string]struct {
+		doc     string
+		wantErr string
+	}{
+		"bad-version": {
+			doc:     `{"version": 2}`,
+			wantErr: "unsupported definition format version 2 (expected 1)",
+		},
+		"unknown-field": {
+			doc:     distroHeader + "image_typs: []",
+			wantErr: "yaml: unmarshal errors:\n  line 9: field image_typs not found in type definition.Definition",
+		},
+		"no-image-types": {
+			doc:     distroHeader,
+			wantErr: `distro "test-1": no image types defined`,
+		},
+		"bad-family": {
+			doc:     `{"version": 1, "distro": {"name": "test", "releasever": "1", "family": "debian"}}`,
+			wantErr: `distro "test": unsupported distro family "debian"`,
+		},
+		"bad-runner": {
+			doc:     `{"version": 1, "distro": {"name": "test", "releasever": "1", "family": "el9", "runner": {"name": "debian"}}}`,
+			wantErr: `distro "test": unsupported runner "debian"`,
+		},
+		"bad-kind": {
+			doc: distroHeader + `
+image_types:
+  - {name: iso, filename: x.iso, kind: installer, arches: {x86_64: {platform: {}}}}
+`,
+			wantErr: `distro "test-1": image type "iso": unsupported kind "installer"`,
+		},
+		"bad-arch": {
+			doc: distroHeader + `
+image_types:
+  - {name: tar, filename: root.tar, kind: archive, arches: {riscv64: {platform: {}}}}
+`,
+			wantErr: `distro "test-1": image type "tar": unsupported architecture "riscv64"`,
+		},
+		"disk-without-pt": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw, kind: disk, arches: {x86_64: {platform: {image_format: raw, bios: true}}}}
+`,
+			wantErr: `distro "test-1": image type "raw" on x86_64: disk images require a partition table`,
+		},
+		"disk-without-format": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw, kind: disk, arches: {x86_64: {platform: {bios: true}}}}
+`,
+			wantErr: `distro "test-1": image type "raw" on x86_64: disk images require an image format`,
+		},
+		"bios-on-aarch64": {
+			doc: distroHeader + `
+image_types:
+  - {name: tar, filename: root.tar, kind: archive, arches: {aarch64: {platform: {bios: true}}}}
+`,
+			wantErr: `distro "test-1": image type "tar" on aarch64: only uefi boot is supported on aarch64`,
+		},
+		"bad-format": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw, kind: disk, arches: {x86_64: {platform: {image_format: vdi}}}}
+`,
+			wantErr: `distro "test-1": image type "raw" on x86_64: unsupported image format "vdi"`,
+		},
+		"bad-compression-extension": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw.xz, kind: disk, compression: zstd, arches: {x86_64: {platform: {image_format: raw}}}}
+`,
+			wantErr: `distro "test-1": image type "raw": filename "disk.raw.xz" of zstd compressed images must end with ".zst"`,
+		},
+		"bad-compression-mime-type": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw.gz, mime_type: application/xz, kind: disk, compression: gzip, arches: {x86_64: {platform: {image_format: raw}}}}
+`,
+			wantErr: `distro "test-1": image type "raw": MIME type "application/xz" of gzip compressed images must be "application/gzip"`,
+		},
+		"bad-compression-level": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw.xz, kind: disk, compression: xz, compression_level: 9, arches: {x86_64: {platform: {image_format: raw}}}}
+`,
+			wantErr: `distro "test-1": image type "raw": xz compression does not support setting the level or threads`,
+		},
+		"compression-level-without-compression": {
+			doc: distroHeader + `
+image_types:
+  - {name: raw, filename: disk.raw, kind: disk, compression_threads: 4, arches: {x86_64: {platform: {image_format: raw}}}}
+`,
+			wantErr: `distro "test-1": image type "raw": compression level and threads require a compression`,
+		},
+		"unknown-nested-field": {
+			doc: distroHeader + `
+image_types:
+  - {name: tar, filename: root.tar, kind: archive, image_config: {timezon: UTC}, arches: {x86_64: {platform: {}}}}
+`,
+			wantErr: `line 11: image config: json: unknown field "timezon"`,
+		},
+		"unknown-partition-field": {
+			doc: distroHeader + `
+image_types:
+  - name: raw
+    filename: disk.raw
+    kind: disk
+    arches:
+      x86_64:
+        platform: {image_format: raw, bios: true}
+        partition_table:
+          type: gpt
+          partitions:
+            - size: 1073741824
+              payload_type: filesystem
+              payload: {type: xfs, mountpiont: /}
+`,
+			wantErr: "yaml: unmarshal errors:\n  line 22: field mountpiont not found in type disk.Filesystem",
+		},
+		"payload-pipelines-mismatch": {
+			doc: distroHeader + `
+image_types:
+  - {name: tar, filename: root.tar, kind: archive, payload_pipelines: [os, tar], arches: {x86_64: {platform: {}}}}
+`,
+			wantErr: `distro "test-1": image type "tar" on x86_64: payload pipelines [os tar] do not match the pipelines of the image [os archive]`,
+		},
+		"unknown-export": {
+			doc: distroHeader + `
+image_types:
+  - {name: tar, filename: root.tar, kind: archive, exports: [tar], arches: {x86_64: {platform: {}}}}
+`,
+			wantErr: `distro "test-1": image type "tar" on x86_64: export "tar" is not one of the payload pipelines [os archive]`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def, err := Parse([]byte(tc.doc))
+			if err == nil {
+				_, err = New(def)
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	tar, err := arch.GetImageType("tar")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/var/log", MinSize: 1024},
+			},
+		},
+	}
+	_, _, err = tar.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "Custom mountpoints and partitioning are not supported for tar on example-9.3")
+
+	bp = &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			OpenSCAP: &blueprint.OpenSCAPCustomization{ProfileID: "cis"},
+		},
+	}
+	_, _, err = tar.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "OpenSCAP customizations are not supported for tar on example-9.3")
+}
+
+func TestVHDXImageType(t *testing.T) {
+	def, err := Parse([]byte(`
+version: 1
+distro:
+  name: test-1
+  releasever: "1"
+  vendor: test
+  family: fedora
+  runner: {name: fedora, major: 39}
+image_types:
+  - name: vhdx
+    filename: disk.vhdx
+    mime_type: application/x-vhdx
+    kind: disk
+    arches:
+      x86_64:
+        platform: {image_format: vhdx, bios: true}
+        partition_table:
+          type: gpt
+          partitions:
+            - size: 1073741824
+              payload_type: filesystem
+              payload: {type: xfs, mountpoint: /}
+`))
+	require.NoError(t, err)
+	d, err := New(def)
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	vhdx, err := arch.GetImageType("vhdx")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"os", "image", "vhdx"}, vhdx.PayloadPipelines())
+	assert.Equal(t, uint64(1074790400), vhdx.Size(1073741825))
+	_, _, err = vhdx.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	options := distro.ImageOptions{VHD: &distro.VHDOptions{Subformat: "fixed"}}
+	_, _, err = vhdx.Manifest(&blueprint.Blueprint{}, options, nil, 0)
+	assert.EqualError(t, err, "VHD options are not supported for vhdx on test-1")
+}
+
+func TestZstdImageType(t *testing.T) {
+	def, err := Parse([]byte(`
+version: 1
+distro:
+  name: test-1
+  releasever: "1"
+  vendor: test
+  family: fedora
+  runner: {name: fedora, major: 39}
+image_types:
+  - name: raw
+    filename: disk.raw.zst
+    kind: disk
+    compression: zstd
+    compression_level: 12
+    compression_threads: 4
+    arches:
+      x86_64:
+        platform: {image_format: raw, bios: true}
+        partition_table:
+          type: gpt
+          partitions:
+            - size: 1073741824
+              payload_type: filesystem
+              payload: {type: xfs, mountpoint: /}
+`))
+	require.NoError(t, err)
+	d, err := New(def)
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	raw, err := arch.GetImageType("raw")
+	require.NoError(t, err)
+
+	assert.Equal(t, "disk.raw.zst", raw.Filename())
+	assert.Equal(t, "application/zstd", raw.MIMEType())
+	assert.Equal(t, []string{"os", "image", "zstd"}, raw.PayloadPipelines())
+	assert.Equal(t, []string{"zstd"}, raw.Exports())
+
+	m, _, err := raw.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+	assert.Contains(t, m.GetPackageSetChains()["build"][0].Include, "zstd")
+	assert.Contains(t, m.GetExports(), "zstd")
+}
+
+func TestNetworkCustomization(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Network: &blueprint.NetworkCustomization{
+				Connections: []blueprint.NetworkConnectionCustomization{
+					{Name: "eth0", Interface: "eth0", IPv4: &blueprint.NetworkIPCustomization{Addresses: []string{"192.168.1.10/24"}}},
+				},
+			},
+		},
+	}
+	mf, _, err := qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+	assert.Contains(t, mf.GetPackageSetChains()["os"][0].Include, "NetworkManager")
+
+	bp.Customizations.Network.Connections[0].IPv4.Addresses = []string{"192.168.1.10"}
+	_, _, err = qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `network connection "eth0": ipv4: invalid address "192.168.1.10"`)
+}
+
+func TestKernelTuningCustomizations(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Sysctl: []blueprint.SysctlCustomization{{Key: "net.ipv4.ip_forward", Value: "1"}},
+			KernelModules: &blueprint.KernelModulesCustomization{
+				Blacklist: []string{"nouveau"},
+				Options:   []blueprint.KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+			},
+			Dracut: &blueprint.DracutCustomization{AddModules: []string{"fips"}},
+			Tuned:  &blueprint.TunedCustomization{Profiles: []string{"throughput-performance"}},
+		},
+	}
+	mf, _, err := qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+	assert.Contains(t, mf.GetPackageSetChains()["os"][0].Include, "tuned")
+
+	bp.Customizations.Tuned.Profiles = []string{}
+	_, _, err = qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "at least one tuned profile is required")
+}
+
+func TestSystemdCustomization(t *testing.T) {
+	d, err := Load("testdata/example.yaml")
+	require.NoError(t, err)
+	arch, err := d.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Systemd: &blueprint.SystemdCustomization{
+				DefaultTarget: "multi-user.target",
+				Units: []blueprint.SystemdUnitCustomization{
+					{
+						Name: "hello.service",
+						Config: blueprint.SystemdUnitConfigCustomization{
+							Service: &blueprint.SystemdServiceSectionCustomization{ExecStart: []string{"/usr/bin/echo hello"}},
+							Install: &blueprint.SystemdInstallSectionCustomization{WantedBy: []string{"multi-user.target"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	_, _, err = qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+
+	bp.Customizations.Systemd.DefaultTarget = "multi-user"
+	_, _, err = qcow2.Manifest(bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `default target "multi-user" is not a valid target unit name`)
+}
@@ -0,0 +1,453 @@
+package definition
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+)
+
+type distribution struct {
+	name               string
+	product            string
+	osVersion          string
+	releaseVersion     string
+	modulePlatformID   string
+	vendor             string
+	ostreeRefTmpl      string
+	family             manifest.Distro
+	runner             runner.Runner
+	arches             map[string]distro.Arch
+	defaultImageConfig *distro.ImageConfig
+
+	packageSets     map[string]PackageSetDefinition
+	archPackageSets map[string]map[string]PackageSetDefinition
+}
+
+func (d *distribution) Name() string {
+	return d.name
+}
+
+func (d *distribution) Releasever() string {
+	return d.releaseVersion
+}
+
+func (d *distribution) ModulePlatformID() string {
+	return d.modulePlatformID
+}
+
+func (d *distribution) OSTreeRef() string {
+	return d.ostreeRefTmpl
+}
+
+func (d *distribution) ListArches() []string {
+	archNames := make([]string, 0, len(d.arches))
+	for name := range d.arches {
+		archNames = append(archNames, name)
+	}
+	sort.Strings(archNames)
+	return archNames
+}
+
+func (d *distribution) GetArch(name string) (distro.Arch, error) {
+	arch, exists := d.arches[name]
+	if !exists {
+		return nil, errors.New("invalid architecture: " + name)
+	}
+	return arch, nil
+}
+
+func (d *distribution) getDefaultImageConfig() *distro.ImageConfig {
+	return d.defaultImageConfig
+}
+
+type architecture struct {
+	distro           *distribution
+	name             string
+	imageTypes       map[string]distro.ImageType
+	imageTypeAliases map[string]string
+}
+
+func (a *architecture) Name() string {
+	return a.name
+}
+
+func (a *architecture) ListImageTypes() []string {
+	itNames := make([]string, 0, len(a.imageTypes))
+	for name := range a.imageTypes {
+		itNames = append(itNames, name)
+	}
+	sort.Strings(itNames)
+	return itNames
+}
+
+func (a *architecture) GetImageType(name string) (distro.ImageType, error) {
+	t, exists := a.imageTypes[name]
+	if !exists {
+		aliasForName, exists := a.imageTypeAliases[name]
+		if !exists {
+			return nil, errors.New("invalid image type: " + name)
+		}
+		t = a.imageTypes[aliasForName]
+	}
+	return t, nil
+}
+
+func (a *architecture) Distro() distro.Distro {
+	return a.distro
+}
+
+// New creates a distribution from the given definition.
+func New(def *Definition) (distro.Distro, error) {
+	dd := &def.Distro
+	if dd.Name == "" {
+		return nil, fmt.Errorf("distro name is required")
+	}
+	if dd.Releasever == "" {
+		return nil, fmt.Errorf("distro %q: releasever is required", dd.Name)
+	}
+
+	family, err := parseFamily(dd.Family)
+	if err != nil {
+		return nil, fmt.Errorf("distro %q: %w", dd.Name, err)
+	}
+	r, err := newRunner(dd.Runner)
+	if err != nil {
+		return nil, fmt.Errorf("distro %q: %w", dd.Name, err)
+	}
+
+	d := &distribution{
+		name:               dd.Name,
+		product:            dd.Product,
+		osVersion:          dd.OSVersion,
+		releaseVersion:     dd.Releasever,
+		modulePlatformID:   dd.ModulePlatformID,
+		vendor:             dd.Vendor,
+		ostreeRefTmpl:      dd.OSTreeRef,
+		family:             family,
+		runner:             r,
+		arches:             map[string]distro.Arch{},
+		defaultImageConfig: dd.DefaultImageConfig,
+		packageSets:        dd.PackageSets,
+		archPackageSets:    dd.ArchPackageSets,
+	}
+
+	if len(def.ImageTypes) == 0 {
+		return nil, fmt.Errorf("distro %q: no image types defined", dd.Name)
+	}
+	for idx := range def.ImageTypes {
+		if err := d.addImageType(&def.ImageTypes[idx]); err != nil {
+			return nil, fmt.Errorf("distro %q: %w", dd.Name, err)
+		}
+	}
+
+	return d, nil
+}
+
+func (d *distribution) addImageType(itd *ImageTypeDefinition) error {
+	if itd.Name == "" {
+		return fmt.Errorf("image type name is required")
+	}
+	if itd.Filename == "" {
+		return fmt.Errorf("image type %q: filename is required", itd.Name)
+	}
+	switch itd.Kind {
+	case KindDisk, KindArchive:
+	default:
+		return fmt.Errorf("image type %q: unsupported kind %q", itd.Name, itd.Kind)
+	}
+	switch itd.Compression {
+	case "":
+		if itd.CompressionLevel != 0 || itd.CompressionThreads != 0 {
+			return fmt.Errorf("image type %q: compression level and threads require a compression", itd.Name)
+		}
+	case manifest.CompressionXZ, manifest.CompressionZstd, manifest.CompressionGzip:
+		if err := manifest.ValidateCompression(itd.Compression, itd.CompressionLevel, itd.CompressionThreads); err != nil {
+			return fmt.Errorf("image type %q: %w", itd.Name, err)
+		}
+		if ext := manifest.CompressionExtension(itd.Compression); !strings.HasSuffix(itd.Filename, ext) {
+			return fmt.Errorf("image type %q: filename %q of %s compressed images must end with %q", itd.Name, itd.Filename, itd.Compression, ext)
+		}
+		if mimeType := manifest.CompressionMIMEType(itd.Compression); itd.MIMEType != "" && itd.MIMEType != mimeType {
+			return fmt.Errorf("image type %q: MIME type %q of %s compressed images must be %q", itd.Name, itd.MIMEType, itd.Compression, mimeType)
+		}
+	default:
+		return fmt.Errorf("image type %q: unsupported compression %q", itd.Name, itd.Compression)
+	}
+	if len(itd.Arches) == 0 {
+		return fmt.Errorf("image type %q: no architectures defined", itd.Name)
+	}
+
+	for archName, itad := range itd.Arches {
+		arch, err := d.getOrAddArch(archName)
+		if err != nil {
+			return fmt.Errorf("image type %q: %w", itd.Name, err)
+		}
+		if _, exists := arch.imageTypes[itd.Name]; exists {
+			return fmt.Errorf("image type %q defined twice for %s", itd.Name, archName)
+		}
+
+		t, err := newImageType(arch, itd, itad)
+		if err != nil {
+			return fmt.Errorf("image type %q on %s: %w", itd.Name, archName, err)
+		}
+		arch.imageTypes[t.name] = t
+
+		for _, alias := range itd.Aliases {
+			if existingAliasFor, exists := arch.imageTypeAliases[alias]; exists {
+				return fmt.Errorf("image type alias %q for %q is already defined for another image type %q", alias, t.name, existingAliasFor)
+			}
+			arch.imageTypeAliases[alias] = t.name
+		}
+	}
+	return nil
+}
+
+func (d *distribution) getOrAddArch(name string) (*architecture, error) {
+	if a, exists := d.arches[name]; exists {
+		return a.(*architecture), nil
+	}
+	switch name {
+	case platform.ARCH_X86_64.String(), platform.ARCH_AARCH64.String(), platform.ARCH_PPC64LE.String(), platform.ARCH_S390X.String():
+	default:
+		return nil, fmt.Errorf("unsupported architecture %q", name)
+	}
+
+	a := &architecture{
+		distro:           d,
+		name:             name,
+		imageTypes:       map[string]distro.ImageType{},
+		imageTypeAliases: map[string]string{},
+	}
+	d.arches[name] = a
+	return a, nil
+}
+
+func newImageType(arch *architecture, itd *ImageTypeDefinition, itad ImageTypeArchDefinition) (*imageType, error) {
+	d := arch.distro
+	p, err := newPlatform(arch.name, d.vendor, itad.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &imageType{
+		arch:               arch,
+		platform:           p,
+		name:               itd.Name,
+		nameAliases:        itd.Aliases,
+		filename:           itd.Filename,
+		mimeType:           itd.MIMEType,
+		kind:               itd.Kind,
+		compression:        itd.Compression,
+		compressionLevel:   itd.CompressionLevel,
+		compressionThreads: itd.CompressionThreads,
+		defaultImageConfig: itd.ImageConfig,
+		kernelOptions:      itd.KernelOptions,
+		defaultSize:        itd.DefaultSize,
+		bootable:           itd.Bootable,
+		buildPipelines:     itd.BuildPipelines,
+		payloadPipelines:   itd.PayloadPipelines,
+		exports:            itd.Exports,
+		packageSets:        map[string]rpmmd.PackageSet{},
+	}
+	if t.mimeType == "" && t.compression != "" {
+		t.mimeType = manifest.CompressionMIMEType(t.compression)
+	}
+
+	switch t.kind {
+	case KindDisk:
+		if p.GetImageFormat() == platform.FORMAT_UNSET {
+			return nil, fmt.Errorf("disk images require an image format")
+		}
+		if itad.PartitionTable == nil {
+			return nil, fmt.Errorf("disk images require a partition table")
+		}
+		if err := itad.PartitionTable.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid partition table: %w", err)
+		}
+		t.basePartitionTable = itad.PartitionTable
+	case KindArchive:
+		if itad.PartitionTable != nil {
+			return nil, fmt.Errorf("partition tables are only supported for disk images")
+		}
+		if t.bootable {
+			return nil, fmt.Errorf("archives cannot be bootable")
+		}
+	}
+
+	// the package sets of the image type are the combination of the
+	// package sets of the same name of the distro and the image type,
+	// each for all and for the specific architecture
+	for _, sets := range []map[string]PackageSetDefinition{
+		d.packageSets,
+		d.archPackageSets[arch.name],
+		itd.PackageSets,
+		itad.PackageSets,
+	} {
+		for name, ps := range sets {
+			t.packageSets[name] = t.packageSets[name].Append(rpmmd.PackageSet{
+				Include: ps.Include,
+				Exclude: ps.Exclude,
+			})
+		}
+	}
+
+	// the pipelines are created by the image kinds in pkg/image, so the
+	// names given in the definition have to match the ones they create
+	buildPipelines := []string{"build"}
+	if len(t.buildPipelines) == 0 {
+		t.buildPipelines = buildPipelines
+	} else if !slices.Equal(t.buildPipelines, buildPipelines) {
+		return nil, fmt.Errorf("build pipelines %v do not match the pipelines of the image %v", t.buildPipelines, buildPipelines)
+	}
+	payloadPipelines := defaultPayloadPipelines(t.kind, p.GetImageFormat(), t.compression)
+	if len(t.payloadPipelines) == 0 {
+		t.payloadPipelines = payloadPipelines
+	} else if !slices.Equal(t.payloadPipelines, payloadPipelines) {
+		return nil, fmt.Errorf("payload pipelines %v do not match the pipelines of the image %v", t.payloadPipelines, payloadPipelines)
+	}
+	if len(t.exports) == 0 {
+		t.exports = t.payloadPipelines[len(t.payloadPipelines)-1:]
+	}
+	for _, export := range t.exports {
+		if !slices.Contains(t.payloadPipelines, export) {
+			return nil, fmt.Errorf("export %q is not one of the payload pipelines %v", export, t.payloadPipelines)
+		}
+	}
+
+	return t, nil
+}
+
+// defaultPayloadPipelines returns the names of the pipelines created by the
+// image kinds in pkg/image for the given image format and compression.
+func defaultPayloadPipelines(kind string, format platform.ImageFormat, compression string) []string {
+	if kind == KindArchive {
+		return []string{"os", "archive"}
+	}
+
+	pipelines := []string{"os", "image"}
+	switch format {
+	case platform.FORMAT_QCOW2:
+		pipelines = append(pipelines, "qcow2")
+	case platform.FORMAT_VHD:
+		pipelines = append(pipelines, "vpc")
+	case platform.FORMAT_VHDX:
+		pipelines = append(pipelines, "vhdx")
+	case platform.FORMAT_VMDK:
+		pipelines = append(pipelines, "vmdk")
+	case platform.FORMAT_OVA:
+		pipelines = append(pipelines, "vmdk", "ovf", "archive")
+	case platform.FORMAT_GCE:
+		pipelines = append(pipelines, "archive")
+	}
+	if compression != "" {
+		pipelines = append(pipelines, compression)
+	}
+	return pipelines
+}
+
+func parseFamily(family string) (manifest.Distro, error) {
+	switch family {
+	case "el7":
+		return manifest.DISTRO_EL7, nil
+	case "el8":
+		return manifest.DISTRO_EL8, nil
+	case "el9":
+		return manifest.DISTRO_EL9, nil
+	case "fedora":
+		return manifest.DISTRO_FEDORA, nil
+	default:
+		return manifest.DISTRO_NULL, fmt.Errorf("unsupported distro family %q", family)
+	}
+}
+
+func newRunner(rd RunnerDefinition) (runner.Runner, error) {
+	switch rd.Name {
+	case "rhel":
+		return &runner.RHEL{Major: rd.Major, Minor: rd.Minor}, nil
+	case "centos":
+		return &runner.CentOS{Version: rd.Major}, nil
+	case "fedora":
+		return &runner.Fedora{Version: rd.Major}, nil
+	case "linux":
+		return &runner.Linux{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported runner %q", rd.Name)
+	}
+}
+
+func parseImageFormat(format string) (platform.ImageFormat, error) {
+	switch format {
+	case "":
+		return platform.FORMAT_UNSET, nil
+	case "raw":
+		return platform.FORMAT_RAW, nil
+	case "qcow2":
+		return platform.FORMAT_QCOW2, nil
+	case "vmdk":
+		return platform.FORMAT_VMDK, nil
+	case "vhd":
+		return platform.FORMAT_VHD, nil
+	case "vhdx":
+		return platform.FORMAT_VHDX, nil
+	case "gce":
+		return platform.FORMAT_GCE, nil
+	case "ova":
+		return platform.FORMAT_OVA, nil
+	default:
+		return platform.FORMAT_UNSET, fmt.Errorf("unsupported image format %q", format)
+	}
+}
+
+func newPlatform(arch, vendor string, pd PlatformDefinition) (platform.Platform, error) {
+	format, err := parseImageFormat(pd.ImageFormat)
+	if err != nil {
+		return nil, err
+	}
+	base := platform.BasePlatform{
+		ImageFormat:      format,
+		QCOW2Compat:      pd.QCOW2Compat,
+		FirmwarePackages: pd.FirmwarePackages,
+	}
+
+	uefiVendor := ""
+	if pd.UEFI {
+		if vendor == "" {
+			return nil, fmt.Errorf("uefi boot requires the distro vendor to be set")
+		}
+		uefiVendor = vendor
+	}
+
+	switch arch {
+	case platform.ARCH_X86_64.String():
+		if pd.Zipl {
+			return nil, fmt.Errorf("zipl is not supported on %s", arch)
+		}
+		return &platform.X86{BasePlatform: base, BIOS: pd.BIOS, UEFIVendor: uefiVendor}, nil
+	case platform.ARCH_AARCH64.String():
+		if pd.BIOS || pd.Zipl {
+			return nil, fmt.Errorf("only uefi boot is supported on %s", arch)
+		}
+		return &platform.Aarch64{BasePlatform: base, UEFIVendor: uefiVendor}, nil
+	case platform.ARCH_PPC64LE.String():
+		if pd.UEFI || pd.Zipl {
+			return nil, fmt.Errorf("only bios boot is supported on %s", arch)
+		}
+		return &platform.PPC64LE{BasePlatform: base, BIOS: pd.BIOS}, nil
+	case platform.ARCH_S390X.String():
+		if pd.BIOS || pd.UEFI {
+			return nil, fmt.Errorf("only zipl boot is supported on %s", arch)
+		}
+		return &platform.S390X{BasePlatform: base, Zipl: pd.Zipl}, nil
+	default:
+		return nil, fmt.Errorf("unsupported architecture %q", arch)
+	}
+}
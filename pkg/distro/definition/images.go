@@ -0,0 +1,91 @@
+package definition
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/image"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func osCustomizations(
+	t *imageType,
+	osPackageSet rpmmd.PackageSet,
+	options distro.ImageOptions,
+	containers []container.SourceSpec,
+	c *blueprint.Customizations,
+) manifest.OSCustomizations {
+
+	imageConfig, err := t.getDefaultImageConfig().MergeCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(fmt.Sprintf("failed to merge customizations into the image config: %v", err))
+	}
+
+	osc, err := distro.NewOSCustomizations(imageConfig, osPackageSet, options, containers, c, distro.OSCustomizationsOptions{
+		Bootable:      t.bootable,
+		KernelOptions: t.kernelOptions,
+	})
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	osc.YUMConfig = imageConfig.YumConfig
+
+	return osc
+}
+
+func diskImage(workload workload.Workload,
+	t *imageType,
+	customizations *blueprint.Customizations,
+	options distro.ImageOptions,
+	packageSets map[string]rpmmd.PackageSet,
+	containers []container.SourceSpec,
+	rng *rand.Rand) (image.ImageKind, error) {
+
+	img := image.NewDiskImage()
+	img.Platform = t.platform
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
+	img.Workload = workload
+	img.Compression = t.compression
+	img.CompressionLevel = t.compressionLevel
+	img.CompressionThreads = t.compressionThreads
+	if options.VHD != nil {
+		img.VPCSubformat = options.VHD.Subformat
+	}
+	pt, err := t.getPartitionTable(customizations, options, rng)
+	if err != nil {
+		return nil, err
+	}
+	img.PartitionTable = pt
+
+	img.Filename = t.Filename()
+
+	return img, nil
+}
+
+func tarImage(workload workload.Workload,
+	t *imageType,
+	customizations *blueprint.Customizations,
+	options distro.ImageOptions,
+	packageSets map[string]rpmmd.PackageSet,
+	containers []container.SourceSpec,
+	rng *rand.Rand) (image.ImageKind, error) {
+
+	img := image.NewArchive()
+	img.Platform = t.platform
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
+	img.Workload = workload
+
+	img.Filename = t.Filename()
+
+	return img, nil
+}
@@ -0,0 +1,367 @@
+package definition
+
+import (
+	"fmt"
+	"math/rand"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/pathpolicy"
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/image"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const (
+	// package set names
+
+	// main/common os image package set name
+	osPkgsKey = "os"
+
+	// blueprint package set name
+	blueprintPkgsKey = "blueprint"
+)
+
+type imageType struct {
+	arch               *architecture
+	platform           platform.Platform
+	name               string
+	nameAliases        []string
+	filename           string
+	mimeType           string
+	kind               string
+	compression        string
+	compressionLevel   int
+	compressionThreads int
+	packageSets        map[string]rpmmd.PackageSet
+	defaultImageConfig *distro.ImageConfig
+	kernelOptions      string
+	defaultSize        uint64
+	buildPipelines     []string
+	payloadPipelines   []string
+	exports            []string
+	bootable           bool
+	basePartitionTable *disk.PartitionTable
+}
+
+func (t *imageType) Name() string {
+	return t.name
+}
+
+func (t *imageType) Arch() distro.Arch {
+	return t.arch
+}
+
+func (t *imageType) Filename() string {
+	return t.filename
+}
+
+func (t *imageType) MIMEType() string {
+	return t.mimeType
+}
+
+func (t *imageType) OSTreeRef() string {
+	return ""
+}
+
+func (t *imageType) Size(size uint64) uint64 {
+	// Microsoft Azure requires vhd images and Hyper-V requires vhdx images to
+	// be rounded up to the nearest MB
+	if (t.platform.GetImageFormat() == platform.FORMAT_VHD || t.platform.GetImageFormat() == platform.FORMAT_VHDX) && size%common.MebiByte != 0 {
+		size = (size/common.MebiByte + 1) * common.MebiByte
+	}
+	if size == 0 {
+		size = t.defaultSize
+	}
+	return size
+}
+
+func (t *imageType) BuildPipelines() []string {
+	return t.buildPipelines
+}
+
+func (t *imageType) PayloadPipelines() []string {
+	return t.payloadPipelines
+}
+
+func (t *imageType) PayloadPackageSets() []string {
+	return []string{blueprintPkgsKey}
+}
+
+func (t *imageType) PackageSetsChains() map[string][]string {
+	return nil
+}
+
+func (t *imageType) Exports() []string {
+	return t.exports
+}
+
+func (t *imageType) BootMode() distro.BootMode {
+	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
+		return distro.BOOT_HYBRID
+	} else if t.platform.GetUEFIVendor() != "" {
+		return distro.BOOT_UEFI
+	} else if t.platform.GetBIOSPlatform() != "" || t.platform.GetZiplSupport() {
+		return distro.BOOT_LEGACY
+	}
+	return distro.BOOT_NONE
+}
+
+func (t *imageType) PartitionType() string {
+	if t.basePartitionTable == nil {
+		return ""
+	}
+	return t.basePartitionTable.Type
+}
+
+func (t *imageType) getPartitionTable(
+	customizations *blueprint.Customizations,
+	options distro.ImageOptions,
+	rng *rand.Rand,
+) (*disk.PartitionTable, error) {
+	imageSize := t.Size(options.Size)
+
+	if partitioning := customizations.GetPartitioning(); partitioning != nil {
+		customPartitionTable, err := disk.NewCustomPartitionTable(partitioning, t.basePartitionTable)
+		if err != nil {
+			return nil, err
+		}
+		return disk.NewPartitionTable(customPartitionTable, nil, imageSize, false, nil, rng)
+	}
+
+	return disk.NewPartitionTable(t.basePartitionTable, customizations.GetFilesystems(), imageSize, true, nil, rng)
+}
+
+func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
+	// ensure that image always returns non-nil default config
+	imageConfig := t.defaultImageConfig
+	if imageConfig == nil {
+		imageConfig = &distro.ImageConfig{}
+	}
+	return imageConfig.InheritFrom(t.arch.distro.getDefaultImageConfig())
+}
+
+func (t *imageType) Manifest(bp *blueprint.Blueprint,
+	options distro.ImageOptions,
+	repos []rpmmd.RepoConfig,
+	seed int64) (*manifest.Manifest, []string, error) {
+
+	warnings, err := t.checkOptions(bp, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staticPackageSets := make(map[string]rpmmd.PackageSet)
+	for name, ps := range t.packageSets {
+		staticPackageSets[name] = ps
+	}
+
+	// amend with repository information and collect payload repos
+	payloadRepos := make([]rpmmd.RepoConfig, 0)
+	for _, repo := range repos {
+		if len(repo.PackageSets) > 0 {
+			// only apply the repo to the listed package sets
+			for _, psName := range repo.PackageSets {
+				if slices.Contains(t.PayloadPackageSets(), psName) {
+					payloadRepos = append(payloadRepos, repo)
+				}
+				ps := staticPackageSets[psName]
+				ps.Repositories = append(ps.Repositories, repo)
+				staticPackageSets[psName] = ps
+			}
+		}
+	}
+
+	cw := &workload.Custom{
+		BaseWorkload: workload.BaseWorkload{
+			Repos: payloadRepos,
+		},
+		Packages:        bp.GetPackagesEx(false),
+		ExcludePackages: bp.ExcludePackages,
+	}
+	if services := bp.Customizations.GetServices(); services != nil {
+		cw.Services = services.Enabled
+		cw.DisabledServices = services.Disabled
+	}
+
+	containerSources := make([]container.SourceSpec, len(bp.Containers))
+	for idx := range bp.Containers {
+		containerSources[idx] = container.SourceSpec(bp.Containers[idx])
+	}
+
+	source := rand.NewSource(seed)
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(source)
+
+	var img image.ImageKind
+	switch t.kind {
+	case KindDisk:
+		img, err = diskImage(cw, t, bp.Customizations, options, staticPackageSets, containerSources, rng)
+	case KindArchive:
+		img, err = tarImage(cw, t, bp.Customizations, options, staticPackageSets, containerSources, rng)
+	default:
+		panic(fmt.Sprintf("unknown image kind %q", t.kind))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mf := manifest.New()
+	mf.Distro = t.arch.distro.family
+	mf.SourceEpoch = options.Reproducible.SourceEpoch()
+	_, err = img.InstantiateManifest(&mf, repos, t.arch.distro.runner, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &mf, warnings, err
+}
+
+// checkOptions checks the validity and compatibility of options and customizations for the image type.
+// Returns ([]string, error) where []string, if non-nil, will hold any generated warnings (e.g. deprecation notices).
+func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOptions) ([]string, error) {
+	customizations := bp.Customizations
+
+	// holds warnings (e.g. deprecation notices)
+	var warnings []string
+
+	if err := bp.ValidatePackageSelection(); err != nil {
+		return warnings, err
+	}
+
+	if options.VHD != nil {
+		if t.platform.GetImageFormat() != platform.FORMAT_VHD {
+			return warnings, fmt.Errorf("VHD options are not supported for %s on %s", t.name, t.arch.distro.name)
+		}
+		if err := options.VHD.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
+	if options.OSTree != nil {
+		return warnings, fmt.Errorf("OSTree options are not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if customizations.GetOpenSCAP() != nil {
+		return warnings, fmt.Errorf("OpenSCAP customizations are not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if !t.bootable && customizations.GetKernel().Append != "" {
+		return warnings, fmt.Errorf("kernel boot parameter customizations are not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	mountpoints := customizations.GetFilesystems()
+	partitioning := customizations.GetPartitioning()
+	if t.kind != KindDisk && (mountpoints != nil || partitioning != nil) {
+		return warnings, fmt.Errorf("Custom mountpoints and partitioning are not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	err := blueprint.CheckMountpointsPolicy(mountpoints, pathpolicy.MountpointPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
+	if partitioning != nil {
+		if mountpoints != nil {
+			return warnings, fmt.Errorf("Custom mountpoints and partitioning customizations cannot be used together")
+		}
+		if partitioning.HasBtrfs() {
+			return warnings, fmt.Errorf("btrfs partitioning customizations are not supported on %s", t.arch.distro.name)
+		}
+		if err := partitioning.Validate(); err != nil {
+			return warnings, err
+		}
+		if err := blueprint.CheckPartitioningPolicy(partitioning, pathpolicy.MountpointPolicies); err != nil {
+			return warnings, err
+		}
+	}
+
+	// Check Directory/File Customizations are valid
+	dc := customizations.GetDirectories()
+	fc := customizations.GetFiles()
+
+	err = blueprint.ValidateDirFileCustomizations(dc, fc)
+	if err != nil {
+		return warnings, err
+	}
+	err = blueprint.CheckDirectoryCustomizationsPolicy(dc, pathpolicy.CustomDirectoriesPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
+	err = blueprint.CheckFileCustomizationsPolicy(fc, pathpolicy.CustomFilesPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if repository customizations are valid
+	_, err = customizations.GetRepositories()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if the network customization is valid
+	_, err = customizations.GetNetwork()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if the kernel tuning customizations are valid
+	if _, err := customizations.GetSysctl(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetKernelModules(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetDracut(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetTuned(); err != nil {
+		return warnings, err
+	}
+
+	// check if the systemd customization is valid
+	if _, err := customizations.GetSystemd(); err != nil {
+		return warnings, err
+	}
+
+	// check if the cloud-init customization is valid and the modules can
+	// be disabled
+	cloudInit, err := customizations.GetCloudInit()
+	if err != nil {
+		return warnings, err
+	}
+	if err := cloudInit.ValidateDisabledModules(t.getDefaultImageConfig().CloudInitModules); err != nil {
+		return warnings, err
+	}
+
+	// check if the SELinux customization is valid
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return warnings, err
+	}
+	if selinux != nil && selinux.HasPolicyModifications() {
+		return warnings, fmt.Errorf("SELinux boolean, file context and port customizations are not supported for %s on %s: osbuild has no stage that modifies the SELinux policy", t.name, t.arch.distro.name)
+	}
+
+	// check if the accounts customization is valid
+	if _, err := customizations.GetAccounts(); err != nil {
+		return warnings, err
+	}
+
+	if installer, err := customizations.GetInstaller(); err != nil {
+		return warnings, err
+	} else if installer != nil {
+		return warnings, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
+	return warnings, nil
+}
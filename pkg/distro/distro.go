@@ -1,9 +1,16 @@
 package distro
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rhsm/facts"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -134,6 +141,44 @@ type ImageOptions struct {
 	OSTree       *ostree.ImageOptions
 	Subscription *subscription.ImageOptions
 	Facts        *facts.ImageOptions
+	Reproducible *ReproducibleOptions
+	VHD          *VHDOptions
+}
+
+// ReproducibleOptions make the contents of an image depend only on the inputs
+// of the build and not on the time it runs. Passwords that are not already
+// hashed in the blueprint are hashed with a random salt, so their hashes still
+// differ between manifests.
+type ReproducibleOptions struct {
+	// SourceDateEpoch is set as the SOURCE_DATE_EPOCH of all the pipelines
+	// and the modification times of the files in the image are clamped to
+	// it.
+	SourceDateEpoch time.Time
+}
+
+// SourceEpoch returns the source epoch of the manifest in seconds since the
+// epoch, or nil if the options are not set.
+func (o *ReproducibleOptions) SourceEpoch() *int64 {
+	if o == nil {
+		return nil
+	}
+	return common.ToPtr(o.SourceDateEpoch.Unix())
+}
+
+// VHDOptions are the options of image types that produce VHD images.
+type VHDOptions struct {
+	// Subformat of the image, fixed or dynamic. Dynamic images are created
+	// if it is not set.
+	Subformat osbuild.VPCSubformat
+}
+
+func (o *VHDOptions) Validate() error {
+	switch o.Subformat {
+	case "", osbuild.VPCSubformatDynamic, osbuild.VPCSubformatFixed:
+		return nil
+	default:
+		return fmt.Errorf("unsupported VHD subformat %q, must be %q or %q", o.Subformat, osbuild.VPCSubformatFixed, osbuild.VPCSubformatDynamic)
+	}
 }
 
 type BasePartitionTableMap map[string]disk.PartitionTable
@@ -159,3 +204,97 @@ func ExportsFallback() []string {
 func PayloadPackageSets() []string {
 	return []string{}
 }
+
+// DerivativeOptions define the identity of a distribution that is derived
+// from one of the supported distributions, e.g. a rebuild of RHEL. The
+// derivative provides the image types of the distribution it is based on,
+// with its own branding. Fields that are left empty are derived from the base
+// distribution or from the other fields.
+type DerivativeOptions struct {
+	// Name of the distribution. To be detected as the host distribution, it
+	// has to follow the "<ID>-<VERSION_ID without dots>" pattern of
+	// /etc/os-release, e.g. "rocky-93". Required.
+	Name string
+
+	// Product name used for installer branding, e.g. "Rocky Linux".
+	// Required.
+	Product string
+
+	// Version of the distribution, e.g. "9.3". Determines the features of
+	// the base distribution that are available and must have the same major
+	// version as the base distribution. Required.
+	OSVersion string
+
+	// Vendor name, which is also the name of the vendor directory on the
+	// EFI system partition. Required.
+	Vendor string
+
+	// Release version used in repository definitions; defaults to the major
+	// version of the base distribution
+	Releasever string
+
+	// Module platform ID; defaults to the one of the base distribution
+	ModulePlatformID string
+
+	// OSTree ref template; defaults to "<vendor>/<major>/%s/edge"
+	OSTreeRefTmpl string
+
+	// ISO volume label template; defaults to
+	// "<VENDOR>-<major>-<minor>-BaseOS-%s"
+	ISOLabelTmpl string
+
+	// Default SCAP source data stream for OpenSCAP remediation; defaults to
+	// the one of the CentOS Stream variant of the base distribution
+	OSCAPDatastream string
+}
+
+// Validate checks that the required options are set and that the OS version
+// of the derivative matches the major version of the base distribution.
+func (o *DerivativeOptions) Validate(baseMajor string) error {
+	switch {
+	case o.Name == "":
+		return fmt.Errorf("derivative distro name is required")
+	case o.Product == "":
+		return fmt.Errorf("derivative distro %q: product is required", o.Name)
+	case o.Vendor == "":
+		return fmt.Errorf("derivative distro %q: vendor is required", o.Name)
+	}
+
+	major, minor, _ := strings.Cut(o.OSVersion, ".")
+	if major != baseMajor || minor == "" {
+		return fmt.Errorf("derivative distro %q: os version %q is not a %s.x version", o.Name, o.OSVersion, baseMajor)
+	}
+	if _, err := strconv.ParseUint(minor, 10, 64); err != nil {
+		return fmt.Errorf("derivative distro %q: invalid os version %q", o.Name, o.OSVersion)
+	}
+	return nil
+}
+
+// Minor returns the minor version of the derivative. It must only be called
+// on validated options.
+func (o *DerivativeOptions) Minor() int {
+	_, minor, _ := strings.Cut(o.OSVersion, ".")
+	m, err := strconv.Atoi(minor)
+	if err != nil {
+		panic(fmt.Sprintf("invalid os version %q: %v", o.OSVersion, err))
+	}
+	return m
+}
+
+// ApplyDefaults fills the optional fields that are not set using the major
+// version and the module platform ID of the base distribution.
+func (o DerivativeOptions) ApplyDefaults(baseMajor, baseModulePlatformID string) DerivativeOptions {
+	if o.Releasever == "" {
+		o.Releasever = baseMajor
+	}
+	if o.ModulePlatformID == "" {
+		o.ModulePlatformID = baseModulePlatformID
+	}
+	if o.OSTreeRefTmpl == "" {
+		o.OSTreeRefTmpl = fmt.Sprintf("%s/%s/%%s/edge", o.Vendor, baseMajor)
+	}
+	if o.ISOLabelTmpl == "" {
+		o.ISOLabelTmpl = fmt.Sprintf("%s-%s-BaseOS-%%s", strings.ToUpper(o.Vendor), strings.ReplaceAll(o.OSVersion, ".", "-"))
+	}
+	return o
+}
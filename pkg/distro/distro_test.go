@@ -12,6 +12,7 @@ import (
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/stretchr/testify/assert"
@@ -174,6 +175,14 @@ func TestImageTypePipelineNames(t *testing.T) {
 					err = json.Unmarshal(mf, pm)
 					assert.NoError(err)
 
+					// the manifest must decode into the typed osbuild
+					// structures without losing any information
+					typed := new(osbuild.Manifest)
+					assert.NoError(json.Unmarshal(mf, typed))
+					reencoded, err := json.Marshal(typed)
+					assert.NoError(err)
+					assert.JSONEq(string(mf), string(reencoded))
+
 					assert.Equal(len(allPipelines), len(pm.Pipelines))
 					for idx := range pm.Pipelines {
 						// manifest pipeline names should be identical to the ones
@@ -1,15 +1,26 @@
 package distro_test_common
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 const RandomTestSeed = 0
@@ -454,3 +465,332 @@ func TestDistro_OSTreeOptions(t *testing.T, d distro.Distro) {
 		}
 	}
 }
+
+// serializeManifest serializes a manifest of the image type with fake content.
+func serializeManifest(t *testing.T, imgType distro.ImageType, bp *blueprint.Blueprint, options distro.ImageOptions) []byte {
+	m, _, err := imgType.Manifest(bp, options, nil, RandomTestSeed)
+	require.NoError(t, err)
+
+	packageSets := make(map[string][]rpmmd.PackageSpec)
+	for name := range m.GetPackageSetChains() {
+		packageSets[name] = []rpmmd.PackageSpec{
+			{Name: "kernel", Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72"},
+			{Name: "filesystem", Checksum: "sha256:6b4bf18ba28ccbdd49f2716c9f33c9211155ff703fa6c195c78a07bd160da0eb"},
+		}
+	}
+	commits := make(map[string][]ostree.CommitSpec)
+	for name, sources := range m.GetOSTreeSourceSpecs() {
+		for _, source := range sources {
+			commits[name] = append(commits[name], ostree.CommitSpec{
+				Ref:      source.Ref,
+				URL:      source.URL,
+				Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(source.URL+source.Ref))),
+			})
+		}
+	}
+
+	mf, err := m.Serialize(packageSets, nil, commits)
+	require.NoError(t, err)
+	return mf
+}
+
+func TestDistro_ReproducibleManifests(t *testing.T, d distro.Distro) {
+	epoch := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, typeName := range arch.ListImageTypes() {
+			imgType, err := arch.GetImageType(typeName)
+			require.NoError(t, err)
+
+			options := distro.ImageOptions{
+				OSTree: &ostree.ImageOptions{
+					URL: "https://example.com/repo", // required by some image types
+				},
+				Reproducible: &distro.ReproducibleOptions{
+					SourceDateEpoch: epoch,
+				},
+			}
+
+			// the repositories are written to files in the order of their
+			// names
+			bp := &blueprint.Blueprint{
+				Customizations: &blueprint.Customizations{
+					Repositories: []blueprint.RepositoryCustomization{
+						{Id: "repo-b", BaseURLs: []string{"https://example.com/b"}, Filename: "b.repo"},
+						{Id: "repo-a", BaseURLs: []string{"https://example.com/a"}, Filename: "a.repo"},
+						{Id: "repo-c", BaseURLs: []string{"https://example.com/c"}, Filename: "c.repo"},
+					},
+				},
+			}
+			if strings.HasSuffix(typeName, "simplified-installer") {
+				// simplified installers require installation device and
+				// don't support repositories
+				bp.Customizations = &blueprint.Customizations{
+					InstallationDevice: "/dev/sda42",
+				}
+			} else if _, _, err := imgType.Manifest(bp, options, nil, RandomTestSeed); err != nil {
+				// not all image types support customizations
+				bp = &blueprint.Blueprint{}
+			}
+
+			first := serializeManifest(t, imgType, bp, options)
+			second := serializeManifest(t, imgType, bp, options)
+			assert.Equal(t, string(first), string(second), "manifests of image type %q (arch %s) differ", typeName, archName)
+
+			var mf osbuild.Manifest
+			require.NoError(t, json.Unmarshal(first, &mf))
+			for _, pipeline := range mf.Pipelines {
+				if assert.NotNil(t, pipeline.SourceEpoch, "pipeline %q of image type %q (arch %s) has no source epoch", pipeline.Name, typeName, archName) {
+					assert.Equal(t, epoch.Unix(), *pipeline.SourceEpoch)
+				}
+			}
+		}
+	}
+}
+
+// TestDistro_VHDOptions checks that the image types that produce VHD images
+// are rounded up to whole MiB and accept the VHD subformat, while the other
+// image types reject the VHD options.
+func TestDistro_VHDOptions(t *testing.T, d distro.Distro) {
+	options := distro.ImageOptions{
+		VHD: &distro.VHDOptions{
+			Subformat: osbuild.VPCSubformatFixed,
+		},
+	}
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, typeName := range arch.ListImageTypes() {
+			imgType, err := arch.GetImageType(typeName)
+			require.NoError(t, err)
+
+			bp := &blueprint.Blueprint{}
+			if !slices.Contains(imgType.PayloadPipelines(), "vpc") {
+				_, _, err := imgType.Manifest(bp, options, nil, RandomTestSeed)
+				assert.EqualError(t, err, fmt.Sprintf("VHD options are not supported for %s on %s", typeName, d.Name()))
+				continue
+			}
+
+			assert.Equal(t, uint64(common.GibiByte+common.MebiByte), imgType.Size(common.GibiByte+1), "size of image type %q (arch %s) is not rounded up", typeName, archName)
+
+			var mf osbuild.Manifest
+			require.NoError(t, json.Unmarshal(serializeManifest(t, imgType, bp, options), &mf))
+			var vpcOptions *osbuild.VPCOptions
+			for _, pipeline := range mf.Pipelines {
+				if pipeline.Name != "vpc" {
+					continue
+				}
+				for _, stage := range pipeline.Stages {
+					if stage.Type == "org.osbuild.qemu" {
+						format := stage.Options.(*osbuild.QEMUStageOptions).Format.(osbuild.VPCOptions)
+						vpcOptions = &format
+					}
+				}
+			}
+			if assert.NotNil(t, vpcOptions, "image type %q (arch %s) has no vpc stage", typeName, archName) {
+				assert.Equal(t, osbuild.VPCSubformatFixed, vpcOptions.Subformat)
+			}
+
+			options := distro.ImageOptions{
+				VHD: &distro.VHDOptions{
+					Subformat: "streamOptimized",
+				},
+			}
+			_, _, err = imgType.Manifest(bp, options, nil, RandomTestSeed)
+			assert.EqualError(t, err, `unsupported VHD subformat "streamOptimized", must be "fixed" or "dynamic"`)
+		}
+	}
+}
+
+// TestDistro_BtrfsPartitioning checks that a btrfs partitioning customization
+// creates the btrfs volume and its subvolumes on the image types that accept
+// custom partitioning, or is rejected if the distro does not support btrfs.
+func TestDistro_BtrfsPartitioning(t *testing.T, d distro.Distro, supported bool) {
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Partitioning: &blueprint.PartitioningCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						MinSize: 10 * common.GibiByte,
+						Btrfs: &blueprint.BtrfsCustomization{
+							Label: "fedora",
+							Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+								{Name: "root", Mountpoint: "/"},
+								{Name: "home", Mountpoint: "/home", MinSize: 2 * common.GibiByte},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	btrfsImageTypes := 0
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, typeName := range arch.ListImageTypes() {
+			imgType, err := arch.GetImageType(typeName)
+			require.NoError(t, err)
+
+			m, _, err := imgType.Manifest(bp, distro.ImageOptions{}, nil, RandomTestSeed)
+			if err != nil || imgType.PartitionType() == "" {
+				continue
+			}
+			btrfsImageTypes++
+
+			buildPackages := []string{}
+			for _, set := range m.GetPackageSetChains()["build"] {
+				buildPackages = append(buildPackages, set.Include...)
+			}
+			assert.Contains(t, buildPackages, "btrfs-progs", "image type %q (arch %s) has no btrfs-progs in the build root", typeName, archName)
+
+			var mf osbuild.Manifest
+			require.NoError(t, json.Unmarshal(serializeManifest(t, imgType, bp, distro.ImageOptions{}), &mf))
+			stages := make(map[string][]*osbuild.Stage)
+			for _, pipeline := range mf.Pipelines {
+				for _, stage := range pipeline.Stages {
+					stages[stage.Type] = append(stages[stage.Type], stage)
+				}
+			}
+			if assert.Len(t, stages["org.osbuild.mkfs.btrfs"], 1, "image type %q (arch %s)", typeName, archName) {
+				assert.Equal(t, "fedora", stages["org.osbuild.mkfs.btrfs"][0].Options.(*osbuild.MkfsBtrfsStageOptions).Label)
+			}
+			if assert.Len(t, stages["org.osbuild.btrfs.subvol"], 1, "image type %q (arch %s)", typeName, archName) {
+				subvol := stages["org.osbuild.btrfs.subvol"][0].Options.(*osbuild.BtrfsSubVolStageOptions)
+				assert.Equal(t, []osbuild.BtrfsSubVol{{Name: "/root"}, {Name: "/home"}}, subvol.Subvolumes)
+			}
+			if assert.Len(t, stages["org.osbuild.fstab"], 1, "image type %q (arch %s)", typeName, archName) {
+				fstab := stages["org.osbuild.fstab"][0].Options.(*osbuild.FSTabStageOptions)
+				fsOptions := make(map[string]string)
+				for _, fs := range fstab.FileSystems {
+					fsOptions[fs.Path] = fs.Options
+				}
+				assert.Equal(t, "subvol=root", fsOptions["/"])
+				assert.Equal(t, "subvol=home", fsOptions["/home"])
+			}
+			if assert.Len(t, stages["org.osbuild.kernel-cmdline"], 1, "image type %q (arch %s)", typeName, archName) {
+				cmdline := stages["org.osbuild.kernel-cmdline"][0].Options.(*osbuild.KernelCmdlineStageOptions)
+				assert.Contains(t, cmdline.KernelOpts, "rootflags=subvol=root")
+			}
+		}
+	}
+	if supported {
+		assert.NotZero(t, btrfsImageTypes, "no image type of %s supports btrfs partitioning", d.Name())
+	} else {
+		assert.Zero(t, btrfsImageTypes, "image types of %s support btrfs partitioning", d.Name())
+	}
+}
+
+// TestDistro_CloudInitDisabledModules checks that the cloud-init modules
+// disabled by a blueprint are removed from the module lists of the distro
+// and that modules that cloud-init doesn't run are rejected.
+func TestDistro_CloudInitDisabledModules(t *testing.T, d distro.Distro) {
+	arch, err := d.GetArch(platform.ARCH_X86_64.String())
+	require.NoError(t, err)
+	imgType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			CloudInit: &blueprint.CloudInitCustomization{
+				DisabledModules: []string{"growpart", "resizefs"},
+			},
+		},
+	}
+	var mf osbuild.Manifest
+	require.NoError(t, json.Unmarshal(serializeManifest(t, imgType, bp, distro.ImageOptions{}), &mf))
+	var config string
+	for _, item := range mf.Sources["org.osbuild.inline"].(*osbuild.InlineSource).Items {
+		data, err := base64.StdEncoding.DecodeString(item.Data)
+		require.NoError(t, err)
+		if strings.Contains(string(data), "cloud_init_modules:") {
+			config = string(data)
+		}
+	}
+	require.NotEmpty(t, config, "%s has no cloud-init module lists", d.Name())
+	assert.Contains(t, config, "cloud_config_modules:")
+	assert.Contains(t, config, "cloud_final_modules:")
+	assert.NotContains(t, config, "growpart")
+	assert.NotContains(t, config, "resizefs")
+
+	bp.Customizations.CloudInit.DisabledModules = []string{"no-such-module"}
+	_, _, err = imgType.Manifest(bp, distro.ImageOptions{}, nil, RandomTestSeed)
+	assert.EqualError(t, err, `cloud-init module "no-such-module" is not run by the image`)
+}
+
+// TestDistro_VHDXImageTypes checks that the distro has image types producing
+// VHDX images, that their sizes are rounded up to whole MiB and that the
+// images are converted to vhdx by qemu.
+func TestDistro_VHDXImageTypes(t *testing.T, d distro.Distro) {
+	vhdxImageTypes := 0
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, typeName := range arch.ListImageTypes() {
+			imgType, err := arch.GetImageType(typeName)
+			require.NoError(t, err)
+			if !slices.Contains(imgType.PayloadPipelines(), "vhdx") {
+				continue
+			}
+			vhdxImageTypes++
+
+			assert.Equal(t, "application/x-vhdx", imgType.MIMEType())
+			assert.True(t, strings.HasSuffix(imgType.Filename(), ".vhdx"), "filename %q of image type %q (arch %s) is not a vhdx file", imgType.Filename(), typeName, archName)
+			assert.Equal(t, uint64(common.GibiByte+common.MebiByte), imgType.Size(common.GibiByte+1), "size of image type %q (arch %s) is not rounded up", typeName, archName)
+
+			var mf osbuild.Manifest
+			require.NoError(t, json.Unmarshal(serializeManifest(t, imgType, &blueprint.Blueprint{}, distro.ImageOptions{}), &mf))
+			vhdxPipeline := mf.Pipelines[len(mf.Pipelines)-1]
+			require.Equal(t, "vhdx", vhdxPipeline.Name)
+			require.Len(t, vhdxPipeline.Stages, 1)
+			require.Equal(t, "org.osbuild.qemu", vhdxPipeline.Stages[0].Type)
+			qemuOptions := vhdxPipeline.Stages[0].Options.(*osbuild.QEMUStageOptions)
+			assert.Equal(t, imgType.Filename(), qemuOptions.Filename)
+			assert.Equal(t, osbuild.VHDXOptions{Type: osbuild.QEMUFormatVHDX}, qemuOptions.Format)
+		}
+	}
+	assert.NotZero(t, vhdxImageTypes, "%s has no vhdx image types", d.Name())
+}
+
+// TestDistro_CompressedImageTypes checks that the filenames and MIME types of
+// the image types that compress their images match the compression and that
+// the zstd compressed image types set the compression level and threads.
+func TestDistro_CompressedImageTypes(t *testing.T, d distro.Distro) {
+	for _, archName := range d.ListArches() {
+		arch, err := d.GetArch(archName)
+		require.NoError(t, err)
+		for _, typeName := range arch.ListImageTypes() {
+			imgType, err := arch.GetImageType(typeName)
+			require.NoError(t, err)
+
+			pipelines := imgType.PayloadPipelines()
+			algorithm := pipelines[len(pipelines)-1]
+			switch algorithm {
+			case manifest.CompressionXZ, manifest.CompressionZstd, manifest.CompressionGzip:
+			default:
+				continue
+			}
+			assert.True(t, strings.HasSuffix(imgType.Filename(), manifest.CompressionExtension(algorithm)), "filename %q of image type %q (arch %s) doesn't match the %s compression", imgType.Filename(), typeName, archName, algorithm)
+			assert.Equal(t, manifest.CompressionMIMEType(algorithm), imgType.MIMEType(), "MIME type of image type %q (arch %s) doesn't match the %s compression", typeName, archName, algorithm)
+
+			if algorithm != manifest.CompressionZstd {
+				continue
+			}
+			options := distro.ImageOptions{
+				OSTree: &ostree.ImageOptions{
+					URL: "https://example.com/repo", // required by some image types
+				},
+			}
+			var mf osbuild.Manifest
+			require.NoError(t, json.Unmarshal(serializeManifest(t, imgType, &blueprint.Blueprint{}, options), &mf))
+			compressionPipeline := mf.Pipelines[len(mf.Pipelines)-1]
+			require.Equal(t, algorithm, compressionPipeline.Name)
+			require.Len(t, compressionPipeline.Stages, 1)
+			zstdOptions := compressionPipeline.Stages[0].Options.(*osbuild.ZstdStageOptions)
+			assert.Equal(t, imgType.Filename(), zstdOptions.Filename)
+			assert.NotZero(t, zstdOptions.Level, "image type %q (arch %s) doesn't set the zstd compression level", typeName, archName)
+			assert.NotZero(t, zstdOptions.Threads, "image type %q (arch %s) doesn't set the zstd compression threads", typeName, archName)
+		}
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/environment"
 	"github.com/osbuild/images/internal/oscap"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -158,12 +160,14 @@ var (
 	}
 
 	iotRawImgType = imageType{
-		name:        "iot-raw-image",
-		nameAliases: []string{"fedora-iot-raw-image"},
-		filename:    "image.raw.xz",
-		compression: "xz",
-		mimeType:    "application/xz",
-		packageSets: map[string]packageSetFunc{},
+		name:               "iot-raw-image",
+		nameAliases:        []string{"fedora-iot-raw-image"},
+		filename:           "image.raw.zst",
+		compression:        manifest.CompressionZstd,
+		compressionLevel:   19,
+		compressionThreads: 4,
+		mimeType:           "application/zstd",
+		packageSets:        map[string]packageSetFunc{},
 		defaultImageConfig: &distro.ImageConfig{
 			Locale: common.ToPtr("en_US.UTF-8"),
 		},
@@ -172,8 +176,8 @@ var (
 		bootable:            true,
 		image:               iotRawImage,
 		buildPipelines:      []string{"build"},
-		payloadPipelines:    []string{"ostree-deployment", "image", "xz"},
-		exports:             []string{"xz"},
+		payloadPipelines:    []string{"ostree-deployment", "image", "zstd"},
+		exports:             []string{"zstd"},
 		basePartitionTables: iotBasePartitionTables,
 
 		// Passing an empty map into the required partition sizes disables the
@@ -247,6 +251,26 @@ var (
 		},
 	}
 
+	vhdxImgType = imageType{
+		name:     "vhdx",
+		filename: "disk.vhdx",
+		mimeType: "application/x-vhdx",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: vhdxCommonPackageSet,
+		},
+		defaultImageConfig: &distro.ImageConfig{
+			Locale: common.ToPtr("en_US.UTF-8"),
+		},
+		kernelOptions:       defaultKernelOptions,
+		bootable:            true,
+		defaultSize:         5 * common.GibiByte,
+		image:               diskImage,
+		buildPipelines:      []string{"build"},
+		payloadPipelines:    []string{"os", "image", "vhdx"},
+		exports:             []string{"vhdx"},
+		basePartitionTables: defaultBasePartitionTables,
+	}
+
 	vmdkImgType = imageType{
 		name:     "vmdk",
 		filename: "disk.vmdk",
@@ -329,10 +353,12 @@ var (
 	}
 
 	minimalrawImgType = imageType{
-		name:        "minimal-raw",
-		filename:    "raw.img.xz",
-		compression: "xz",
-		mimeType:    "application/xz",
+		name:               "minimal-raw",
+		filename:           "raw.img.zst",
+		compression:        manifest.CompressionZstd,
+		compressionLevel:   19,
+		compressionThreads: 4,
+		mimeType:           "application/zstd",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: minimalrpmPackageSet,
 		},
@@ -342,8 +368,8 @@ var (
 		defaultSize:         2 * common.GibiByte,
 		image:               diskImage,
 		buildPipelines:      []string{"build"},
-		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		payloadPipelines:    []string{"os", "image", "zstd"},
+		exports:             []string{"zstd"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 )
@@ -365,6 +391,60 @@ type distribution struct {
 var defaultDistroImageConfig = &distro.ImageConfig{
 	Timezone: common.ToPtr("UTC"),
 	Locale:   common.ToPtr("en_US"),
+	// modules of the cloud.cfg of the cloud-init package
+	CloudInitModules: &blueprint.CloudInitModules{
+		Init: []string{
+			"migrator",
+			"seed_random",
+			"bootcmd",
+			"write_files",
+			"growpart",
+			"resizefs",
+			"disk_setup",
+			"mounts",
+			"set_hostname",
+			"update_hostname",
+			"update_etc_hosts",
+			"ca_certs",
+			"rsyslog",
+			"users_groups",
+			"ssh",
+		},
+		Config: []string{
+			"ssh_import_id",
+			"keyboard",
+			"locale",
+			"set_passwords",
+			"spacewalk",
+			"yum_add_repo",
+			"ntp",
+			"timezone",
+			"disable_ec2_metadata",
+			"runcmd",
+		},
+		Final: []string{
+			"package_update_upgrade_install",
+			"write_files_deferred",
+			"puppet",
+			"chef",
+			"ansible",
+			"mcollective",
+			"salt_minion",
+			"reset_rmc",
+			"rightscale_userdata",
+			"scripts_vendor",
+			"scripts_per_once",
+			"scripts_per_boot",
+			"scripts_per_instance",
+			"scripts_user",
+			"ssh_authkey_fingerprints",
+			"keys_to_console",
+			"install_hotplug",
+			"phone_home",
+			"final_message",
+			"power_state_change",
+		},
+	},
 }
 
 func getDistro(version int) distribution {
@@ -562,6 +642,16 @@ func newDistro(version int) distro.Distro {
 		},
 		vhdImgType,
 	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VHDX,
+			},
+		},
+		vhdxImgType,
+	)
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -80,6 +80,14 @@ func TestFilenameFromType(t *testing.T) {
 				mimeType: "application/x-vhd",
 			},
 		},
+		{
+			name: "vhdx",
+			args: args{"vhdx"},
+			want: wantResult{
+				filename: "disk.vhdx",
+				mimeType: "application/x-vhdx",
+			},
+		},
 		{
 			name: "vmdk",
 			args: args{"vmdk"},
@@ -193,8 +201,8 @@ func TestFilenameFromType(t *testing.T) {
 			name: "minimal-raw",
 			args: args{"minimal-raw"},
 			want: wantResult{
-				filename: "raw.img.xz",
-				mimeType: "application/xz",
+				filename: "raw.img.zst",
+				mimeType: "application/zstd",
 			},
 		},
 	}
@@ -289,6 +297,7 @@ func TestImageType_Name(t *testing.T) {
 				"qcow2",
 				"openstack",
 				"vhd",
+				"vhdx",
 				"vmdk",
 				"ova",
 				"ami",
@@ -447,7 +456,7 @@ func TestDistro_ManifestError(t *testing.T) {
 			} else if imgTypeName == "iot-installer" {
 				assert.EqualError(t, err, fmt.Sprintf("boot ISO image type \"%s\" requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
 			} else if imgTypeName == "image-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group)", imgTypeName))
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group, Installer)", imgTypeName))
 			} else if imgTypeName == "live-installer" {
 				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
 			} else if imgTypeName == "iot-raw-image" {
@@ -471,6 +480,7 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"qcow2",
 				"openstack",
 				"vhd",
+				"vhdx",
 				"vmdk",
 				"ova",
 				"ami",
@@ -580,6 +590,22 @@ func TestFedora37_KernelOption(t *testing.T) {
 	distro_test_common.TestDistro_KernelOption(t, fedora.NewF37())
 }
 
+func TestFedora37_ReproducibleManifests(t *testing.T) {
+	distro_test_common.TestDistro_ReproducibleManifests(t, fedora.NewF37())
+}
+
+func TestFedora37_VHDOptions(t *testing.T) {
+	distro_test_common.TestDistro_VHDOptions(t, fedora.NewF37())
+}
+
+func TestFedora37_BtrfsPartitioning(t *testing.T) {
+	distro_test_common.TestDistro_BtrfsPartitioning(t, fedora.NewF37(), true)
+}
+
+func TestFedora37_CompressedImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_CompressedImageTypes(t, fedora.NewF37())
+}
+
 func TestFedora_OSTreeOptions(t *testing.T) {
 	distro_test_common.TestDistro_OSTreeOptions(t, fedora.NewF37())
 }
@@ -823,3 +849,11 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestFedora37_VHDXImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_VHDXImageTypes(t, fedora.NewF37())
+}
+
+func TestFedora37_CloudInitDisabledModules(t *testing.T) {
+	distro_test_common.TestDistro_CloudInitDisabledModules(t, fedora.NewF37())
+}
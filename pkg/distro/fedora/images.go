@@ -5,6 +5,9 @@ import (
 	"math/rand"
 	"strings"
 
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/internal/users"
@@ -27,7 +30,12 @@ func osCustomizations(
 	containers []container.SourceSpec,
 	c *blueprint.Customizations) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().MergeCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(fmt.Sprintf("failed to merge customizations into the image config: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -55,13 +63,6 @@ func osCustomizations(
 		osc.ExcludeDocs = *imageConfig.ExcludeDocs
 	}
 
-	if !t.bootISO {
-		// don't put users and groups in the payload of an installer
-		// add them via kickstart instead
-		osc.Groups = users.GroupsFromBP(c.GetGroups())
-		osc.Users = users.UsersFromBP(c.GetUsers())
-	}
-
 	osc.EnabledServices = imageConfig.EnabledServices
 	osc.DisabledServices = imageConfig.DisabledServices
 	if imageConfig.DefaultTarget != nil {
@@ -134,7 +135,6 @@ func osCustomizations(
 		)
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -170,8 +170,26 @@ func osCustomizations(
 		osc.Files = append(osc.Files, gpgKeyFiles...)
 	}
 
-	for filename, repos := range yumRepos {
-		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
+	if err := distro.AddCustomizationFiles(&osc, c, imageConfig.CloudInitModules); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	// don't put users and groups in the payload of an installer
+	// add them via kickstart instead
+	if err := distro.AddAccounts(&osc, c, t.bootISO); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	// sort the repo files so that the manifest doesn't depend on the map
+	// iteration order
+	repoFiles := maps.Keys(yumRepos)
+	slices.Sort(repoFiles)
+	for _, filename := range repoFiles {
+		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, yumRepos[filename]))
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -214,8 +232,13 @@ func diskImage(workload workload.Workload,
 	img.Environment = t.environment
 	img.Workload = workload
 	img.Compression = t.compression
+	img.CompressionLevel = t.compressionLevel
+	img.CompressionThreads = t.compressionThreads
+	if options.VHD != nil {
+		img.VPCSubformat = options.VHD.Subformat
+	}
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -283,8 +306,7 @@ func imageInstallerImage(workload workload.Workload,
 	img := image.NewAnacondaTarInstaller()
 
 	// Enable anaconda-webui for Fedora > 38
-	distro := t.Arch().Distro()
-	if strings.HasPrefix(distro.Name(), "fedora") && !common.VersionLessThan(distro.Releasever(), "38") {
+	if d := t.Arch().Distro(); strings.HasPrefix(d.Name(), "fedora") && !common.VersionLessThan(d.Releasever(), "38") {
 		img.AdditionalAnacondaModules = []string{
 			"org.fedoraproject.Anaconda.Modules.Security",
 			"org.fedoraproject.Anaconda.Modules.Timezone",
@@ -298,9 +320,21 @@ func imageInstallerImage(workload workload.Workload,
 	img.Workload = workload
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, customizations)
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	var err error
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+	// unattended installations need the kickstart file to be passed to the
+	// installer instead of only setting the interactive defaults
+	img.ISORootKickstart = img.Kickstart != nil
+
 	img.SquashfsCompression = "lz4"
 
 	d := t.arch.distro
@@ -382,8 +416,16 @@ func iotInstallerImage(workload workload.Workload,
 
 	img.Platform = t.platform
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
+
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
 	img.AdditionalAnacondaModules = []string{
 		"org.fedoraproject.Anaconda.Modules.Timezone",
 		"org.fedoraproject.Anaconda.Modules.Localization",
@@ -425,7 +467,10 @@ func iotRawImage(workload workload.Workload,
 		img.SysrootReadOnly = true
 	}
 
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	img.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(customizations.GetDirectories())
@@ -455,7 +500,7 @@ func iotRawImage(workload workload.Workload,
 	img.OSName = "fedora-iot"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -463,6 +508,8 @@ func iotRawImage(workload workload.Workload,
 
 	img.Filename = t.Filename()
 	img.Compression = t.compression
+	img.CompressionLevel = t.compressionLevel
+	img.CompressionThreads = t.compressionThreads
 
 	return img, nil
 }
@@ -35,6 +35,8 @@ type imageType struct {
 	nameAliases        []string
 	filename           string
 	compression        string
+	compressionLevel   int
+	compressionThreads int
 	mimeType           string
 	packageSets        map[string]packageSetFunc
 	defaultImageConfig *distro.ImageConfig
@@ -81,8 +83,9 @@ func (t *imageType) OSTreeRef() string {
 }
 
 func (t *imageType) Size(size uint64) uint64 {
-	// Microsoft Azure requires vhd images to be rounded up to the nearest MB
-	if t.name == "vhd" && size%common.MebiByte != 0 {
+	// Microsoft Azure requires vhd images and Hyper-V requires vhdx images to
+	// be rounded up to the nearest MB
+	if t.platform != nil && (t.platform.GetImageFormat() == platform.FORMAT_VHD || t.platform.GetImageFormat() == platform.FORMAT_VHDX) && size%common.MebiByte != 0 {
 		size = (size/common.MebiByte + 1) * common.MebiByte
 	}
 	if size == 0 {
@@ -126,7 +129,7 @@ func (t *imageType) BootMode() distro.BootMode {
 }
 
 func (t *imageType) getPartitionTable(
-	mountpoints []blueprint.FilesystemCustomization,
+	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
 	rng *rand.Rand,
 ) (*disk.PartitionTable, error) {
@@ -139,7 +142,15 @@ func (t *imageType) getPartitionTable(
 
 	lvmify := !t.rpmOstree
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, lvmify, t.requiredPartitionSizes, rng)
+	if partitioning := customizations.GetPartitioning(); partitioning != nil {
+		customPartitionTable, err := disk.NewCustomPartitionTable(partitioning, &basePartitionTable)
+		if err != nil {
+			return nil, err
+		}
+		return disk.NewPartitionTable(customPartitionTable, nil, imageSize, false, t.requiredPartitionSizes, rng)
+	}
+
+	return disk.NewPartitionTable(&basePartitionTable, customizations.GetFilesystems(), imageSize, lvmify, t.requiredPartitionSizes, rng)
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
@@ -201,7 +212,8 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.ExcludePackages,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -226,6 +238,7 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	}
 	mf := manifest.New()
 	mf.Distro = manifest.DISTRO_FEDORA
+	mf.SourceEpoch = options.Reproducible.SourceEpoch()
 	_, err = img.InstantiateManifest(&mf, repos, t.arch.distro.runner, rng)
 	if err != nil {
 		return nil, nil, err
@@ -240,6 +253,23 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 
 	customizations := bp.Customizations
 
+	if err := bp.ValidatePackageSelection(); err != nil {
+		return nil, err
+	}
+
+	if options.VHD != nil {
+		if t.platform.GetImageFormat() != platform.FORMAT_VHD {
+			return nil, fmt.Errorf("VHD options are not supported for %s on %s", t.name, t.arch.distro.name)
+		}
+		if err := options.VHD.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.workload != nil && (len(bp.PackagePins) > 0 || len(bp.ExcludePackages) > 0) {
+		return nil, fmt.Errorf("image type %q does not support package pins and excludes", t.name)
+	}
+
 	// we do not support embedding containers on ostree-derived images, only on commits themselves
 	if len(bp.Containers) > 0 && t.rpmOstree && (t.name != "iot-commit" && t.name != "iot-container") {
 		return nil, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
@@ -273,7 +303,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 	// TODO: Support kernel name selection for image-installer
 	if t.bootISO {
 		if t.name == "iot-installer" || t.name == "image-installer" {
-			allowed := []string{"User", "Group"}
+			allowed := []string{"User", "Group", "Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return nil, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -300,6 +330,21 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, err
 	}
 
+	if partitioning := customizations.GetPartitioning(); partitioning != nil {
+		if t.rpmOstree {
+			return nil, fmt.Errorf("Custom partitioning is not supported for ostree types")
+		}
+		if mountpoints != nil {
+			return nil, fmt.Errorf("Custom mountpoints and partitioning customizations cannot be used together")
+		}
+		if err := partitioning.Validate(); err != nil {
+			return nil, err
+		}
+		if err := blueprint.CheckPartitioningPolicy(partitioning, pathpolicy.MountpointPolicies); err != nil {
+			return nil, err
+		}
+	}
+
 	if osc := customizations.GetOpenSCAP(); osc != nil {
 		supported := oscap.IsProfileAllowed(osc.ProfileID, oscapProfileAllowList)
 		if !supported {
@@ -338,5 +383,66 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, err
 	}
 
+	// check if the network customization is valid
+	_, err = customizations.GetNetwork()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if the kernel tuning customizations are valid
+	if _, err := customizations.GetSysctl(); err != nil {
+		return nil, err
+	}
+	if _, err := customizations.GetKernelModules(); err != nil {
+		return nil, err
+	}
+	if _, err := customizations.GetDracut(); err != nil {
+		return nil, err
+	}
+	if _, err := customizations.GetTuned(); err != nil {
+		return nil, err
+	}
+
+	// check if the systemd customization is valid
+	if _, err := customizations.GetSystemd(); err != nil {
+		return nil, err
+	}
+
+	// check if the cloud-init customization is valid and the modules can
+	// be disabled
+	cloudInit, err := customizations.GetCloudInit()
+	if err != nil {
+		return nil, err
+	}
+	if err := cloudInit.ValidateDisabledModules(t.getDefaultImageConfig().CloudInitModules); err != nil {
+		return nil, err
+	}
+
+	// check if the SELinux customization is valid
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return nil, err
+	}
+	if selinux != nil && selinux.HasPolicyModifications() {
+		return nil, fmt.Errorf("SELinux boolean, file context and port customizations are not supported for %s on %s: osbuild has no stage that modifies the SELinux policy", t.name, t.arch.distro.name)
+	}
+
+	// check if the accounts customization is valid
+	accounts, err := customizations.GetAccounts()
+	if err != nil {
+		return nil, err
+	}
+	if t.bootISO && accounts != nil && accounts.Root != nil {
+		return nil, fmt.Errorf("root account customizations are not supported for installer image type %q", t.name)
+	}
+
+	installer, err := customizations.GetInstaller()
+	if err != nil {
+		return nil, err
+	}
+	if installer != nil && t.name != "image-installer" && t.name != "iot-installer" {
+		return nil, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
 	return nil, nil
 }
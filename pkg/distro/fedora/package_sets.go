@@ -50,6 +50,24 @@ func vhdCommonPackageSet(t *imageType) rpmmd.PackageSet {
 	}
 }
 
+func vhdxCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"@Fedora Cloud Server",
+			"chrony",
+			"hyperv-daemons",
+			"langpacks-en",
+		},
+		Exclude: []string{
+			"dracut-config-rescue",
+			"firewalld",
+			"geolite2-city",
+			"geolite2-country",
+			"plymouth",
+		},
+	}
+}
+
 func vmdkCommonPackageSet(t *imageType) rpmmd.PackageSet {
 	return rpmmd.PackageSet{
 		Include: []string{
@@ -1,67 +1,98 @@
 package distro
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 
 	"github.com/osbuild/images/internal/shell"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/subscription"
 )
 
 // ImageConfig represents a (default) configuration applied to the image
 type ImageConfig struct {
-	Timezone            *string
-	TimeSynchronization *osbuild.ChronyStageOptions
-	Locale              *string
-	Keyboard            *osbuild.KeymapStageOptions
-	EnabledServices     []string
-	DisabledServices    []string
-	DefaultTarget       *string
-	Sysconfig           []*osbuild.SysconfigStageOptions
+	Timezone            *string                          `json:"timezone,omitempty"`
+	TimeSynchronization *osbuild.ChronyStageOptions      `json:"time_synchronization,omitempty"`
+	Locale              *string                          `json:"locale,omitempty"`
+	Keyboard            *osbuild.KeymapStageOptions      `json:"keyboard,omitempty"`
+	EnabledServices     []string                         `json:"enabled_services,omitempty"`
+	DisabledServices    []string                         `json:"disabled_services,omitempty"`
+	DefaultTarget       *string                          `json:"default_target,omitempty"`
+	Sysconfig           []*osbuild.SysconfigStageOptions `json:"sysconfig,omitempty"`
 
 	// List of files from which to import GPG keys into the RPM database
-	GPGKeyFiles []string
+	GPGKeyFiles []string `json:"gpg_key_files,omitempty"`
 
 	// Disable SELinux labelling
-	NoSElinux *bool
+	NoSElinux *bool `json:"no_selinux,omitempty"`
 
 	// Do not use. Forces auto-relabelling on first boot.
 	// See https://github.com/osbuild/osbuild/commit/52cb27631b587c1df177cd17625c5b473e1e85d2
-	SELinuxForceRelabel *bool
+	SELinuxForceRelabel *bool `json:"selinux_force_relabel,omitempty"`
 
 	// Disable documentation
-	ExcludeDocs *bool
+	ExcludeDocs *bool `json:"exclude_docs,omitempty"`
 
-	ShellInit []shell.InitFile
+	ShellInit []shell.InitFile `json:"shell_init,omitempty"`
 
 	// for RHSM configuration, we need to potentially distinguish the case
 	// when the user want the image to be subscribed on first boot and when not
-	RHSMConfig          map[subscription.RHSMStatus]*osbuild.RHSMStageOptions
-	SystemdLogind       []*osbuild.SystemdLogindStageOptions
-	CloudInit           []*osbuild.CloudInitStageOptions
-	Modprobe            []*osbuild.ModprobeStageOptions
-	DracutConf          []*osbuild.DracutConfStageOptions
-	SystemdUnit         []*osbuild.SystemdUnitStageOptions
-	Authselect          *osbuild.AuthselectStageOptions
-	SELinuxConfig       *osbuild.SELinuxConfigStageOptions
-	Tuned               *osbuild.TunedStageOptions
-	Tmpfilesd           []*osbuild.TmpfilesdStageOptions
-	PamLimitsConf       []*osbuild.PamLimitsConfStageOptions
-	Sysctld             []*osbuild.SysctldStageOptions
-	DNFConfig           []*osbuild.DNFConfigStageOptions
-	SshdConfig          *osbuild.SshdConfigStageOptions
-	Authconfig          *osbuild.AuthconfigStageOptions
-	PwQuality           *osbuild.PwqualityConfStageOptions
-	WAAgentConfig       *osbuild.WAAgentConfStageOptions
-	Grub2Config         *osbuild.GRUB2Config
-	DNFAutomaticConfig  *osbuild.DNFAutomaticConfigStageOptions
-	YumConfig           *osbuild.YumConfigStageOptions
-	YUMRepos            []*osbuild.YumReposStageOptions
-	Firewall            *osbuild.FirewallStageOptions
-	UdevRules           *osbuild.UdevRulesStageOptions
-	GCPGuestAgentConfig *osbuild.GcpGuestAgentConfigOptions
-	WSLConfig           *osbuild.WSLConfStageOptions
+	RHSMConfig          map[subscription.RHSMStatus]*osbuild.RHSMStageOptions `json:"rhsm_config,omitempty"`
+	SystemdLogind       []*osbuild.SystemdLogindStageOptions                  `json:"systemd_logind,omitempty"`
+	CloudInit           []*osbuild.CloudInitStageOptions                      `json:"cloud_init,omitempty"`
+	Modprobe            []*osbuild.ModprobeStageOptions                       `json:"modprobe,omitempty"`
+	DracutConf          []*osbuild.DracutConfStageOptions                     `json:"dracut_conf,omitempty"`
+	SystemdUnit         []*osbuild.SystemdUnitStageOptions                    `json:"systemd_unit,omitempty"`
+	Authselect          *osbuild.AuthselectStageOptions                       `json:"authselect,omitempty"`
+	SELinuxConfig       *osbuild.SELinuxConfigStageOptions                    `json:"selinux_config,omitempty"`
+	Tuned               *osbuild.TunedStageOptions                            `json:"tuned,omitempty"`
+	Tmpfilesd           []*osbuild.TmpfilesdStageOptions                      `json:"tmpfilesd,omitempty"`
+	PamLimitsConf       []*osbuild.PamLimitsConfStageOptions                  `json:"pam_limits_conf,omitempty"`
+	Sysctld             []*osbuild.SysctldStageOptions                        `json:"sysctld,omitempty"`
+	DNFConfig           []*osbuild.DNFConfigStageOptions                      `json:"dnf_config,omitempty"`
+	SshdConfig          *osbuild.SshdConfigStageOptions                       `json:"sshd_config,omitempty"`
+	Authconfig          *osbuild.AuthconfigStageOptions                       `json:"authconfig,omitempty"`
+	PwQuality           *osbuild.PwqualityConfStageOptions                    `json:"pw_quality,omitempty"`
+	WAAgentConfig       *osbuild.WAAgentConfStageOptions                      `json:"waagent_config,omitempty"`
+	Grub2Config         *osbuild.GRUB2Config                                  `json:"grub2_config,omitempty"`
+	DNFAutomaticConfig  *osbuild.DNFAutomaticConfigStageOptions               `json:"dnf_automatic_config,omitempty"`
+	YumConfig           *osbuild.YumConfigStageOptions                        `json:"yum_config,omitempty"`
+	YUMRepos            []*osbuild.YumReposStageOptions                       `json:"yum_repos,omitempty"`
+	Firewall            *osbuild.FirewallStageOptions                         `json:"firewall,omitempty"`
+	UdevRules           *osbuild.UdevRulesStageOptions                        `json:"udev_rules,omitempty"`
+	GCPGuestAgentConfig *osbuild.GcpGuestAgentConfigOptions                   `json:"gcp_guest_agent_config,omitempty"`
+	WSLConfig           *osbuild.WSLConfStageOptions                          `json:"wsl_config,omitempty"`
+
+	// Module lists of /etc/cloud/cloud.cfg of the cloud-init package, which
+	// the modules disabled by the blueprint are removed from
+	CloudInitModules *blueprint.CloudInitModules `json:"cloud_init_modules,omitempty"`
+}
+
+// UnmarshalYAML reads an image config from YAML, e.g. from a distro
+// definition. The options of the osbuild stages only have a JSON
+// representation, so the YAML is decoded with it. Unknown fields are rejected.
+func (c *ImageConfig) UnmarshalYAML(value *yaml.Node) error {
+	var generic interface{}
+	if err := value.Decode(&generic); err != nil {
+		return err
+	}
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return fmt.Errorf("line %d: image config: %w", value.Line, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("line %d: image config: %w", value.Line, err)
+	}
+	return nil
 }
 
 // InheritFrom inherits unset values from the provided parent configuration and
@@ -88,3 +119,156 @@ func (c *ImageConfig) InheritFrom(parentConfig *ImageConfig) *ImageConfig {
 	}
 	return &finalConfig
 }
+
+// Filenames of the configuration files created from blueprint customizations.
+const (
+	blueprintSysctldFilename    = "99-blueprint.conf"
+	blueprintModprobeFilename   = "blueprint-blacklist.conf"
+	blueprintDracutConfFilename = "90-blueprint.conf"
+)
+
+// MergeCustomizations returns a copy of the configuration with the blueprint
+// customizations that configure the same stages merged into it. The
+// customizations extend the configuration instead of replacing it.
+func (c *ImageConfig) MergeCustomizations(customizations *blueprint.Customizations) (*ImageConfig, error) {
+	finalConfig := ImageConfig(*c)
+
+	sysctl, err := customizations.GetSysctl()
+	if err != nil {
+		return nil, err
+	}
+	if len(sysctl) > 0 {
+		config := make([]osbuild.SysctldConfigLine, 0, len(sysctl))
+		for _, s := range sysctl {
+			config = append(config, osbuild.SysctldConfigLine{Key: s.Key, Value: s.Value})
+		}
+		// sysctl.d files are applied in lexical order, so the blueprint
+		// values override the defaults of the image type
+		finalConfig.Sysctld = append(append([]*osbuild.SysctldStageOptions{}, c.Sysctld...),
+			osbuild.NewSysctldStageOptions(blueprintSysctldFilename, config))
+	}
+
+	kernelModules, err := customizations.GetKernelModules()
+	if err != nil {
+		return nil, err
+	}
+	if kernelModules != nil && len(kernelModules.Blacklist) > 0 {
+		commands := make(osbuild.ModprobeConfigCmdList, 0, len(kernelModules.Blacklist))
+		for _, module := range kernelModules.Blacklist {
+			commands = append(commands, osbuild.NewModprobeConfigCmdBlacklist(module))
+		}
+		finalConfig.Modprobe = append(append([]*osbuild.ModprobeStageOptions{}, c.Modprobe...),
+			&osbuild.ModprobeStageOptions{Filename: blueprintModprobeFilename, Commands: commands})
+	}
+
+	dracut, err := customizations.GetDracut()
+	if err != nil {
+		return nil, err
+	}
+	if dracut != nil && (len(dracut.AddModules) > 0 || len(dracut.OmitModules) > 0 || len(dracut.AddDrivers) > 0) {
+		finalConfig.DracutConf = append(append([]*osbuild.DracutConfStageOptions{}, c.DracutConf...),
+			&osbuild.DracutConfStageOptions{
+				Filename: blueprintDracutConfFilename,
+				Config: osbuild.DracutConfigFile{
+					AddModules:  dracut.AddModules,
+					OmitModules: dracut.OmitModules,
+					AddDrivers:  dracut.AddDrivers,
+				},
+			})
+	}
+
+	tuned, err := customizations.GetTuned()
+	if err != nil {
+		return nil, err
+	}
+	if tuned != nil {
+		var profiles []string
+		if c.Tuned != nil {
+			profiles = append(profiles, c.Tuned.Profiles...)
+		}
+		seen := make(map[string]bool, len(profiles))
+		for _, profile := range profiles {
+			seen[profile] = true
+		}
+		for _, profile := range tuned.Profiles {
+			if !seen[profile] {
+				profiles = append(profiles, profile)
+				seen[profile] = true
+			}
+		}
+		finalConfig.Tuned = osbuild.NewTunedStageOptions(profiles...)
+	}
+
+	systemd, err := customizations.GetSystemd()
+	if err != nil {
+		return nil, err
+	}
+	if systemd != nil {
+		if systemd.DefaultTarget != "" {
+			finalConfig.DefaultTarget = &systemd.DefaultTarget
+		}
+		if enabled := systemd.EnabledUnits(); len(enabled) > 0 {
+			finalConfig.EnabledServices = append(append([]string{}, c.EnabledServices...), enabled...)
+		}
+		if len(systemd.Dropins) > 0 {
+			finalConfig.SystemdUnit = append([]*osbuild.SystemdUnitStageOptions{}, c.SystemdUnit...)
+			for _, dropin := range systemd.Dropins {
+				finalConfig.SystemdUnit = append(finalConfig.SystemdUnit, &osbuild.SystemdUnitStageOptions{
+					Unit:   dropin.Unit,
+					Dropin: dropin.Filename,
+					Config: systemdUnitDropinFromCustomization(dropin.Config),
+				})
+			}
+		}
+	}
+
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return nil, err
+	}
+	if selinux != nil && selinux.Mode != "" {
+		selinuxConfig := osbuild.SELinuxConfigStageOptions{}
+		if c.SELinuxConfig != nil {
+			selinuxConfig = *c.SELinuxConfig
+		}
+		selinuxConfig.State = osbuild.SELinuxPolicyState(selinux.Mode)
+		finalConfig.SELinuxConfig = &selinuxConfig
+	}
+
+	return &finalConfig, nil
+}
+
+func systemdUnitDropinFromCustomization(config blueprint.SystemdUnitConfigCustomization) osbuild.SystemdServiceUnitDropin {
+	var dropin osbuild.SystemdServiceUnitDropin
+	if s := config.Unit; s != nil {
+		dropin.Unit = &osbuild.SystemdUnitSection{
+			Description:              s.Description,
+			DefaultDependencies:      s.DefaultDependencies,
+			ConditionPathExists:      s.ConditionPathExists,
+			ConditionPathIsDirectory: s.ConditionPathIsDirectory,
+			Requires:                 s.Requires,
+			Wants:                    s.Wants,
+			After:                    s.After,
+			Before:                   s.Before,
+		}
+	}
+	if s := config.Service; s != nil {
+		dropin.Service = &osbuild.SystemdUnitServiceSection{
+			Type:            s.Type,
+			RemainAfterExit: s.RemainAfterExit,
+			ExecStartPre:    s.ExecStartPre,
+			ExecStart:       s.ExecStart,
+			ExecStopPost:    s.ExecStopPost,
+			// the assignments are validated to not contain spaces
+			Environment:     strings.Join(s.Environment, " "),
+			EnvironmentFile: s.EnvironmentFile,
+		}
+	}
+	if s := config.Install; s != nil {
+		dropin.Install = &osbuild.SystemdUnitInstallSection{
+			WantedBy:   s.WantedBy,
+			RequiredBy: s.RequiredBy,
+		}
+	}
+	return dropin
+}
@@ -4,8 +4,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 )
 
@@ -216,3 +219,186 @@ func TestImageConfigInheritFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestImageConfigMergeCustomizations(t *testing.T) {
+	defaultSysctld := osbuild.NewSysctldStageOptions("10-default.conf", []osbuild.SysctldConfigLine{{Key: "vm.swappiness", Value: "10"}})
+	defaultModprobe := &osbuild.ModprobeStageOptions{
+		Filename: "blacklist-floppy.conf",
+		Commands: osbuild.ModprobeConfigCmdList{osbuild.NewModprobeConfigCmdBlacklist("floppy")},
+	}
+	defaultDracutConf := &osbuild.DracutConfStageOptions{
+		Filename: "sgdisk.conf",
+		Config:   osbuild.DracutConfigFile{Install: []string{"sgdisk"}},
+	}
+	config := &ImageConfig{
+		Sysctld:    []*osbuild.SysctldStageOptions{defaultSysctld},
+		Modprobe:   []*osbuild.ModprobeStageOptions{defaultModprobe},
+		DracutConf: []*osbuild.DracutConfStageOptions{defaultDracutConf},
+		Tuned:      osbuild.NewTunedStageOptions("virtual-guest"),
+	}
+
+	merged, err := config.MergeCustomizations(&blueprint.Customizations{
+		Sysctl: []blueprint.SysctlCustomization{{Key: "net.ipv4.ip_forward", Value: "1"}},
+		KernelModules: &blueprint.KernelModulesCustomization{
+			Blacklist: []string{"nouveau"},
+		},
+		Dracut: &blueprint.DracutCustomization{AddModules: []string{"fips"}, OmitModules: []string{"plymouth"}},
+		Tuned:  &blueprint.TunedCustomization{Profiles: []string{"virtual-guest", "throughput-performance"}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*osbuild.SysctldStageOptions{
+		defaultSysctld,
+		osbuild.NewSysctldStageOptions("99-blueprint.conf", []osbuild.SysctldConfigLine{{Key: "net.ipv4.ip_forward", Value: "1"}}),
+	}, merged.Sysctld)
+	assert.Equal(t, []*osbuild.ModprobeStageOptions{
+		defaultModprobe,
+		{
+			Filename: "blueprint-blacklist.conf",
+			Commands: osbuild.ModprobeConfigCmdList{osbuild.NewModprobeConfigCmdBlacklist("nouveau")},
+		},
+	}, merged.Modprobe)
+	assert.Equal(t, []*osbuild.DracutConfStageOptions{
+		defaultDracutConf,
+		{
+			Filename: "90-blueprint.conf",
+			Config:   osbuild.DracutConfigFile{AddModules: []string{"fips"}, OmitModules: []string{"plymouth"}},
+		},
+	}, merged.DracutConf)
+	assert.Equal(t, osbuild.NewTunedStageOptions("virtual-guest", "throughput-performance"), merged.Tuned)
+
+	// the original configuration is not modified
+	assert.Len(t, config.Sysctld, 1)
+	assert.Len(t, config.Modprobe, 1)
+	assert.Len(t, config.DracutConf, 1)
+	assert.Equal(t, []string{"virtual-guest"}, config.Tuned.Profiles)
+}
+
+func TestImageConfigMergeCustomizationsEmpty(t *testing.T) {
+	config := &ImageConfig{Tuned: osbuild.NewTunedStageOptions("virtual-guest")}
+
+	merged, err := config.MergeCustomizations(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, config, merged)
+
+	merged, err = (&ImageConfig{}).MergeCustomizations(&blueprint.Customizations{
+		Tuned: &blueprint.TunedCustomization{Profiles: []string{"balanced"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, osbuild.NewTunedStageOptions("balanced"), merged.Tuned)
+}
+
+func TestImageConfigMergeCustomizationsInvalid(t *testing.T) {
+	_, err := (&ImageConfig{}).MergeCustomizations(&blueprint.Customizations{
+		Sysctl: []blueprint.SysctlCustomization{{Key: "kernel.panic"}},
+	})
+	assert.EqualError(t, err, `sysctl "kernel.panic": value is required`)
+}
+
+func TestImageConfigMergeSystemdCustomization(t *testing.T) {
+	defaultDropin := &osbuild.SystemdUnitStageOptions{
+		Unit:   "nm-cloud-setup.service",
+		Dropin: "10-rh-enable-for-ec2.conf",
+		Config: osbuild.SystemdServiceUnitDropin{
+			Service: &osbuild.SystemdUnitServiceSection{Environment: "NM_CLOUD_SETUP_EC2=yes"},
+		},
+	}
+	config := &ImageConfig{
+		EnabledServices: []string{"sshd"},
+		DefaultTarget:   common.ToPtr("graphical.target"),
+		SystemdUnit:     []*osbuild.SystemdUnitStageOptions{defaultDropin},
+	}
+
+	merged, err := config.MergeCustomizations(&blueprint.Customizations{
+		Systemd: &blueprint.SystemdCustomization{
+			DefaultTarget: "multi-user.target",
+			Units: []blueprint.SystemdUnitCustomization{
+				{
+					Name: "cleanup.timer",
+					Config: blueprint.SystemdUnitConfigCustomization{
+						Timer:   &blueprint.SystemdTimerSectionCustomization{OnBootSec: "15min"},
+						Install: &blueprint.SystemdInstallSectionCustomization{WantedBy: []string{"timers.target"}},
+					},
+				},
+			},
+			Dropins: []blueprint.SystemdDropinCustomization{
+				{
+					Unit:     "sshd.service",
+					Filename: "10-options.conf",
+					Config: blueprint.SystemdUnitConfigCustomization{
+						Unit:    &blueprint.SystemdUnitSectionCustomization{After: []string{"network-online.target"}},
+						Service: &blueprint.SystemdServiceSectionCustomization{Environment: []string{"OPTIONS=-4", "DEBUG=1"}},
+						Install: &blueprint.SystemdInstallSectionCustomization{WantedBy: []string{"multi-user.target"}},
+					},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "multi-user.target", *merged.DefaultTarget)
+	assert.Equal(t, []string{"sshd", "cleanup.timer"}, merged.EnabledServices)
+	assert.Equal(t, []*osbuild.SystemdUnitStageOptions{
+		defaultDropin,
+		{
+			Unit:   "sshd.service",
+			Dropin: "10-options.conf",
+			Config: osbuild.SystemdServiceUnitDropin{
+				Unit:    &osbuild.SystemdUnitSection{After: []string{"network-online.target"}},
+				Service: &osbuild.SystemdUnitServiceSection{Environment: "OPTIONS=-4 DEBUG=1"},
+				Install: &osbuild.SystemdUnitInstallSection{WantedBy: []string{"multi-user.target"}},
+			},
+		},
+	}, merged.SystemdUnit)
+
+	// the original configuration is not modified
+	assert.Equal(t, "graphical.target", *config.DefaultTarget)
+	assert.Equal(t, []string{"sshd"}, config.EnabledServices)
+	assert.Len(t, config.SystemdUnit, 1)
+}
+
+func TestImageConfigMergeSELinuxCustomization(t *testing.T) {
+	config := &ImageConfig{
+		SELinuxConfig: &osbuild.SELinuxConfigStageOptions{
+			State: osbuild.SELinuxStateEnforcing,
+			Type:  osbuild.SELinuxTypeTargeted,
+		},
+	}
+
+	merged, err := config.MergeCustomizations(&blueprint.Customizations{
+		SELinux: &blueprint.SELinuxCustomization{
+			Mode:     "permissive",
+			Booleans: []blueprint.SELinuxBooleanCustomization{{Name: "httpd_can_network_connect", Value: true}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &osbuild.SELinuxConfigStageOptions{
+		State: osbuild.SELinuxStatePermissive,
+		Type:  osbuild.SELinuxTypeTargeted,
+	}, merged.SELinuxConfig)
+
+	// the original configuration is not modified
+	assert.Equal(t, osbuild.SELinuxStateEnforcing, config.SELinuxConfig.State)
+
+	// the state is set without a base configuration
+	merged, err = (&ImageConfig{}).MergeCustomizations(&blueprint.Customizations{
+		SELinux: &blueprint.SELinuxCustomization{Mode: "disabled"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &osbuild.SELinuxConfigStageOptions{State: osbuild.SELinuxStateDisabled}, merged.SELinuxConfig)
+}
+
+func TestImageConfigUnmarshalYAML(t *testing.T) {
+	doc := `
+timezone: UTC
+enabled_services: [sshd]
+keyboard: {keymap: us}
+`
+	var config ImageConfig
+	require.NoError(t, yaml.Unmarshal([]byte(doc), &config))
+	assert.Equal(t, "UTC", *config.Timezone)
+	assert.Equal(t, []string{"sshd"}, config.EnabledServices)
+	assert.Equal(t, "us", config.Keyboard.Keymap)
+
+	err := yaml.Unmarshal([]byte("locale: C.UTF-8\ntimezon: UTC\n"), &config)
+	assert.EqualError(t, err, `line 1: image config: json: unknown field "timezon"`)
+}
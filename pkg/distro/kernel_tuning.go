@@ -0,0 +1,35 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// addKernelTuningFiles adds the modprobe.d file with the options of the
+// kernel modules customization to the OS customizations, and tuned when the
+// blueprint selects tuned profiles.
+func addKernelTuningFiles(osc *manifest.OSCustomizations, c *blueprint.Customizations) error {
+	kernelModules, err := c.GetKernelModules()
+	if err != nil {
+		return fmt.Errorf("failed to get kernel modules customization: %w", err)
+	}
+	modprobeOptionsFile, err := blueprint.KernelModuleOptionsToFsNodeFile(kernelModules)
+	if err != nil {
+		return fmt.Errorf("failed to convert kernel module options to fs node file: %w", err)
+	}
+	if modprobeOptionsFile != nil {
+		osc.Files = append(osc.Files, modprobeOptionsFile)
+	}
+
+	tuned, err := c.GetTuned()
+	if err != nil {
+		return fmt.Errorf("failed to get tuned customization: %w", err)
+	}
+	if tuned != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "tuned")
+	}
+
+	return nil
+}
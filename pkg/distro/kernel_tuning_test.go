@@ -0,0 +1,29 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+func TestAddKernelTuningFiles(t *testing.T) {
+	osc := manifest.OSCustomizations{}
+	require.NoError(t, addKernelTuningFiles(&osc, &blueprint.Customizations{}))
+	assert.Empty(t, osc.Files)
+	assert.Empty(t, osc.ExtraBasePackages)
+
+	c := &blueprint.Customizations{
+		KernelModules: &blueprint.KernelModulesCustomization{
+			Options: []blueprint.KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+		},
+		Tuned: &blueprint.TunedCustomization{Profiles: []string{"virtual-guest"}},
+	}
+	require.NoError(t, addKernelTuningFiles(&osc, c))
+	require.Len(t, osc.Files, 1)
+	assert.Equal(t, blueprint.KernelModuleOptionsFile, osc.Files[0].Path())
+	assert.Equal(t, []string{"tuned"}, osc.ExtraBasePackages)
+}
@@ -0,0 +1,79 @@
+package distro
+
+import (
+	"strings"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// NewKickstartInstallOptions converts the installer customization of a
+// blueprint into the kickstart commands of an unattended installation. It
+// returns nil if there is nothing to configure.
+func NewKickstartInstallOptions(c *blueprint.Customizations) (*osbuild.KickstartInstallOptions, error) {
+	installer, err := c.GetInstaller()
+	if err != nil {
+		return nil, err
+	}
+	if installer == nil {
+		return nil, nil
+	}
+
+	options := &osbuild.KickstartInstallOptions{
+		Timezone: installer.Timezone,
+		ZeroMBR:  installer.ZeroMBR,
+	}
+
+	if len(installer.TargetDisks) > 0 {
+		options.IgnoreDisk = &osbuild.KickstartIgnoreDisk{
+			OnlyUse: installer.TargetDisks,
+		}
+	}
+
+	if cp := installer.ClearPart; cp != nil {
+		options.ClearPart = &osbuild.KickstartClearPart{
+			All:       cp.Mode == "all",
+			Linux:     cp.Mode == "linux",
+			Drives:    cp.Drives,
+			InitLabel: cp.InitLabel,
+		}
+	}
+
+	if ap := installer.AutoPart; ap != nil {
+		options.AutoPart = &osbuild.KickstartAutoPart{
+			Type:   ap.Type,
+			FSType: ap.FSType,
+			NoHome: ap.NoHome,
+		}
+	}
+
+	for _, n := range installer.Network {
+		options.Network = append(options.Network, osbuild.KickstartNetwork{
+			Device:      n.Device,
+			Activate:    n.Activate,
+			BootProto:   n.GetBootProto(),
+			IP:          n.IP,
+			Netmask:     n.Netmask,
+			Gateway:     n.Gateway,
+			Nameservers: n.Nameservers,
+			Hostname:    n.Hostname,
+		})
+	}
+
+	if installer.Reboot {
+		options.Reboot = &osbuild.KickstartReboot{
+			Eject: installer.EjectMedia,
+		}
+	}
+
+	for _, post := range installer.Post {
+		options.Post = append(options.Post, osbuild.KickstartPost{
+			Interpreter: post.Interpreter,
+			NoChroot:    post.NoChroot,
+			ErrorOnFail: post.ErrorOnFail,
+			Commands:    strings.Split(strings.TrimRight(post.Script, "\n"), "\n"),
+		})
+	}
+
+	return options, nil
+}
@@ -0,0 +1,57 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+func TestNewKickstartInstallOptions(t *testing.T) {
+	options, err := NewKickstartInstallOptions(&blueprint.Customizations{})
+	require.NoError(t, err)
+	assert.Nil(t, options)
+
+	installer := &blueprint.InstallerCustomization{
+		TargetDisks: []string{"sda"},
+		ZeroMBR:     true,
+		ClearPart:   &blueprint.InstallerClearPartCustomization{Mode: "linux", Drives: []string{"sda"}},
+		AutoPart:    &blueprint.InstallerAutoPartCustomization{Type: "plain", FSType: "xfs", NoHome: true},
+		Network: []blueprint.InstallerNetworkCustomization{
+			{Device: "link", Activate: true},
+			{Device: "eth1", BootProto: "static", IP: "192.168.1.10", Netmask: "255.255.255.0", Gateway: "192.168.1.1", Nameservers: []string{"192.168.1.1"}},
+		},
+		Timezone:   "Europe/Berlin",
+		Reboot:     true,
+		EjectMedia: true,
+		Post: []blueprint.InstallerPostScriptCustomization{
+			{Script: "echo done > /root/installed\nsystemctl enable cockpit.socket\n", ErrorOnFail: true},
+		},
+	}
+
+	expected := &osbuild.KickstartInstallOptions{
+		Timezone:   "Europe/Berlin",
+		ZeroMBR:    true,
+		ClearPart:  &osbuild.KickstartClearPart{Linux: true, Drives: []string{"sda"}},
+		AutoPart:   &osbuild.KickstartAutoPart{Type: "plain", FSType: "xfs", NoHome: true},
+		IgnoreDisk: &osbuild.KickstartIgnoreDisk{OnlyUse: []string{"sda"}},
+		Network: []osbuild.KickstartNetwork{
+			{Device: "link", Activate: true, BootProto: "dhcp"},
+			{Device: "eth1", BootProto: "static", IP: "192.168.1.10", Netmask: "255.255.255.0", Gateway: "192.168.1.1", Nameservers: []string{"192.168.1.1"}},
+		},
+		Reboot: &osbuild.KickstartReboot{Eject: true},
+		Post: []osbuild.KickstartPost{
+			{ErrorOnFail: true, Commands: []string{"echo done > /root/installed", "systemctl enable cockpit.socket"}},
+		},
+	}
+	options, err = NewKickstartInstallOptions(&blueprint.Customizations{Installer: installer})
+	require.NoError(t, err)
+	assert.Equal(t, expected, options)
+
+	installer.Post = []blueprint.InstallerPostScriptCustomization{{Script: "true\n%end\n"}}
+	_, err = NewKickstartInstallOptions(&blueprint.Customizations{Installer: installer})
+	assert.EqualError(t, err, "installer post script #1: script must not contain %end")
+}
@@ -0,0 +1,28 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// addNetworkFiles adds the NetworkManager keyfiles of the network
+// customization to the OS customizations, together with NetworkManager, which
+// owns the connection profiles directory.
+func addNetworkFiles(osc *manifest.OSCustomizations, c *blueprint.Customizations) error {
+	network, err := c.GetNetwork()
+	if err != nil {
+		return fmt.Errorf("failed to get network customization: %w", err)
+	}
+	networkFiles, err := blueprint.NetworkCustomizationToFsNodeFiles(network)
+	if err != nil {
+		return fmt.Errorf("failed to convert network customization to fs node files: %w", err)
+	}
+	if len(networkFiles) > 0 {
+		osc.Files = append(osc.Files, networkFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "NetworkManager")
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+func TestAddNetworkFiles(t *testing.T) {
+	osc := manifest.OSCustomizations{}
+	require.NoError(t, addNetworkFiles(&osc, &blueprint.Customizations{}))
+	assert.Empty(t, osc.Files)
+	assert.Empty(t, osc.ExtraBasePackages)
+
+	c := &blueprint.Customizations{
+		Network: &blueprint.NetworkCustomization{
+			Connections: []blueprint.NetworkConnectionCustomization{{Name: "uplink", Interface: "eth0"}},
+		},
+	}
+	require.NoError(t, addNetworkFiles(&osc, c))
+	require.Len(t, osc.Files, 1)
+	assert.Equal(t, "/etc/NetworkManager/system-connections/uplink.nmconnection", osc.Files[0].Path())
+	assert.Equal(t, []string{"NetworkManager"}, osc.ExtraBasePackages)
+}
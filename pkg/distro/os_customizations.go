@@ -0,0 +1,239 @@
+package distro
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// OSCustomizationsOptions hold the properties of an image type that affect
+// the OS customizations created by NewOSCustomizations.
+type OSCustomizationsOptions struct {
+	// Set up the kernel and its command line
+	Bootable bool
+
+	// Kernel command line arguments of the image type, appended before the
+	// ones of the blueprint
+	KernelOptions string
+
+	// Don't create users and groups, e.g. in the payload of an installer
+	// that adds them via kickstart instead
+	NoUsers bool
+}
+
+// NewOSCustomizations creates the OS customizations shared by the rhel9 image
+// types and the image types of definitions from the image config, which must
+// already have the blueprint customizations merged into it, and the blueprint
+// customizations. Distro specific customizations are left for the caller to
+// add.
+func NewOSCustomizations(
+	imageConfig *ImageConfig,
+	osPackageSet rpmmd.PackageSet,
+	options ImageOptions,
+	containers []container.SourceSpec,
+	c *blueprint.Customizations,
+	opts OSCustomizationsOptions,
+) (manifest.OSCustomizations, error) {
+	osc := manifest.OSCustomizations{}
+
+	if opts.Bootable {
+		osc.KernelName = c.GetKernel().Name
+
+		var kernelOptions []string
+		if opts.KernelOptions != "" {
+			kernelOptions = append(kernelOptions, opts.KernelOptions)
+		}
+		if bpKernel := c.GetKernel(); bpKernel.Append != "" {
+			kernelOptions = append(kernelOptions, bpKernel.Append)
+		}
+		osc.KernelOptionsAppend = kernelOptions
+	}
+
+	osc.ExtraBasePackages = osPackageSet.Include
+	osc.ExcludeBasePackages = osPackageSet.Exclude
+	osc.ExtraBaseRepos = osPackageSet.Repositories
+
+	osc.Containers = containers
+
+	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
+	if imageConfig.ExcludeDocs != nil {
+		osc.ExcludeDocs = *imageConfig.ExcludeDocs
+	}
+
+	osc.EnabledServices = imageConfig.EnabledServices
+	osc.DisabledServices = imageConfig.DisabledServices
+	if imageConfig.DefaultTarget != nil {
+		osc.DefaultTarget = *imageConfig.DefaultTarget
+	}
+
+	osc.Firewall = imageConfig.Firewall
+	if fw := c.GetFirewall(); fw != nil {
+		options := osbuild.FirewallStageOptions{
+			Ports: fw.Ports,
+		}
+
+		if fw.Services != nil {
+			options.EnabledServices = fw.Services.Enabled
+			options.DisabledServices = fw.Services.Disabled
+		}
+		if fw.Zones != nil {
+			for _, z := range fw.Zones {
+				options.Zones = append(options.Zones, osbuild.FirewallZone{
+					Name:    *z.Name,
+					Sources: z.Sources,
+				})
+			}
+		}
+		osc.Firewall = &options
+	}
+
+	language, keyboard := c.GetPrimaryLocale()
+	if language != nil {
+		osc.Language = *language
+	} else if imageConfig.Locale != nil {
+		osc.Language = *imageConfig.Locale
+	}
+	if keyboard != nil {
+		osc.Keyboard = keyboard
+	} else if imageConfig.Keyboard != nil {
+		osc.Keyboard = &imageConfig.Keyboard.Keymap
+		if imageConfig.Keyboard.X11Keymap != nil {
+			osc.X11KeymapLayouts = imageConfig.Keyboard.X11Keymap.Layouts
+		}
+	}
+
+	if hostname := c.GetHostname(); hostname != nil {
+		osc.Hostname = *hostname
+	}
+
+	timezone, ntpServers := c.GetTimezoneSettings()
+	if timezone != nil {
+		osc.Timezone = *timezone
+	} else if imageConfig.Timezone != nil {
+		osc.Timezone = *imageConfig.Timezone
+	}
+
+	if len(ntpServers) > 0 {
+		for _, server := range ntpServers {
+			osc.NTPServers = append(osc.NTPServers, osbuild.ChronyConfigServer{Hostname: server})
+		}
+	} else if imageConfig.TimeSynchronization != nil {
+		osc.NTPServers = imageConfig.TimeSynchronization.Servers
+		osc.LeapSecTZ = imageConfig.TimeSynchronization.LeapsecTz
+	}
+
+	// Relabel the tree, unless the `NoSElinux` flag is explicitly set to `true`
+	if imageConfig.NoSElinux == nil || imageConfig.NoSElinux != nil && !*imageConfig.NoSElinux {
+		osc.SElinux = "targeted"
+	}
+
+	var err error
+	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
+	if err != nil {
+		return osc, fmt.Errorf("failed to convert directory customizations to fs node directories: %w", err)
+	}
+
+	osc.Files, err = blueprint.FileCustomizationsToFsNodeFiles(c.GetFiles())
+	if err != nil {
+		return osc, fmt.Errorf("failed to convert file customizations to fs node files: %w", err)
+	}
+
+	// set yum repos first, so it doesn't get overridden by
+	// imageConfig.YUMRepos
+	osc.YUMRepos = imageConfig.YUMRepos
+
+	customRepos, err := c.GetRepositories()
+	if err != nil {
+		return osc, fmt.Errorf("failed to get custom repos: %w", err)
+	}
+
+	// This function returns a map of filename and corresponding yum repos
+	// and a list of fs node files for the inline gpg keys so we can save
+	// them to disk. This step also swaps the inline gpg key with the path
+	// to the file in the os file tree
+	yumRepos, gpgKeyFiles, err := blueprint.RepoCustomizationsToRepoConfigAndGPGKeyFiles(customRepos)
+	if err != nil {
+		return osc, fmt.Errorf("failed to convert inline gpgkeys to fs node files: %w", err)
+	}
+
+	// add the gpg key files to the list of files to be added to the tree
+	if len(gpgKeyFiles) > 0 {
+		osc.Files = append(osc.Files, gpgKeyFiles...)
+	}
+
+	if err := AddCustomizationFiles(&osc, c, imageConfig.CloudInitModules); err != nil {
+		return osc, err
+	}
+
+	if err := AddAccounts(&osc, c, opts.NoUsers); err != nil {
+		return osc, err
+	}
+
+	// sort the repo files so that the manifest doesn't depend on the map
+	// iteration order
+	repoFiles := maps.Keys(yumRepos)
+	slices.Sort(repoFiles)
+	for _, filename := range repoFiles {
+		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, yumRepos[filename]))
+	}
+
+	osc.ShellInit = imageConfig.ShellInit
+
+	osc.Grub2Config = imageConfig.Grub2Config
+	osc.Sysconfig = imageConfig.Sysconfig
+	osc.SystemdLogind = imageConfig.SystemdLogind
+	osc.CloudInit = imageConfig.CloudInit
+	osc.Modprobe = imageConfig.Modprobe
+	osc.DracutConf = imageConfig.DracutConf
+	osc.SystemdUnit = imageConfig.SystemdUnit
+	osc.Authselect = imageConfig.Authselect
+	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	osc.Tuned = imageConfig.Tuned
+	osc.Tmpfilesd = imageConfig.Tmpfilesd
+	osc.PamLimitsConf = imageConfig.PamLimitsConf
+	osc.Sysctld = imageConfig.Sysctld
+	osc.DNFConfig = imageConfig.DNFConfig
+	osc.DNFAutomaticConfig = imageConfig.DNFAutomaticConfig
+	osc.SshdConfig = imageConfig.SshdConfig
+	osc.AuthConfig = imageConfig.Authconfig
+	osc.PwQuality = imageConfig.PwQuality
+	osc.RHSMConfig = imageConfig.RHSMConfig
+	osc.Subscription = options.Subscription
+	osc.WAAgentConfig = imageConfig.WAAgentConfig
+	osc.UdevRules = imageConfig.UdevRules
+	osc.GCPGuestAgentConfig = imageConfig.GCPGuestAgentConfig
+	osc.WSLConfig = imageConfig.WSLConfig
+
+	return osc, nil
+}
+
+// AddCustomizationFiles adds the files generated from the network, kernel
+// module, systemd and cloud-init customizations to the OS customizations,
+// together with the packages they need. The disabled cloud-init modules are
+// removed from cloudInitModules, the module lists of the image.
+func AddCustomizationFiles(osc *manifest.OSCustomizations, c *blueprint.Customizations, cloudInitModules *blueprint.CloudInitModules) error {
+	if err := addNetworkFiles(osc, c); err != nil {
+		return err
+	}
+
+	if err := addKernelTuningFiles(osc, c); err != nil {
+		return err
+	}
+
+	if err := addSystemdUnitFiles(osc, c); err != nil {
+		return err
+	}
+
+	if err := addCloudInitFiles(osc, c, cloudInitModules); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestNewOSCustomizations(t *testing.T) {
+	imageConfig := &ImageConfig{
+		Timezone:        common.ToPtr("UTC"),
+		EnabledServices: []string{"sshd"},
+		YumConfig:       &osbuild.YumConfigStageOptions{},
+	}
+	c := &blueprint.Customizations{
+		User:   []blueprint.UserCustomization{{Name: "admin"}},
+		Kernel: &blueprint.KernelCustomization{Append: "debug"},
+	}
+	pkgs := rpmmd.PackageSet{Include: []string{"kernel"}}
+
+	osc, err := NewOSCustomizations(imageConfig, pkgs, ImageOptions{}, nil, c, OSCustomizationsOptions{
+		Bootable:      true,
+		KernelOptions: "console=ttyS0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"console=ttyS0", "debug"}, osc.KernelOptionsAppend)
+	assert.Equal(t, []string{"kernel"}, osc.ExtraBasePackages)
+	assert.Equal(t, "UTC", osc.Timezone)
+	assert.Equal(t, []string{"sshd"}, osc.EnabledServices)
+	assert.Equal(t, "targeted", osc.SElinux)
+	// the yum config is only set by the image types that use it
+	assert.Nil(t, osc.YUMConfig)
+	require.Len(t, osc.Users, 1)
+	assert.Equal(t, "admin", osc.Users[0].Name)
+
+	osc, err = NewOSCustomizations(imageConfig, pkgs, ImageOptions{}, nil, c, OSCustomizationsOptions{NoUsers: true})
+	require.NoError(t, err)
+	assert.Nil(t, osc.KernelOptionsAppend)
+	assert.Empty(t, osc.Users)
+}
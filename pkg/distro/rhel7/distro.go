@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
@@ -42,6 +43,50 @@ var defaultDistroImageConfig = &distro.ImageConfig{
 			},
 		},
 	},
+	// modules of the cloud.cfg of the cloud-init package
+	CloudInitModules: &blueprint.CloudInitModules{
+		Init: []string{
+			"disk_setup",
+			"migrator",
+			"bootcmd",
+			"write-files",
+			"growpart",
+			"resizefs",
+			"set_hostname",
+			"update_hostname",
+			"update_etc_hosts",
+			"rsyslog",
+			"users-groups",
+			"ssh",
+		},
+		Config: []string{
+			"mounts",
+			"locale",
+			"set-passwords",
+			"rh_subscription",
+			"yum-add-repo",
+			"package-update-upgrade-install",
+			"timezone",
+			"puppet",
+			"chef",
+			"salt-minion",
+			"mcollective",
+			"disable-ec2-metadata",
+			"runcmd",
+		},
+		Final: []string{
+			"rightscale_userdata",
+			"scripts-per-once",
+			"scripts-per-boot",
+			"scripts-per-instance",
+			"scripts-user",
+			"ssh-authkey-fingerprints",
+			"keys-to-console",
+			"phone-home",
+			"final-message",
+			"power-state-change",
+		},
+	},
 }
 
 // distribution objects without the arches > image types
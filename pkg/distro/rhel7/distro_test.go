@@ -269,6 +269,22 @@ func TestRhel7_KernelOption(t *testing.T) {
 	distro_test_common.TestDistro_KernelOption(t, rhel7.New())
 }
 
+func TestRhel7_ReproducibleManifests(t *testing.T) {
+	distro_test_common.TestDistro_ReproducibleManifests(t, rhel7.New())
+}
+
+func TestRhel7_VHDOptions(t *testing.T) {
+	distro_test_common.TestDistro_VHDOptions(t, rhel7.New())
+}
+
+func TestRhel7_BtrfsPartitioning(t *testing.T) {
+	distro_test_common.TestDistro_BtrfsPartitioning(t, rhel7.New(), false)
+}
+
+func TestRhel7_CompressedImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_CompressedImageTypes(t, rhel7.New())
+}
+
 func TestDistro_CustomFileSystemManifestError(t *testing.T) {
 	r7distro := rhel7.New()
 	bp := blueprint.Blueprint{
@@ -450,3 +466,7 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestRhel7_CloudInitDisabledModules(t *testing.T) {
+	distro_test_common.TestDistro_CloudInitDisabledModules(t, rhel7.New())
+}
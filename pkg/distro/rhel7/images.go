@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"math/rand"
 
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
 	"github.com/osbuild/images/internal/common"
-	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
@@ -25,7 +27,12 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().MergeCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(fmt.Sprintf("failed to merge customizations into the image config: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -56,11 +63,6 @@ func osCustomizations(
 		osc.ExcludeDocs = *imageConfig.ExcludeDocs
 	}
 
-	// don't put users and groups in the payload of an installer
-	// add them via kickstart instead
-	osc.Groups = users.GroupsFromBP(c.GetGroups())
-	osc.Users = users.UsersFromBP(c.GetUsers())
-
 	osc.EnabledServices = imageConfig.EnabledServices
 	osc.DisabledServices = imageConfig.DisabledServices
 	if imageConfig.DefaultTarget != nil {
@@ -142,7 +144,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -182,8 +183,24 @@ func osCustomizations(
 		osc.Files = append(osc.Files, gpgKeyFiles...)
 	}
 
-	for filename, repos := range yumRepos {
-		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
+	if err := distro.AddCustomizationFiles(&osc, c, imageConfig.CloudInitModules); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	if err := distro.AddAccounts(&osc, c, false); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	// sort the repo files so that the manifest doesn't depend on the map
+	// iteration order
+	repoFiles := maps.Keys(yumRepos)
+	slices.Sort(repoFiles)
+	for _, filename := range repoFiles {
+		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, yumRepos[filename]))
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -236,9 +253,12 @@ func diskImage(workload workload.Workload,
 	img.OSProduct = t.arch.distro.product
 	img.OSVersion = t.arch.distro.osVersion
 	img.OSNick = t.arch.distro.nick
+	if options.VHD != nil {
+		img.VPCSubformat = options.VHD.Subformat
+	}
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
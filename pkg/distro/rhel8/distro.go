@@ -8,6 +8,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/oscap"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
@@ -45,6 +46,7 @@ type distribution struct {
 	vendor             string
 	ostreeRefTmpl      string
 	isolabelTmpl       string
+	oscapDatastream    string
 	runner             runner.Runner
 	arches             map[string]distro.Arch
 	defaultImageConfig *distro.ImageConfig
@@ -66,6 +68,60 @@ var defaultDistroImageConfig = &distro.ImageConfig{
 			},
 		},
 	},
+	// modules of the cloud.cfg of the cloud-init package
+	CloudInitModules: &blueprint.CloudInitModules{
+		Init: []string{
+			"migrator",
+			"seed_random",
+			"bootcmd",
+			"write_files",
+			"growpart",
+			"resizefs",
+			"disk_setup",
+			"mounts",
+			"set_hostname",
+			"update_hostname",
+			"update_etc_hosts",
+			"ca_certs",
+			"rsyslog",
+			"users_groups",
+			"ssh",
+		},
+		Config: []string{
+			"ssh_import_id",
+			"locale",
+			"set_passwords",
+			"rh_subscription",
+			"spacewalk",
+			"yum_add_repo",
+			"ntp",
+			"timezone",
+			"disable_ec2_metadata",
+			"runcmd",
+		},
+		Final: []string{
+			"package_update_upgrade_install",
+			"write_files_deferred",
+			"puppet",
+			"chef",
+			"ansible",
+			"mcollective",
+			"salt_minion",
+			"reset_rmc",
+			"rightscale_userdata",
+			"scripts_vendor",
+			"scripts_per_once",
+			"scripts_per_boot",
+			"scripts_per_instance",
+			"scripts_user",
+			"ssh_authkey_fingerprints",
+			"keys_to_console",
+			"install_hotplug",
+			"phone_home",
+			"final_message",
+			"power_state_change",
+		},
+	},
 }
 
 func (d *distribution) Name() string {
@@ -122,6 +178,13 @@ func (d *distribution) getDefaultImageConfig() *distro.ImageConfig {
 	return d.defaultImageConfig
 }
 
+func (d *distribution) defaultOSCAPDatastream() string {
+	if d.oscapDatastream != "" {
+		return d.oscapDatastream
+	}
+	return oscap.DefaultRHEL8Datastream(d.isRHEL())
+}
+
 // New creates a new distro object, defining the supported architectures and image types
 func New() distro.Distro {
 	// default minor: create default minor version (current GA) and rename it
@@ -159,6 +222,36 @@ func NewCentos() distro.Distro {
 	return newDistro("centos", 0)
 }
 
+// NewDerivative creates a distribution that provides the RHEL 8 image types
+// with the identity defined by the options, e.g. for rebuilds of RHEL 8.
+// Derivatives get the image types of the CentOS Stream variant, i.e. without
+// the RHEL specific cloud image types and subscription support.
+func NewDerivative(options distro.DerivativeOptions) (distro.Distro, error) {
+	if err := options.Validate("8"); err != nil {
+		return nil, err
+	}
+	options = options.ApplyDefaults("8", "platform:el8")
+	if strings.HasPrefix(options.Name, "rhel") {
+		return nil, fmt.Errorf("derivative distro name %q must not start with \"rhel\"", options.Name)
+	}
+
+	rd := distribution{
+		name:               options.Name,
+		product:            options.Product,
+		osVersion:          options.OSVersion,
+		releaseVersion:     options.Releasever,
+		modulePlatformID:   options.ModulePlatformID,
+		vendor:             options.Vendor,
+		ostreeRefTmpl:      options.OSTreeRefTmpl,
+		isolabelTmpl:       options.ISOLabelTmpl,
+		oscapDatastream:    options.OSCAPDatastream,
+		runner:             &runner.RHEL{Major: uint64(8), Minor: uint64(options.Minor())},
+		defaultImageConfig: defaultDistroImageConfig,
+	}
+	rd.addArchesAndImageTypes()
+	return &rd, nil
+}
+
 func newDistro(name string, minor int) *distribution {
 	var rd distribution
 	switch name {
@@ -192,27 +285,35 @@ func newDistro(name string, minor int) *distribution {
 		panic(fmt.Sprintf("unknown distro name: %s", name))
 	}
 
+	rd.addArchesAndImageTypes()
+	return &rd
+}
+
+// addArchesAndImageTypes defines the supported architectures and image types
+// of the distribution. The image types depend on the identity of the
+// distribution, which must be set before calling it.
+func (rd *distribution) addArchesAndImageTypes() {
 	// Architecture definitions
 	x86_64 := architecture{
 		name:   platform.ARCH_X86_64.String(),
-		distro: &rd,
+		distro: rd,
 	}
 
 	aarch64 := architecture{
 		name:   platform.ARCH_AARCH64.String(),
-		distro: &rd,
+		distro: rd,
 	}
 
 	ppc64le := architecture{
-		distro: &rd,
+		distro: rd,
 		name:   platform.ARCH_PPC64LE.String(),
 	}
 	s390x := architecture{
-		distro: &rd,
+		distro: rd,
 		name:   platform.ARCH_S390X.String(),
 	}
 
-	ociImgType := qcow2ImgType(rd)
+	ociImgType := qcow2ImgType(*rd)
 	ociImgType.name = "oci"
 
 	x86_64.addImageTypes(
@@ -224,7 +325,7 @@ func newDistro(name string, minor int) *distribution {
 				QCOW2Compat: "0.10",
 			},
 		},
-		qcow2ImgType(rd),
+		qcow2ImgType(*rd),
 		ociImgType,
 	)
 
@@ -248,7 +349,7 @@ func newDistro(name string, minor int) *distribution {
 	}
 	x86_64.addImageTypes(
 		ec2X86Platform,
-		amiImgTypeX86_64(rd),
+		amiImgTypeX86_64(*rd),
 	)
 
 	bareMetalX86Platform := &platform.X86{
@@ -273,9 +374,9 @@ func newDistro(name string, minor int) *distribution {
 
 	x86_64.addImageTypes(
 		bareMetalX86Platform,
-		edgeOCIImgType(rd),
-		edgeCommitImgType(rd),
-		edgeInstallerImgType(rd),
+		edgeOCIImgType(*rd),
+		edgeCommitImgType(*rd),
+		edgeInstallerImgType(*rd),
 		imageInstaller(),
 	)
 
@@ -288,7 +389,7 @@ func newDistro(name string, minor int) *distribution {
 
 	x86_64.addImageTypes(
 		gceX86Platform,
-		gceImgType(rd),
+		gceImgType(*rd),
 	)
 
 	x86_64.addImageTypes(
@@ -327,7 +428,7 @@ func newDistro(name string, minor int) *distribution {
 				QCOW2Compat: "0.10",
 			},
 		},
-		qcow2ImgType(rd),
+		qcow2ImgType(*rd),
 	)
 
 	aarch64.addImageTypes(
@@ -353,9 +454,9 @@ func newDistro(name string, minor int) *distribution {
 
 	aarch64.addImageTypes(
 		bareMetalAarch64Platform,
-		edgeOCIImgType(rd),
-		edgeCommitImgType(rd),
-		edgeInstallerImgType(rd),
+		edgeOCIImgType(*rd),
+		edgeCommitImgType(*rd),
+		edgeInstallerImgType(*rd),
 		imageInstaller(),
 	)
 
@@ -368,8 +469,8 @@ func newDistro(name string, minor int) *distribution {
 
 	aarch64.addImageTypes(
 		rawAarch64Platform,
-		amiImgTypeAarch64(rd),
-		minimalRawImgType(rd),
+		amiImgTypeAarch64(*rd),
+		minimalRawImgType(*rd),
 	)
 
 	ppc64le.addImageTypes(
@@ -380,7 +481,7 @@ func newDistro(name string, minor int) *distribution {
 				QCOW2Compat: "0.10",
 			},
 		},
-		qcow2ImgType(rd),
+		qcow2ImgType(*rd),
 	)
 
 	ppc64le.addImageTypes(
@@ -396,7 +497,7 @@ func newDistro(name string, minor int) *distribution {
 				QCOW2Compat: "0.10",
 			},
 		},
-		qcow2ImgType(rd),
+		qcow2ImgType(*rd),
 	)
 
 	s390x.addImageTypes(
@@ -429,7 +530,7 @@ func newDistro(name string, minor int) *distribution {
 
 	x86_64.addImageTypes(
 		rawUEFIx86Platform,
-		minimalRawImgType(rd),
+		minimalRawImgType(*rd),
 	)
 
 	if rd.isRHEL() {
@@ -443,7 +544,7 @@ func newDistro(name string, minor int) *distribution {
 
 			x86_64.addImageTypes(
 				rawUEFIx86Platform,
-				edgeSimplifiedInstallerImgType(rd),
+				edgeSimplifiedInstallerImgType(*rd),
 			)
 
 			azureEap := azureEap7RhuiImgType()
@@ -452,7 +553,7 @@ func newDistro(name string, minor int) *distribution {
 			aarch64.addImageTypes(
 				rawAarch64Platform,
 				edgeRawImgType(),
-				edgeSimplifiedInstallerImgType(rd),
+				edgeSimplifiedInstallerImgType(*rd),
 			)
 
 			// The Azure image types require hyperv-daemons which isn't available on older versions
@@ -460,7 +561,7 @@ func newDistro(name string, minor int) *distribution {
 		}
 
 		// add azure to RHEL distro only
-		x86_64.addImageTypes(azureX64Platform, azureRhuiImgType(), azureByosImgType(), azureSapRhuiImgType(rd))
+		x86_64.addImageTypes(azureX64Platform, azureRhuiImgType(), azureByosImgType(), azureSapRhuiImgType(*rd))
 
 		// keep the RHEL EC2 x86_64 images before 8.9 BIOS-only for backward compatibility
 		if common.VersionLessThan(rd.osVersion, "8.9") {
@@ -473,18 +574,18 @@ func newDistro(name string, minor int) *distribution {
 		}
 
 		// add ec2 image types to RHEL distro only
-		x86_64.addImageTypes(ec2X86Platform, ec2ImgTypeX86_64(rd), ec2HaImgTypeX86_64(rd))
-		aarch64.addImageTypes(rawAarch64Platform, ec2ImgTypeAarch64(rd))
+		x86_64.addImageTypes(ec2X86Platform, ec2ImgTypeX86_64(*rd), ec2HaImgTypeX86_64(*rd))
+		aarch64.addImageTypes(rawAarch64Platform, ec2ImgTypeAarch64(*rd))
 
 		if rd.osVersion != "8.5" {
 			// NOTE: RHEL 8.5 is going away and these image types require some
 			// work to get working, so we just disable them here until the
 			// whole distro gets deleted
-			x86_64.addImageTypes(ec2X86Platform, ec2SapImgTypeX86_64(rd))
+			x86_64.addImageTypes(ec2X86Platform, ec2SapImgTypeX86_64(*rd))
 		}
 
 		// add GCE RHUI image to RHEL only
-		x86_64.addImageTypes(gceX86Platform, gceRhuiImgType(rd))
+		x86_64.addImageTypes(gceX86Platform, gceRhuiImgType(*rd))
 
 		// add s390x to RHEL distro only
 		rd.addArches(s390x)
@@ -496,7 +597,7 @@ func newDistro(name string, minor int) *distribution {
 
 		x86_64.addImageTypes(
 			rawUEFIx86Platform,
-			edgeSimplifiedInstallerImgType(rd),
+			edgeSimplifiedInstallerImgType(*rd),
 		)
 
 		x86_64.addImageTypes(azureX64Platform, azureImgType())
@@ -504,11 +605,10 @@ func newDistro(name string, minor int) *distribution {
 		aarch64.addImageTypes(
 			rawAarch64Platform,
 			edgeRawImgType(),
-			edgeSimplifiedInstallerImgType(rd),
+			edgeSimplifiedInstallerImgType(*rd),
 		)
 
 		aarch64.addImageTypes(azureAarch64Platform, azureImgType())
 	}
 	rd.addArches(x86_64, aarch64, ppc64le)
-	return &rd
 }
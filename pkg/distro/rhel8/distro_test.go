@@ -650,6 +650,22 @@ func TestRhel86_KernelOption(t *testing.T) {
 	distro_test_common.TestDistro_KernelOption(t, rhel8.New())
 }
 
+func TestRhel86_ReproducibleManifests(t *testing.T) {
+	distro_test_common.TestDistro_ReproducibleManifests(t, rhel8.New())
+}
+
+func TestRhel86_VHDOptions(t *testing.T) {
+	distro_test_common.TestDistro_VHDOptions(t, rhel8.New())
+}
+
+func TestRhel86_BtrfsPartitioning(t *testing.T) {
+	distro_test_common.TestDistro_BtrfsPartitioning(t, rhel8.New(), false)
+}
+
+func TestRhel86_CompressedImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_CompressedImageTypes(t, rhel8.New())
+}
+
 func TestRhel8_OSTreeOptions(t *testing.T) {
 	distro_test_common.TestDistro_OSTreeOptions(t, rhel8.New())
 }
@@ -919,3 +935,31 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestRhel8_NewDerivative(t *testing.T) {
+	d, err := rhel8.NewDerivative(distro.DerivativeOptions{
+		Name:      "almalinux-89",
+		Product:   "AlmaLinux",
+		OSVersion: "8.9",
+		Vendor:    "almalinux",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "almalinux-89", d.Name())
+	assert.Equal(t, "8", d.Releasever())
+	assert.Equal(t, "platform:el8", d.ModulePlatformID())
+	assert.Equal(t, "almalinux/8/%s/edge", d.OSTreeRef())
+
+	arch, err := d.GetArch(platform.ARCH_X86_64.String())
+	require.NoError(t, err)
+	it, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+	_, _, err = it.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+
+	_, err = rhel8.NewDerivative(distro.DerivativeOptions{Name: "almalinux-89", OSVersion: "8.9", Vendor: "almalinux"})
+	assert.EqualError(t, err, `derivative distro "almalinux-89": product is required`)
+}
+
+func TestRhel86_CloudInitDisabledModules(t *testing.T) {
+	distro_test_common.TestDistro_CloudInitDisabledModules(t, rhel8.New())
+}
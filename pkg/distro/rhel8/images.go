@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"math/rand"
 
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
 	"github.com/osbuild/images/internal/fdo"
 	"github.com/osbuild/images/internal/ignition"
-	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/blueprint"
@@ -28,7 +30,12 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().MergeCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(fmt.Sprintf("failed to merge customizations into the image config: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -59,13 +66,6 @@ func osCustomizations(
 		osc.ExcludeDocs = *imageConfig.ExcludeDocs
 	}
 
-	if !t.bootISO {
-		// don't put users and groups in the payload of an installer
-		// add them via kickstart instead
-		osc.Groups = users.GroupsFromBP(c.GetGroups())
-		osc.Users = users.UsersFromBP(c.GetUsers())
-	}
-
 	osc.EnabledServices = imageConfig.EnabledServices
 	osc.DisabledServices = imageConfig.DisabledServices
 	if imageConfig.DefaultTarget != nil {
@@ -139,7 +139,7 @@ func osCustomizations(
 		}
 		var datastream = oscapConfig.DataStream
 		if datastream == "" {
-			datastream = oscap.DefaultRHEL8Datastream(t.arch.distro.isRHEL())
+			datastream = t.arch.distro.defaultOSCAPDatastream()
 		}
 		osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(
 			osbuild.OscapConfig{
@@ -153,7 +153,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -193,8 +192,26 @@ func osCustomizations(
 		osc.Files = append(osc.Files, gpgKeyFiles...)
 	}
 
-	for filename, repos := range yumRepos {
-		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
+	if err := distro.AddCustomizationFiles(&osc, c, imageConfig.CloudInitModules); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	// don't put users and groups in the payload of an installer
+	// add them via kickstart instead
+	if err := distro.AddAccounts(&osc, c, t.bootISO); err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
+	}
+
+	// sort the repo files so that the manifest doesn't depend on the map
+	// iteration order
+	repoFiles := maps.Keys(yumRepos)
+	slices.Sort(repoFiles)
+	for _, filename := range repoFiles {
+		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, yumRepos[filename]))
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -241,8 +258,11 @@ func diskImage(workload workload.Workload,
 	img.Environment = t.environment
 	img.Workload = workload
 	img.Compression = t.compression
+	if options.VHD != nil {
+		img.VPCSubformat = options.VHD.Subformat
+	}
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -267,9 +287,18 @@ func imageInstallerImage(workload workload.Workload,
 	img.Workload = workload
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	var err error
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.AdditionalDracutModules = []string{"prefixdevname", "prefixdevname-tools"}
 	img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 
@@ -376,9 +405,17 @@ func edgeInstallerImage(workload workload.Workload,
 
 	img.Platform = t.platform
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "xz"
 	img.AdditionalDracutModules = []string{"prefixdevname", "prefixdevname-tools"}
 
@@ -414,7 +451,10 @@ func edgeRawImage(workload workload.Workload,
 
 	img := image.NewOSTreeRawImage(commit)
 
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	img.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
@@ -432,7 +472,7 @@ func edgeRawImage(workload workload.Workload,
 	img.OSName = "redhat"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -459,7 +499,10 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 
 	rawImg := image.NewOSTreeRawImage(commit)
 
-	rawImg.Users = users.UsersFromBP(customizations.GetUsers())
+	rawImg.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	rawImg.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	rawImg.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
@@ -476,7 +519,7 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.OSName = "redhat"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +99,7 @@ func (t *imageType) OSTreeRef() string {
 
 func (t *imageType) Size(size uint64) uint64 {
 	// Microsoft Azure requires vhd images to be rounded up to the nearest MB
-	if t.name == "vhd" && size%common.MebiByte != 0 {
+	if t.platform != nil && t.platform.GetImageFormat() == platform.FORMAT_VHD && size%common.MebiByte != 0 {
 		size = (size/common.MebiByte + 1) * common.MebiByte
 	}
 	if size == 0 {
@@ -143,7 +143,7 @@ func (t *imageType) BootMode() distro.BootMode {
 }
 
 func (t *imageType) getPartitionTable(
-	mountpoints []blueprint.FilesystemCustomization,
+	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
 	rng *rand.Rand,
 ) (*disk.PartitionTable, error) {
@@ -159,7 +159,15 @@ func (t *imageType) getPartitionTable(
 
 	lvmify := !t.rpmOstree
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, lvmify, nil, rng)
+	if partitioning := customizations.GetPartitioning(); partitioning != nil {
+		customPartitionTable, err := disk.NewCustomPartitionTable(partitioning, &basePartitionTable)
+		if err != nil {
+			return nil, err
+		}
+		return disk.NewPartitionTable(customPartitionTable, nil, imageSize, false, nil, rng)
+	}
+
+	return disk.NewPartitionTable(&basePartitionTable, customizations.GetFilesystems(), imageSize, lvmify, nil, rng)
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
@@ -222,7 +230,8 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.ExcludePackages,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -250,6 +259,7 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	}
 	mf := manifest.New()
 	mf.Distro = manifest.DISTRO_EL8
+	mf.SourceEpoch = options.Reproducible.SourceEpoch()
 	_, err = img.InstantiateManifest(&mf, repos, t.arch.distro.runner, rng)
 	if err != nil {
 		return nil, nil, err
@@ -274,6 +284,24 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 			return warnings, fmt.Errorf("image type %q does not support customizations", t.name)
 		}
 	}
+
+	if err := bp.ValidatePackageSelection(); err != nil {
+		return warnings, err
+	}
+
+	if options.VHD != nil {
+		if t.platform.GetImageFormat() != platform.FORMAT_VHD {
+			return warnings, fmt.Errorf("VHD options are not supported for %s on %s", t.name, t.arch.distro.name)
+		}
+		if err := options.VHD.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
+	if t.workload != nil && (len(bp.PackagePins) > 0 || len(bp.ExcludePackages) > 0) {
+		return warnings, fmt.Errorf("image type %q does not support package pins and excludes", t.name)
+	}
+
 	// we do not support embedding containers on ostree-derived images, only on commits themselves
 	if len(bp.Containers) > 0 && t.rpmOstree && (t.name != "edge-commit" && t.name != "edge-container") {
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
@@ -322,7 +350,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 				}
 			}
 		} else if t.name == "edge-installer" {
-			allowed := []string{"User", "Group"}
+			allowed := []string{"User", "Group", "Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -372,6 +400,24 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	if partitioning := customizations.GetPartitioning(); partitioning != nil {
+		if t.rpmOstree {
+			return warnings, fmt.Errorf("Custom partitioning is not supported for ostree types")
+		}
+		if mountpoints != nil {
+			return warnings, fmt.Errorf("Custom mountpoints and partitioning customizations cannot be used together")
+		}
+		if partitioning.HasBtrfs() {
+			return warnings, fmt.Errorf("btrfs partitioning customizations are not supported on %s", t.arch.distro.name)
+		}
+		if err := partitioning.Validate(); err != nil {
+			return warnings, err
+		}
+		if err := blueprint.CheckPartitioningPolicy(partitioning, pathpolicy.MountpointPolicies); err != nil {
+			return warnings, err
+		}
+	}
+
 	if osc := customizations.GetOpenSCAP(); osc != nil {
 		// only add support for RHEL 8.7 and above.
 		if common.VersionLessThan(t.arch.distro.osVersion, "8.7") {
@@ -414,5 +460,66 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	// check if the network customization is valid
+	_, err = customizations.GetNetwork()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if the kernel tuning customizations are valid
+	if _, err := customizations.GetSysctl(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetKernelModules(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetDracut(); err != nil {
+		return warnings, err
+	}
+	if _, err := customizations.GetTuned(); err != nil {
+		return warnings, err
+	}
+
+	// check if the systemd customization is valid
+	if _, err := customizations.GetSystemd(); err != nil {
+		return warnings, err
+	}
+
+	// check if the cloud-init customization is valid and the modules can
+	// be disabled
+	cloudInit, err := customizations.GetCloudInit()
+	if err != nil {
+		return warnings, err
+	}
+	if err := cloudInit.ValidateDisabledModules(t.getDefaultImageConfig().CloudInitModules); err != nil {
+		return warnings, err
+	}
+
+	// check if the SELinux customization is valid
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return warnings, err
+	}
+	if selinux != nil && selinux.HasPolicyModifications() {
+		return warnings, fmt.Errorf("SELinux boolean, file context and port customizations are not supported for %s on %s: osbuild has no stage that modifies the SELinux policy", t.name, t.arch.distro.name)
+	}
+
+	// check if the accounts customization is valid
+	accounts, err := customizations.GetAccounts()
+	if err != nil {
+		return warnings, err
+	}
+	if t.bootISO && accounts != nil && accounts.Root != nil {
+		return warnings, fmt.Errorf("root account customizations are not supported for installer image type %q", t.name)
+	}
+
+	installer, err := customizations.GetInstaller()
+	if err != nil {
+		return warnings, err
+	}
+	if installer != nil && t.name != "image-installer" && t.name != "edge-installer" {
+		return warnings, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
 	return warnings, nil
 }
@@ -3,6 +3,7 @@ package rhel9
 import (
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/subscription"
@@ -32,7 +33,7 @@ var (
 		name:        "ec2",
 		filename:    "image.raw.xz",
 		mimeType:    "application/xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2PackageSet,
 		},
@@ -50,7 +51,7 @@ var (
 		name:        "ec2-ha",
 		filename:    "image.raw.xz",
 		mimeType:    "application/xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
 			osPkgsKey:    rhelEc2HaPackageSet,
@@ -87,7 +88,7 @@ var (
 		name:        "ec2",
 		filename:    "image.raw.xz",
 		mimeType:    "application/xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
 			osPkgsKey:    rhelEc2PackageSet,
@@ -106,7 +107,7 @@ var (
 		name:        "ec2-sap",
 		filename:    "image.raw.xz",
 		mimeType:    "application/xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
 			osPkgsKey:    rhelEc2SapPackageSet,
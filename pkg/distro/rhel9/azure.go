@@ -4,6 +4,7 @@ import (
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -54,7 +55,7 @@ var (
 		name:        "azure-rhui",
 		filename:    "disk.vhd.xz",
 		mimeType:    "application/xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: azureRhuiPackageSet,
 		},
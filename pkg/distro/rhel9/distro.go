@@ -8,6 +8,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/oscap"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
@@ -47,6 +48,7 @@ type distribution struct {
 	vendor             string
 	ostreeRefTmpl      string
 	isolabelTmpl       string
+	oscapDatastream    string
 	runner             runner.Runner
 	arches             map[string]distro.Arch
 	defaultImageConfig *distro.ImageConfig
@@ -68,6 +70,60 @@ var defaultDistroImageConfig = &distro.ImageConfig{
 			},
 		},
 	},
+	// modules of the cloud.cfg of the cloud-init package
+	CloudInitModules: &blueprint.CloudInitModules{
+		Init: []string{
+			"migrator",
+			"seed_random",
+			"bootcmd",
+			"write_files",
+			"growpart",
+			"resizefs",
+			"disk_setup",
+			"mounts",
+			"set_hostname",
+			"update_hostname",
+			"update_etc_hosts",
+			"ca_certs",
+			"rsyslog",
+			"users_groups",
+			"ssh",
+		},
+		Config: []string{
+			"ssh_import_id",
+			"locale",
+			"set_passwords",
+			"rh_subscription",
+			"spacewalk",
+			"yum_add_repo",
+			"ntp",
+			"timezone",
+			"disable_ec2_metadata",
+			"runcmd",
+		},
+		Final: []string{
+			"package_update_upgrade_install",
+			"write_files_deferred",
+			"puppet",
+			"chef",
+			"ansible",
+			"mcollective",
+			"salt_minion",
+			"reset_rmc",
+			"rightscale_userdata",
+			"scripts_vendor",
+			"scripts_per_once",
+			"scripts_per_boot",
+			"scripts_per_instance",
+			"scripts_user",
+			"ssh_authkey_fingerprints",
+			"keys_to_console",
+			"install_hotplug",
+			"phone_home",
+			"final_message",
+			"power_state_change",
+		},
+	},
 }
 
 func (d *distribution) Name() string {
@@ -124,6 +180,13 @@ func (d *distribution) getDefaultImageConfig() *distro.ImageConfig {
 	return d.defaultImageConfig
 }
 
+func (d *distribution) defaultOSCAPDatastream() string {
+	if d.oscapDatastream != "" {
+		return d.oscapDatastream
+	}
+	return oscap.DefaultRHEL9Datastream(d.isRHEL())
+}
+
 func New() distro.Distro {
 	// default minor: create default minor version (current GA) and rename it
 	d := newDistro("rhel", 1)
@@ -151,6 +214,36 @@ func NewRHEL93() distro.Distro {
 	return newDistro("rhel", 3)
 }
 
+// NewDerivative creates a distribution that provides the RHEL 9 image types
+// with the identity defined by the options, e.g. for rebuilds of RHEL 9.
+// Derivatives get the image types of the CentOS Stream variant, i.e. without
+// the RHEL specific cloud image types and subscription support.
+func NewDerivative(options distro.DerivativeOptions) (distro.Distro, error) {
+	if err := options.Validate("9"); err != nil {
+		return nil, err
+	}
+	options = options.ApplyDefaults("9", "platform:el9")
+	if strings.HasPrefix(options.Name, "rhel") {
+		return nil, fmt.Errorf("derivative distro name %q must not start with \"rhel\"", options.Name)
+	}
+
+	rd := distribution{
+		name:               options.Name,
+		product:            options.Product,
+		osVersion:          options.OSVersion,
+		releaseVersion:     options.Releasever,
+		modulePlatformID:   options.ModulePlatformID,
+		vendor:             options.Vendor,
+		ostreeRefTmpl:      options.OSTreeRefTmpl,
+		isolabelTmpl:       options.ISOLabelTmpl,
+		oscapDatastream:    options.OSCAPDatastream,
+		runner:             &runner.RHEL{Major: uint64(9), Minor: uint64(options.Minor())},
+		defaultImageConfig: defaultDistroImageConfig,
+	}
+	rd.addArchesAndImageTypes()
+	return &rd, nil
+}
+
 func newDistro(name string, minor int) *distribution {
 	var rd distribution
 	switch name {
@@ -185,27 +278,35 @@ func newDistro(name string, minor int) *distribution {
 	}
 
 	// Architecture definitions
+	rd.addArchesAndImageTypes()
+	return &rd
+}
+
+// addArchesAndImageTypes defines the supported architectures and image types
+// of the distribution. The image types depend on the identity of the
+// distribution, which must be set before calling it.
+func (rd *distribution) addArchesAndImageTypes() {
 	x86_64 := architecture{
 		name:   platform.ARCH_X86_64.String(),
-		distro: &rd,
+		distro: rd,
 	}
 
 	aarch64 := architecture{
 		name:   platform.ARCH_AARCH64.String(),
-		distro: &rd,
+		distro: rd,
 	}
 
 	ppc64le := architecture{
-		distro: &rd,
+		distro: rd,
 		name:   platform.ARCH_PPC64LE.String(),
 	}
 
 	s390x := architecture{
-		distro: &rd,
+		distro: rd,
 		name:   platform.ARCH_S390X.String(),
 	}
 
-	qcow2ImgType := mkQcow2ImgType(rd)
+	qcow2ImgType := mkQcow2ImgType(*rd)
 	ociImgType := qcow2ImgType
 	ociImgType.name = "oci"
 
@@ -270,6 +371,17 @@ func newDistro(name string, minor int) *distribution {
 		ovaImgType,
 	)
 
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VHDX,
+			},
+		},
+		vhdxImgType,
+	)
+
 	ec2X86Platform := &platform.X86{
 		BIOS:       true,
 		UEFIVendor: rd.vendor,
@@ -485,5 +597,4 @@ func newDistro(name string, minor int) *distribution {
 		aarch64.addImageTypes(azureAarch64Platform, azureImgType)
 	}
 	rd.addArches(x86_64, aarch64, ppc64le, s390x)
-	return &rd
 }
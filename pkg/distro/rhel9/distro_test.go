@@ -97,6 +97,14 @@ func TestFilenameFromType(t *testing.T) {
 				mimeType: "application/x-vhd",
 			},
 		},
+		{
+			name: "vhdx",
+			args: args{"vhdx"},
+			want: wantResult{
+				filename: "disk.vhdx",
+				mimeType: "application/x-vhdx",
+			},
+		},
 		{
 			name: "azure-rhui",
 			args: args{"azure-rhui"},
@@ -221,8 +229,8 @@ func TestFilenameFromType(t *testing.T) {
 			name: "minimal-raw",
 			args: args{"minimal-raw"},
 			want: wantResult{
-				filename: "raw.img.xz",
-				mimeType: "application/xz",
+				filename: "raw.img.zst",
+				mimeType: "application/zstd",
 			},
 		},
 	}
@@ -317,6 +325,7 @@ func TestImageType_Name(t *testing.T) {
 				"qcow2",
 				"openstack",
 				"vhd",
+				"vhdx",
 				"azure-rhui",
 				"vmdk",
 				"ova",
@@ -510,6 +519,7 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"qcow2",
 				"openstack",
 				"vhd",
+				"vhdx",
 				"azure-rhui",
 				"vmdk",
 				"ova",
@@ -649,6 +659,22 @@ func TestRhel9_KernelOption(t *testing.T) {
 	distro_test_common.TestDistro_KernelOption(t, rhel9.New())
 }
 
+func TestRhel9_ReproducibleManifests(t *testing.T) {
+	distro_test_common.TestDistro_ReproducibleManifests(t, rhel9.New())
+}
+
+func TestRhel9_VHDOptions(t *testing.T) {
+	distro_test_common.TestDistro_VHDOptions(t, rhel9.New())
+}
+
+func TestRhel9_BtrfsPartitioning(t *testing.T) {
+	distro_test_common.TestDistro_BtrfsPartitioning(t, rhel9.New(), false)
+}
+
+func TestRhel9_CompressedImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_CompressedImageTypes(t, rhel9.New())
+}
+
 func TestRhel9_OSTreeOptions(t *testing.T) {
 	distro_test_common.TestDistro_OSTreeOptions(t, rhel9.New())
 }
@@ -870,3 +896,129 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestRhel9_NewDerivative(t *testing.T) {
+	d, err := rhel9.NewDerivative(distro.DerivativeOptions{
+		Name:            "rocky-93",
+		Product:         "Rocky Linux",
+		OSVersion:       "9.3",
+		Vendor:          "rocky",
+		OSCAPDatastream: "/usr/share/xml/scap/ssg/content/ssg-rl9-ds.xml",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "rocky-93", d.Name())
+	assert.Equal(t, "9", d.Releasever())
+	assert.Equal(t, "platform:el9", d.ModulePlatformID())
+	assert.Equal(t, "rocky/9/%s/edge", d.OSTreeRef())
+	assert.Equal(t, []string{"aarch64", "ppc64le", "s390x", "x86_64"}, d.ListArches())
+
+	arch, err := d.GetArch(platform.ARCH_X86_64.String())
+	require.NoError(t, err)
+	// derivatives get the image types of CentOS Stream
+	centosArch, err := rhel9.NewCentOS9().GetArch(platform.ARCH_X86_64.String())
+	require.NoError(t, err)
+	assert.Equal(t, centosArch.ListImageTypes(), arch.ListImageTypes())
+
+	it, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+	assert.Equal(t, distro.BOOT_HYBRID, it.BootMode())
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			OpenSCAP: &blueprint.OpenSCAPCustomization{ProfileID: "xccdf_org.ssgproject.content_profile_cis"},
+		},
+	}
+	_, _, err = it.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+}
+
+func TestRhel9_NewDerivativeErrors(t *testing.T) {
+	_, err := rhel9.NewDerivative(distro.DerivativeOptions{Name: "rocky-88", Product: "Rocky Linux", OSVersion: "8.8", Vendor: "rocky"})
+	assert.EqualError(t, err, `derivative distro "rocky-88": os version "8.8" is not a 9.x version`)
+
+	_, err = rhel9.NewDerivative(distro.DerivativeOptions{Name: "rhel-93", Product: "Rebuild", OSVersion: "9.3", Vendor: "rebuild"})
+	assert.EqualError(t, err, `derivative distro name "rhel-93" must not start with "rhel"`)
+}
+
+func TestDistro_SELinuxPolicyCustomization(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			SELinux: &blueprint.SELinuxCustomization{Mode: "permissive"},
+		},
+	}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	// the policy cannot be modified at build time
+	bp.Customizations.SELinux.Booleans = []blueprint.SELinuxBooleanCustomization{{Name: "httpd_can_network_connect", Value: true}}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "SELinux boolean, file context and port customizations are not supported for qcow2 on rhel-9: osbuild has no stage that modifies the SELinux policy")
+}
+
+func TestDistro_InstallerCustomization(t *testing.T) {
+	r9distro := rhel9.New()
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Installer: &blueprint.InstallerCustomization{
+				TargetDisks: []string{"vda"},
+				ClearPart:   &blueprint.InstallerClearPartCustomization{Mode: "all", InitLabel: true},
+				AutoPart:    &blueprint.InstallerAutoPartCustomization{Type: "lvm"},
+				Reboot:      true,
+			},
+		},
+	}
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+
+	installer, err := arch.GetImageType("image-installer")
+	require.NoError(t, err)
+	_, _, err = installer.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `installer customizations are not supported for image type "qcow2"`)
+
+	bp.Customizations.Installer.ClearPart.Mode = "none"
+	_, _, err = installer.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `unsupported installer clearpart mode "none"`)
+}
+
+func TestDistro_PackagePinsAndExcludes(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := blueprint.Blueprint{
+		Packages:        []blueprint.Package{{Name: "tmux"}},
+		PackagePins:     []string{"bash-5.1.8-6.el9.x86_64"},
+		ExcludePackages: []string{"dracut-config-rescue"},
+	}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	bp.ExcludePackages = []string{"tmux"}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `package "tmux" is both excluded and requested`)
+
+	bp.ExcludePackages = nil
+	bp.PackagePins = []string{"bash-5.1.8"}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `package pin "bash-5.1.8" is not in the name-[epoch:]version-release.arch format`)
+}
+
+func TestRhel9_VHDXImageTypes(t *testing.T) {
+	distro_test_common.TestDistro_VHDXImageTypes(t, rhel9.New())
+}
+
+func TestRhel9_CloudInitDisabledModules(t *testing.T) {
+	distro_test_common.TestDistro_CloudInitDisabledModules(t, rhel9.New())
+}
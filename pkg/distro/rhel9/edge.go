@@ -7,6 +7,7 @@ import (
 	"github.com/osbuild/images/internal/environment"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 )
@@ -59,7 +60,7 @@ var (
 		name:        "edge-raw-image",
 		nameAliases: []string{"rhel-edge-raw-image"},
 		filename:    "image.raw.xz",
-		compression: "xz",
+		compression: manifest.CompressionXZ,
 		mimeType:    "application/xz",
 		packageSets: nil,
 		defaultImageConfig: &distro.ImageConfig{
@@ -173,10 +174,12 @@ var (
 	}
 
 	minimalrawImgType = imageType{
-		name:        "minimal-raw",
-		filename:    "raw.img.xz",
-		compression: "xz",
-		mimeType:    "application/xz",
+		name:               "minimal-raw",
+		filename:           "raw.img.zst",
+		compression:        manifest.CompressionZstd,
+		compressionLevel:   19,
+		compressionThreads: 4,
+		mimeType:           "application/zstd",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: minimalrpmPackageSet,
 		},
@@ -186,8 +189,8 @@ var (
 		defaultSize:         2 * common.GibiByte,
 		image:               diskImage,
 		buildPipelines:      []string{"build"},
-		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		payloadPipelines:    []string{"os", "image", "zstd"},
+		exports:             []string{"zstd"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
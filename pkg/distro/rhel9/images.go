@@ -7,7 +7,6 @@ import (
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/fdo"
 	"github.com/osbuild/images/internal/ignition"
-	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/blueprint"
@@ -28,106 +27,24 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
-
-	osc := manifest.OSCustomizations{}
-
-	if t.bootable || t.rpmOstree {
-		osc.KernelName = c.GetKernel().Name
-
-		var kernelOptions []string
-		if t.kernelOptions != "" {
-			kernelOptions = append(kernelOptions, t.kernelOptions)
-		}
-		if bpKernel := c.GetKernel(); bpKernel.Append != "" {
-			kernelOptions = append(kernelOptions, bpKernel.Append)
-		}
-		osc.KernelOptionsAppend = kernelOptions
-	}
-
-	osc.ExtraBasePackages = osPackageSet.Include
-	osc.ExcludeBasePackages = osPackageSet.Exclude
-	osc.ExtraBaseRepos = osPackageSet.Repositories
-
-	osc.Containers = containers
-
-	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
-	if imageConfig.ExcludeDocs != nil {
-		osc.ExcludeDocs = *imageConfig.ExcludeDocs
+	imageConfig, err := t.getDefaultImageConfig().MergeCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(fmt.Sprintf("failed to merge customizations into the image config: %v", err))
 	}
 
-	if !t.bootISO {
+	osc, err := distro.NewOSCustomizations(imageConfig, osPackageSet, options, containers, c, distro.OSCustomizationsOptions{
+		Bootable:      t.bootable || t.rpmOstree,
+		KernelOptions: t.kernelOptions,
 		// don't put users and groups in the payload of an installer
 		// add them via kickstart instead
-		osc.Groups = users.GroupsFromBP(c.GetGroups())
-		osc.Users = users.UsersFromBP(c.GetUsers())
-	}
-
-	osc.EnabledServices = imageConfig.EnabledServices
-	osc.DisabledServices = imageConfig.DisabledServices
-	if imageConfig.DefaultTarget != nil {
-		osc.DefaultTarget = *imageConfig.DefaultTarget
-	}
-
-	osc.Firewall = imageConfig.Firewall
-	if fw := c.GetFirewall(); fw != nil {
-		options := osbuild.FirewallStageOptions{
-			Ports: fw.Ports,
-		}
-
-		if fw.Services != nil {
-			options.EnabledServices = fw.Services.Enabled
-			options.DisabledServices = fw.Services.Disabled
-		}
-		if fw.Zones != nil {
-			for _, z := range fw.Zones {
-				options.Zones = append(options.Zones, osbuild.FirewallZone{
-					Name:    *z.Name,
-					Sources: z.Sources,
-				})
-			}
-		}
-		osc.Firewall = &options
-	}
-
-	language, keyboard := c.GetPrimaryLocale()
-	if language != nil {
-		osc.Language = *language
-	} else if imageConfig.Locale != nil {
-		osc.Language = *imageConfig.Locale
-	}
-	if keyboard != nil {
-		osc.Keyboard = keyboard
-	} else if imageConfig.Keyboard != nil {
-		osc.Keyboard = &imageConfig.Keyboard.Keymap
-		if imageConfig.Keyboard.X11Keymap != nil {
-			osc.X11KeymapLayouts = imageConfig.Keyboard.X11Keymap.Layouts
-		}
-	}
-
-	if hostname := c.GetHostname(); hostname != nil {
-		osc.Hostname = *hostname
-	}
-
-	timezone, ntpServers := c.GetTimezoneSettings()
-	if timezone != nil {
-		osc.Timezone = *timezone
-	} else if imageConfig.Timezone != nil {
-		osc.Timezone = *imageConfig.Timezone
-	}
-
-	if len(ntpServers) > 0 {
-		for _, server := range ntpServers {
-			osc.NTPServers = append(osc.NTPServers, osbuild.ChronyConfigServer{Hostname: server})
-		}
-	} else if imageConfig.TimeSynchronization != nil {
-		osc.NTPServers = imageConfig.TimeSynchronization.Servers
-		osc.LeapSecTZ = imageConfig.TimeSynchronization.LeapsecTz
-	}
-
-	// Relabel the tree, unless the `NoSElinux` flag is explicitly set to `true`
-	if imageConfig.NoSElinux == nil || imageConfig.NoSElinux != nil && !*imageConfig.NoSElinux {
-		osc.SElinux = "targeted"
+		NoUsers: t.bootISO,
+	})
+	if err != nil {
+		// This shouldn't happen since the customizations should have
+		// already been validated
+		panic(err.Error())
 	}
 
 	if oscapConfig := c.GetOpenSCAP(); oscapConfig != nil {
@@ -136,7 +53,7 @@ func osCustomizations(
 		}
 		var datastream = oscapConfig.DataStream
 		if datastream == "" {
-			datastream = oscap.DefaultRHEL9Datastream(t.arch.distro.isRHEL())
+			datastream = t.arch.distro.defaultOSCAPDatastream()
 		}
 		osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(
 			osbuild.OscapConfig{
@@ -150,77 +67,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
-	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
-	if err != nil {
-		// In theory this should never happen, because the blueprint directory customizations
-		// should have been validated before this point.
-		panic(fmt.Sprintf("failed to convert directory customizations to fs node directories: %v", err))
-	}
-
-	osc.Files, err = blueprint.FileCustomizationsToFsNodeFiles(c.GetFiles())
-	if err != nil {
-		// In theory this should never happen, because the blueprint file customizations
-		// should have been validated before this point.
-		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
-	}
-
-	// set yum repos first, so it doesn't get overridden by
-	// imageConfig.YUMRepos
-	osc.YUMRepos = imageConfig.YUMRepos
-
-	customRepos, err := c.GetRepositories()
-	if err != nil {
-		// This shouldn't happen and since the repos
-		// should have already been validated
-		panic(fmt.Sprintf("failed to get custom repos: %v", err))
-	}
-
-	// This function returns a map of filename and corresponding yum repos
-	// and a list of fs node files for the inline gpg keys so we can save
-	// them to disk. This step also swaps the inline gpg key with the path
-	// to the file in the os file tree
-	yumRepos, gpgKeyFiles, err := blueprint.RepoCustomizationsToRepoConfigAndGPGKeyFiles(customRepos)
-	if err != nil {
-		panic(fmt.Sprintf("failed to convert inline gpgkeys to fs node files: %v", err))
-	}
-
-	// add the gpg key files to the list of files to be added to the tree
-	if len(gpgKeyFiles) > 0 {
-		osc.Files = append(osc.Files, gpgKeyFiles...)
-	}
-
-	for filename, repos := range yumRepos {
-		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
-	}
-
-	osc.ShellInit = imageConfig.ShellInit
-
-	osc.Grub2Config = imageConfig.Grub2Config
-	osc.Sysconfig = imageConfig.Sysconfig
-	osc.SystemdLogind = imageConfig.SystemdLogind
-	osc.CloudInit = imageConfig.CloudInit
-	osc.Modprobe = imageConfig.Modprobe
-	osc.DracutConf = imageConfig.DracutConf
-	osc.SystemdUnit = imageConfig.SystemdUnit
-	osc.Authselect = imageConfig.Authselect
-	osc.SELinuxConfig = imageConfig.SELinuxConfig
-	osc.Tuned = imageConfig.Tuned
-	osc.Tmpfilesd = imageConfig.Tmpfilesd
-	osc.PamLimitsConf = imageConfig.PamLimitsConf
-	osc.Sysctld = imageConfig.Sysctld
-	osc.DNFConfig = imageConfig.DNFConfig
-	osc.DNFAutomaticConfig = imageConfig.DNFAutomaticConfig
-	osc.SshdConfig = imageConfig.SshdConfig
-	osc.AuthConfig = imageConfig.Authconfig
-	osc.PwQuality = imageConfig.PwQuality
-	osc.RHSMConfig = imageConfig.RHSMConfig
-	osc.Subscription = options.Subscription
-	osc.WAAgentConfig = imageConfig.WAAgentConfig
-	osc.UdevRules = imageConfig.UdevRules
-	osc.GCPGuestAgentConfig = imageConfig.GCPGuestAgentConfig
-	osc.WSLConfig = imageConfig.WSLConfig
-
 	return osc
 }
 
@@ -238,8 +84,13 @@ func diskImage(workload workload.Workload,
 	img.Environment = t.environment
 	img.Workload = workload
 	img.Compression = t.compression
+	img.CompressionLevel = t.compressionLevel
+	img.CompressionThreads = t.compressionThreads
+	if options.VHD != nil {
+		img.VPCSubformat = options.VHD.Subformat
+	}
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -329,9 +180,17 @@ func edgeInstallerImage(workload workload.Workload,
 
 	img.Platform = t.platform
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "xz"
 	img.AdditionalDracutModules = []string{
 		"nvdimm", // non-volatile DIMM firmware (provides nfit, cuse, and nd_e820)
@@ -376,7 +235,10 @@ func edgeRawImage(workload workload.Workload,
 		img.Ignition = true
 	}
 
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	// "rw" kernel option is required when /sysroot is mounted read-only to
@@ -408,7 +270,7 @@ func edgeRawImage(workload workload.Workload,
 	}
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -416,6 +278,8 @@ func edgeRawImage(workload workload.Workload,
 
 	img.Filename = t.Filename()
 	img.Compression = t.compression
+	img.CompressionLevel = t.compressionLevel
+	img.CompressionThreads = t.compressionThreads
 
 	return img, nil
 }
@@ -438,7 +302,10 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 		rawImg.Ignition = true
 	}
 
-	rawImg.Users = users.UsersFromBP(customizations.GetUsers())
+	rawImg.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	rawImg.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	// "rw" kernel option is required when /sysroot is mounted read-only to
@@ -461,7 +328,7 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.OSName = "redhat"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -522,9 +389,18 @@ func imageInstallerImage(workload workload.Workload,
 	img.Workload = workload
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
-	img.Users = users.UsersFromBP(customizations.GetUsers())
+	var err error
+	img.Users, err = users.UsersFromBP(customizations.GetUsers())
+	if err != nil {
+		return nil, err
+	}
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	img.Kickstart, err = distro.NewKickstartInstallOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.AdditionalDracutModules = []string{
 		"nvdimm", // non-volatile DIMM firmware (provides nfit, cuse, and nd_e820)
 		"prefixdevname",
@@ -0,0 +1,45 @@
+package rhel9
+
+import (
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const vhdxKernelOptions = "ro net.ifnames=0"
+
+// Hyper-V image type
+var vhdxImgType = imageType{
+	name:     "vhdx",
+	filename: "disk.vhdx",
+	mimeType: "application/x-vhdx",
+	packageSets: map[string]packageSetFunc{
+		osPkgsKey: vhdxCommonPackageSet,
+	},
+	defaultImageConfig: &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	},
+	kernelOptions:       vhdxKernelOptions,
+	bootable:            true,
+	defaultSize:         4 * common.GibiByte,
+	image:               diskImage,
+	buildPipelines:      []string{"build"},
+	payloadPipelines:    []string{"os", "image", "vhdx"},
+	exports:             []string{"vhdx"},
+	basePartitionTables: defaultBasePartitionTables,
+}
+
+func vhdxCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"chrony",
+			"cloud-init",
+			"firewalld",
+			"hyperv-daemons",
+			"langpacks-en",
+		},
+		Exclude: []string{
+			"rng-tools",
+		},
+	}.Append(coreOsCommonPackageSet(t))
+}
@@ -0,0 +1,24 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// addSystemdUnitFiles adds the unit and drop-in files of the systemd
+// customization to the OS customizations.
+func addSystemdUnitFiles(osc *manifest.OSCustomizations, c *blueprint.Customizations) error {
+	systemd, err := c.GetSystemd()
+	if err != nil {
+		return fmt.Errorf("failed to get systemd customization: %w", err)
+	}
+	systemdUnitFiles, err := blueprint.SystemdUnitsToFsNodeFiles(systemd)
+	if err != nil {
+		return fmt.Errorf("failed to convert systemd units to fs node files: %w", err)
+	}
+	osc.Files = append(osc.Files, systemdUnitFiles...)
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+func TestAddSystemdUnitFiles(t *testing.T) {
+	osc := manifest.OSCustomizations{}
+	require.NoError(t, addSystemdUnitFiles(&osc, &blueprint.Customizations{}))
+	assert.Empty(t, osc.Files)
+
+	c := &blueprint.Customizations{
+		Systemd: &blueprint.SystemdCustomization{
+			Units: []blueprint.SystemdUnitCustomization{
+				{
+					Name: "backup.service",
+					Config: blueprint.SystemdUnitConfigCustomization{
+						Service: &blueprint.SystemdServiceSectionCustomization{ExecStart: []string{"/usr/bin/backup"}},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, addSystemdUnitFiles(&osc, c))
+	require.Len(t, osc.Files, 1)
+	assert.Equal(t, "/etc/systemd/system/backup.service", osc.Files[0].Path())
+}
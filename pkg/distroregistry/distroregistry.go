@@ -7,6 +7,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/definition"
 	"github.com/osbuild/images/pkg/distro/fedora"
 	"github.com/osbuild/images/pkg/distro/rhel7"
 	"github.com/osbuild/images/pkg/distro/rhel8"
@@ -39,10 +40,18 @@ var supportedDistros = []func() distro.Distro{
 	rhel9.NewCentOS9,
 }
 
+// Constructors for derivatives of supported distributions, keyed by the name
+// of the family they belong to.
+var derivativeFamilies = map[string]func(distro.DerivativeOptions) (distro.Distro, error){
+	"rhel8": rhel8.NewDerivative,
+	"rhel9": rhel9.NewDerivative,
+}
+
 type Registry struct {
-	distros      map[string]distro.Distro
-	hostDistro   distro.Distro
-	hostArchName string
+	distros        map[string]distro.Distro
+	hostDistro     distro.Distro
+	hostDistroName string
+	hostArchName   string
 }
 
 func New(hostDistro distro.Distro, distros ...distro.Distro) (*Registry, error) {
@@ -85,10 +94,68 @@ func NewDefault() *Registry {
 	if err != nil {
 		panic(fmt.Sprintf("two supported distros have the same name, this is a programming error: %v", err))
 	}
+	registry.hostDistroName = hostDistroName
 
 	return registry
 }
 
+// Add registers an additional distro, e.g. one created from a distro
+// definition, with the Registry.
+func (r *Registry) Add(d distro.Distro) error {
+	name := d.Name()
+	if _, exists := r.distros[name]; exists {
+		return fmt.Errorf("Add: a distro with the same name is already registered: %s", name)
+	}
+	r.distros[name] = d
+	if r.hostDistro == nil && r.hostDistroName != "" && name == r.hostDistroName {
+		r.hostDistro = d
+	}
+	return nil
+}
+
+// RegisterDerivative creates a distro derived from a supported distro family,
+// e.g. "rhel9", with the identity defined by the options and registers it
+// with the Registry. The repositories of the derivative are looked up by its
+// name, like for all other distros.
+func (r *Registry) RegisterDerivative(family string, options distro.DerivativeOptions) (distro.Distro, error) {
+	newDerivative, exists := derivativeFamilies[family]
+	if !exists {
+		return nil, fmt.Errorf("RegisterDerivative: unknown distro family %q (supported: %s)", family, strings.Join(DerivativeFamilies(), ", "))
+	}
+	d, err := newDerivative(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Add(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DerivativeFamilies returns the sorted names of the distro families that
+// derivatives can be based on.
+func DerivativeFamilies() []string {
+	families := make([]string, 0, len(derivativeFamilies))
+	for family := range derivativeFamilies {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	return families
+}
+
+// LoadDefinition creates a distro from the distro definition file at the
+// given path and registers it with the Registry.
+func (r *Registry) LoadDefinition(path string) (distro.Distro, error) {
+	d, err := definition.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Add(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 func (r *Registry) GetDistro(name string) distro.Distro {
 	d, ok := r.distros[name]
 	if !ok {
@@ -120,3 +120,53 @@ func TestRegistry_FromHost(t *testing.T) {
 		require.Equal(t, gotDistro.Name(), hostDistro.Name())
 	})
 }
+
+func TestRegistry_LoadDefinition(t *testing.T) {
+	distros := NewDefault()
+
+	d, err := distros.LoadDefinition("../distro/definition/testdata/example.yaml")
+	require.NoError(t, err)
+	require.Equal(t, d, distros.GetDistro("example-9.3"))
+	require.Contains(t, distros.List(), "example-9.3")
+
+	_, err = distros.LoadDefinition("../distro/definition/testdata/example.yaml")
+	require.EqualError(t, err, "Add: a distro with the same name is already registered: example-9.3")
+
+	require.Error(t, distros.Add(rhel8.New()))
+}
+
+func TestRegistry_RegisterDerivative(t *testing.T) {
+	distros := NewDefault()
+
+	options := distro.DerivativeOptions{
+		Name:      "rocky-93",
+		Product:   "Rocky Linux",
+		OSVersion: "9.3",
+		Vendor:    "rocky",
+	}
+	d, err := distros.RegisterDerivative("rhel9", options)
+	require.NoError(t, err)
+	require.Equal(t, d, distros.GetDistro("rocky-93"))
+	require.Equal(t, "platform:el9", d.ModulePlatformID())
+
+	_, err = distros.RegisterDerivative("rhel9", options)
+	require.EqualError(t, err, "Add: a distro with the same name is already registered: rocky-93")
+
+	_, err = distros.RegisterDerivative("debian12", options)
+	require.EqualError(t, err, `RegisterDerivative: unknown distro family "debian12" (supported: rhel8, rhel9)`)
+}
+
+func TestRegistry_DerivativeAsHostDistro(t *testing.T) {
+	distros, err := New(nil)
+	require.NoError(t, err)
+	distros.hostDistroName = "rocky-93"
+
+	d, err := distros.RegisterDerivative("rhel9", distro.DerivativeOptions{
+		Name:      "rocky-93",
+		Product:   "Rocky Linux",
+		OSVersion: "9.3",
+		Vendor:    "rocky",
+	})
+	require.NoError(t, err)
+	require.Equal(t, d, distros.FromHost())
+}
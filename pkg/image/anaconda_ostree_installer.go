@@ -9,6 +9,7 @@ import (
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -22,6 +23,9 @@ type AnacondaOSTreeInstaller struct {
 	Users             []users.User
 	Groups            []users.Group
 
+	// Kickstart commands for unattended installations
+	Kickstart *osbuild.KickstartInstallOptions
+
 	SquashfsCompression string
 
 	ISOLabelTempl string
@@ -119,6 +123,7 @@ func (img *AnacondaOSTreeInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	// For ostree installers, always put the kickstart file in the root of the ISO
 	isoTreePipeline.KSPath = kspath
+	isoTreePipeline.Kickstart = img.Kickstart
 	isoTreePipeline.PayloadPath = "/ostree/repo"
 
 	isoTreePipeline.OSTreeCommitSource = &img.Commit
@@ -12,6 +12,7 @@ import (
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/runner"
@@ -35,6 +36,10 @@ type AnacondaTarInstaller struct {
 	// default /usr/share/anaconda/interactive-defaults.ks in the rootfs.
 	ISORootKickstart bool
 
+	// Kickstart commands for unattended installations; requires
+	// ISORootKickstart.
+	Kickstart *osbuild.KickstartInstallOptions
+
 	SquashfsCompression string
 
 	ISOLabelTempl string
@@ -62,6 +67,10 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
 	rng *rand.Rand) (*artifact.Artifact, error) {
+	if img.Kickstart != nil && !img.ISORootKickstart {
+		return nil, fmt.Errorf("kickstart options require the kickstart file in the root of the ISO")
+	}
+
 	buildPipeline := manifest.NewBuild(m, runner, repos)
 	buildPipeline.Checkpoint()
 
@@ -148,6 +157,7 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	isoTreePipeline.PayloadPath = tarPath
 	if img.ISORootKickstart {
 		isoTreePipeline.KSPath = kspath
+		isoTreePipeline.Kickstart = img.Kickstart
 	}
 
 	isoTreePipeline.SquashfsCompression = img.SquashfsCompression
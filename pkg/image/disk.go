@@ -26,8 +26,13 @@ type DiskImage struct {
 	Filename         string
 	Compression      string
 	ForceSize        *bool
+	VPCSubformat     osbuild.VPCSubformat
 	PartTool         osbuild.PartTool
 
+	// Compression level of zstd and gzip and the number of threads of zstd
+	CompressionLevel   int
+	CompressionThreads int
+
 	NoBLS     bool
 	OSProduct string
 	OSVersion string
@@ -84,8 +89,16 @@ func (img *DiskImage) InstantiateManifest(m *manifest.Manifest,
 			vpcPipeline.Filename = img.Filename
 		}
 		vpcPipeline.ForceSize = img.ForceSize
+		vpcPipeline.Subformat = img.VPCSubformat
 		artifactPipeline = vpcPipeline
 		artifact = vpcPipeline.Export()
+	case platform.FORMAT_VHDX:
+		vhdxPipeline := manifest.NewVHDX(m, buildPipeline, imagePipeline)
+		if img.Compression == "" {
+			vhdxPipeline.Filename = img.Filename
+		}
+		artifactPipeline = vhdxPipeline
+		artifact = vhdxPipeline.Export()
 	case platform.FORMAT_VMDK:
 		vmdkPipeline := manifest.NewVMDK(m, buildPipeline, imagePipeline, nil)
 		if img.Compression == "" {
@@ -118,10 +131,12 @@ func (img *DiskImage) InstantiateManifest(m *manifest.Manifest,
 	}
 
 	switch img.Compression {
-	case "xz":
-		xzPipeline := manifest.NewXZ(m, buildPipeline, artifactPipeline)
-		xzPipeline.Filename = img.Filename
-		artifact = xzPipeline.Export()
+	case manifest.CompressionXZ, manifest.CompressionZstd, manifest.CompressionGzip:
+		compressionPipeline := manifest.NewCompression(m, buildPipeline, artifactPipeline, img.Compression)
+		compressionPipeline.Filename = img.Filename
+		compressionPipeline.Level = img.CompressionLevel
+		compressionPipeline.Threads = img.CompressionThreads
+		artifact = compressionPipeline.Export()
 	case "":
 		// do nothing
 	default:
@@ -41,6 +41,10 @@ type OSTreeRawImage struct {
 
 	Compression string
 
+	// Compression level of zstd and gzip and the number of threads of zstd
+	CompressionLevel   int
+	CompressionThreads int
+
 	Ignition bool
 
 	Directories []*fsnode.Directory
@@ -54,13 +58,15 @@ func NewOSTreeRawImage(commit ostree.SourceSpec) *OSTreeRawImage {
 	}
 }
 
-func ostreeCompressedImagePipelines(img *OSTreeRawImage, m *manifest.Manifest, buildPipeline *manifest.Build) *manifest.XZ {
+func ostreeCompressedImagePipelines(img *OSTreeRawImage, m *manifest.Manifest, buildPipeline *manifest.Build, algorithm string) *manifest.Compression {
 	imagePipeline := baseRawOstreeImage(img, m, buildPipeline)
 
-	xzPipeline := manifest.NewXZ(m, buildPipeline, imagePipeline)
-	xzPipeline.Filename = img.Filename
+	compressionPipeline := manifest.NewCompression(m, buildPipeline, imagePipeline, algorithm)
+	compressionPipeline.Filename = img.Filename
+	compressionPipeline.Level = img.CompressionLevel
+	compressionPipeline.Threads = img.CompressionThreads
 
-	return xzPipeline
+	return compressionPipeline
 }
 
 func baseRawOstreeImage(img *OSTreeRawImage, m *manifest.Manifest, buildPipeline *manifest.Build) *manifest.RawOSTreeImage {
@@ -102,8 +108,8 @@ func (img *OSTreeRawImage) InstantiateManifest(m *manifest.Manifest,
 		art = vmdkPipeline.Export()
 	default:
 		switch img.Compression {
-		case "xz":
-			ostreeCompressed := ostreeCompressedImagePipelines(img, m, buildPipeline)
+		case manifest.CompressionXZ, manifest.CompressionZstd, manifest.CompressionGzip:
+			ostreeCompressed := ostreeCompressedImagePipelines(img, m, buildPipeline, img.Compression)
 			art = ostreeCompressed.Export()
 		case "":
 			ostreeBase := baseRawOstreeImage(img, m, buildPipeline)
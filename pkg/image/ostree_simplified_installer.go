@@ -77,9 +77,9 @@ func (img *OSTreeSimplifiedInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	rawImageFilename := "image.raw.xz"
 
-	// create the raw image
+	// create the raw image, coreos-installer expects it to be xz compressed
 	img.rawImage.Filename = rawImageFilename
-	rawImage := ostreeCompressedImagePipelines(img.rawImage, m, buildPipeline)
+	rawImage := ostreeCompressedImagePipelines(img.rawImage, m, buildPipeline, manifest.CompressionXZ)
 
 	coiPipeline := manifest.NewCoreOSInstaller(m,
 		buildPipeline,
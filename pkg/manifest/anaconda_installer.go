@@ -384,12 +384,10 @@ func NewAnacondaInteractiveDefaults(tarPath string) *AnacondaInteractiveDefaults
 }
 
 func (p *AnacondaInstaller) getInline() []string {
-	inlineData := []string{}
-
 	// inline data for custom files
-	for _, file := range p.Files {
-		inlineData = append(inlineData, string(file.Data()))
-	}
+	return fileNodesInlineData(p.Files)
+}
 
-	return inlineData
+func (p *AnacondaInstaller) getRemoteFiles() []*fsnode.File {
+	return remoteFileNodes(p.Files)
 }
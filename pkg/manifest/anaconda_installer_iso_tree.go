@@ -37,6 +37,10 @@ type AnacondaInstallerISOTree struct {
 	// Anaconda pipeline.
 	KSPath string
 
+	// Additional kickstart commands for unattended installations. They are
+	// only used if the kickstart file is added to the bootiso-tree.
+	Kickstart *osbuild.KickstartInstallOptions
+
 	// The path where the payload (tarball or ostree repo) will be stored.
 	PayloadPath string
 
@@ -280,6 +284,9 @@ func (p *AnacondaInstallerISOTree) serialize() osbuild.Pipeline {
 		if err != nil {
 			panic("failed to create kickstartstage options")
 		}
+		if p.Kickstart != nil {
+			kickstartOptions.KickstartInstallOptions = *p.Kickstart
+		}
 
 		pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
 	}
@@ -295,6 +302,9 @@ func (p *AnacondaInstallerISOTree) serialize() osbuild.Pipeline {
 			if err != nil {
 				panic("failed to create kickstartstage options")
 			}
+			if p.Kickstart != nil {
+				kickstartOptions.KickstartInstallOptions = *p.Kickstart
+			}
 
 			pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
 		}
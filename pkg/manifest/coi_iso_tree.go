@@ -20,7 +20,7 @@ type CoreOSISOTree struct {
 
 	PartitionTable *disk.PartitionTable
 
-	payloadPipeline  *XZ
+	payloadPipeline  *Compression
 	coiPipeline      *CoreOSInstaller
 	bootTreePipeline *EFIBootTree
 
@@ -37,7 +37,7 @@ type CoreOSISOTree struct {
 
 func NewCoreOSISOTree(m *Manifest,
 	buildPipeline *Build,
-	payloadPipeline *XZ,
+	payloadPipeline *Compression,
 	coiPipeline *CoreOSInstaller,
 	bootTreePipeline *EFIBootTree,
 	isoLabel string) *CoreOSISOTree {
@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// Algorithms the Compression pipeline compresses files with
+const (
+	CompressionXZ   = "xz"
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+)
+
+// CompressionExtension returns the filename extension of files compressed
+// with the algorithm, including the leading dot.
+func CompressionExtension(algorithm string) string {
+	switch algorithm {
+	case CompressionXZ:
+		return ".xz"
+	case CompressionZstd:
+		return ".zst"
+	case CompressionGzip:
+		return ".gz"
+	default:
+		panic(fmt.Sprintf("unsupported compression type %q", algorithm))
+	}
+}
+
+// CompressionMIMEType returns the MIME type of files compressed with the
+// algorithm.
+func CompressionMIMEType(algorithm string) string {
+	switch algorithm {
+	case CompressionXZ:
+		return "application/xz"
+	case CompressionZstd:
+		return "application/zstd"
+	case CompressionGzip:
+		return "application/gzip"
+	default:
+		panic(fmt.Sprintf("unsupported compression type %q", algorithm))
+	}
+}
+
+// ValidateCompression checks that the compression level and number of
+// threads are supported by the algorithm. Zero values select the defaults.
+func ValidateCompression(algorithm string, level, threads int) error {
+	switch algorithm {
+	case CompressionXZ:
+		if level != 0 || threads != 0 {
+			return fmt.Errorf("xz compression does not support setting the level or threads")
+		}
+	case CompressionZstd:
+		if level < 0 || level > 19 {
+			return fmt.Errorf("zstd compression level %d is not between 1 and 19", level)
+		}
+		if threads < 0 {
+			return fmt.Errorf("zstd thread count %d is negative", threads)
+		}
+	case CompressionGzip:
+		if level < 0 || level > 9 {
+			return fmt.Errorf("gzip compression level %d is not between 1 and 9", level)
+		}
+		if threads != 0 {
+			return fmt.Errorf("gzip compression does not support setting the threads")
+		}
+	default:
+		return fmt.Errorf("unsupported compression type %q", algorithm)
+	}
+	return nil
+}
+
+// The Compression pipeline compresses the file exported by another pipeline,
+// e.g. a raw image. The pipeline is named after the compression algorithm.
+type Compression struct {
+	Base
+	Filename string
+
+	// Compression level of zstd and gzip, the default of the algorithm is
+	// used if unset
+	Level int
+	// Number of worker threads of zstd
+	Threads int
+
+	algorithm   string
+	imgPipeline Pipeline
+}
+
+// NewCompression creates a new Compression pipeline. imgPipeline is the
+// pipeline producing the file that will be compressed with the algorithm,
+// one of xz, zstd and gzip.
+func NewCompression(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline Pipeline,
+	algorithm string) *Compression {
+	p := &Compression{
+		Base:        NewBase(m, algorithm, buildPipeline),
+		Filename:    "image" + CompressionExtension(algorithm),
+		algorithm:   algorithm,
+		imgPipeline: imgPipeline,
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *Compression) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	if err := ValidateCompression(p.algorithm, p.Level, p.Threads); err != nil {
+		panic(err)
+	}
+
+	input := osbuild.NewFilesInputPipelineObjectRef(p.imgPipeline.Name(), p.imgPipeline.Export().Filename(), nil)
+	switch p.algorithm {
+	case CompressionXZ:
+		pipeline.AddStage(osbuild.NewXzStage(osbuild.NewXzStageOptions(p.Filename), osbuild.NewXzStageInputs(input)))
+	case CompressionZstd:
+		options := osbuild.NewZstdStageOptions(p.Filename)
+		options.Level = p.Level
+		options.Threads = p.Threads
+		pipeline.AddStage(osbuild.NewZstdStage(options, osbuild.NewZstdStageInputs(input)))
+	case CompressionGzip:
+		options := osbuild.NewGzipStageOptions(p.Filename)
+		options.Level = p.Level
+		pipeline.AddStage(osbuild.NewGzipStage(options, osbuild.NewGzipStageInputs(input)))
+	}
+
+	return pipeline
+}
+
+func (p *Compression) getBuildPackages(Distro) []string {
+	return []string{p.algorithm}
+}
+
+func (p *Compression) Export() *artifact.Artifact {
+	p.Base.export = true
+	mimeType := CompressionMIMEType(p.algorithm)
+	return artifact.New(p.Name(), p.Filename, &mimeType)
+}
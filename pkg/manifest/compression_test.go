@@ -0,0 +1,108 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+)
+
+func newTestCompression(algorithm string) *Compression {
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 39}, []rpmmd.RepoConfig{})
+	os := NewOS(&m, build, &platform.X86{BIOS: true}, []rpmmd.RepoConfig{})
+	img := NewRawImage(&m, build, os)
+	return NewCompression(&m, build, img, algorithm)
+}
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		level     int
+		threads   int
+		filename  string
+		mimeType  string
+		stage     *osbuild.Stage
+	}{
+		{
+			algorithm: CompressionXZ,
+			filename:  "image.xz",
+			mimeType:  "application/xz",
+			stage: osbuild.NewXzStage(
+				osbuild.NewXzStageOptions("image.xz"),
+				osbuild.NewXzStageInputs(osbuild.NewFilesInputPipelineObjectRef("image", "disk.img", nil)),
+			),
+		},
+		{
+			algorithm: CompressionZstd,
+			level:     19,
+			threads:   4,
+			filename:  "image.zst",
+			mimeType:  "application/zstd",
+			stage: osbuild.NewZstdStage(
+				&osbuild.ZstdStageOptions{Filename: "image.zst", Level: 19, Threads: 4},
+				osbuild.NewZstdStageInputs(osbuild.NewFilesInputPipelineObjectRef("image", "disk.img", nil)),
+			),
+		},
+		{
+			algorithm: CompressionGzip,
+			level:     1,
+			filename:  "image.gz",
+			mimeType:  "application/gzip",
+			stage: osbuild.NewGzipStage(
+				&osbuild.GzipStageOptions{Filename: "image.gz", Level: 1},
+				osbuild.NewGzipStageInputs(osbuild.NewFilesInputPipelineObjectRef("image", "disk.img", nil)),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			p := newTestCompression(tc.algorithm)
+			p.Level = tc.level
+			p.Threads = tc.threads
+
+			assert.Equal(t, tc.algorithm, p.Name())
+			assert.Equal(t, []string{tc.algorithm}, p.getBuildPackages(DISTRO_NULL))
+
+			artifact := p.Export()
+			assert.Equal(t, tc.filename, artifact.Filename())
+			assert.Equal(t, tc.mimeType, artifact.MIMEType())
+
+			pipeline := p.serialize()
+			assert.Equal(t, []*osbuild.Stage{tc.stage}, pipeline.Stages)
+		})
+	}
+}
+
+func TestCompressionInvalid(t *testing.T) {
+	assert.PanicsWithValue(t, `unsupported compression type "bzip2"`, func() { newTestCompression("bzip2") })
+
+	p := newTestCompression(CompressionXZ)
+	p.Level = 9
+	assert.PanicsWithError(t, "xz compression does not support setting the level or threads", func() { p.serialize() })
+
+	p = newTestCompression(CompressionGzip)
+	p.Threads = 2
+	assert.PanicsWithError(t, "gzip compression does not support setting the threads", func() { p.serialize() })
+
+	p = newTestCompression(CompressionZstd)
+	p.Level = 22
+	assert.PanicsWithError(t, "zstd compression level 22 is not between 1 and 19", func() { p.serialize() })
+}
+
+func TestNewXZ(t *testing.T) {
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 39}, []rpmmd.RepoConfig{})
+	os := NewOS(&m, build, &platform.X86{BIOS: true}, []rpmmd.RepoConfig{})
+	img := NewRawImage(&m, build, os)
+	p := NewXZ(&m, build, img)
+
+	assert.Equal(t, CompressionXZ, p.Name())
+	assert.Equal(t, "image.xz", p.Filename)
+	assert.Equal(t, "application/xz", p.Export().MIMEType())
+}
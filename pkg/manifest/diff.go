@@ -0,0 +1,617 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ManifestDiff is the semantic difference between two serialized manifests.
+// Values that change between otherwise identical manifests, like content
+// checksums and the UUIDs generated from the seed, are ignored. Content that
+// stages reference from the sources (e.g. packages) is compared by name.
+type ManifestDiff struct {
+	Pipelines []PipelineDiff `json:"pipelines,omitempty"`
+	Sources   []SourceDiff   `json:"sources,omitempty"`
+}
+
+// PipelineDiff describes the changes of a pipeline. Pipelines are matched by
+// name.
+type PipelineDiff struct {
+	Name    string `json:"name"`
+	Added   bool   `json:"added,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+
+	// Changes of the pipeline properties, e.g. the build pipeline or runner
+	Changes []ValueChange `json:"changes,omitempty"`
+
+	Stages []StageDiff `json:"stages,omitempty"`
+}
+
+// StageDiff describes the changes of a stage. Stages are matched by type in
+// the order they appear in the pipelines.
+type StageDiff struct {
+	Type string `json:"type"`
+
+	// Position of the stage in the old and new pipeline; -1 if the stage was
+	// added or removed respectively
+	OldIndex int `json:"old_index"`
+	NewIndex int `json:"new_index"`
+
+	// The stage was moved to a different position relative to the other
+	// stages of the pipeline
+	Moved bool `json:"moved,omitempty"`
+
+	// Changes of the options, inputs, devices and mounts of the stage
+	Changes []ValueChange `json:"changes,omitempty"`
+
+	// Names of the content the stage references from the sources, e.g. the
+	// file names of the packages installed by an org.osbuild.rpm stage
+	AddedContent   []string `json:"added_content,omitempty"`
+	RemovedContent []string `json:"removed_content,omitempty"`
+}
+
+func (sd StageDiff) Added() bool {
+	return sd.OldIndex < 0
+}
+
+func (sd StageDiff) Removed() bool {
+	return sd.NewIndex < 0
+}
+
+// SourceDiff lists the items that were added to or removed from a source.
+// Items are described by their URL where possible.
+type SourceDiff struct {
+	Type    string   `json:"type"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ValueChange is a change of a single value. Old is nil if the value was
+// added, New is nil if it was removed.
+type ValueChange struct {
+	// JSON pointer to the value
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Empty returns true if there are no differences.
+func (d *ManifestDiff) Empty() bool {
+	return len(d.Pipelines) == 0 && len(d.Sources) == 0
+}
+
+func (d *ManifestDiff) String() string {
+	var b strings.Builder
+	for _, p := range d.Pipelines {
+		switch {
+		case p.Added:
+			fmt.Fprintf(&b, "+ pipeline %q\n", p.Name)
+			continue
+		case p.Removed:
+			fmt.Fprintf(&b, "- pipeline %q\n", p.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "~ pipeline %q\n", p.Name)
+		writeValueChanges(&b, "    ", p.Changes)
+		for _, s := range p.Stages {
+			switch {
+			case s.Added():
+				fmt.Fprintf(&b, "  + stage %d %s\n", s.NewIndex, s.Type)
+				continue
+			case s.Removed():
+				fmt.Fprintf(&b, "  - stage %d %s\n", s.OldIndex, s.Type)
+				continue
+			case s.Moved:
+				fmt.Fprintf(&b, "  ~ stage %d %s (moved from %d)\n", s.NewIndex, s.Type, s.OldIndex)
+			default:
+				fmt.Fprintf(&b, "  ~ stage %d %s\n", s.NewIndex, s.Type)
+			}
+			writeValueChanges(&b, "      ", s.Changes)
+			for _, c := range s.AddedContent {
+				fmt.Fprintf(&b, "      + %s\n", c)
+			}
+			for _, c := range s.RemovedContent {
+				fmt.Fprintf(&b, "      - %s\n", c)
+			}
+		}
+	}
+	for _, s := range d.Sources {
+		fmt.Fprintf(&b, "~ source %s\n", s.Type)
+		for _, item := range s.Added {
+			fmt.Fprintf(&b, "    + %s\n", item)
+		}
+		for _, item := range s.Removed {
+			fmt.Fprintf(&b, "    - %s\n", item)
+		}
+	}
+	return b.String()
+}
+
+func writeValueChanges(b *strings.Builder, indent string, changes []ValueChange) {
+	for _, c := range changes {
+		switch {
+		case c.Old == nil:
+			fmt.Fprintf(b, "%s+ %s: %s\n", indent, c.Path, formatValue(c.New))
+		case c.New == nil:
+			fmt.Fprintf(b, "%s- %s: %s\n", indent, c.Path, formatValue(c.Old))
+		default:
+			fmt.Fprintf(b, "%s~ %s: %s -> %s\n", indent, c.Path, formatValue(c.Old), formatValue(c.New))
+		}
+	}
+}
+
+func formatValue(v interface{}) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	// keep the placeholders of volatile values readable
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Diff computes the semantic difference between the manifests a and b.
+func Diff(a, b OSBuildManifest) (*ManifestDiff, error) {
+	oldMf, err := parseDiffManifest(a)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse old manifest: %w", err)
+	}
+	newMf, err := parseDiffManifest(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse new manifest: %w", err)
+	}
+
+	d := &ManifestDiff{}
+
+	oldPipelines := make(map[string]*diffPipeline, len(oldMf.pipelines))
+	for idx := range oldMf.pipelines {
+		oldPipelines[oldMf.pipelines[idx].Name] = &oldMf.pipelines[idx]
+	}
+	newPipelines := make(map[string]*diffPipeline, len(newMf.pipelines))
+	for idx := range newMf.pipelines {
+		newPipelines[newMf.pipelines[idx].Name] = &newMf.pipelines[idx]
+	}
+
+	for _, p := range oldMf.pipelines {
+		if _, exists := newPipelines[p.Name]; !exists {
+			d.Pipelines = append(d.Pipelines, PipelineDiff{Name: p.Name, Removed: true})
+		}
+	}
+	for idx := range newMf.pipelines {
+		p := &newMf.pipelines[idx]
+		oldP, exists := oldPipelines[p.Name]
+		if !exists {
+			d.Pipelines = append(d.Pipelines, PipelineDiff{Name: p.Name, Added: true})
+			continue
+		}
+		if pd := diffPipelines(oldP, p); pd != nil {
+			d.Pipelines = append(d.Pipelines, *pd)
+		}
+	}
+
+	d.Sources = diffSources(oldMf.sources, newMf.sources)
+
+	return d, nil
+}
+
+// diffManifest is the normalized form of a manifest used for the comparison
+type diffManifest struct {
+	pipelines []diffPipeline
+
+	// descriptions of the source items by source type and item ID
+	sources map[string]map[string]string
+}
+
+type diffPipeline struct {
+	Name   string        `json:"name"`
+	Build  string        `json:"build"`
+	Runner string        `json:"runner"`
+	Stages []interface{} `json:"stages"`
+
+	stages []diffStage
+}
+
+type diffStage struct {
+	typ     string
+	value   interface{}
+	content []string
+}
+
+func parseDiffManifest(data OSBuildManifest) (*diffManifest, error) {
+	var mf struct {
+		Pipelines []diffPipeline `json:"pipelines"`
+		Sources   map[string]struct {
+			Items map[string]interface{} `json:"items"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &mf); err != nil {
+		return nil, err
+	}
+
+	dm := &diffManifest{
+		pipelines: mf.Pipelines,
+		sources:   make(map[string]map[string]string, len(mf.Sources)),
+	}
+
+	// content references in the stages are resolved to the base name of the
+	// URL, e.g. the package file name, or the description of the item
+	contentNames := make(map[string]string)
+	for sourceType, source := range mf.Sources {
+		items := make(map[string]string, len(source.Items))
+		for id, item := range source.Items {
+			desc := describeSourceItem(sourceType, item)
+			items[id] = desc
+			if sourceType == "org.osbuild.curl" {
+				contentNames[id] = path.Base(desc)
+			} else {
+				contentNames[id] = desc
+			}
+		}
+		dm.sources[sourceType] = items
+	}
+
+	for pIdx := range dm.pipelines {
+		p := &dm.pipelines[pIdx]
+		p.Build = normalizeString(p.Build)
+		for sIdx, s := range p.Stages {
+			stage, ok := s.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("pipeline %q: stage %d is not an object", p.Name, sIdx)
+			}
+			p.stages = append(p.stages, newDiffStage(stage, contentNames))
+		}
+	}
+
+	return dm, nil
+}
+
+func newDiffStage(stage map[string]interface{}, contentNames map[string]string) diffStage {
+	ds := diffStage{}
+	ds.typ, _ = stage["type"].(string)
+
+	// references to content from the sources are compared separately by
+	// name, the remaining properties of the inputs as part of the stage
+	if inputs, ok := stage["inputs"].(map[string]interface{}); ok {
+		for _, i := range inputs {
+			input, ok := i.(map[string]interface{})
+			if !ok || input["origin"] != "org.osbuild.source" {
+				continue
+			}
+			for _, id := range referenceIDs(input["references"]) {
+				name, exists := contentNames[id]
+				if !exists {
+					name = normalizeString(id)
+				}
+				ds.content = append(ds.content, name)
+			}
+			delete(input, "references")
+		}
+	}
+	sort.Strings(ds.content)
+
+	delete(stage, "type")
+	ds.value = normalizeValue(stage)
+	return ds
+}
+
+// referenceIDs returns the IDs of the referenced items of an input, which are
+// either a list of IDs, a list of objects with an ID or an object with the IDs
+// as keys.
+func referenceIDs(references interface{}) []string {
+	var ids []string
+	switch refs := references.(type) {
+	case []interface{}:
+		for _, ref := range refs {
+			switch r := ref.(type) {
+			case string:
+				ids = append(ids, r)
+			case map[string]interface{}:
+				if id, ok := r["id"].(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for id := range refs {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func describeSourceItem(sourceType string, item interface{}) string {
+	if sourceType == "org.osbuild.curl" {
+		switch i := item.(type) {
+		case string:
+			return i
+		case map[string]interface{}:
+			if url, ok := i["url"].(string); ok {
+				return url
+			}
+		}
+	}
+	return formatValue(normalizeValue(item))
+}
+
+var (
+	checksumRe = regexp.MustCompile(`(md5|sha1|sha256|sha384|sha512):[0-9a-f]{32,128}`)
+	uuidRe     = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	volIDRe    = regexp.MustCompile(`^[0-9A-F]{4}-[0-9A-F]{4}$`)
+)
+
+// normalizeString replaces volatile values in s with placeholders.
+func normalizeString(s string) string {
+	s = checksumRe.ReplaceAllString(s, "<checksum>")
+	s = uuidRe.ReplaceAllString(s, "<uuid>")
+	// vfat volume IDs
+	s = volIDRe.ReplaceAllString(s, "<volid>")
+	return s
+}
+
+// normalizeValue replaces volatile values in v, a value decoded from JSON,
+// with placeholders.
+func normalizeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return normalizeString(val)
+	case []interface{}:
+		norm := make([]interface{}, len(val))
+		for idx, item := range val {
+			norm[idx] = normalizeValue(item)
+		}
+		return norm
+	case map[string]interface{}:
+		norm := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			norm[normalizeString(key)] = normalizeValue(item)
+		}
+		return norm
+	default:
+		return v
+	}
+}
+
+func diffPipelines(a, b *diffPipeline) *PipelineDiff {
+	pd := &PipelineDiff{Name: b.Name}
+	if a.Build != b.Build {
+		pd.Changes = append(pd.Changes, newValueChange("/build", a.Build, b.Build))
+	}
+	if a.Runner != b.Runner {
+		pd.Changes = append(pd.Changes, newValueChange("/runner", a.Runner, b.Runner))
+	}
+
+	// match stages by type; the stages that are not part of the longest
+	// common subsequence are added or removed, unless an identical stage was
+	// removed or added at another position, i.e. the stage was moved
+	pairs := matchStages(a.stages, b.stages)
+	var added, removed []int
+	matchedOld := make(map[int]bool, len(pairs))
+	matchedNew := make(map[int]bool, len(pairs))
+	for _, pair := range pairs {
+		matchedOld[pair[0]] = true
+		matchedNew[pair[1]] = true
+	}
+	for idx := range a.stages {
+		if !matchedOld[idx] {
+			removed = append(removed, idx)
+		}
+	}
+	for idx := range b.stages {
+		if !matchedNew[idx] {
+			added = append(added, idx)
+		}
+	}
+
+	var moved [][2]int
+	for aIdx := 0; aIdx < len(added); aIdx++ {
+		newStage := b.stages[added[aIdx]]
+		for rIdx := 0; rIdx < len(removed); rIdx++ {
+			oldStage := a.stages[removed[rIdx]]
+			if oldStage.typ == newStage.typ && reflect.DeepEqual(oldStage, newStage) {
+				moved = append(moved, [2]int{removed[rIdx], added[aIdx]})
+				removed = append(removed[:rIdx], removed[rIdx+1:]...)
+				added = append(added[:aIdx], added[aIdx+1:]...)
+				aIdx--
+				break
+			}
+		}
+	}
+
+	for _, idx := range removed {
+		pd.Stages = append(pd.Stages, StageDiff{Type: a.stages[idx].typ, OldIndex: idx, NewIndex: -1})
+	}
+	for _, idx := range added {
+		pd.Stages = append(pd.Stages, StageDiff{Type: b.stages[idx].typ, OldIndex: -1, NewIndex: idx})
+	}
+	for _, pair := range moved {
+		pd.Stages = append(pd.Stages, StageDiff{Type: b.stages[pair[1]].typ, OldIndex: pair[0], NewIndex: pair[1], Moved: true})
+	}
+	for _, pair := range pairs {
+		oldStage, newStage := a.stages[pair[0]], b.stages[pair[1]]
+		sd := StageDiff{
+			Type:     newStage.typ,
+			OldIndex: pair[0],
+			NewIndex: pair[1],
+			Changes:  diffValues("", oldStage.value, newStage.value),
+		}
+		sd.AddedContent, sd.RemovedContent = diffStringSets(oldStage.content, newStage.content)
+		if len(sd.Changes) > 0 || len(sd.AddedContent) > 0 || len(sd.RemovedContent) > 0 {
+			pd.Stages = append(pd.Stages, sd)
+		}
+	}
+
+	// list the stages in the order of the new pipeline, removed stages
+	// before the stage that follows them in the old pipeline
+	sort.SliceStable(pd.Stages, func(i, j int) bool {
+		return stageSortKey(pd.Stages[i]) < stageSortKey(pd.Stages[j])
+	})
+
+	if len(pd.Changes) == 0 && len(pd.Stages) == 0 {
+		return nil
+	}
+	return pd
+}
+
+func stageSortKey(sd StageDiff) float64 {
+	if sd.Removed() {
+		return float64(sd.OldIndex) - 0.5
+	}
+	return float64(sd.NewIndex)
+}
+
+// matchStages returns the index pairs of the longest common subsequence of
+// the stage types of a and b.
+func matchStages(a, b []diffStage) [][2]int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].typ == b[j].typ {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].typ == b[j].typ:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+func newValueChange(path string, old, new interface{}) ValueChange {
+	if old == "" {
+		old = nil
+	}
+	if new == "" {
+		new = nil
+	}
+	return ValueChange{Path: path, Old: old, New: new}
+}
+
+// diffValues compares two values decoded from JSON and returns the changes
+// of the leaf values. Arrays are compared element by element.
+func diffValues(pointer string, a, b interface{}) []ValueChange {
+	switch aVal := a.(type) {
+	case map[string]interface{}:
+		bVal, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(aVal)+len(bVal))
+		for key := range aVal {
+			keys = append(keys, key)
+		}
+		for key := range bVal {
+			if _, exists := aVal[key]; !exists {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var changes []ValueChange
+		for _, key := range keys {
+			keyPointer := pointer + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+			changes = append(changes, diffValues(keyPointer, aVal[key], bVal[key])...)
+		}
+		return changes
+
+	case []interface{}:
+		bVal, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		var changes []ValueChange
+		for idx := 0; idx < len(aVal) || idx < len(bVal); idx++ {
+			var aItem, bItem interface{}
+			if idx < len(aVal) {
+				aItem = aVal[idx]
+			}
+			if idx < len(bVal) {
+				bItem = bVal[idx]
+			}
+			changes = append(changes, diffValues(fmt.Sprintf("%s/%d", pointer, idx), aItem, bItem)...)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return []ValueChange{{Path: pointer, Old: a, New: b}}
+}
+
+func diffStringSets(a, b []string) (added, removed []string) {
+	count := make(map[string]int, len(a))
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		if count[s] > 0 {
+			count[s]--
+		} else {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if count[s] > 0 {
+			count[s]--
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func diffSources(a, b map[string]map[string]string) []SourceDiff {
+	types := make([]string, 0, len(a)+len(b))
+	for sourceType := range a {
+		types = append(types, sourceType)
+	}
+	for sourceType := range b {
+		if _, exists := a[sourceType]; !exists {
+			types = append(types, sourceType)
+		}
+	}
+	sort.Strings(types)
+
+	var diffs []SourceDiff
+	for _, sourceType := range types {
+		sd := SourceDiff{Type: sourceType}
+		sd.Added, sd.Removed = diffStringSets(sourceDescriptions(a[sourceType]), sourceDescriptions(b[sourceType]))
+		if len(sd.Added) > 0 || len(sd.Removed) > 0 {
+			diffs = append(diffs, sd)
+		}
+	}
+	return diffs
+}
+
+func sourceDescriptions(items map[string]string) []string {
+	descs := make([]string, 0, len(items))
+	for _, desc := range items {
+		descs = append(descs, desc)
+	}
+	return descs
+}
@@ -0,0 +1,234 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffTestManifest = `{
+  "version": "2",
+  "pipelines": [
+    {
+      "name": "build",
+      "runner": "org.osbuild.fedora38",
+      "stages": [
+        {
+          "type": "org.osbuild.rpm",
+          "inputs": {
+            "packages": {
+              "type": "org.osbuild.files",
+              "origin": "org.osbuild.source",
+              "references": [
+                {"id": "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+                {"id": "sha256:2222222222222222222222222222222222222222222222222222222222222222"}
+              ]
+            }
+          },
+          "options": {"gpgkeys": ["key1"]}
+        }
+      ]
+    },
+    {
+      "name": "os",
+      "build": "name:build",
+      "stages": [
+        {
+          "type": "org.osbuild.rpm",
+          "inputs": {
+            "packages": {
+              "type": "org.osbuild.files",
+              "origin": "org.osbuild.source",
+              "references": {
+                "sha256:2222222222222222222222222222222222222222222222222222222222222222": {}
+              }
+            }
+          }
+        },
+        {"type": "org.osbuild.locale", "options": {"language": "en_US.UTF-8"}},
+        {"type": "org.osbuild.hostname", "options": {"hostname": "localhost"}},
+        {
+          "type": "org.osbuild.fstab",
+          "options": {
+            "filesystems": [
+              {"uuid": "6264d520-3fb9-423f-8ab8-7a0a8e3d3562", "path": "/"},
+              {"uuid": "7B77-95E7", "path": "/boot/efi"}
+            ]
+          }
+        }
+      ]
+    }
+  ],
+  "sources": {
+    "org.osbuild.curl": {
+      "items": {
+        "sha256:1111111111111111111111111111111111111111111111111111111111111111": {"url": "https://example.com/dnf-4.14.0-1.fc38.noarch.rpm"},
+        "sha256:2222222222222222222222222222222222222222222222222222222222222222": "https://example.com/bash-5.2.15-3.fc38.x86_64.rpm"
+      }
+    }
+  }
+}`
+
+func TestDiffIgnoresVolatileValues(t *testing.T) {
+	// same manifest with a different seed and package checksums
+	other := diffTestManifest
+	for old, new := range map[string]string{
+		"1111111111111111111111111111111111111111111111111111111111111111": "3333333333333333333333333333333333333333333333333333333333333333",
+		"6264d520-3fb9-423f-8ab8-7a0a8e3d3562":                             "cb07c243-bc44-4717-853e-28852021225b",
+		"7B77-95E7":                                                        "A1B2-C3D4",
+	} {
+		other = strings.ReplaceAll(other, old, new)
+	}
+
+	d, err := Diff(OSBuildManifest(diffTestManifest), OSBuildManifest(other))
+	require.NoError(t, err)
+	assert.True(t, d.Empty(), d.String())
+}
+
+func TestDiff(t *testing.T) {
+	testCases := map[string]struct {
+		replacements [][2]string
+		pipelines    []PipelineDiff
+		sources      []SourceDiff
+	}{
+		"pipeline-removed-and-added": {
+			replacements: [][2]string{{`"name": "os"`, `"name": "tree"`}},
+			pipelines: []PipelineDiff{
+				{Name: "os", Removed: true},
+				{Name: "tree", Added: true},
+			},
+		},
+		"pipeline-properties": {
+			replacements: [][2]string{{`"runner": "org.osbuild.fedora38"`, `"runner": "org.osbuild.fedora39"`}},
+			pipelines: []PipelineDiff{
+				{Name: "build", Changes: []ValueChange{{Path: "/runner", Old: "org.osbuild.fedora38", New: "org.osbuild.fedora39"}}},
+			},
+		},
+		"stage-options": {
+			replacements: [][2]string{
+				{`{"language": "en_US.UTF-8"}`, `{"language": "de_DE.UTF-8"}`},
+				{`{"gpgkeys": ["key1"]}`, `{"gpgkeys": ["key1", "key2"], "exclude": {"docs": true}}`},
+			},
+			pipelines: []PipelineDiff{
+				{
+					Name: "build",
+					Stages: []StageDiff{
+						{
+							Type: "org.osbuild.rpm", OldIndex: 0, NewIndex: 0,
+							Changes: []ValueChange{
+								{Path: "/options/exclude", New: map[string]interface{}{"docs": true}},
+								{Path: "/options/gpgkeys/1", New: "key2"},
+							},
+						},
+					},
+				},
+				{
+					Name: "os",
+					Stages: []StageDiff{
+						{
+							Type: "org.osbuild.locale", OldIndex: 1, NewIndex: 1,
+							Changes: []ValueChange{{Path: "/options/language", Old: "en_US.UTF-8", New: "de_DE.UTF-8"}},
+						},
+					},
+				},
+			},
+		},
+		"stage-inserted-and-removed": {
+			replacements: [][2]string{
+				{`{"type": "org.osbuild.hostname", "options": {"hostname": "localhost"}},`, `{"type": "org.osbuild.timezone", "options": {"zone": "UTC"}},`},
+			},
+			pipelines: []PipelineDiff{
+				{
+					Name: "os",
+					Stages: []StageDiff{
+						{Type: "org.osbuild.hostname", OldIndex: 2, NewIndex: -1},
+						{Type: "org.osbuild.timezone", OldIndex: -1, NewIndex: 2},
+					},
+				},
+			},
+		},
+		"stage-moved": {
+			replacements: [][2]string{
+				{`{"type": "org.osbuild.locale", "options": {"language": "en_US.UTF-8"}},
+        {"type": "org.osbuild.hostname", "options": {"hostname": "localhost"}},`,
+					`{"type": "org.osbuild.hostname", "options": {"hostname": "localhost"}},
+        {"type": "org.osbuild.locale", "options": {"language": "en_US.UTF-8"}},`},
+			},
+			pipelines: []PipelineDiff{
+				{
+					Name: "os",
+					Stages: []StageDiff{
+						{Type: "org.osbuild.locale", OldIndex: 1, NewIndex: 2, Moved: true},
+					},
+				},
+			},
+		},
+		"packages": {
+			replacements: [][2]string{{"dnf-4.14.0-1.fc38", "dnf-4.15.0-1.fc38"}},
+			pipelines: []PipelineDiff{
+				{
+					Name: "build",
+					Stages: []StageDiff{
+						{
+							Type: "org.osbuild.rpm", OldIndex: 0, NewIndex: 0,
+							AddedContent:   []string{"dnf-4.15.0-1.fc38.noarch.rpm"},
+							RemovedContent: []string{"dnf-4.14.0-1.fc38.noarch.rpm"},
+						},
+					},
+				},
+			},
+			sources: []SourceDiff{
+				{
+					Type:    "org.osbuild.curl",
+					Added:   []string{"https://example.com/dnf-4.15.0-1.fc38.noarch.rpm"},
+					Removed: []string{"https://example.com/dnf-4.14.0-1.fc38.noarch.rpm"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			other := diffTestManifest
+			for _, r := range tc.replacements {
+				require.Contains(t, other, r[0])
+				other = strings.ReplaceAll(other, r[0], r[1])
+			}
+
+			d, err := Diff(OSBuildManifest(diffTestManifest), OSBuildManifest(other))
+			require.NoError(t, err)
+			assert.Equal(t, tc.pipelines, d.Pipelines)
+			assert.Equal(t, tc.sources, d.Sources)
+			assert.False(t, d.Empty())
+		})
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	other := strings.ReplaceAll(diffTestManifest, "dnf-4.14.0-1.fc38", "dnf-4.15.0-1.fc38")
+	other = strings.ReplaceAll(other, `{"language": "en_US.UTF-8"}`, `{"language": "de_DE.UTF-8"}`)
+	other = strings.ReplaceAll(other, `{"type": "org.osbuild.hostname", "options": {"hostname": "localhost"}},`, "")
+	other = strings.ReplaceAll(other, `"name": "build",`, `"name": "build-root",`)
+	other = strings.ReplaceAll(other, `"build": "name:build"`, `"build": "name:build-root"`)
+
+	d, err := Diff(OSBuildManifest(diffTestManifest), OSBuildManifest(other))
+	require.NoError(t, err)
+	assert.Equal(t, `- pipeline "build"
++ pipeline "build-root"
+~ pipeline "os"
+    ~ /build: "name:build" -> "name:build-root"
+  ~ stage 1 org.osbuild.locale
+      ~ /options/language: "en_US.UTF-8" -> "de_DE.UTF-8"
+  - stage 2 org.osbuild.hostname
+~ source org.osbuild.curl
+    + https://example.com/dnf-4.15.0-1.fc38.noarch.rpm
+    - https://example.com/dnf-4.14.0-1.fc38.noarch.rpm
+`, d.String())
+}
+
+func TestDiffInvalid(t *testing.T) {
+	_, err := Diff(OSBuildManifest("{}"), OSBuildManifest(`{"pipelines": [{"name": "os", "stages": [1]}]}`))
+	assert.EqualError(t, err, `cannot parse new manifest: pipeline "os": stage 0 is not an object`)
+}
@@ -15,7 +15,10 @@ package manifest
 import (
 	"encoding/json"
 
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/remotefile"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -71,6 +74,11 @@ type Manifest struct {
 	// generate. It is used for determining package names that differ between
 	// different distributions and version.
 	Distro Distro
+
+	// SourceEpoch, if set, is the timestamp (in seconds since the epoch)
+	// used for all the pipelines of the manifest, which makes the contents
+	// of the image independent of the time of the build.
+	SourceEpoch *int64
 }
 
 func New() Manifest {
@@ -129,20 +137,40 @@ func (m Manifest) GetOSTreeSourceSpecs() map[string][]ostree.SourceSpec {
 	return ostreeSpecs
 }
 
+// GetRemoteFileSourceSpecs returns the remote files embedded in the trees of
+// the pipelines, keyed by the name of the pipeline. They can be fetched and
+// verified against their checksums before the manifest is serialized.
+func (m Manifest) GetRemoteFileSourceSpecs() map[string][]remotefile.SourceSpec {
+	fileSpecs := make(map[string][]remotefile.SourceSpec)
+	for _, pipeline := range m.pipelines {
+		for _, file := range pipeline.getRemoteFiles() {
+			fileSpecs[pipeline.Name()] = append(fileSpecs[pipeline.Name()], remotefile.SourceSpec{
+				URL:      file.URL(),
+				Checksum: file.Checksum(),
+			})
+		}
+	}
+	return fileSpecs
+}
+
 func (m Manifest) Serialize(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	packages := make([]rpmmd.PackageSpec, 0)
 	commits := make([]ostree.CommitSpec, 0)
 	inline := make([]string, 0)
+	remoteFiles := make([]*fsnode.File, 0)
 	containers := make([]container.Spec, 0)
 	for _, pipeline := range m.pipelines {
 		pipeline.serializeStart(packageSets[pipeline.Name()], containerSpecs[pipeline.Name()], ostreeCommits[pipeline.Name()])
 	}
 	for _, pipeline := range m.pipelines {
 		commits = append(commits, pipeline.getOSTreeCommits()...)
-		pipelines = append(pipelines, pipeline.serialize())
+		pl := pipeline.serialize()
+		pl.SourceEpoch = m.SourceEpoch
+		pipelines = append(pipelines, pl)
 		packages = append(packages, packageSets[pipeline.Name()]...)
 		inline = append(inline, pipeline.getInline()...)
+		remoteFiles = append(remoteFiles, pipeline.getRemoteFiles()...)
 		containers = append(containers, pipeline.getContainerSpecs()...)
 	}
 	for _, pipeline := range m.pipelines {
@@ -153,11 +181,47 @@ func (m Manifest) Serialize(packageSets map[string][]rpmmd.PackageSpec, containe
 		osbuild.Manifest{
 			Version:   "2",
 			Pipelines: pipelines,
-			Sources:   osbuild.GenSources(packages, commits, inline, containers),
+			Sources:   osbuild.GenSources(packages, commits, inline, remoteFiles, containers),
 		},
 	)
 }
 
+// EstimateUsage estimates the usage of the filesystems of the partition
+// tables in the manifest for the depsolved packages and resolved containers
+// of each pipeline. The result is keyed by the name of the pipeline the
+// partition table belongs to.
+func (m Manifest) EstimateUsage(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec) map[string][]disk.MountpointUsage {
+	usage := make(map[string][]disk.MountpointUsage)
+	for _, pipeline := range m.pipelines {
+		os, ok := pipeline.(*OS)
+		if !ok || os.PartitionTable == nil {
+			continue
+		}
+		usage[os.Name()] = os.EstimateUsage(packageSets[os.Name()], containerSpecs[os.Name()])
+	}
+	return usage
+}
+
+// GrowToFit grows the partition tables in the manifest such that the
+// estimated content of the depsolved packages and resolved containers of each
+// pipeline fits on the filesystems. It must be called before the manifest is
+// serialized with the same packages and containers. The result is the usage
+// of the mountpoints that were grown, keyed by the name of the pipeline the
+// partition table belongs to.
+func (m Manifest) GrowToFit(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec) map[string][]disk.MountpointUsage {
+	grown := make(map[string][]disk.MountpointUsage)
+	for _, pipeline := range m.pipelines {
+		os, ok := pipeline.(*OS)
+		if !ok || os.PartitionTable == nil {
+			continue
+		}
+		if usage := os.GrowToFit(packageSets[os.Name()], containerSpecs[os.Name()]); len(usage) > 0 {
+			grown[os.Name()] = usage
+		}
+	}
+	return grown
+}
+
 func (m Manifest) GetCheckpoints() []string {
 	checkpoints := []string{}
 	for _, p := range m.pipelines {
@@ -21,6 +21,36 @@ import (
 	"github.com/osbuild/images/pkg/subscription"
 )
 
+// nondeterministicFiles are the files of the tree that are emptied when the
+// manifest is reproducible. The paths are relative to the root of the tree
+// and their parent directories must exist in every tree.
+var nondeterministicFiles = []string{
+	"etc/machine-id",
+	"var/cache/ldconfig/aux-cache",
+}
+
+// nondeterministicPaths are the files and directories of the tree that are
+// removed when the manifest is reproducible: the logs, history and cache of
+// the package installation, the environment and write-ahead log files of the
+// rpm database and the random seed of systemd. The paths are shell globs
+// relative to the root of the tree and don't have to exist.
+var nondeterministicPaths = []string{
+	"var/log/dnf*.log",
+	"var/log/hawkey.log",
+	"var/lib/dnf/history*",
+	"var/cache/dnf",
+	"var/lib/rpm/__db.*",
+	"var/lib/rpm/rpmdb.sqlite-shm",
+	"var/lib/rpm/rpmdb.sqlite-wal",
+	"usr/lib/sysimage/rpm/__db.*",
+	"usr/lib/sysimage/rpm/rpmdb.sqlite-shm",
+	"usr/lib/sysimage/rpm/rpmdb.sqlite-wal",
+	"usr/share/rpm/__db.*",
+	"usr/share/rpm/rpmdb.sqlite-shm",
+	"usr/share/rpm/rpmdb.sqlite-wal",
+	"var/lib/systemd/random-seed",
+}
+
 // OSCustomizations encapsulates all configuration applied to the base
 // operating system independently of where and how it is integrated and what
 // workload it is running.
@@ -231,10 +261,17 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 
 	osRepos := append(p.repos, p.ExtraBaseRepos...)
 
+	// packages excluded by the workload must not be pulled in by any of the
+	// transactions
+	var workloadExcludes []string
+	if p.Workload != nil {
+		workloadExcludes = p.Workload.GetExcludePackages()
+	}
+
 	chain := []rpmmd.PackageSet{
 		{
 			Include:         append(packages, p.ExtraBasePackages...),
-			Exclude:         p.ExcludeBasePackages,
+			Exclude:         append(append([]string{}, p.ExcludeBasePackages...), workloadExcludes...),
 			Repositories:    osRepos,
 			InstallWeakDeps: p.InstallWeakDeps,
 		},
@@ -245,6 +282,7 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 		if len(workloadPackages) > 0 {
 			chain = append(chain, rpmmd.PackageSet{
 				Include:      workloadPackages,
+				Exclude:      workloadExcludes,
 				Repositories: append(osRepos, p.Workload.GetRepos()...),
 			})
 		}
@@ -340,6 +378,82 @@ func (p *OS) serializeStart(packages []rpmmd.PackageSpec, containers []container
 	}
 }
 
+// containerLayersExpansion is the factor by which the compressed size of the
+// layers of a container grows when they are unpacked into the storage.
+const containerLayersExpansion = 2
+
+// bootFilesSize is the estimated size of the kernel image and the initramfs
+// that are generated in /boot for the kernel package.
+const bootFilesSize = 100 * common.MebiByte
+
+// rpmdbEntrySize is the estimated size of the entry of a package in the rpm
+// database.
+const rpmdbEntrySize = 64 * common.KibiByte
+
+// estimateDirectorySizes estimates the size of the content of the tree per
+// directory: the installed sizes of the packages, which are accounted to
+// /usr, the unpacked layers of the containers and the inline files. The size
+// of remote files is not known and they are not accounted for.
+func (p *OS) estimateDirectorySizes(packages []rpmmd.PackageSpec, containers []container.Spec) map[string]uint64 {
+	sizes := make(map[string]uint64)
+
+	// the content of packages is mostly installed into /usr, except for the
+	// kernel and initramfs in /boot and the rpm database, which is in /var
+	// unless the tree is an ostree commit
+	rpmdbPath := "/var/lib/rpm"
+	storagePath := "/var/lib/containers/storage"
+	if p.OSTreeRef != "" {
+		rpmdbPath = "/usr/share/rpm"
+		storagePath = "/usr/share/containers/storage"
+	}
+	for _, pkg := range packages {
+		sizes["/usr"] += pkg.InstalledSize
+		sizes[rpmdbPath] += rpmdbEntrySize
+		if p.KernelName != "" && pkg.Name == p.KernelName {
+			sizes["/boot"] += bootFilesSize
+		}
+	}
+
+	for _, c := range containers {
+		sizes[storagePath] += c.Size * containerLayersExpansion
+	}
+
+	for _, file := range p.Files {
+		if !file.IsRemote() {
+			sizes[file.Path()] += uint64(len(file.Data()))
+		}
+	}
+
+	for dir, size := range sizes {
+		if size == 0 {
+			delete(sizes, dir)
+		}
+	}
+	return sizes
+}
+
+// EstimateUsage estimates the usage of the filesystems of the partition
+// table when the given packages and containers are installed into the tree.
+// Returns nil if the pipeline has no partition table.
+func (p *OS) EstimateUsage(packages []rpmmd.PackageSpec, containers []container.Spec) []disk.MountpointUsage {
+	if p.PartitionTable == nil {
+		return nil
+	}
+	return p.PartitionTable.EstimateUsage(p.estimateDirectorySizes(packages, containers))
+}
+
+// GrowToFit grows the partitions and volumes of the partition table that are
+// too small for their estimated content when the given packages and
+// containers are installed into the tree, which would otherwise only be
+// noticed when the build runs out of space. Returns the usage of the
+// mountpoints before they were grown.
+func (p *OS) GrowToFit(packages []rpmmd.PackageSpec, containers []container.Spec) []disk.MountpointUsage {
+	if p.PartitionTable == nil {
+		return nil
+	}
+	return p.PartitionTable.GrowToFit(p.estimateDirectorySizes(packages, containers))
+}
+
 func (p *OS) serializeEnd() {
 	if len(p.packageSpecs) == 0 {
 		panic("serializeEnd() call when serialization not in progress")
@@ -449,7 +563,7 @@ func (p *OS) serialize() osbuild.Pipeline {
 				panic("password encryption failed")
 			}
 			pipeline.AddStage(usersStageSansKeys)
-			pipeline.AddStage(osbuild.NewFirstBootStage(usersFirstBootOptions(p.Users)))
+			addFirstBootStage(&pipeline, usersFirstBootOptions(p.Users))
 		} else {
 			usersStage, err := osbuild.GenUsersStage(p.Users, false)
 			if err != nil {
@@ -458,6 +572,11 @@ func (p *OS) serialize() osbuild.Pipeline {
 			}
 			pipeline.AddStage(usersStage)
 		}
+
+		// the users stage doesn't support password aging
+		if commands := passwordAgingCommands(p.Users); len(commands) > 0 {
+			addFirstBootStage(&pipeline, &osbuild.FirstBootStageOptions{Commands: commands})
+		}
 	}
 
 	if p.Firewall != nil {
@@ -568,10 +687,10 @@ func (p *OS) serialize() osbuild.Pipeline {
 			}
 		}
 
-		pipeline.AddStage(osbuild.NewFirstBootStage(&osbuild.FirstBootStageOptions{
+		addFirstBootStage(&pipeline, &osbuild.FirstBootStageOptions{
 			Commands:       commands,
 			WaitForNetwork: true,
-		}))
+		})
 
 		if rhsmConfig, exists := p.RHSMConfig[subscription.RHSMConfigWithSubscription]; exists {
 			pipeline.AddStage(osbuild.NewRHSMStage(rhsmConfig))
@@ -715,6 +834,23 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewWSLConfStage(wslConf))
 	}
 
+	if p.manifest.SourceEpoch != nil {
+		// empty the files that are generated with random or time dependent
+		// contents during the package installation; they are regenerated
+		// on boot
+		for _, filename := range nondeterministicFiles {
+			pipeline.AddStage(osbuild.NewTruncateStage(&osbuild.TruncateStageOptions{
+				Filename: filename,
+				Size:     "0",
+			}))
+		}
+		paths := make([]string, len(nondeterministicPaths))
+		for idx, path := range nondeterministicPaths {
+			paths[idx] = "/" + path
+		}
+		pipeline.AddStage(osbuild.NewScriptStage(osbuild.NewScriptStageOptions("rm -rf -- " + strings.Join(paths, " "))))
+	}
+
 	if p.SElinux != "" {
 		pipeline.AddStage(osbuild.NewSELinuxStage(&osbuild.SELinuxStageOptions{
 			FileContexts:     fmt.Sprintf("etc/selinux/%s/contexts/files/file_contexts", p.SElinux),
@@ -745,6 +881,41 @@ func prependKernelCmdlineStage(pipeline osbuild.Pipeline, kernelOptions string,
 	return pipeline
 }
 
+// addFirstBootStage adds a first-boot stage to the pipeline, or merges the
+// options into the existing one, since all of them write the same service.
+func addFirstBootStage(pipeline *osbuild.Pipeline, options *osbuild.FirstBootStageOptions) {
+	for _, stage := range pipeline.Stages {
+		if existing, ok := stage.Options.(*osbuild.FirstBootStageOptions); ok {
+			existing.Commands = append(existing.Commands, options.Commands...)
+			existing.WaitForNetwork = existing.WaitForNetwork || options.WaitForNetwork
+			return
+		}
+	}
+	pipeline.AddStage(osbuild.NewFirstBootStage(options))
+}
+
+// passwordAgingCommands returns the commands that set the password aging of
+// the users.
+func passwordAgingCommands(users []users.User) []string {
+	var cmds []string
+	for _, user := range users {
+		args := ""
+		if user.PasswordMinDays != nil {
+			args += fmt.Sprintf(" -m %d", *user.PasswordMinDays)
+		}
+		if user.PasswordMaxDays != nil {
+			args += fmt.Sprintf(" -M %d", *user.PasswordMaxDays)
+		}
+		if user.PasswordWarnDays != nil {
+			args += fmt.Sprintf(" -W %d", *user.PasswordWarnDays)
+		}
+		if args != "" {
+			cmds = append(cmds, fmt.Sprintf("/usr/bin/chage%s %s", args, user.Name))
+		}
+	}
+	return cmds
+}
+
 func usersFirstBootOptions(users []users.User) *osbuild.FirstBootStageOptions {
 	cmds := make([]string, 0, 3*len(users)+2)
 	// workaround for creating authorized_keys file for user
@@ -785,12 +956,10 @@ func (p *OS) GetPlatform() platform.Platform {
 }
 
 func (p *OS) getInline() []string {
-	inlineData := []string{}
-
 	// inline data for custom files
-	for _, file := range p.Files {
-		inlineData = append(inlineData, string(file.Data()))
-	}
+	return fileNodesInlineData(p.Files)
+}
 
-	return inlineData
+func (p *OS) getRemoteFiles() []*fsnode.File {
+	return remoteFileNodes(p.Files)
 }
@@ -1,8 +1,16 @@
 package manifest
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/remotefile"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -152,3 +160,208 @@ func TestRhcInsightsPackages(t *testing.T) {
 	}
 	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"rhc", "subscription-manager", "insights-client"})
 }
+
+func TestPasswordAgingFirstBoot(t *testing.T) {
+	os := NewTestOS()
+	os.Users = []users.User{
+		{Name: "alice", PasswordMinDays: common.ToPtr(1), PasswordMaxDays: common.ToPtr(90), PasswordWarnDays: common.ToPtr(7)},
+		{Name: "bob"},
+		{Name: "carol", PasswordMaxDays: common.ToPtr(30)},
+	}
+	pipeline := os.serialize()
+
+	var firstBoot []*osbuild.FirstBootStageOptions
+	for _, stage := range pipeline.Stages {
+		if options, ok := stage.Options.(*osbuild.FirstBootStageOptions); ok {
+			firstBoot = append(firstBoot, options)
+		}
+	}
+	require.Len(t, firstBoot, 1)
+	assert.Equal(t, []string{
+		"/usr/bin/chage -m 1 -M 90 -W 7 alice",
+		"/usr/bin/chage -M 30 carol",
+	}, firstBoot[0].Commands)
+}
+
+func TestReproducibleTruncatesFiles(t *testing.T) {
+	truncated := func(pipeline osbuild.Pipeline) []string {
+		var filenames []string
+		for _, stage := range pipeline.Stages {
+			if options, ok := stage.Options.(*osbuild.TruncateStageOptions); ok {
+				assert.Equal(t, "0", options.Size)
+				filenames = append(filenames, options.Filename)
+			}
+		}
+		return filenames
+	}
+
+	removed := func(pipeline osbuild.Pipeline) []string {
+		var paths []string
+		for _, stage := range pipeline.Stages {
+			if options, ok := stage.Options.(*osbuild.ScriptStageOptions); ok {
+				require.True(t, strings.HasPrefix(options.Script, "rm -rf -- "), options.Script)
+				paths = append(paths, strings.Fields(strings.TrimPrefix(options.Script, "rm -rf -- "))...)
+			}
+		}
+		return paths
+	}
+
+	os := NewTestOS()
+	os.SElinux = "targeted"
+	assert.Empty(t, truncated(os.serialize()))
+	assert.Empty(t, removed(os.serialize()))
+
+	os.manifest.SourceEpoch = common.ToPtr(int64(1700000000))
+	pipeline := os.serialize()
+	assert.Equal(t, []string{"etc/machine-id", "var/cache/ldconfig/aux-cache"}, truncated(pipeline))
+	paths := removed(pipeline)
+	for _, path := range []string{
+		"/var/log/dnf*.log",
+		"/var/log/hawkey.log",
+		"/var/lib/dnf/history*",
+		"/var/cache/dnf",
+		"/var/lib/rpm/__db.*",
+		"/var/lib/rpm/rpmdb.sqlite-shm",
+		"/var/lib/rpm/rpmdb.sqlite-wal",
+		"/usr/lib/sysimage/rpm/__db.*",
+		"/usr/lib/sysimage/rpm/rpmdb.sqlite-shm",
+		"/usr/lib/sysimage/rpm/rpmdb.sqlite-wal",
+		"/var/lib/systemd/random-seed",
+	} {
+		assert.Contains(t, paths, path)
+	}
+
+	// the files are emptied before the tree is labeled
+	assert.Equal(t, "org.osbuild.selinux", pipeline.Stages[len(pipeline.Stages)-1].Type)
+}
+
+func TestRemoteFilesNotInlined(t *testing.T) {
+	os := NewTestOS()
+	inline, err := fsnode.NewFile("/etc/motd", nil, nil, nil, []byte("hello"))
+	require.NoError(t, err)
+	remote, err := fsnode.NewRemoteFile("/usr/local/bin/agent", nil, nil, nil, "https://example.com/agent", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+	require.NoError(t, err)
+	os.Files = []*fsnode.File{inline, remote}
+
+	assert.Equal(t, []string{"hello"}, os.getInline())
+	assert.Equal(t, []*fsnode.File{remote}, os.getRemoteFiles())
+}
+
+func TestGetRemoteFileSourceSpecs(t *testing.T) {
+	manifest := New()
+	build := NewBuild(&manifest, &runner.Fedora{Version: 37}, nil)
+	os := NewOS(&manifest, build, &platform.X86{BIOS: true}, nil)
+	inline, err := fsnode.NewFile("/etc/motd", nil, nil, nil, []byte("hello"))
+	require.NoError(t, err)
+	remote, err := fsnode.NewRemoteFile("/usr/local/bin/agent", nil, nil, nil, "https://example.com/agent", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+	require.NoError(t, err)
+	os.Files = []*fsnode.File{inline, remote}
+
+	assert.Equal(t, map[string][]remotefile.SourceSpec{
+		"os": {
+			{
+				URL:      "https://example.com/agent",
+				Checksum: "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+			},
+		},
+	}, manifest.GetRemoteFileSourceSpecs())
+}
+
+func TestWorkloadExcludesInPackageSetChain(t *testing.T) {
+	os := NewTestOS()
+	os.ExcludeBasePackages = []string{"dracut-config-rescue"}
+	os.Workload = &workload.Custom{
+		Packages:        []string{"tmux"},
+		ExcludePackages: []string{"iwl*-firmware"},
+	}
+
+	chain := os.getPackageSetChain(DISTRO_NULL)
+	require.Len(t, chain, 2)
+	assert.Equal(t, []string{"dracut-config-rescue", "iwl*-firmware"}, chain[0].Exclude)
+	assert.Equal(t, []string{"tmux"}, chain[1].Include)
+	assert.Equal(t, []string{"iwl*-firmware"}, chain[1].Exclude)
+
+	// the base excludes of the OS are not modified
+	assert.Equal(t, []string{"dracut-config-rescue"}, os.ExcludeBasePackages)
+}
+
+func newTestUsagePartitionTable() *disk.PartitionTable {
+	return &disk.PartitionTable{
+		Size: 2 * common.GibiByte,
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Start:   1 * common.MebiByte,
+				Size:    512 * common.MebiByte,
+				Payload: &disk.Filesystem{Type: "xfs", UUID: "0194fdc2-fa2f-4cc0-81d3-ff12045b73c8", Mountpoint: "/boot"},
+			},
+			{
+				Start:   513 * common.MebiByte,
+				Size:    1 * common.GibiByte,
+				Payload: &disk.Filesystem{Type: "xfs", UUID: "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75", Mountpoint: "/"},
+			},
+		},
+	}
+}
+
+func TestEstimateUsage(t *testing.T) {
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 37}, nil)
+	os := NewOS(&m, build, &platform.X86{BIOS: true}, nil)
+	os.PartitionTable = newTestUsagePartitionTable()
+	os.KernelName = "kernel"
+
+	motd, err := fsnode.NewFile("/etc/motd", nil, nil, nil, []byte("hello\n"))
+	require.NoError(t, err)
+	remote, err := fsnode.NewRemoteFile("/var/lib/blob", nil, nil, nil, "https://example.com/blob", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+	require.NoError(t, err)
+	os.Files = []*fsnode.File{motd, remote}
+
+	packages := []rpmmd.PackageSpec{
+		{Name: "kernel", Version: "6.5.6", Release: "300.fc39", Arch: "x86_64", Checksum: "sha1:c02524e2bd19490f2a7167958f792262754c5f46", InstalledSize: 900 * common.MebiByte},
+		{Name: "pkg2", Checksum: "sha1:e2b5d4fb0e4b2b1dc4fc0e4fb3b5b7fdd1e5d4f0", InstalledSize: 600 * common.MebiByte},
+	}
+	containers := []container.Spec{
+		{
+			Source:    "example.com/container",
+			Digest:    "sha256:f29b6cd42a94a574583439addcd6694e6224f0e4b32044c9e3aee4c4856c2a50",
+			ImageID:   "sha256:c2ecf25cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f",
+			LocalName: "example.com/container",
+			Size:      100 * common.MebiByte,
+		},
+	}
+
+	// the kernel and initramfs are estimated on /boot, the packages, the
+	// rpm database, the containers and the files on /
+	expected := []disk.MountpointUsage{
+		{Mountpoint: "/", Size: 1 * common.GibiByte, Used: 1700*common.MebiByte + 2*rpmdbEntrySize + 6},
+		{Mountpoint: "/boot", Size: 512 * common.MebiByte, Used: bootFilesSize},
+	}
+	assert.Equal(t, expected, os.EstimateUsage(packages, containers))
+	assert.Equal(t, map[string][]disk.MountpointUsage{"os": expected}, m.EstimateUsage(
+		map[string][]rpmmd.PackageSpec{"build": packages, "os": packages},
+		map[string][]container.Spec{"os": containers},
+	))
+
+	// serialization does not grow the partitions
+	packageSets := map[string][]rpmmd.PackageSpec{"build": packages, "os": packages}
+	containerSpecs := map[string][]container.Spec{"os": containers}
+	_, err = m.Serialize(packageSets, containerSpecs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, os.EstimateUsage(packages, containers))
+
+	// only the root partition is grown
+	assert.Equal(t, map[string][]disk.MountpointUsage{"os": expected[:1]}, m.GrowToFit(packageSets, containerSpecs))
+	for _, u := range os.EstimateUsage(packages, containers) {
+		assert.True(t, u.Fits(), u.Mountpoint)
+	}
+	assert.Equal(t, uint64(512*common.MebiByte), os.PartitionTable.Partitions[0].Size)
+	assert.Greater(t, os.PartitionTable.Size, uint64(2*common.GibiByte))
+	assert.Empty(t, m.GrowToFit(packageSets, containerSpecs))
+
+	// no estimate without a partition table
+	os.PartitionTable = nil
+	assert.Nil(t, os.EstimateUsage(packages, containers))
+	assert.Empty(t, m.EstimateUsage(map[string][]rpmmd.PackageSpec{"os": packages}, nil))
+	assert.Empty(t, m.GrowToFit(map[string][]rpmmd.PackageSpec{"os": packages}, nil))
+}
@@ -337,12 +337,10 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 }
 
 func (p *OSTreeDeployment) getInline() []string {
-	inlineData := []string{}
-
 	// inline data for custom files
-	for _, file := range p.Files {
-		inlineData = append(inlineData, string(file.Data()))
-	}
+	return fileNodesInlineData(p.Files)
+}
 
-	return inlineData
+func (p *OSTreeDeployment) getRemoteFiles() []*fsnode.File {
+	return remoteFileNodes(p.Files)
 }
@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/osbuild"
@@ -60,6 +61,9 @@ type Pipeline interface {
 	// getInline returns the list of inlined data content that will be used to
 	// embed files in the pipeline tree.
 	getInline() []string
+	// getRemoteFiles returns the list of files embedded in the pipeline tree
+	// whose content is fetched from a URL.
+	getRemoteFiles() []*fsnode.File
 }
 
 // A Base represents the core functionality shared between each of the pipeline
@@ -131,6 +135,33 @@ func (p Base) getInline() []string {
 	return []string{}
 }
 
+func (p Base) getRemoteFiles() []*fsnode.File {
+	return nil
+}
+
+// fileNodesInlineData returns the content of the files that is inlined in
+// the manifest. The content of remote files is fetched by osbuild instead.
+func fileNodesInlineData(files []*fsnode.File) []string {
+	inlineData := []string{}
+	for _, file := range files {
+		if !file.IsRemote() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+	return inlineData
+}
+
+// remoteFileNodes returns the files whose content is fetched from a URL.
+func remoteFileNodes(files []*fsnode.File) []*fsnode.File {
+	var remoteFiles []*fsnode.File
+	for _, file := range files {
+		if file.IsRemote() {
+			remoteFiles = append(remoteFiles, file)
+		}
+	}
+	return remoteFiles
+}
+
 // NewBase returns a generic Pipeline object. The name is mandatory, immutable and must
 // be unique among all the pipelines used in a manifest, which is currently not enforced.
 // The build argument is a pipeline representing a build root in which the rest of the
@@ -0,0 +1,53 @@
+package manifest
+
+import (
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// A VHDX turns a raw image file into a vhdx image for Hyper-V.
+type VHDX struct {
+	Base
+	Filename string
+
+	imgPipeline *RawImage
+}
+
+// NewVHDX creates a new VHDX pipeline. imgPipeline is the pipeline producing
+// the raw image. Filename is the name of the produced image.
+func NewVHDX(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline *RawImage) *VHDX {
+	p := &VHDX{
+		Base:        NewBase(m, "vhdx", buildPipeline),
+		imgPipeline: imgPipeline,
+		Filename:    "image.vhdx",
+	}
+	if imgPipeline.Base.manifest != m {
+		panic("live image pipeline from different manifest")
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *VHDX) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	pipeline.AddStage(osbuild.NewQEMUStage(
+		osbuild.NewQEMUStageOptions(p.Filename, osbuild.QEMUFormatVHDX, nil),
+		osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), p.imgPipeline.Filename),
+	))
+
+	return pipeline
+}
+
+func (p *VHDX) getBuildPackages(Distro) []string {
+	return []string{"qemu-img"}
+}
+
+func (p *VHDX) Export() *artifact.Artifact {
+	p.Base.export = true
+	mimeType := "application/x-vhdx"
+	return artifact.New(p.Name(), p.Filename, &mimeType)
+}
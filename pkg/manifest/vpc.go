@@ -12,6 +12,9 @@ type VPC struct {
 
 	ForceSize *bool
 
+	// Subformat of the VHD, dynamic if empty
+	Subformat osbuild.VPCSubformat
+
 	imgPipeline *RawImage
 }
 
@@ -37,7 +40,10 @@ func NewVPC(m *Manifest,
 func (p *VPC) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	formatOptions := osbuild.VPCOptions{ForceSize: p.ForceSize}
+	formatOptions := osbuild.VPCOptions{
+		ForceSize: p.ForceSize,
+		Subformat: p.Subformat,
+	}
 
 	pipeline.AddStage(osbuild.NewQEMUStage(
 		osbuild.NewQEMUStageOptions(p.Filename, osbuild.QEMUFormatVPC, formatOptions),
@@ -1,5 +1,12 @@
 package osbuild
 
+type BtrfsMountOptions struct {
+	// Subvolume to mount instead of the top-level volume
+	Subvol string `json:"subvol,omitempty"`
+}
+
+func (BtrfsMountOptions) isMountOptions() {}
+
 func NewBtrfsMount(name, source, target string) *Mount {
 	return &Mount{
 		Type:   "org.osbuild.btrfs",
@@ -8,3 +15,13 @@ func NewBtrfsMount(name, source, target string) *Mount {
 		Target: target,
 	}
 }
+
+// NewBtrfsSubvolumeMount creates a mount of the subvolume with the given name
+// of the btrfs volume on source.
+func NewBtrfsSubvolumeMount(name, source, target, subvol string) *Mount {
+	mount := NewBtrfsMount(name, source, target)
+	mount.Options = &BtrfsMountOptions{
+		Subvol: subvol,
+	}
+	return mount
+}
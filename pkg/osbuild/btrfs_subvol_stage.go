@@ -0,0 +1,57 @@
+package osbuild
+
+import (
+	"strings"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+type BtrfsSubVolStageOptions struct {
+	Subvolumes []BtrfsSubVol `json:"subvolumes"`
+}
+
+func (BtrfsSubVolStageOptions) isStageOptions() {}
+
+type BtrfsSubVol struct {
+	Name string `json:"name"`
+}
+
+// NewBtrfsSubVolStage creates the subvolumes of the btrfs volume mounted at
+// the root of the given mounts.
+func NewBtrfsSubVolStage(options *BtrfsSubVolStageOptions, devices *Devices, mounts *Mounts) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.btrfs.subvol",
+		Options: options,
+		Devices: *devices,
+		Mounts:  *mounts,
+	}
+}
+
+// GenBtrfsSubVolStages generates an org.osbuild.btrfs.subvol stage for each
+// btrfs volume in the partition table, which creates its subvolumes.
+func GenBtrfsSubVolStages(filename string, pt *disk.PartitionTable) []*Stage {
+	stages := make([]*Stage, 0)
+
+	genStage := func(e disk.Entity, path []disk.Entity) error {
+		volume, ok := e.(*disk.Btrfs)
+		if !ok {
+			return nil
+		}
+
+		options := &BtrfsSubVolStageOptions{
+			Subvolumes: make([]BtrfsSubVol, len(volume.Subvolumes)),
+		}
+		for idx, subvol := range volume.Subvolumes {
+			options.Subvolumes[idx] = BtrfsSubVol{Name: "/" + strings.TrimLeft(subvol.Name, "/")}
+		}
+
+		stageDevices, name := getDevices(path, filename, true)
+		devices := Devices(stageDevices)
+		mounts := Mounts{*NewBtrfsMount("volume", name, "/")}
+		stages = append(stages, NewBtrfsSubVolStage(options, &devices, &mounts))
+		return nil
+	}
+
+	_ = pt.ForEachEntity(genStage) // genStage always returns nil
+	return stages
+}
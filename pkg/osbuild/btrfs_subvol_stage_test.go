@@ -0,0 +1,58 @@
+package osbuild
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func TestGenBtrfsSubVolStages(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	btrfs := testPartitionTables["btrfs"]
+	pt, err := disk.NewPartitionTable(&btrfs, []blueprint.FilesystemCustomization{}, 0, false, make(map[string]uint64), rng)
+	require.NoError(t, err)
+
+	stages := GenBtrfsSubVolStages("disk.img", pt)
+	require.Len(t, stages, 1)
+
+	stage := stages[0]
+	assert.Equal(t, "org.osbuild.btrfs.subvol", stage.Type)
+	assert.Equal(t, &BtrfsSubVolStageOptions{
+		Subvolumes: []BtrfsSubVol{{Name: "/root"}, {Name: "/var"}},
+	}, stage.Options)
+	require.Len(t, stage.Mounts, 1)
+	assert.Equal(t, "org.osbuild.btrfs", stage.Mounts[0].Type)
+	assert.Equal(t, "/", stage.Mounts[0].Target)
+	assert.Contains(t, stage.Devices, stage.Mounts[0].Source)
+
+	// the filesystem is created once for all subvolumes
+	mkfs := GenMkfsStages(pt, NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img"}))
+	btrfsStages := 0
+	for _, stage := range mkfs {
+		if stage.Type == "org.osbuild.mkfs.btrfs" {
+			btrfsStages++
+			assert.Equal(t, "rootfs", stage.Options.(*MkfsBtrfsStageOptions).Label)
+		}
+	}
+	assert.Equal(t, 1, btrfsStages)
+
+	// every subvolume is mounted for copying the tree
+	_, _, mounts := GenCopyFSTreeOptions("tree", "os", "disk.img", pt)
+	subvols := make(map[string]string)
+	for _, mount := range *mounts {
+		if mount.Type == "org.osbuild.btrfs" {
+			subvols[mount.Target] = mount.Options.(*BtrfsMountOptions).Subvol
+		}
+	}
+	assert.Equal(t, map[string]string{"/": "root", "/var": "var"}, subvols)
+
+	assert.Contains(t, GenImageKernelOptions(pt), "rootflags=subvol=root")
+}
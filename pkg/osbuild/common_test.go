@@ -325,11 +325,13 @@ var testPartitionTables = map[string]disk.PartitionTable{
 					Label: "rootfs",
 					Subvolumes: []disk.BtrfsSubvolume{
 						{
+							Name:       "root",
 							Size:       0,
 							Mountpoint: "/",
 							GroupID:    0,
 						},
 						{
+							Name:       "var",
 							Size:       5 * 1024 * 1024 * 1024,
 							Mountpoint: "/var",
 							GroupID:    0,
@@ -1,6 +1,8 @@
 package osbuild
 
 import (
+	"encoding/json"
+
 	"github.com/osbuild/images/pkg/container"
 )
 
@@ -23,6 +25,19 @@ type ContainersInput struct {
 
 const InputTypeContainers string = "org.osbuild.containers"
 
+func (c *ContainersInput) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		inputCommon
+		References ContainersInputSourceMap `json:"references"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c.inputCommon = raw.inputCommon
+	c.References = raw.References
+	return nil
+}
+
 func NewContainersInputForSources(containers []container.Spec) ContainersInput {
 	refs := make(ContainersInputSourceMap, len(containers))
 	for _, c := range containers {
@@ -78,7 +78,10 @@ func GenCopyFSTreeOptions(inputName, inputPipeline, filename string, pt *disk.Pa
 		case "ext4":
 			mount = NewExt4Mount(name, name, mountpoint)
 		case "btrfs":
-			mount = NewBtrfsMount(name, name, mountpoint)
+			// all subvolumes share the device of their volume, so the
+			// mounts are named after their mountpoints like the devices
+			// of filesystems are
+			mount = NewBtrfsSubvolumeMount(pathdot(mountpoint), name, mountpoint, mnt.GetFSSpec().Label)
 		default:
 			panic("unknown fs type " + t)
 		}
@@ -1,6 +1,7 @@
 package osbuild
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -19,6 +20,42 @@ type DeviceOptions interface {
 	isDeviceOptions()
 }
 
+// UnmarshalJSON decodes a device into the options type of its device type.
+func (d *Device) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type    string          `json:"type"`
+		Parent  string          `json:"parent"`
+		Options json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var options DeviceOptions
+	switch raw.Type {
+	case "org.osbuild.loopback":
+		options = new(LoopbackDeviceOptions)
+	case "org.osbuild.luks2":
+		options = new(LUKS2DeviceOptions)
+	case "org.osbuild.lvm2.lv":
+		options = new(LVM2LVDeviceOptions)
+	default:
+		return fmt.Errorf("unexpected device type: %s", raw.Type)
+	}
+	if isSet(raw.Options) {
+		if err := json.Unmarshal(raw.Options, options); err != nil {
+			return fmt.Errorf("cannot decode options of device %s: %w", raw.Type, err)
+		}
+	} else {
+		options = nil
+	}
+
+	d.Type = raw.Type
+	d.Parent = raw.Parent
+	d.Options = options
+	return nil
+}
+
 func GenDeviceCreationStages(pt *disk.PartitionTable, filename string) []*Stage {
 	stages := make([]*Stage, 0)
 
@@ -160,6 +197,8 @@ func deviceName(p disk.Entity) string {
 		return payload.Name + "vg"
 	case *disk.LVMLogicalVolume:
 		return payload.Name
+	case *disk.Btrfs:
+		return "btrfs-" + payload.UUID[:4]
 	}
 	panic(fmt.Sprintf("unsupported device type in deviceName: '%T'", p))
 }
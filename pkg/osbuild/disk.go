@@ -102,6 +102,10 @@ func GenImagePrepareStages(pt *disk.PartitionTable, filename string, partTool Pa
 	s = GenMkfsStages(pt, loopback)
 	stages = append(stages, s...)
 
+	// Create the subvolumes of all btrfs volumes
+	s = GenBtrfsSubVolStages(filename, pt)
+	stages = append(stages, s...)
+
 	return stages
 }
 
@@ -117,6 +121,11 @@ func GenImageKernelOptions(pt *disk.PartitionTable) []string {
 		case *disk.LUKSContainer:
 			karg := "luks.uuid=" + ent.UUID
 			cmdline = append(cmdline, karg)
+		case *disk.BtrfsSubvolume:
+			if ent.Mountpoint == "/" {
+				karg := "rootflags=subvol=" + ent.Name
+				cmdline = append(cmdline, karg)
+			}
 		}
 		return nil
 	}
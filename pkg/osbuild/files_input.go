@@ -109,6 +109,29 @@ type FilesInputRefMetadata interface {
 	isFilesInputRefMetadata()
 }
 
+// unmarshalFilesInputRefMetadata decodes the metadata of a files input
+// reference into the type of the stage that consumes it, which is determined
+// by the prefix of the metadata keys.
+func unmarshalFilesInputRefMetadata(data json.RawMessage) (FilesInputRefMetadata, error) {
+	if !isSet(data) {
+		return nil, nil
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	for key := range keys {
+		if !strings.HasPrefix(key, "rpm.") {
+			return nil, fmt.Errorf("FilesInput: unsupported reference metadata key: %s", key)
+		}
+	}
+	metadata := new(RPMStageReferenceMetadata)
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 // Pipeline Object Reference
 // The expected JSON structure is:
 //
@@ -134,6 +157,23 @@ type FilesInputPipelineOptions struct {
 	Metadata FilesInputRefMetadata `json:"metadata,omitempty"`
 }
 
+func (o *FilesInputPipelineOptions) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		File     string          `json:"file,omitempty"`
+		Metadata json.RawMessage `json:"metadata,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	metadata, err := unmarshalFilesInputRefMetadata(raw.Metadata)
+	if err != nil {
+		return err
+	}
+	o.File = raw.File
+	o.Metadata = metadata
+	return nil
+}
+
 func NewFilesInputPipelineObjectRef(pipeline, filename string, metadata FilesInputRefMetadata) FilesInputRef {
 	// The files input schema allows for multiple pipelines to be specified, but we don't use it.
 	ref := &FilesInputPipelineObjectRef{
@@ -232,6 +272,21 @@ type FilesInputSourceOptions struct {
 	Metadata FilesInputRefMetadata `json:"metadata,omitempty"`
 }
 
+func (o *FilesInputSourceOptions) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Metadata json.RawMessage `json:"metadata,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	metadata, err := unmarshalFilesInputRefMetadata(raw.Metadata)
+	if err != nil {
+		return err
+	}
+	o.Metadata = metadata
+	return nil
+}
+
 type FilesInputSourceArrayRefEntry struct {
 	ID      string                   `json:"id"`
 	Options *FilesInputSourceOptions `json:"options,omitempty"`
@@ -1,8 +1,8 @@
 package osbuild
 
 import (
-	"crypto/sha256"
 	"fmt"
+	"strings"
 
 	"github.com/osbuild/images/internal/fsnode"
 )
@@ -10,8 +10,9 @@ import (
 // GenFileNodesStages generates the stages for a list of file nodes.
 // It generates the following stages:
 //   - copy stage with all the files that need to be created by copying their
-//     content from the list of inline sources. The SHA256 sum of the file is
-//     used as the name of the stage input.
+//     content from the list of inline sources, or from the curl sources for
+//     remote files. The SHA256 sum of the file is used as the name of the
+//     stage input.
 //   - chmod stage with all the files that need to have their permissions set.
 //   - chown stage with all the files that need to have their ownership set.
 func GenFileNodesStages(files []*fsnode.File) []*Stage {
@@ -22,7 +23,9 @@ func GenFileNodesStages(files []*fsnode.File) []*Stage {
 	chownPaths := make(map[string]ChownStagePathOptions)
 
 	for _, file := range files {
-		fileDataChecksum := fmt.Sprintf("%x", sha256.Sum256(file.Data()))
+		// inline and remote files are both referenced by the checksum of
+		// their content
+		fileDataChecksum := strings.TrimPrefix(file.Checksum(), "sha256:")
 		copyStageInputKey := fmt.Sprintf("file-%s", fileDataChecksum)
 		copyStagePaths = append(copyStagePaths, CopyStagePath{
 			From: fmt.Sprintf("input://%s/sha256:%s", copyStageInputKey, fileDataChecksum),
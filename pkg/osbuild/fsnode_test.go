@@ -37,6 +37,27 @@ func TestGenFileNodesStages(t *testing.T) {
 			files:    nil,
 			expected: nil,
 		},
+		{
+			name: "single-remote-file",
+			files: []*fsnode.File{
+				ensureFileCreation(fsnode.NewRemoteFile("/usr/local/bin/agent", nil, nil, nil, "https://example.com/agent", fmt.Sprintf("%x", sha256.Sum256(fileData1)))),
+			},
+			expected: []*Stage{
+				NewCopyStageSimple(&CopyStageOptions{
+					Paths: []CopyStagePath{
+						{
+							From:              fmt.Sprintf("input://file-%[1]x/sha256:%[1]x", sha256.Sum256(fileData1)),
+							To:                "tree:///usr/local/bin/agent",
+							RemoveDestination: true,
+						},
+					},
+				}, &CopyStageFilesInputs{
+					fmt.Sprintf("file-%x", sha256.Sum256(fileData1)): NewFilesInput(NewFilesInputSourceArrayRef([]FilesInputSourceArrayRefEntry{
+						NewFilesInputSourceArrayRefEntry(fmt.Sprintf("sha256:%x", sha256.Sum256(fileData1)), nil),
+					})),
+				}),
+			},
+		},
 		{
 			name: "single-file-simple",
 			files: []*fsnode.File{
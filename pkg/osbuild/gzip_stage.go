@@ -0,0 +1,56 @@
+package osbuild
+
+import "fmt"
+
+type GzipStageOptions struct {
+	// Filename for the gzip compressed file
+	Filename string `json:"filename"`
+
+	// Compression level from 1 to 9, the gzip default is used if unset
+	Level int `json:"level,omitempty"`
+}
+
+func (GzipStageOptions) isStageOptions() {}
+
+func (o *GzipStageOptions) validate() error {
+	if o.Level < 0 || o.Level > 9 {
+		return fmt.Errorf("gzip compression level %d is not between 1 and 9", o.Level)
+	}
+	return nil
+}
+
+func NewGzipStageOptions(filename string) *GzipStageOptions {
+	return &GzipStageOptions{
+		Filename: filename,
+	}
+}
+
+type GzipStageInputs struct {
+	File *FilesInput `json:"file"`
+}
+
+func (*GzipStageInputs) isStageInputs() {}
+
+func NewGzipStageInputs(references FilesInputRef) *GzipStageInputs {
+	return &GzipStageInputs{
+		File: NewFilesInput(references),
+	}
+}
+
+// Compresses a file with gzip.
+func NewGzipStage(options *GzipStageOptions, inputs *GzipStageInputs) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+
+	var stageInputs Inputs
+	if inputs != nil {
+		stageInputs = inputs
+	}
+
+	return &Stage{
+		Type:    "org.osbuild.gzip",
+		Options: options,
+		Inputs:  stageInputs,
+	}
+}
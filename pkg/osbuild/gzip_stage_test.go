@@ -0,0 +1,33 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGzipStage(t *testing.T) {
+	options := NewGzipStageOptions("image.raw.gz")
+	options.Level = 9
+	inputs := NewGzipStageInputs(NewFilesInputPipelineObjectRef("image", "image.raw", nil))
+
+	expectedStage := &Stage{
+		Type:    "org.osbuild.gzip",
+		Options: options,
+		Inputs:  inputs,
+	}
+	actualStage := NewGzipStage(options, inputs)
+	assert.Equal(t, expectedStage, actualStage)
+
+	data, err := json.Marshal(actualStage.Options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"filename": "image.raw.gz", "level": 9}`, string(data))
+}
+
+func TestNewGzipStageInvalid(t *testing.T) {
+	assert.PanicsWithError(t, "gzip compression level 10 is not between 1 and 9", func() {
+		NewGzipStage(&GzipStageOptions{Filename: "image.raw.gz", Level: 10}, nil)
+	})
+}
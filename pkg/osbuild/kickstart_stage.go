@@ -1,6 +1,8 @@
 package osbuild
 
-import "github.com/osbuild/images/internal/users"
+import (
+	"github.com/osbuild/images/internal/users"
+)
 
 type KickstartStageOptions struct {
 	// Where to place the kickstart file
@@ -13,6 +15,64 @@ type KickstartStageOptions struct {
 	Users map[string]UsersStageOptionsUser `json:"users,omitempty"`
 
 	Groups map[string]GroupsStageOptionsGroup `json:"groups,omitempty"`
+
+	// Settings for unattended installations
+	KickstartInstallOptions
+}
+
+// KickstartInstallOptions are the kickstart commands that make an
+// installation unattended.
+type KickstartInstallOptions struct {
+	Timezone   string               `json:"timezone,omitempty"`
+	ZeroMBR    bool                 `json:"zerombr,omitempty"`
+	ClearPart  *KickstartClearPart  `json:"clearpart,omitempty"`
+	AutoPart   *KickstartAutoPart   `json:"autopart,omitempty"`
+	IgnoreDisk *KickstartIgnoreDisk `json:"ignoredisk,omitempty"`
+	Network    []KickstartNetwork   `json:"network,omitempty"`
+	Reboot     *KickstartReboot     `json:"reboot,omitempty"`
+	Post       []KickstartPost      `json:"post,omitempty"`
+}
+
+type KickstartClearPart struct {
+	All       bool     `json:"all,omitempty"`
+	Linux     bool     `json:"linux,omitempty"`
+	Drives    []string `json:"drives,omitempty"`
+	InitLabel bool     `json:"initlabel,omitempty"`
+}
+
+type KickstartAutoPart struct {
+	// plain, lvm, thinp or btrfs
+	Type   string `json:"type,omitempty"`
+	FSType string `json:"fstype,omitempty"`
+	NoHome bool   `json:"nohome,omitempty"`
+}
+
+type KickstartIgnoreDisk struct {
+	OnlyUse []string `json:"only-use,omitempty"`
+}
+
+type KickstartNetwork struct {
+	Device      string   `json:"device,omitempty"`
+	Activate    bool     `json:"activate,omitempty"`
+	BootProto   string   `json:"bootproto,omitempty"`
+	IP          string   `json:"ip,omitempty"`
+	Netmask     string   `json:"netmask,omitempty"`
+	Gateway     string   `json:"gateway,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty"`
+	Hostname    string   `json:"hostname,omitempty"`
+}
+
+// KickstartReboot reboots the machine once the installation is done.
+type KickstartReboot struct {
+	Eject bool `json:"eject,omitempty"`
+}
+
+// KickstartPost is a %post section of the kickstart file.
+type KickstartPost struct {
+	Interpreter string   `json:"interpreter,omitempty"`
+	NoChroot    bool     `json:"nochroot,omitempty"`
+	ErrorOnFail bool     `json:"erroronfail,omitempty"`
+	Commands    []string `json:"commands"`
 }
 
 type LiveIMG struct {
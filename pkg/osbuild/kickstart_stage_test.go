@@ -0,0 +1,23 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKickstartStageOptionsJSON(t *testing.T) {
+	options := &KickstartStageOptions{
+		Path: "/osbuild.ks",
+		KickstartInstallOptions: KickstartInstallOptions{
+			ClearPart: &KickstartClearPart{All: true, InitLabel: true},
+			Reboot:    &KickstartReboot{},
+		},
+	}
+	data, err := json.Marshal(NewKickstartStage(options).Options)
+	require.NoError(t, err)
+	// the install options are part of the top level stage options
+	assert.JSONEq(t, `{"path":"/osbuild.ks","clearpart":{"all":true,"initlabel":true},"reboot":{}}`, string(data))
+}
@@ -18,10 +18,27 @@ func GenMkfsStages(pt *disk.PartitionTable, device *Device) []*Stage {
 		panic("GenMkfsStages: failed to convert device options to loopback options")
 	}
 
+	// btrfs subvolumes share the filesystem of their volume, which must only
+	// be created once
+	btrfsVolumes := make(map[*disk.Btrfs]bool)
+
 	genStage := func(mnt disk.Mountable, path []disk.Entity) error {
 		t := mnt.GetFSType()
 		var stage *Stage
 
+		fsSpec := mnt.GetFSSpec()
+		if t == "btrfs" {
+			volume, ok := path[len(path)-2].(*disk.Btrfs)
+			if !ok {
+				panic("GenMkfsStages: btrfs subvolume is not part of a btrfs volume")
+			}
+			if btrfsVolumes[volume] {
+				return nil
+			}
+			btrfsVolumes[volume] = true
+			fsSpec = disk.FSSpec{UUID: volume.UUID, Label: volume.Label}
+		}
+
 		stageDevices, lastName := getDevices(path, devOptions.Filename, true)
 
 		// the last device on the PartitionTable must be named "device"
@@ -29,7 +46,6 @@ func GenMkfsStages(pt *disk.PartitionTable, device *Device) []*Stage {
 		delete(stageDevices, lastName)
 		stageDevices["device"] = lastDevice
 
-		fsSpec := mnt.GetFSSpec()
 		switch t {
 		case "xfs":
 			options := &MkfsXfsStageOptions{
@@ -0,0 +1,254 @@
+package osbuild
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// StatusType is the type of a Status reported while osbuild is running.
+type StatusType string
+
+const (
+	// A pipeline started building
+	StatusPipelineStarted StatusType = "pipeline-started"
+	// A stage started running
+	StatusStageStarted StatusType = "stage-started"
+	// A stage finished running, successfully or not
+	StatusStageFinished StatusType = "stage-finished"
+	// A log line of osbuild or one of its stages
+	StatusLog StatusType = "log"
+	// A stage failed; reported after the corresponding StatusStageFinished
+	StatusError StatusType = "error"
+)
+
+// Status is a structured event from the monitor output of a running osbuild
+// process.
+type Status struct {
+	Type      StatusType
+	Timestamp time.Time
+
+	// Name of the pipeline the event belongs to, if any
+	Pipeline string
+	// Type of the stage the event belongs to, e.g. org.osbuild.rpm, if any
+	Stage string
+	// ID of the stage the event belongs to, if any
+	StageID string
+
+	// Message of the event, e.g. the log line or the error output
+	Message string
+	// Origin of the message, e.g. osbuild.monitor or org.osbuild
+	Origin string
+
+	// Duration of a finished stage, zero if its start was not seen
+	Duration time.Duration
+	// Success of a finished stage
+	Success bool
+
+	// Overall progress of the build, if reported
+	Progress *Progress
+}
+
+// Progress of a build. Progress is nested: the top level reports the
+// progress over all pipelines and SubProgress the progress within the
+// current pipeline.
+type Progress struct {
+	Name        string
+	Done        int
+	Total       int
+	SubProgress *Progress
+}
+
+// monitorRecord is a single record of the osbuild JSONSeqMonitor
+type monitorRecord struct {
+	Message   string           `json:"message"`
+	Context   *monitorContext  `json:"context"`
+	Progress  *monitorProgress `json:"progress"`
+	Result    *monitorResult   `json:"result"`
+	Timestamp float64          `json:"timestamp"`
+}
+
+// monitorContext describes where a record originated. The full context is
+// only sent the first time, later records with the same context only contain
+// its ID.
+type monitorContext struct {
+	ID       string `json:"id"`
+	Origin   string `json:"origin"`
+	Pipeline *struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Stage *struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"stage"`
+	} `json:"pipeline"`
+}
+
+type monitorProgress struct {
+	Name     string           `json:"name"`
+	Total    int              `json:"total"`
+	Done     int              `json:"done"`
+	Progress *monitorProgress `json:"progress"`
+}
+
+type monitorResult struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Success bool            `json:"success"`
+	Output  string          `json:"output"`
+	Error   json.RawMessage `json:"error"`
+}
+
+func (p *monitorProgress) toProgress() *Progress {
+	if p == nil {
+		return nil
+	}
+	return &Progress{
+		Name:        p.Name,
+		Done:        p.Done,
+		Total:       p.Total,
+		SubProgress: p.Progress.toProgress(),
+	}
+}
+
+// recordSeparator starts every record of a JSON text sequence (RFC 7464)
+const recordSeparator = 0x1e
+
+// maxMonitorRecordSize is the maximum size of a monitor record, which can
+// contain the complete output of a stage
+const maxMonitorRecordSize = 64 * 1024 * 1024
+
+func splitRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// skip the separator(s) at the start of the record
+	start := 0
+	for start < len(data) && data[start] == recordSeparator {
+		start++
+	}
+	if i := bytes.IndexByte(data[start:], recordSeparator); i >= 0 {
+		return start + i, data[start : start+i], nil
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// monitorReader converts the records of the osbuild JSONSeqMonitor into
+// Status events.
+type monitorReader struct {
+	contexts    map[string]*monitorContext
+	stageStarts map[string]time.Time
+}
+
+func newMonitorReader() *monitorReader {
+	return &monitorReader{
+		contexts:    make(map[string]*monitorContext),
+		stageStarts: make(map[string]time.Time),
+	}
+}
+
+// readMonitor reads the monitor output from r until EOF and calls onStatus
+// for every event.
+func readMonitor(r io.Reader, onStatus func(*Status)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMonitorRecordSize)
+	scanner.Split(splitRecords)
+
+	mr := newMonitorReader()
+	for scanner.Scan() {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record monitorRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("error decoding osbuild monitor record: %v", err)
+		}
+		for _, status := range mr.statuses(&record) {
+			onStatus(status)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading osbuild monitor output: %v", err)
+	}
+	return nil
+}
+
+// resolveContext returns the full context of a record
+func (mr *monitorReader) resolveContext(ctx *monitorContext) *monitorContext {
+	if ctx == nil {
+		return &monitorContext{}
+	}
+	if ctx.Origin == "" && ctx.Pipeline == nil {
+		if full, ok := mr.contexts[ctx.ID]; ok {
+			return full
+		}
+		return ctx
+	}
+	if ctx.ID != "" {
+		mr.contexts[ctx.ID] = ctx
+	}
+	return ctx
+}
+
+func (mr *monitorReader) statuses(record *monitorRecord) []*Status {
+	ctx := mr.resolveContext(record.Context)
+
+	sec, frac := math.Modf(record.Timestamp)
+	status := &Status{
+		Type:      StatusLog,
+		Timestamp: time.Unix(int64(sec), int64(frac*1e9)),
+		Message:   record.Message,
+		Origin:    ctx.Origin,
+		Progress:  record.Progress.toProgress(),
+	}
+	if ctx.Pipeline != nil {
+		status.Pipeline = ctx.Pipeline.Name
+		if ctx.Pipeline.Stage != nil {
+			status.Stage = ctx.Pipeline.Stage.Name
+			status.StageID = ctx.Pipeline.Stage.ID
+		}
+	}
+
+	if result := record.Result; result != nil {
+		status.Type = StatusStageFinished
+		status.Success = result.Success
+		if result.Name != "" {
+			status.Stage = result.Name
+		}
+		if result.ID != "" {
+			status.StageID = result.ID
+		}
+		if start, ok := mr.stageStarts[status.StageID]; ok {
+			status.Duration = status.Timestamp.Sub(start)
+			delete(mr.stageStarts, status.StageID)
+		}
+		if result.Success {
+			return []*Status{status}
+		}
+
+		errStatus := *status
+		errStatus.Type = StatusError
+		errStatus.Message = result.Output
+		if isSet(result.Error) {
+			errStatus.Message = strings.TrimSpace(fmt.Sprintf("%s\n%s", result.Output, string(result.Error)))
+		}
+		return []*Status{status, &errStatus}
+	}
+
+	if status.Origin == "osbuild.monitor" {
+		switch {
+		case strings.HasPrefix(record.Message, "Starting pipeline"):
+			status.Type = StatusPipelineStarted
+		case strings.HasPrefix(record.Message, "Starting module"):
+			status.Type = StatusStageStarted
+			mr.stageStarts[status.StageID] = status.Timestamp
+		}
+	}
+	return []*Status{status}
+}
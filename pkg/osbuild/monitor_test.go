@@ -0,0 +1,172 @@
+package osbuild
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monitor output of a build with one pipeline and two stages, of which the
+// second fails
+const monitorTestOutput = "\x1e" + `{"message": "Starting pipeline os", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p1", "stage": {}}, "id": "c1"}, "progress": {"name": "pipelines/sources", "total": 1, "done": 0, "progress": {"name": "pipeline: os", "total": 2, "done": null}}, "timestamp": 1700000000.0}
+` + "\x1e" + `{"message": "Starting module org.osbuild.rpm", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p1", "stage": {"name": "org.osbuild.rpm", "id": "s1"}}, "id": "c2"}, "progress": {"name": "pipelines/sources", "total": 1, "done": 0, "progress": {"name": "pipeline: os", "total": 2, "done": 0}}, "timestamp": 1700000001.0}
+` + "\x1e" + `{"message": "installing bash\n", "context": {"origin": "org.osbuild", "pipeline": {"name": "os", "id": "p1", "stage": {"name": "org.osbuild.rpm", "id": "s1"}}, "id": "c3"}, "progress": null, "timestamp": 1700000002.0}
+` + "\x1e" + `{"message": "installing tmux\n", "context": {"id": "c3"}, "progress": null, "timestamp": 1700000003.0}
+` + "\x1e" + `{"message": "Finished module org.osbuild.rpm", "context": {"id": "c2"}, "progress": null, "result": {"id": "s1", "name": "org.osbuild.rpm", "success": true, "output": "installing bash\ninstalling tmux\n"}, "timestamp": 1700000004.5}
+` + "\x1e" + `{"message": "Starting module org.osbuild.locale", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p1", "stage": {"name": "org.osbuild.locale", "id": "s2"}}, "id": "c4"}, "progress": {"name": "pipelines/sources", "total": 1, "done": 0, "progress": {"name": "pipeline: os", "total": 2, "done": 1}}, "timestamp": 1700000005.0}
+` + "\x1e" + `{"message": "Finished module org.osbuild.locale", "context": {"id": "c4"}, "progress": null, "result": {"id": "s2", "name": "org.osbuild.locale", "success": false, "output": "invalid locale"}, "timestamp": 1700000005.25}
+`
+
+func TestReadMonitor(t *testing.T) {
+	var statuses []*Status
+	err := readMonitor(strings.NewReader(monitorTestOutput), func(s *Status) {
+		statuses = append(statuses, s)
+	})
+	require.NoError(t, err)
+	require.Len(t, statuses, 8)
+
+	types := make([]StatusType, len(statuses))
+	for idx, s := range statuses {
+		types[idx] = s.Type
+		assert.Equal(t, "os", s.Pipeline)
+	}
+	assert.Equal(t, []StatusType{
+		StatusPipelineStarted,
+		StatusStageStarted,
+		StatusLog,
+		StatusLog,
+		StatusStageFinished,
+		StatusStageStarted,
+		StatusStageFinished,
+		StatusError,
+	}, types)
+
+	assert.Equal(t, &Progress{
+		Name:        "pipelines/sources",
+		Total:       1,
+		SubProgress: &Progress{Name: "pipeline: os", Total: 2},
+	}, statuses[0].Progress)
+	assert.Equal(t, time.Unix(1700000000, 0), statuses[0].Timestamp)
+
+	// log lines with a context that was only referenced by ID
+	assert.Equal(t, "installing tmux\n", statuses[3].Message)
+	assert.Equal(t, "org.osbuild", statuses[3].Origin)
+	assert.Equal(t, "org.osbuild.rpm", statuses[3].Stage)
+	assert.Nil(t, statuses[3].Progress)
+
+	assert.Equal(t, "org.osbuild.rpm", statuses[4].Stage)
+	assert.Equal(t, "s1", statuses[4].StageID)
+	assert.True(t, statuses[4].Success)
+	assert.Equal(t, 3500*time.Millisecond, statuses[4].Duration)
+
+	assert.Equal(t, "org.osbuild.locale", statuses[6].Stage)
+	assert.False(t, statuses[6].Success)
+	assert.Equal(t, 250*time.Millisecond, statuses[6].Duration)
+	assert.Equal(t, "org.osbuild.locale", statuses[7].Stage)
+	assert.Equal(t, "invalid locale", statuses[7].Message)
+}
+
+func TestReadMonitorInvalid(t *testing.T) {
+	err := readMonitor(strings.NewReader("\x1e{\"message\": \n"), func(*Status) {})
+	assert.ErrorContains(t, err, "error decoding osbuild monitor record")
+}
+
+// fakeOSBuild puts a script named osbuild that runs the given shell code in
+// front of the PATH.
+func fakeOSBuild(t *testing.T, script string) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "osbuild"), []byte("#!/bin/sh\n"+script), 0755) // #nosec G306
+	require.NoError(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunOSBuildWithProgress(t *testing.T) {
+	monitorFile := filepath.Join(t.TempDir(), "monitor")
+	require.NoError(t, os.WriteFile(monitorFile, []byte(monitorTestOutput), 0600))
+	fakeOSBuild(t, `cat > /dev/null
+cat `+monitorFile+` >&3
+echo '{"type": "result", "success": false}'
+exit 1
+`)
+
+	var statuses []*Status
+	res, err := RunOSBuildWithProgress(context.Background(), []byte("{}"), OSBuildOptions{}, func(s *Status) {
+		statuses = append(statuses, s)
+	})
+	require.NoError(t, err)
+	assert.False(t, res.Success)
+	assert.Len(t, statuses, 8)
+}
+
+func TestRunOSBuildWithProgressMonitorError(t *testing.T) {
+	fakeOSBuild(t, `cat > /dev/null
+printf '\036{"message": \n' >&3
+echo '{"type": "result", "success": true}'
+`)
+
+	res, err := RunOSBuildWithProgress(context.Background(), []byte("{}"), OSBuildOptions{}, nil)
+	var monitorErr *MonitorError
+	require.ErrorAs(t, err, &monitorErr)
+	assert.ErrorContains(t, err, "error decoding osbuild monitor record")
+	require.NotNil(t, res)
+	assert.True(t, res.Success)
+}
+
+func TestRunOSBuildWithProgressCancel(t *testing.T) {
+	fakeOSBuild(t, `trap 'echo terminated >&2; exit 1' TERM
+cat > /dev/null
+while true; do sleep 0.1; done
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	var stderr strings.Builder
+	start := time.Now()
+	_, err := RunOSBuildWithProgress(ctx, []byte("{}"), OSBuildOptions{Stderr: &stderr}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), DefaultTerminationGracePeriod)
+	// the shell also reports that its sleep was terminated with the group
+	assert.Contains(t, stderr.String(), "terminated\n")
+}
+
+func TestRunOSBuildWithProgressKill(t *testing.T) {
+	fakeOSBuild(t, `trap '' TERM
+cat > /dev/null
+while true; do sleep 0.1; done
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := RunOSBuildWithProgress(ctx, []byte("{}"), OSBuildOptions{TerminationGracePeriod: 200 * time.Millisecond}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	assert.Less(t, time.Since(start), DefaultTerminationGracePeriod)
+}
+
+func TestRunOSBuildWithProgressKillGroup(t *testing.T) {
+	// the child ignores SIGTERM and keeps stdout open after osbuild exited,
+	// so the run only ends once the whole group is killed
+	fakeOSBuild(t, `sh -c 'trap "" TERM; while true; do sleep 0.1; done' &
+trap 'exit 1' TERM
+cat > /dev/null
+while true; do sleep 0.1; done
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := RunOSBuildWithProgress(ctx, []byte("{}"), OSBuildOptions{TerminationGracePeriod: 200 * time.Millisecond}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	assert.Less(t, time.Since(start), DefaultTerminationGracePeriod)
+}
@@ -1,5 +1,10 @@
 package osbuild
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type Mounts []Mount
 
 type Mount struct {
@@ -13,3 +18,41 @@ type Mount struct {
 type MountOptions interface {
 	isMountOptions()
 }
+
+// UnmarshalJSON decodes a mount into the options type of its mount type.
+func (m *Mount) UnmarshalJSON(data []byte) error {
+	// decode everything but the options with the default decoder
+	type mount Mount
+	var raw struct {
+		mount
+		Options json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var options MountOptions
+	switch raw.Type {
+	case "org.osbuild.btrfs":
+		options = new(BtrfsMountOptions)
+	case "org.osbuild.ext4", "org.osbuild.fat", "org.osbuild.xfs":
+	case "org.osbuild.ostree.deployment":
+		options = new(OSTreeMountOptions)
+	default:
+		return fmt.Errorf("unexpected mount type: %s", raw.Type)
+	}
+	if isSet(raw.Options) {
+		if options == nil {
+			return fmt.Errorf("mount type %s has no options", raw.Type)
+		}
+		if err := json.Unmarshal(raw.Options, options); err != nil {
+			return fmt.Errorf("cannot decode options of mount %s: %w", raw.Type, err)
+		}
+	} else {
+		options = nil
+	}
+
+	*m = Mount(raw.mount)
+	m.Options = options
+	return nil
+}
@@ -20,6 +20,18 @@ func TestNewMounts(t *testing.T) {
 		assert.Equal(expected, actual)
 	}
 
+	{ // btrfs subvolume
+		actual := NewBtrfsSubvolumeMount("home", "/dev/sda1", "/home", "home")
+		expected := &Mount{
+			Name:    "home",
+			Type:    "org.osbuild.btrfs",
+			Source:  "/dev/sda1",
+			Target:  "/home",
+			Options: &BtrfsMountOptions{Subvol: "home"},
+		}
+		assert.Equal(expected, actual)
+	}
+
 	{ // ext4
 		actual := NewExt4Mount("ext4", "/dev/sda2", "/mnt/ext4")
 		expected := &Mount{
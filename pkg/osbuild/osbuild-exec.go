@@ -2,13 +2,37 @@ package osbuild
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// DefaultTerminationGracePeriod is the time osbuild is given to clean up
+// after it was asked to terminate, before it is killed.
+const DefaultTerminationGracePeriod = 30 * time.Second
+
+// OSBuildOptions configures RunOSBuildWithProgress.
+type OSBuildOptions struct {
+	Store           string
+	OutputDirectory string
+	Exports         []string
+	Checkpoints     []string
+	ExtraEnv        []string
+
+	// Writer for the stderr of osbuild; discarded if nil
+	Stderr io.Writer
+
+	// Time osbuild is given to terminate after the context is cancelled,
+	// before it is killed; DefaultTerminationGracePeriod if zero
+	TerminationGracePeriod time.Duration
+}
+
 // Run an instance of osbuild, returning a parsed osbuild.Result.
 //
 // Note that osbuild returns non-zero when the pipeline fails. This function
@@ -84,3 +108,160 @@ func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpo
 
 	return &res, nil
 }
+
+// RunOSBuildWithProgress runs an instance of osbuild like RunOSBuild, but
+// reads its machine-readable monitor output while it is running and calls
+// onStatus for every event, e.g. to show the progress of the build. onStatus
+// is called sequentially from a single goroutine; osbuild is blocked while it
+// runs, so it should return quickly.
+//
+// osbuild runs in its own process group. When ctx is cancelled, the group is
+// sent SIGTERM so that osbuild can clean up and killed if osbuild did not exit
+// after the termination grace period, so that no process started by osbuild
+// outlives it. The error returned in this case wraps the error of the context.
+//
+// Like RunOSBuild, a failure of the pipeline is not reported as an error but
+// through the returned Result. If the result of osbuild was decoded but its
+// monitor output could not be read, the Result is returned together with a
+// *MonitorError.
+func RunOSBuildWithProgress(ctx context.Context, manifest []byte, opts OSBuildOptions, onStatus func(*Status)) (*Result, error) {
+	var stdoutBuffer bytes.Buffer
+	var res Result
+
+	monitorReader, monitorWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the osbuild monitor pipe: %v", err)
+	}
+	defer monitorReader.Close()
+
+	cmd := exec.Command(
+		"osbuild",
+		"--store", opts.Store,
+		"--output-directory", opts.OutputDirectory,
+		"--json",
+		"--monitor", "JSONSeqMonitor",
+		// the first entry of ExtraFiles is fd 3 in the child
+		"--monitor-fd", "3",
+		"-",
+	)
+	for _, export := range opts.Exports {
+		cmd.Args = append(cmd.Args, "--export", export)
+	}
+	for _, checkpoint := range opts.Checkpoints {
+		cmd.Args = append(cmd.Args, "--checkpoint", checkpoint)
+	}
+	if len(opts.ExtraEnv) > 0 {
+		cmd.Env = append(os.Environ(), opts.ExtraEnv...)
+	}
+	cmd.Stdout = &stdoutBuffer
+	cmd.Stderr = opts.Stderr
+	cmd.ExtraFiles = []*os.File{monitorWriter}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		monitorWriter.Close()
+		return nil, fmt.Errorf("error setting up stdin for osbuild: %v", err)
+	}
+
+	err = cmd.Start()
+	// the write end is only needed by osbuild
+	monitorWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error starting osbuild: %v", err)
+	}
+
+	var monitorErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if onStatus == nil {
+			onStatus = func(*Status) {}
+		}
+		monitorErr = readMonitor(monitorReader, onStatus)
+		// keep draining the pipe so that osbuild never blocks on it
+		_, _ = io.Copy(io.Discard, monitorReader)
+	}()
+
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			terminate(cmd.Process, opts.TerminationGracePeriod, exited)
+		case <-exited:
+		}
+	}()
+
+	// osbuild reads the whole manifest before it starts, so writing it
+	// fails if osbuild exited early; the error of osbuild is more useful
+	// in that case
+	_, writeErr := stdin.Write(manifest)
+	if writeErr == nil {
+		writeErr = stdin.Close()
+	}
+
+	err = cmd.Wait()
+	close(exited)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("running osbuild was cancelled: %w", ctx.Err())
+	}
+	if writeErr != nil && err == nil {
+		return nil, fmt.Errorf("error writing osbuild manifest: %v", writeErr)
+	}
+
+	// try to decode the output even though the job could have failed
+	decodeErr := json.Unmarshal(stdoutBuffer.Bytes(), &res)
+	if decodeErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("running osbuild failed: %v", err)
+		}
+		return nil, fmt.Errorf("error decoding osbuild output: %v\nthe raw output:\n%s", decodeErr, stdoutBuffer.String())
+	}
+	if err != nil {
+		// ignore ExitError if output could be decoded correctly
+		if _, isExitError := err.(*exec.ExitError); !isExitError {
+			return nil, fmt.Errorf("running osbuild failed: %v", err)
+		}
+	}
+	if monitorErr != nil {
+		return &res, &MonitorError{Err: monitorErr}
+	}
+
+	return &res, nil
+}
+
+// MonitorError is returned by RunOSBuildWithProgress together with the
+// Result of osbuild when its monitor output could not be read, e.g. because
+// of a malformed record. The build itself is not affected by it.
+type MonitorError struct {
+	Err error
+}
+
+func (e *MonitorError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MonitorError) Unwrap() error {
+	return e.Err
+}
+
+// terminate asks the process group led by the process to terminate and kills
+// the group if the process did not exit after the grace period.
+func terminate(process *os.Process, gracePeriod time.Duration, exited <-chan struct{}) {
+	if gracePeriod == 0 {
+		gracePeriod = DefaultTerminationGracePeriod
+	}
+	// a negative pid signals the whole process group
+	if err := syscall.Kill(-process.Pid, syscall.SIGTERM); err != nil {
+		_ = syscall.Kill(-process.Pid, syscall.SIGKILL)
+		return
+	}
+	select {
+	case <-time.After(gracePeriod):
+		_ = syscall.Kill(-process.Pid, syscall.SIGKILL)
+	case <-exited:
+	}
+}
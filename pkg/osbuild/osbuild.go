@@ -17,6 +17,11 @@ type Pipeline struct {
 
 	Runner string `json:"runner,omitempty"`
 
+	// Timestamp, in seconds since the epoch, that osbuild sets as
+	// SOURCE_DATE_EPOCH for the stages of the pipeline and to which it clamps
+	// the modification times of the files in the tree.
+	SourceEpoch *int64 `json:"source-epoch,omitempty"`
+
 	// Sequence of stages that produce the filesystem tree, which is the
 	// payload of the produced image.
 	Stages []*Stage `json:"stages,omitempty"`
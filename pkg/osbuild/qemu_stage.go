@@ -9,6 +9,7 @@ import (
 //
 // Some formats support format-specific options:
 //   qcow2: The compatibility version can be specified via 'compat'
+//   vpc: The subformat can be set to 'fixed' or 'dynamic' via 'subformat'
 
 type QEMUStageOptions struct {
 	// Filename for resulting image
@@ -22,6 +23,7 @@ func (QEMUStageOptions) isStageOptions() {}
 
 type QEMUFormat string
 type VMDKSubformat string
+type VPCSubformat string
 
 const (
 	QEMUFormatQCOW2 QEMUFormat = "qcow2"
@@ -35,6 +37,9 @@ const (
 	VMDKSubformatTwoGbMaxExtentSparse VMDKSubformat = "twoGbMaxExtentSparse"
 	VMDKSubformatTwoGbMaxExtentFlat   VMDKSubformat = "twoGbMaxExtentFlat"
 	VMDKSubformatStreamOptimized      VMDKSubformat = "streamOptimized"
+
+	VPCSubformatDynamic VPCSubformat = "dynamic"
+	VPCSubformatFixed   VPCSubformat = "fixed"
 )
 
 type QEMUFormatOptions interface {
@@ -88,6 +93,8 @@ type VPCOptions struct {
 
 	// VPC related options
 	ForceSize *bool `json:"force_size,omitempty"`
+
+	Subformat VPCSubformat `json:"subformat,omitempty"`
 }
 
 func (VPCOptions) isQEMUFormatOptions() {}
@@ -96,6 +103,13 @@ func (o VPCOptions) validate() error {
 	if o.Type != QEMUFormatVPC {
 		return fmt.Errorf("invalid format type %q for %q options", o.Type, QEMUFormatVPC)
 	}
+
+	switch o.Subformat {
+	case "", VPCSubformatDynamic, VPCSubformatFixed:
+	default:
+		return fmt.Errorf("'subformat' option does not allow %q as a value", o.Subformat)
+	}
+
 	return nil
 }
 
@@ -253,6 +267,57 @@ func (options QEMUStageOptions) MarshalJSON() ([]byte, error) {
 	return json.Marshal(qemuStageOptions(options))
 }
 
+// Custom unmarshaller for decoding the format options based on their type
+func (options *QEMUStageOptions) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Filename string          `json:"filename"`
+		Format   json.RawMessage `json:"format"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var formatType struct {
+		Type QEMUFormat `json:"type"`
+	}
+	if err := json.Unmarshal(raw.Format, &formatType); err != nil {
+		return err
+	}
+
+	var formatOptions QEMUFormatOptions
+	var err error
+	switch formatType.Type {
+	case QEMUFormatQCOW2:
+		var o QCOW2Options
+		err = json.Unmarshal(raw.Format, &o)
+		formatOptions = o
+	case QEMUFormatVDI:
+		var o VDIOptions
+		err = json.Unmarshal(raw.Format, &o)
+		formatOptions = o
+	case QEMUFormatVPC:
+		var o VPCOptions
+		err = json.Unmarshal(raw.Format, &o)
+		formatOptions = o
+	case QEMUFormatVMDK:
+		var o VMDKOptions
+		err = json.Unmarshal(raw.Format, &o)
+		formatOptions = o
+	case QEMUFormatVHDX:
+		var o VHDXOptions
+		err = json.Unmarshal(raw.Format, &o)
+		formatOptions = o
+	default:
+		return fmt.Errorf("unknown format in qemu stage: %q", formatType.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	options.Filename = raw.Filename
+	options.Format = formatOptions
+	return nil
+}
+
 func NewQemuStagePipelineFilesInputs(pipeline, file string) *QEMUStageInputs {
 	input := NewFilesInput(NewFilesInputPipelineObjectRef(pipeline, file, nil))
 	return &QEMUStageInputs{Image: input}
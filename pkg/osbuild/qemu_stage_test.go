@@ -142,6 +142,20 @@ func TestNewQEMUStageOptions(t *testing.T) {
 				},
 			},
 		},
+		{
+			Filename: "image.vpc",
+			Format:   QEMUFormatVPC,
+			FormatOptions: VPCOptions{
+				Subformat: VPCSubformatFixed,
+			},
+			ExpectedOptions: &QEMUStageOptions{
+				Filename: "image.vpc",
+				Format: VPCOptions{
+					Type:      QEMUFormatVPC,
+					Subformat: VPCSubformatFixed,
+				},
+			},
+		},
 		{
 			Filename:      "image.vmdk",
 			Format:        QEMUFormatVMDK,
@@ -203,6 +217,15 @@ func TestNewQEMUStageOptions(t *testing.T) {
 			},
 			Error: true,
 		},
+		// invalid subformat
+		{
+			Filename: "image.vpc",
+			Format:   QEMUFormatVPC,
+			FormatOptions: VPCOptions{
+				Subformat: "streamOptimized",
+			},
+			Error: true,
+		},
 		// unknown format
 		{
 			Filename:      "image.qcow2",
@@ -0,0 +1,442 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// DefaultLibDir is the directory osbuild installs its modules to.
+const DefaultLibDir = "/usr/lib/osbuild"
+
+// SchemaValidator checks serialized manifests against the JSON schemas of
+// the osbuild stages, inputs and sources, without running osbuild.
+type SchemaValidator struct {
+	stages  map[string]*spec.Schema
+	inputs  map[string]*spec.Schema
+	sources map[string]*spec.Schema
+}
+
+// SchemaError is a single schema violation found in a manifest.
+type SchemaError struct {
+	// Name of the pipeline; empty for errors in the sources
+	Pipeline string
+
+	// Index of the stage in the pipeline; -1 for errors outside of stages
+	Stage int
+
+	// Type of the osbuild module whose schema was violated
+	Module string
+
+	// JSON pointer to the offending value in the manifest
+	Pointer string
+
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	var location string
+	switch {
+	case e.Pipeline != "" && e.Stage >= 0:
+		location = fmt.Sprintf("pipeline %q, stage %d (%s)", e.Pipeline, e.Stage, e.Module)
+	case e.Module != "":
+		location = e.Module
+	default:
+		location = "manifest"
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Pointer, e.Message)
+}
+
+// SchemaValidationError holds all schema violations found in a manifest.
+type SchemaValidationError struct {
+	Errors []SchemaError
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for idx, err := range e.Errors {
+		msgs[idx] = err.Error()
+	}
+	return fmt.Sprintf("manifest is invalid (%d errors):\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+// LoadSchemaValidator creates a SchemaValidator from the osbuild modules in
+// libDir, which is either an osbuild installation (see DefaultLibDir) or a
+// vendored checkout of the osbuild sources.
+func LoadSchemaValidator(libDir string) (*SchemaValidator, error) {
+	return NewSchemaValidator(os.DirFS(libDir))
+}
+
+// NewSchemaValidator creates a SchemaValidator from the osbuild modules in
+// fsys. The modules are read from the "stages", "inputs" and "sources"
+// directories. The schemas are taken from the "<module>.meta.json" files if
+// they exist, otherwise from the SCHEMA_2 or SCHEMA variables of the python
+// modules.
+func NewSchemaValidator(fsys fs.FS) (*SchemaValidator, error) {
+	v := &SchemaValidator{}
+	var err error
+	if v.stages, err = loadModuleSchemas(fsys, "stages", stageSchema); err != nil {
+		return nil, err
+	}
+	if v.inputs, err = loadModuleSchemas(fsys, "inputs", objectSchema); err != nil {
+		return nil, err
+	}
+	if v.sources, err = loadModuleSchemas(fsys, "sources", objectSchema); err != nil {
+		return nil, err
+	}
+	if len(v.stages) == 0 {
+		return nil, fmt.Errorf("no stage schemas found")
+	}
+	return v, nil
+}
+
+// schemaBuilder creates the schema of a module object from the raw schema
+// of the module. The boolean argument is true for schemas in the version 2
+// format.
+type schemaBuilder func(name string, raw map[string]interface{}, v2 bool) map[string]interface{}
+
+// stageSchema creates the schema of a stage object. Version 2 stage schemas
+// define the properties of the stage object (options, inputs, devices,
+// mounts), version 1 stage schemas only define the options.
+func stageSchema(name string, raw map[string]interface{}, v2 bool) map[string]interface{} {
+	properties := map[string]interface{}{
+		"type":    map[string]interface{}{"enum": []interface{}{name}},
+		"options": map[string]interface{}{"type": "object"},
+		"inputs":  map[string]interface{}{"type": "object"},
+		"devices": map[string]interface{}{"type": "object"},
+		"mounts":  map[string]interface{}{"type": "array"},
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []interface{}{"type"},
+		"properties":           properties,
+	}
+
+	if defs, ok := raw["definitions"]; ok {
+		schema["definitions"] = defs
+		delete(raw, "definitions")
+	}
+	if v2 {
+		for prop, propSchema := range raw {
+			properties[prop] = propSchema
+		}
+	} else {
+		raw["type"] = "object"
+		properties["options"] = raw
+	}
+	return schema
+}
+
+// objectSchema is used for modules whose raw schema is the schema of the
+// module object itself, e.g. inputs and sources.
+func objectSchema(name string, raw map[string]interface{}, v2 bool) map[string]interface{} {
+	if _, ok := raw["type"]; !ok {
+		raw["type"] = "object"
+	}
+	return raw
+}
+
+var pythonSchemaRe = map[string]*regexp.Regexp{
+	"SCHEMA_2": regexp.MustCompile(`(?s)\nSCHEMA_2\s*=\s*r?"""(.*?)"""`),
+	"SCHEMA":   regexp.MustCompile(`(?s)\nSCHEMA\s*=\s*r?"""(.*?)"""`),
+}
+
+func loadModuleSchemas(fsys fs.FS, dir string, build schemaBuilder) (map[string]*spec.Schema, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if os.IsNotExist(err) {
+		return map[string]*spec.Schema{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*spec.Schema)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "org.osbuild.") {
+			continue
+		}
+		moduleName := strings.TrimSuffix(name, ".meta.json")
+		if _, exists := schemas[moduleName]; exists {
+			continue
+		}
+
+		raw, v2, err := readModuleSchema(fsys, dir, moduleName)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", dir, moduleName, err)
+		}
+		if raw == nil {
+			// module without schema: all options are accepted
+			raw = map[string]interface{}{}
+		}
+
+		schema, err := newSchema(build(moduleName, raw, v2))
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: invalid schema: %w", dir, moduleName, err)
+		}
+		schemas[moduleName] = schema
+	}
+	return schemas, nil
+}
+
+// readModuleSchema returns the raw schema of a module and whether it is in
+// the version 2 format.
+func readModuleSchema(fsys fs.FS, dir, module string) (map[string]interface{}, bool, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, module+".meta.json"))
+	if err == nil {
+		var meta struct {
+			Schema  map[string]interface{} `json:"schema"`
+			Schema2 map[string]interface{} `json:"schema_2"`
+		}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, false, err
+		}
+		if meta.Schema2 != nil {
+			return meta.Schema2, true, nil
+		}
+		return meta.Schema, false, nil
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	data, err = fs.ReadFile(fsys, path.Join(dir, module))
+	if err != nil {
+		return nil, false, err
+	}
+	for _, variable := range []string{"SCHEMA_2", "SCHEMA"} {
+		match := pythonSchemaRe[variable].FindSubmatch(data)
+		if match == nil {
+			continue
+		}
+		// the python modules contain the members of the schema object
+		// without the enclosing braces
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte("{"+string(match[1])+"}"), &raw); err != nil {
+			return nil, false, fmt.Errorf("cannot parse %s: %w", variable, err)
+		}
+		return raw, variable == "SCHEMA_2", nil
+	}
+	return nil, false, nil
+}
+
+func newSchema(raw map[string]interface{}) (*spec.Schema, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var schema spec.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	// resolve references to the definitions of the schema up front; the
+	// validator panics on unresolvable references
+	if err := spec.ExpandSchema(&schema, &schema, nil); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Validate checks the serialized manifest against the module schemas. If the
+// manifest violates any schema, a *SchemaValidationError is returned that
+// contains all violations.
+func (v *SchemaValidator) Validate(manifest []byte) error {
+	var mf struct {
+		Version   string                     `json:"version"`
+		Pipelines []map[string]interface{}   `json:"pipelines"`
+		Sources   map[string]json.RawMessage `json:"sources"`
+	}
+	if err := json.Unmarshal(manifest, &mf); err != nil {
+		return fmt.Errorf("cannot parse manifest: %w", err)
+	}
+
+	var errs []SchemaError
+	if mf.Version != "2" {
+		errs = append(errs, SchemaError{Stage: -1, Pointer: "/version", Message: fmt.Sprintf("unsupported manifest version %q", mf.Version)})
+	}
+
+	for pIdx, pipeline := range mf.Pipelines {
+		name, _ := pipeline["name"].(string)
+		pPointer := fmt.Sprintf("/pipelines/%d", pIdx)
+		stages, ok := pipeline["stages"].([]interface{})
+		if !ok {
+			if pipeline["stages"] != nil {
+				errs = append(errs, SchemaError{Pipeline: name, Stage: -1, Pointer: pPointer + "/stages", Message: "stages must be an array"})
+			}
+			continue
+		}
+		for sIdx, s := range stages {
+			errs = append(errs, v.validateStage(name, sIdx, fmt.Sprintf("%s/stages/%d", pPointer, sIdx), s)...)
+		}
+	}
+
+	sourceTypes := make([]string, 0, len(mf.Sources))
+	for sourceType := range mf.Sources {
+		sourceTypes = append(sourceTypes, sourceType)
+	}
+	sort.Strings(sourceTypes)
+	for _, sourceType := range sourceTypes {
+		pointer := "/sources/" + escapePointerToken(sourceType)
+		schema, exists := v.sources[sourceType]
+		if !exists {
+			errs = append(errs, SchemaError{Stage: -1, Module: sourceType, Pointer: pointer, Message: "unknown source type"})
+			continue
+		}
+		var source interface{}
+		if err := json.Unmarshal(mf.Sources[sourceType], &source); err != nil {
+			return fmt.Errorf("cannot parse source %q: %w", sourceType, err)
+		}
+		errs = append(errs, validateAgainst(schema, source, SchemaError{Stage: -1, Module: sourceType, Pointer: pointer})...)
+	}
+
+	if len(errs) > 0 {
+		return &SchemaValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func (v *SchemaValidator) validateStage(pipeline string, idx int, pointer string, s interface{}) []SchemaError {
+	base := SchemaError{Pipeline: pipeline, Stage: idx, Pointer: pointer}
+
+	stage, ok := s.(map[string]interface{})
+	if !ok {
+		base.Message = "stage must be an object"
+		return []SchemaError{base}
+	}
+	stageType, _ := stage["type"].(string)
+	base.Module = stageType
+	schema, exists := v.stages[stageType]
+	if !exists {
+		base.Pointer += "/type"
+		base.Message = fmt.Sprintf("unknown stage type %q", stageType)
+		return []SchemaError{base}
+	}
+	errs := validateAgainst(schema, stage, base)
+
+	inputs, _ := stage["inputs"].(map[string]interface{})
+	inputNames := make([]string, 0, len(inputs))
+	for inputName := range inputs {
+		inputNames = append(inputNames, inputName)
+	}
+	sort.Strings(inputNames)
+	for _, inputName := range inputNames {
+		input, _ := inputs[inputName].(map[string]interface{})
+		inputType, _ := input["type"].(string)
+		inputBase := base
+		inputBase.Module = inputType
+		inputBase.Pointer = pointer + "/inputs/" + escapePointerToken(inputName)
+		inputSchema, exists := v.inputs[inputType]
+		if !exists {
+			inputBase.Message = fmt.Sprintf("unknown input type %q", inputType)
+			errs = append(errs, inputBase)
+			continue
+		}
+		errs = append(errs, validateAgainst(inputSchema, input, inputBase)...)
+	}
+	return errs
+}
+
+// validateAgainst validates data against the schema and returns an error for
+// each violation, based on base, with the pointer of the offending value.
+func validateAgainst(schema *spec.Schema, data interface{}, base SchemaError) []SchemaError {
+	err := validate.AgainstSchema(schema, data, strfmt.Default)
+	if err == nil {
+		return nil
+	}
+
+	var errs []SchemaError
+	for _, e := range flattenErrors(err) {
+		se := base
+		se.Message = e.Error()
+		if ve, ok := e.(*errors.Validation); ok {
+			path := validationPathTokens(ve.Name)
+			if key, ok := ve.Value.(string); ok && ve.Code() == errors.UnallowedPropertyCode {
+				// reported for the parent object; point to the property
+				path = append(path, key)
+				se.Message = "is a forbidden property"
+			} else {
+				// the messages start with the path of the value, which is
+				// already part of the pointer
+				msg := strings.Replace(se.Message, ve.Name, "", 1)
+				msg = strings.Replace(msg, " in "+ve.In+" ", " ", 1)
+				se.Message = strings.Join(strings.Fields(msg), " ")
+			}
+
+			pointer, rest := pathToPointer(data, path)
+			se.Pointer += pointer
+			if rest != "" {
+				se.Message = fmt.Sprintf("array item %s %s", rest, se.Message)
+			}
+		}
+		errs = append(errs, se)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pointer < errs[j].Pointer
+	})
+	return errs
+}
+
+func flattenErrors(err error) []error {
+	if ce, ok := err.(*errors.CompositeError); ok {
+		var errs []error
+		for _, e := range ce.Errors {
+			errs = append(errs, flattenErrors(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// validationPathTokens splits the dotted path of a validation error, e.g.
+// ".options.paths.0", into its tokens.
+func validationPathTokens(name string) []string {
+	name = strings.TrimPrefix(name, ".")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
+
+// pathToPointer converts the path of a value in data into a JSON pointer.
+//
+// The validator omits the index from the paths of values inside of array
+// items, e.g. "options.paths.mode" instead of "options.paths.0.mode". In this
+// case the pointer ends at the array and the remainder of the path is returned
+// separately, e.g. "/mode".
+func pathToPointer(data interface{}, path []string) (string, string) {
+	var pointer string
+	for idx, token := range path {
+		switch node := data.(type) {
+		case map[string]interface{}:
+			data = node[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				var rest string
+				for _, t := range path[idx:] {
+					rest += "/" + escapePointerToken(t)
+				}
+				return pointer, rest
+			}
+			data = node[i]
+		default:
+			data = nil
+		}
+		pointer += "/" + escapePointerToken(token)
+	}
+	return pointer, ""
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
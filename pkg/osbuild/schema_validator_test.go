@@ -0,0 +1,191 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManifest() Manifest {
+	build := Pipeline{Name: "build"}
+	build.AddStage(NewMkdirStage(&MkdirStageOptions{
+		Paths: []MkdirStagePath{{Path: "/etc/foo", Parents: true}},
+	}))
+
+	os := Pipeline{Name: "os", Build: "name:build"}
+	os.AddStage(NewLocaleStage(&LocaleStageOptions{Language: "en_US.UTF-8"}))
+	os.AddStage(NewCopyStageSimple(
+		&CopyStageOptions{Paths: []CopyStagePath{{From: "input://file/sha256:aaa", To: "tree:///etc/file"}}},
+		&CopyStageFilesInputs{"file": NewFilesInput(NewFilesInputSourcePlainRef([]string{"sha256:aaa"}))},
+	))
+
+	return Manifest{
+		Version:   "2",
+		Pipelines: []Pipeline{build, os},
+		Sources: Sources{
+			"org.osbuild.curl": &CurlSource{
+				Items: map[string]CurlSourceItem{
+					"sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855": URL("https://example.com/file"),
+				},
+			},
+		},
+	}
+}
+
+func loadTestValidator(t *testing.T) *SchemaValidator {
+	v, err := LoadSchemaValidator("testdata/schemas")
+	require.NoError(t, err)
+	return v
+}
+
+func TestLoadSchemaValidator(t *testing.T) {
+	v := loadTestValidator(t)
+	assert.Len(t, v.stages, 3)
+	assert.Contains(t, v.stages, "org.osbuild.mkdir")
+	assert.Contains(t, v.stages, "org.osbuild.locale")
+	assert.Contains(t, v.stages, "org.osbuild.copy")
+	assert.Contains(t, v.inputs, "org.osbuild.files")
+	assert.Contains(t, v.sources, "org.osbuild.curl")
+
+	_, err := LoadSchemaValidator("testdata/schemas/sources")
+	assert.EqualError(t, err, "no stage schemas found")
+}
+
+func TestSchemaValidatorValid(t *testing.T) {
+	v := loadTestValidator(t)
+	data, err := json.Marshal(newTestManifest())
+	require.NoError(t, err)
+	assert.NoError(t, v.Validate(data))
+}
+
+func TestSchemaValidatorErrors(t *testing.T) {
+	v := loadTestValidator(t)
+
+	testCases := map[string]struct {
+		modify func(*Manifest)
+		errors []SchemaError
+	}{
+		"bad-options": {
+			modify: func(m *Manifest) {
+				m.Pipelines[1].Stages[0].Options = &MkdirStageOptions{}
+			},
+			errors: []SchemaError{
+				{Pipeline: "os", Stage: 0, Module: "org.osbuild.locale", Pointer: "/pipelines/1/stages/0/options/language"},
+				{Pipeline: "os", Stage: 0, Module: "org.osbuild.locale", Pointer: "/pipelines/1/stages/0/options/paths"},
+			},
+		},
+		"bad-nested-option": {
+			modify: func(m *Manifest) {
+				m.Pipelines[1].Stages[1].Options.(*CopyStageOptions).Paths[0].RemoveDestination = true
+			},
+			errors: []SchemaError{
+				// the validator does not report the index of the item
+				{Pipeline: "os", Stage: 1, Module: "org.osbuild.copy", Pointer: "/pipelines/1/stages/1/options/paths"},
+			},
+		},
+		"unknown-stage": {
+			modify: func(m *Manifest) {
+				m.Pipelines[1].Stages[1].Type = "org.osbuild.nope"
+			},
+			errors: []SchemaError{
+				{Pipeline: "os", Stage: 1, Module: "org.osbuild.nope", Pointer: "/pipelines/1/stages/1/type"},
+			},
+		},
+		"bad-input": {
+			modify: func(m *Manifest) {
+				m.Pipelines[1].Stages[1].Inputs = &CopyStageFilesInputs{
+					"file/1": &FilesInput{
+						inputCommon: inputCommon{Type: InputTypeFiles, Origin: "org.osbuild.nowhere"},
+						References:  NewFilesInputSourcePlainRef([]string{"sha256:aaa"}),
+					},
+				}
+			},
+			errors: []SchemaError{
+				{Pipeline: "os", Stage: 1, Module: "org.osbuild.files", Pointer: "/pipelines/1/stages/1/inputs/file~11/origin"},
+			},
+		},
+		"unknown-source": {
+			modify: func(m *Manifest) {
+				m.Sources["org.osbuild.skopeo"] = &CurlSource{}
+			},
+			errors: []SchemaError{
+				{Stage: -1, Module: "org.osbuild.skopeo", Pointer: "/sources/org.osbuild.skopeo"},
+			},
+		},
+		"bad-version": {
+			modify: func(m *Manifest) {
+				m.Version = "1"
+			},
+			errors: []SchemaError{
+				{Stage: -1, Pointer: "/version"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManifest()
+			tc.modify(&m)
+			data, err := json.Marshal(m)
+			require.NoError(t, err)
+
+			err = v.Validate(data)
+			require.Error(t, err)
+			verr, ok := err.(*SchemaValidationError)
+			require.True(t, ok, "unexpected error type: %v", err)
+
+			// messages come from the schema library; only check that they
+			// are set
+			for idx := range verr.Errors {
+				assert.NotEmpty(t, verr.Errors[idx].Message)
+				verr.Errors[idx].Message = ""
+			}
+			assert.Equal(t, tc.errors, verr.Errors)
+		})
+	}
+}
+
+func TestSchemaValidationErrorMessage(t *testing.T) {
+	v := loadTestValidator(t)
+	m := newTestManifest()
+	m.Pipelines[1].Stages[1].Options.(*CopyStageOptions).Paths[0].RemoveDestination = true
+	m.Sources["org.osbuild.skopeo"] = &CurlSource{}
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	err = v.Validate(data)
+	assert.EqualError(t, err, `manifest is invalid (2 errors):
+pipeline "os", stage 1 (org.osbuild.copy): /pipelines/1/stages/1/options/paths: array item /remove_destination is a forbidden property
+org.osbuild.skopeo: /sources/org.osbuild.skopeo: unknown source type`)
+}
+
+func TestPathToPointer(t *testing.T) {
+	data := map[string]interface{}{
+		"options": map[string]interface{}{
+			"paths": []interface{}{
+				map[string]interface{}{"mode": "0755"},
+			},
+			"a/b~c": true,
+		},
+	}
+
+	testCases := []struct {
+		path    string
+		pointer string
+		rest    string
+	}{
+		{"", "", ""},
+		{".", "", ""},
+		{".options.paths.0.mode", "/options/paths/0/mode", ""},
+		{"options.a/b~c", "/options/a~1b~0c", ""},
+		{".options.paths.mode", "/options/paths", "/mode"},
+		{".options.missing.key", "/options/missing/key", ""},
+	}
+	for _, tc := range testCases {
+		pointer, rest := pathToPointer(data, validationPathTokens(tc.path))
+		assert.Equal(t, tc.pointer, pointer, tc.path)
+		assert.Equal(t, tc.rest, rest, tc.path)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -41,6 +42,10 @@ func (sources *Sources) UnmarshalJSON(data []byte) error {
 			source = new(InlineSource)
 		case "org.osbuild.ostree":
 			source = new(OSTreeSource)
+		case "org.osbuild.skopeo":
+			source = new(SkopeoSource)
+		case "org.osbuild.skopeo-index":
+			source = new(SkopeoIndexSource)
 		default:
 			return errors.New("unexpected source name: " + name)
 		}
@@ -54,7 +59,7 @@ func (sources *Sources) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func GenSources(packages []rpmmd.PackageSpec, ostreeCommits []ostree.CommitSpec, inlineData []string, containers []container.Spec) Sources {
+func GenSources(packages []rpmmd.PackageSpec, ostreeCommits []ostree.CommitSpec, inlineData []string, remoteFiles []*fsnode.File, containers []container.Spec) Sources {
 	sources := Sources{}
 	curl := &CurlSource{
 		Items: make(map[string]CurlSourceItem),
@@ -70,6 +75,10 @@ func GenSources(packages []rpmmd.PackageSpec, ostreeCommits []ostree.CommitSpec,
 		item.Insecure = pkg.IgnoreSSL
 		curl.Items[pkg.Checksum] = item
 	}
+	// osbuild verifies the content of the remote files against the checksum
+	for _, file := range remoteFiles {
+		curl.Items[file.Checksum()] = URL(file.URL())
+	}
 	if len(curl.Items) > 0 {
 		sources["org.osbuild.curl"] = curl
 	}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+
+	"github.com/osbuild/images/internal/fsnode"
 )
 
 func TestSource_UnmarshalJSON(t *testing.T) {
@@ -113,3 +115,23 @@ func TestSource_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestGenSourcesRemoteFiles(t *testing.T) {
+	checksum := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	remote, err := fsnode.NewRemoteFile("/usr/local/bin/agent", nil, nil, nil, "https://example.com/agent", checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := GenSources(nil, nil, nil, []*fsnode.File{remote}, nil)
+	expected := Sources{
+		"org.osbuild.curl": &CurlSource{
+			Items: map[string]CurlSourceItem{
+				"sha256:" + checksum: URL("https://example.com/agent"),
+			},
+		},
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("GenSources() = %v, want %v", sources, expected)
+	}
+}
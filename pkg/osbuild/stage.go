@@ -1,5 +1,10 @@
 package osbuild
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // Single stage of a pipeline executing one step
 type Stage struct {
 	// Well-known name in reverse domain-name notation, uniquely identifying
@@ -25,3 +30,48 @@ func (s *Stage) MountOSTree(osName, ref string, serial int) {
 	ostreeMount := NewOSTreeDeploymentMount(name, osName, ref, serial)
 	s.Mounts = append(s.Mounts, *ostreeMount)
 }
+
+// UnmarshalJSON decodes a stage into the Go types of the options and inputs of
+// its stage type (see StageTypes).
+func (s *Stage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type    string          `json:"type"`
+		Inputs  json.RawMessage `json:"inputs"`
+		Options json.RawMessage `json:"options"`
+		Devices Devices         `json:"devices"`
+		Mounts  Mounts          `json:"mounts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	st, exists := stageTypes[raw.Type]
+	if !exists {
+		return fmt.Errorf("unexpected stage type: %s", raw.Type)
+	}
+
+	stage := Stage{
+		Type:    raw.Type,
+		Devices: raw.Devices,
+		Mounts:  raw.Mounts,
+	}
+	var err error
+	if isSet(raw.Options) {
+		if stage.Options, err = st.unmarshalOptions(raw.Type, raw.Options); err != nil {
+			return err
+		}
+	}
+	if isSet(raw.Inputs) {
+		if stage.Inputs, err = st.unmarshalInputs(raw.Type, raw.Inputs); err != nil {
+			return err
+		}
+	}
+
+	*s = stage
+	return nil
+}
+
+// isSet returns true if the raw JSON value of an optional property is set.
+func isSet(data json.RawMessage) bool {
+	return len(data) > 0 && string(data) != "null"
+}
@@ -0,0 +1,230 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// stageType describes the Go types a stage type is decoded into.
+type stageType struct {
+	// Options of the stage; nil if the stage has no options
+	options StageOptions
+
+	// Inputs of the stage; nil if the stage has no inputs or they are
+	// decoded by decodeInputs
+	inputs Inputs
+
+	// Decoder for stages that accept different kinds of inputs
+	decodeInputs func(data []byte) (Inputs, error)
+}
+
+// stageTypes maps the stage types to the types of their options and inputs.
+// Stage types must be added here when they are added to the package, so that
+// manifests that contain them can be decoded.
+var stageTypes = map[string]stageType{
+	"org.osbuild.anaconda":                {options: new(AnacondaStageOptions)},
+	"org.osbuild.authconfig":              {options: new(AuthconfigStageOptions)},
+	"org.osbuild.authselect":              {options: new(AuthselectStageOptions)},
+	"org.osbuild.bootiso.mono":            {options: new(BootISOMonoStageOptions), inputs: new(BootISOMonoStageInputs)},
+	"org.osbuild.btrfs.subvol":            {options: new(BtrfsSubVolStageOptions)},
+	"org.osbuild.buildstamp":              {options: new(BuildstampStageOptions)},
+	"org.osbuild.chmod":                   {options: new(ChmodStageOptions)},
+	"org.osbuild.chown":                   {options: new(ChownStageOptions)},
+	"org.osbuild.chrony":                  {options: new(ChronyStageOptions)},
+	"org.osbuild.clevis.luks-bind":        {options: new(ClevisLuksBindStageOptions)},
+	"org.osbuild.cloud-init":              {options: new(CloudInitStageOptions)},
+	"org.osbuild.containers.storage.conf": {options: new(ContainersStorageConfStageOptions)},
+	"org.osbuild.copy":                    {options: new(CopyStageOptions), decodeInputs: decodeInputsByType},
+	"org.osbuild.discinfo":                {options: new(DiscinfoStageOptions)},
+	"org.osbuild.dnf-automatic.config":    {options: new(DNFAutomaticConfigStageOptions)},
+	"org.osbuild.dnf.config":              {options: new(DNFConfigStageOptions)},
+	"org.osbuild.dracut":                  {options: new(DracutStageOptions)},
+	"org.osbuild.dracut.conf":             {options: new(DracutConfStageOptions)},
+	"org.osbuild.fdo":                     {inputs: new(FDOStageInputs)},
+	"org.osbuild.firewall":                {options: new(FirewallStageOptions)},
+	"org.osbuild.first-boot":              {options: new(FirstBootStageOptions)},
+	"org.osbuild.fix-bls":                 {options: new(FixBLSStageOptions)},
+	"org.osbuild.fstab":                   {options: new(FSTabStageOptions)},
+	"org.osbuild.gcp.guest-agent.conf":    {options: new(GcpGuestAgentConfigOptions)},
+	"org.osbuild.groups":                  {options: new(GroupsStageOptions)},
+	"org.osbuild.grub2":                   {options: new(GRUB2StageOptions)},
+	"org.osbuild.grub2.inst":              {options: new(Grub2InstStageOptions)},
+	"org.osbuild.grub2.iso":               {options: new(GrubISOStageOptions)},
+	"org.osbuild.grub2.legacy":            {options: new(GRUB2LegacyStageOptions)},
+	"org.osbuild.gzip":                    {options: new(GzipStageOptions), inputs: new(GzipStageInputs)},
+	"org.osbuild.hostname":                {options: new(HostnameStageOptions)},
+	"org.osbuild.ignition":                {options: new(IgnitionStageOptions)},
+	"org.osbuild.implantisomd5":           {options: new(Implantisomd5StageOptions)},
+	"org.osbuild.isolinux":                {options: new(ISOLinuxStageOptions), inputs: new(PipelineTreeInputs)},
+	"org.osbuild.kernel-cmdline":          {options: new(KernelCmdlineStageOptions)},
+	"org.osbuild.keymap":                  {options: new(KeymapStageOptions)},
+	"org.osbuild.kickstart":               {options: new(KickstartStageOptions)},
+	"org.osbuild.locale":                  {options: new(LocaleStageOptions)},
+	"org.osbuild.lorax-script":            {options: new(LoraxScriptStageOptions)},
+	"org.osbuild.luks2.format":            {options: new(LUKS2CreateStageOptions)},
+	"org.osbuild.luks2.remove-key":        {options: new(LUKS2RemoveKeyStageOptions)},
+	"org.osbuild.lvm2.create":             {options: new(LVM2CreateStageOptions)},
+	"org.osbuild.lvm2.metadata":           {options: new(LVM2MetadataStageOptions)},
+	"org.osbuild.mkdir":                   {options: new(MkdirStageOptions)},
+	"org.osbuild.mkfs.btrfs":              {options: new(MkfsBtrfsStageOptions)},
+	"org.osbuild.mkfs.ext4":               {options: new(MkfsExt4StageOptions)},
+	"org.osbuild.mkfs.fat":                {options: new(MkfsFATStageOptions)},
+	"org.osbuild.mkfs.xfs":                {options: new(MkfsXfsStageOptions)},
+	"org.osbuild.modprobe":                {options: new(ModprobeStageOptions)},
+	"org.osbuild.nginx.conf":              {options: new(NginxConfigStageOptions)},
+	"org.osbuild.oci-archive":             {options: new(OCIArchiveStageOptions), inputs: new(OCIArchiveStageInputs)},
+	"org.osbuild.oscap.remediation":       {options: new(OscapRemediationStageOptions)},
+	"org.osbuild.ostree.commit":           {options: new(OSTreeCommitStageOptions), inputs: new(PipelineTreeInputs)},
+	"org.osbuild.ostree.config":           {options: new(OSTreeConfigStageOptions)},
+	"org.osbuild.ostree.deploy":           {options: new(OSTreeDeployStageOptions)},
+	"org.osbuild.ostree.fillvar":          {options: new(OSTreeFillvarStageOptions)},
+	"org.osbuild.ostree.init":             {options: new(OSTreeInitStageOptions)},
+	"org.osbuild.ostree.init-fs":          {},
+	"org.osbuild.ostree.os-init":          {options: new(OSTreeOsInitStageOptions)},
+	"org.osbuild.ostree.passwd":           {options: new(OSTreePasswdStageOptions), inputs: new(OSTreePasswdStageInputs)},
+	"org.osbuild.ostree.preptree":         {options: new(OSTreePrepTreeStageOptions)},
+	"org.osbuild.ostree.pull":             {options: new(OSTreePullStageOptions), inputs: new(OSTreePullStageInputs)},
+	"org.osbuild.ostree.remotes":          {options: new(OSTreeRemotesStageOptions)},
+	"org.osbuild.ostree.selinux":          {options: new(OSTreeSelinuxStageOptions)},
+	"org.osbuild.ovf":                     {options: new(OVFStageOptions)},
+	"org.osbuild.pam.limits.conf":         {options: new(PamLimitsConfStageOptions)},
+	"org.osbuild.pwquality.conf":          {options: new(PwqualityConfStageOptions)},
+	"org.osbuild.qemu":                    {options: new(QEMUStageOptions), inputs: new(QEMUStageInputs)},
+	"org.osbuild.rhsm":                    {options: new(RHSMStageOptions)},
+	"org.osbuild.rhsm.facts":              {options: new(RHSMFactsStageOptions)},
+	"org.osbuild.rpm":                     {options: new(RPMStageOptions), inputs: new(RPMStageInputs)},
+	"org.osbuild.script":                  {options: new(ScriptStageOptions)},
+	"org.osbuild.selinux":                 {options: new(SELinuxStageOptions)},
+	"org.osbuild.selinux.config":          {options: new(SELinuxConfigStageOptions)},
+	"org.osbuild.sfdisk":                  {options: new(SfdiskStageOptions)},
+	"org.osbuild.sgdisk":                  {options: new(SgdiskStageOptions)},
+	"org.osbuild.shell.init":              {options: new(ShellInitStageOptions)},
+	"org.osbuild.skopeo":                  {options: new(SkopeoStageOptions), inputs: new(SkopeoStageInputs)},
+	"org.osbuild.squashfs":                {options: new(SquashfsStageOptions), inputs: new(PipelineTreeInputs)},
+	"org.osbuild.sshd.config":             {options: new(SshdConfigStageOptions)},
+	"org.osbuild.sysconfig":               {options: new(SysconfigStageOptions)},
+	"org.osbuild.sysctld":                 {options: new(SysctldStageOptions)},
+	"org.osbuild.systemd":                 {options: new(SystemdStageOptions)},
+	"org.osbuild.systemd-journald":        {options: new(SystemdJournaldStageOptions)},
+	"org.osbuild.systemd-logind":          {options: new(SystemdLogindStageOptions)},
+	"org.osbuild.systemd.unit":            {options: new(SystemdUnitStageOptions)},
+	"org.osbuild.tar":                     {options: new(TarStageOptions), inputs: new(PipelineTreeInputs)},
+	"org.osbuild.timezone":                {options: new(TimezoneStageOptions)},
+	"org.osbuild.tmpfilesd":               {options: new(TmpfilesdStageOptions)},
+	"org.osbuild.truncate":                {options: new(TruncateStageOptions)},
+	"org.osbuild.tuned":                   {options: new(TunedStageOptions)},
+	"org.osbuild.udev.rules":              {options: new(UdevRulesStageOptions)},
+	"org.osbuild.users":                   {options: new(UsersStageOptions)},
+	"org.osbuild.waagent.conf":            {options: new(WAAgentConfStageOptions)},
+	"org.osbuild.wsl.conf":                {options: new(WSLConfStageOptions)},
+	"org.osbuild.xorrisofs":               {options: new(XorrisofsStageOptions), inputs: new(PipelineTreeInputs)},
+	"org.osbuild.xz":                      {options: new(XzStageOptions), inputs: new(XzStageInputs)},
+	"org.osbuild.yum.config":              {options: new(YumConfigStageOptions)},
+	"org.osbuild.yum.repos":               {options: new(YumReposStageOptions)},
+	"org.osbuild.zipl":                    {options: new(ZiplStageOptions)},
+	"org.osbuild.zipl.inst":               {options: new(ZiplInstStageOptions)},
+	"org.osbuild.zstd":                    {options: new(ZstdStageOptions), inputs: new(ZstdStageInputs)},
+}
+
+// StageTypes returns the sorted list of the stage types that can be decoded.
+func StageTypes() []string {
+	types := make([]string, 0, len(stageTypes))
+	for name := range stageTypes {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewStageOptions returns a pointer to new, empty options of the given stage
+// type, e.g. a *RPMStageOptions for "org.osbuild.rpm". It returns nil if the
+// stage type has no options.
+func NewStageOptions(typ string) (StageOptions, error) {
+	st, exists := stageTypes[typ]
+	if !exists {
+		return nil, fmt.Errorf("unknown stage type: %s", typ)
+	}
+	if st.options == nil {
+		return nil, nil
+	}
+	return newOfType(st.options).(StageOptions), nil
+}
+
+// newOfType returns a pointer to a new zero value of the type of v, which is
+// either a value or a pointer.
+func newOfType(v interface{}) interface{} {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return reflect.New(t).Interface()
+}
+
+func (st stageType) unmarshalOptions(typ string, data []byte) (StageOptions, error) {
+	if st.options == nil {
+		return nil, fmt.Errorf("stage type %s has no options", typ)
+	}
+	options := newOfType(st.options).(StageOptions)
+	if err := json.Unmarshal(data, options); err != nil {
+		return nil, fmt.Errorf("cannot decode options of stage %s: %w", typ, err)
+	}
+	return options, nil
+}
+
+func (st stageType) unmarshalInputs(typ string, data []byte) (Inputs, error) {
+	if st.decodeInputs != nil {
+		inputs, err := st.decodeInputs(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode inputs of stage %s: %w", typ, err)
+		}
+		return inputs, nil
+	}
+	if st.inputs == nil {
+		return nil, fmt.Errorf("stage type %s has no inputs", typ)
+	}
+	inputs := newOfType(st.inputs).(Inputs)
+	if err := json.Unmarshal(data, inputs); err != nil {
+		return nil, fmt.Errorf("cannot decode inputs of stage %s: %w", typ, err)
+	}
+	return inputs, nil
+}
+
+// decodeInputsByType decodes the inputs of stages that accept inputs of any
+// type, like org.osbuild.copy, based on the type of the inputs. All inputs of
+// a stage must be of the same type.
+func decodeInputsByType(data []byte) (Inputs, error) {
+	var raw map[string]struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var inputType string
+	for name, input := range raw {
+		if inputType != "" && input.Type != inputType {
+			return nil, fmt.Errorf("input %q: mixed input types %s and %s are not supported", name, inputType, input.Type)
+		}
+		inputType = input.Type
+	}
+
+	var inputs Inputs
+	switch inputType {
+	case "org.osbuild.tree":
+		inputs = new(PipelineTreeInputs)
+	case InputTypeFiles:
+		inputs = new(CopyStageFilesInputs)
+	case "org.osbuild.ostree.checkout":
+		inputs = new(OSTreeCheckoutInputs)
+	case InputTypeContainers:
+		inputs = new(ContainersInputs)
+	default:
+		return nil, fmt.Errorf("unsupported input type: %s", inputType)
+	}
+	if err := json.Unmarshal(data, inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
@@ -0,0 +1,189 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageTypes(t *testing.T) {
+	types := StageTypes()
+	assert.IsIncreasing(t, types)
+	assert.Contains(t, types, "org.osbuild.rpm")
+	assert.Contains(t, types, "org.osbuild.copy")
+
+	options, err := NewStageOptions("org.osbuild.rpm")
+	require.NoError(t, err)
+	assert.IsType(t, &RPMStageOptions{}, options)
+
+	// a new value is returned on every call
+	other, err := NewStageOptions("org.osbuild.rpm")
+	require.NoError(t, err)
+	assert.NotSame(t, options, other)
+
+	options, err = NewStageOptions("org.osbuild.ostree.init-fs")
+	require.NoError(t, err)
+	assert.Nil(t, options)
+
+	_, err = NewStageOptions("org.osbuild.foo")
+	assert.EqualError(t, err, "unknown stage type: org.osbuild.foo")
+}
+
+func TestStageUnmarshalJSON(t *testing.T) {
+	testCases := map[string]struct {
+		data  string
+		check func(t *testing.T, stage *Stage)
+	}{
+		"rpm": {
+			data: `{
+				"type": "org.osbuild.rpm",
+				"inputs": {
+					"packages": {
+						"type": "org.osbuild.files",
+						"origin": "org.osbuild.source",
+						"references": [
+							{"id": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "options": {"metadata": {"rpm.check_gpg": true}}}
+						]
+					}
+				},
+				"options": {"gpgkeys": ["key"]}
+			}`,
+			check: func(t *testing.T, stage *Stage) {
+				require.IsType(t, &RPMStageOptions{}, stage.Options)
+				assert.Equal(t, []string{"key"}, stage.Options.(*RPMStageOptions).GPGKeys)
+				require.IsType(t, &RPMStageInputs{}, stage.Inputs)
+				refs := stage.Inputs.(*RPMStageInputs).Packages.References
+				require.IsType(t, &FilesInputSourceArrayRef{}, refs)
+				assert.Equal(t, &RPMStageReferenceMetadata{CheckGPG: true}, (*refs.(*FilesInputSourceArrayRef))[0].Options.Metadata)
+			},
+		},
+		"copy-tree-with-devices-and-mounts": {
+			data: `{
+				"type": "org.osbuild.copy",
+				"inputs": {
+					"root-tree": {"type": "org.osbuild.tree", "origin": "org.osbuild.pipeline", "references": ["name:os"]}
+				},
+				"options": {"paths": [{"from": "input://root-tree/", "to": "mount://root/"}]},
+				"devices": {
+					"disk": {"type": "org.osbuild.loopback", "options": {"filename": "disk.img", "size": 2048}},
+					"root": {"type": "org.osbuild.luks2", "parent": "disk", "options": {"passphrase": "secret"}}
+				},
+				"mounts": [
+					{"name": "root", "type": "org.osbuild.xfs", "source": "root", "target": "/"}
+				]
+			}`,
+			check: func(t *testing.T, stage *Stage) {
+				assert.IsType(t, &PipelineTreeInputs{}, stage.Inputs)
+				require.Len(t, stage.Devices, 2)
+				assert.IsType(t, &LoopbackDeviceOptions{}, stage.Devices["disk"].Options)
+				assert.IsType(t, &LUKS2DeviceOptions{}, stage.Devices["root"].Options)
+				require.Len(t, stage.Mounts, 1)
+				assert.Equal(t, "org.osbuild.xfs", stage.Mounts[0].Type)
+			},
+		},
+		"copy-files": {
+			data: `{
+				"type": "org.osbuild.copy",
+				"inputs": {
+					"file": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": {"sha256:1111111111111111111111111111111111111111111111111111111111111111": {}}}
+				},
+				"options": {"paths": [{"from": "input://file/sha256:1111111111111111111111111111111111111111111111111111111111111111", "to": "tree:///etc/file"}]}
+			}`,
+			check: func(t *testing.T, stage *Stage) {
+				assert.IsType(t, &CopyStageFilesInputs{}, stage.Inputs)
+			},
+		},
+		"qemu": {
+			data: `{
+				"type": "org.osbuild.qemu",
+				"inputs": {"image": {"type": "org.osbuild.files", "origin": "org.osbuild.pipeline", "references": {"name:image": {"file": "disk.img"}}}},
+				"options": {"filename": "disk.qcow2", "format": {"type": "qcow2", "compat": "1.1"}}
+			}`,
+			check: func(t *testing.T, stage *Stage) {
+				require.IsType(t, &QEMUStageOptions{}, stage.Options)
+				assert.Equal(t, QCOW2Options{Type: QEMUFormatQCOW2, Compat: "1.1"}, stage.Options.(*QEMUStageOptions).Format)
+			},
+		},
+		"udev-rules": {
+			data: `{
+				"type": "org.osbuild.udev.rules",
+				"options": {
+					"filename": "/etc/udev/rules.d/68-azure.rules",
+					"rules": [
+						{"comment": ["Azure"]},
+						[
+							{"key": "ACTION", "op": "==", "val": "add"},
+							{"key": {"name": "ATTRS", "arg": "device_id"}, "op": "==", "val": "?00000000-0000-*"}
+						]
+					]
+				}
+			}`,
+			check: func(t *testing.T, stage *Stage) {
+				require.IsType(t, &UdevRulesStageOptions{}, stage.Options)
+				rules := stage.Options.(*UdevRulesStageOptions).Rules
+				require.Len(t, rules, 2)
+				assert.IsType(t, UdevRuleComment{}, rules[0])
+				require.IsType(t, UdevOps{}, rules[1])
+				assert.IsType(t, UdevOpSimple{}, rules[1].(UdevOps)[0])
+				assert.IsType(t, UdevOpArg{}, rules[1].(UdevOps)[1])
+			},
+		},
+		"no-options": {
+			data: `{"type": "org.osbuild.ostree.init-fs"}`,
+			check: func(t *testing.T, stage *Stage) {
+				assert.Nil(t, stage.Options)
+				assert.Nil(t, stage.Inputs)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stage Stage
+			require.NoError(t, json.Unmarshal([]byte(tc.data), &stage))
+			tc.check(t, &stage)
+
+			data, err := json.Marshal(&stage)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.data, string(data))
+		})
+	}
+}
+
+func TestStageUnmarshalJSONErrors(t *testing.T) {
+	testCases := map[string]struct {
+		data    string
+		wantErr string
+	}{
+		"unknown-stage": {
+			data:    `{"type": "org.osbuild.foo"}`,
+			wantErr: "unexpected stage type: org.osbuild.foo",
+		},
+		"unknown-device": {
+			data:    `{"type": "org.osbuild.mkfs.xfs", "options": {"uuid": "x"}, "devices": {"device": {"type": "org.osbuild.foo"}}}`,
+			wantErr: "unexpected device type: org.osbuild.foo",
+		},
+		"unknown-mount": {
+			data:    `{"type": "org.osbuild.copy", "mounts": [{"name": "root", "type": "org.osbuild.foo", "source": "root", "target": "/"}]}`,
+			wantErr: "unexpected mount type: org.osbuild.foo",
+		},
+		"mixed-inputs": {
+			data: `{"type": "org.osbuild.copy", "inputs": {
+				"a": {"type": "org.osbuild.tree", "origin": "org.osbuild.pipeline", "references": ["name:os"]},
+				"b": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": []}
+			}}`,
+			wantErr: "mixed input types",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stage Stage
+			err := json.Unmarshal([]byte(tc.data), &stage)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -17,11 +17,41 @@ func NewSystemdUnitStage(options *SystemdUnitStageOptions) *Stage {
 
 // Drop-in configuration for a '.service' unit
 type SystemdServiceUnitDropin struct {
+	Unit    *SystemdUnitSection        `json:"Unit,omitempty"`
 	Service *SystemdUnitServiceSection `json:"Service,omitempty"`
+	Install *SystemdUnitInstallSection `json:"Install,omitempty"`
+}
+
+// 'Unit' configuration section of a unit file
+type SystemdUnitSection struct {
+	Description              string   `json:"Description,omitempty"`
+	DefaultDependencies      *bool    `json:"DefaultDependencies,omitempty"`
+	ConditionPathExists      []string `json:"ConditionPathExists,omitempty"`
+	ConditionPathIsDirectory []string `json:"ConditionPathIsDirectory,omitempty"`
+	Requires                 []string `json:"Requires,omitempty"`
+	Wants                    []string `json:"Wants,omitempty"`
+	After                    []string `json:"After,omitempty"`
+	Before                   []string `json:"Before,omitempty"`
 }
 
 // 'Service' configuration section of a unit file
 type SystemdUnitServiceSection struct {
+	// Configures the process start-up type
+	Type            string `json:"Type,omitempty"`
+	RemainAfterExit bool   `json:"RemainAfterExit,omitempty"`
+
+	// Commands executed around the main process
+	ExecStartPre []string `json:"ExecStartPre,omitempty"`
+	ExecStopPost []string `json:"ExecStopPost,omitempty"`
+	ExecStart    []string `json:"ExecStart,omitempty"`
+
 	// Sets environment variables for executed process
-	Environment string `json:"Environment,omitempty"`
+	Environment     string   `json:"Environment,omitempty"`
+	EnvironmentFile []string `json:"EnvironmentFile,omitempty"`
+}
+
+// 'Install' configuration section of a unit file
+type SystemdUnitInstallSection struct {
+	WantedBy   []string `json:"WantedBy,omitempty"`
+	RequiredBy []string `json:"RequiredBy,omitempty"`
 }
@@ -1,6 +1,7 @@
 package osbuild
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,3 +15,30 @@ func TestNewSystemdUnitStage(t *testing.T) {
 	actualStage := NewSystemdUnitStage(&SystemdUnitStageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestSystemdUnitStageOptionsJSON(t *testing.T) {
+	options := &SystemdUnitStageOptions{
+		Unit:   "sshd.service",
+		Dropin: "10-restart.conf",
+		Config: SystemdServiceUnitDropin{
+			Unit: &SystemdUnitSection{
+				After: []string{"network-online.target"},
+				Wants: []string{"network-online.target"},
+			},
+			Service: &SystemdUnitServiceSection{
+				ExecStartPre: []string{"/usr/bin/ssh-keygen -A"},
+				Environment:  "OPTIONS=-4",
+			},
+		},
+	}
+	data, err := json.Marshal(options)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"unit": "sshd.service",
+		"dropin": "10-restart.conf",
+		"config": {
+			"Unit": {"Wants": ["network-online.target"], "After": ["network-online.target"]},
+			"Service": {"ExecStartPre": ["/usr/bin/ssh-keygen -A"], "Environment": "OPTIONS=-4"}
+		}
+	}`, string(data))
+}
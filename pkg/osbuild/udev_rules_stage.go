@@ -1,6 +1,7 @@
 package osbuild
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 )
@@ -130,6 +131,35 @@ func NewUdevRulesStage(options *UdevRulesStageOptions) *Stage {
 
 type UdevRules []UdevRule
 
+// UnmarshalJSON decodes the rules into comments or lists of operations.
+func (r *UdevRules) UnmarshalJSON(data []byte) error {
+	var rawRules []json.RawMessage
+	if err := json.Unmarshal(data, &rawRules); err != nil {
+		return err
+	}
+
+	rules := make(UdevRules, 0, len(rawRules))
+	for _, rawRule := range rawRules {
+		var rule UdevRule
+		if len(rawRule) > 0 && rawRule[0] == '{' {
+			var comment UdevRuleComment
+			if err := json.Unmarshal(rawRule, &comment); err != nil {
+				return err
+			}
+			rule = comment
+		} else {
+			var ops UdevOps
+			if err := json.Unmarshal(rawRule, &ops); err != nil {
+				return err
+			}
+			rule = ops
+		}
+		rules = append(rules, rule)
+	}
+	*r = rules
+	return nil
+}
+
 type UdevRule interface {
 	isUdevRule()
 }
@@ -153,6 +183,42 @@ type UdevOps []UdevOp
 
 func (UdevOps) isUdevRule() {}
 
+// UnmarshalJSON decodes the operations based on the type of their key: keys
+// with an argument are objects, simple keys are strings.
+func (o *UdevOps) UnmarshalJSON(data []byte) error {
+	var rawOps []struct {
+		Key json.RawMessage `json:"key"`
+	}
+	if err := json.Unmarshal(data, &rawOps); err != nil {
+		return err
+	}
+	var rawData []json.RawMessage
+	if err := json.Unmarshal(data, &rawData); err != nil {
+		return err
+	}
+
+	ops := make(UdevOps, 0, len(rawOps))
+	for idx, rawOp := range rawOps {
+		var op UdevOp
+		if len(rawOp.Key) > 0 && rawOp.Key[0] == '{' {
+			var argOp UdevOpArg
+			if err := json.Unmarshal(rawData[idx], &argOp); err != nil {
+				return err
+			}
+			op = argOp
+		} else {
+			var simpleOp UdevOpSimple
+			if err := json.Unmarshal(rawData[idx], &simpleOp); err != nil {
+				return err
+			}
+			op = simpleOp
+		}
+		ops = append(ops, op)
+	}
+	*o = ops
+	return nil
+}
+
 type UdevOp interface {
 	isUdevOp()
 	validate() error
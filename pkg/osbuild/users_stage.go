@@ -20,8 +20,16 @@ type UsersStageOptionsUser struct {
 	Shell       *string  `json:"shell,omitempty"`
 	Password    *string  `json:"password,omitempty"`
 	Key         *string  `json:"key,omitempty"`
+
+	// Expiry date of the account in days since the epoch
+	ExpireDate         *int  `json:"expiredate,omitempty"`
+	ForcePasswordReset *bool `json:"force_password_reset,omitempty"`
 }
 
+// lockedPassword is an invalid password hash, which locks the password of an
+// account
+const lockedPassword = "!"
+
 func NewUsersStage(options *UsersStageOptions) *Stage {
 	return &Stage{
 		Type:    "org.osbuild.users",
@@ -29,6 +37,46 @@ func NewUsersStage(options *UsersStageOptions) *Stage {
 	}
 }
 
+func newUsersStageOptionsUser(user users.User, omitKey bool) (UsersStageOptionsUser, error) {
+	// Don't hash empty passwords, set to nil to lock account
+	if user.Password != nil && len(*user.Password) == 0 {
+		user.Password = nil
+	}
+
+	// Hash non-empty un-hashed passwords
+	if user.Password != nil && !crypt.PasswordIsCrypted(*user.Password) {
+		cryptedPassword, err := crypt.CryptSHA512(*user.Password)
+		if err != nil {
+			return UsersStageOptionsUser{}, err
+		}
+
+		user.Password = &cryptedPassword
+	}
+
+	// Lock accounts explicitly, for users that exist in the tree already
+	if user.Locked {
+		password := lockedPassword
+		user.Password = &password
+	}
+
+	options := UsersStageOptionsUser{
+		UID:                user.UID,
+		GID:                user.GID,
+		Groups:             user.Groups,
+		Description:        user.Description,
+		Home:               user.Home,
+		Shell:              user.Shell,
+		Password:           user.Password,
+		Key:                nil,
+		ExpireDate:         user.ExpireDate,
+		ForcePasswordReset: user.ForcePasswordReset,
+	}
+	if !omitKey {
+		options.Key = user.Key
+	}
+	return options, nil
+}
+
 func NewUsersStageOptions(userCustomizations []users.User, omitKey bool) (*UsersStageOptions, error) {
 	if len(userCustomizations) == 0 {
 		return nil, nil
@@ -36,33 +84,9 @@ func NewUsersStageOptions(userCustomizations []users.User, omitKey bool) (*Users
 
 	users := make(map[string]UsersStageOptionsUser, len(userCustomizations))
 	for _, uc := range userCustomizations {
-		// Don't hash empty passwords, set to nil to lock account
-		if uc.Password != nil && len(*uc.Password) == 0 {
-			uc.Password = nil
-		}
-
-		// Hash non-empty un-hashed passwords
-		if uc.Password != nil && !crypt.PasswordIsCrypted(*uc.Password) {
-			cryptedPassword, err := crypt.CryptSHA512(*uc.Password)
-			if err != nil {
-				return nil, err
-			}
-
-			uc.Password = &cryptedPassword
-		}
-
-		user := UsersStageOptionsUser{
-			UID:         uc.UID,
-			GID:         uc.GID,
-			Groups:      uc.Groups,
-			Description: uc.Description,
-			Home:        uc.Home,
-			Shell:       uc.Shell,
-			Password:    uc.Password,
-			Key:         nil,
-		}
-		if !omitKey {
-			user.Key = uc.Key
+		user, err := newUsersStageOptionsUser(uc, omitKey)
+		if err != nil {
+			return nil, err
 		}
 		users[uc.Name] = user
 	}
@@ -76,33 +100,9 @@ func GenUsersStage(users []users.User, omitKey bool) (*Stage, error) {
 	}
 
 	for _, user := range users {
-		// Don't hash empty passwords, set to nil to lock account
-		if user.Password != nil && len(*user.Password) == 0 {
-			user.Password = nil
-		}
-
-		// Hash non-empty un-hashed passwords
-		if user.Password != nil && !crypt.PasswordIsCrypted(*user.Password) {
-			cryptedPassword, err := crypt.CryptSHA512(*user.Password)
-			if err != nil {
-				return nil, err
-			}
-
-			user.Password = &cryptedPassword
-		}
-
-		userOptions := UsersStageOptionsUser{
-			UID:         user.UID,
-			GID:         user.GID,
-			Groups:      user.Groups,
-			Description: user.Description,
-			Home:        user.Home,
-			Shell:       user.Shell,
-			Password:    user.Password,
-			Key:         nil,
-		}
-		if !omitKey {
-			userOptions.Key = user.Key
+		userOptions, err := newUsersStageOptionsUser(user, omitKey)
+		if err != nil {
+			return nil, err
 		}
 		options.Users[user.Name] = userOptions
 	}
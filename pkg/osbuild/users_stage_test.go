@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/users"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,3 +58,36 @@ func TestNewUsersStageOptionsPassword(t *testing.T) {
 	// homer's password should still be nil (locked account)
 	assert.Nil(t, options.Users["homer"].Password)
 }
+
+func TestNewUsersStageOptionsAccountPolicy(t *testing.T) {
+	pass := "testpass"
+	users := []users.User{
+		{
+			Name:     "root",
+			Password: &pass,
+			Locked:   true,
+		},
+		{
+			Name:               "bart",
+			ExpireDate:         common.ToPtr(20089),
+			ForcePasswordReset: common.ToPtr(true),
+			PasswordMaxDays:    common.ToPtr(90),
+		},
+	}
+
+	options, err := NewUsersStageOptions(users, false)
+	require.NoError(t, err)
+
+	// locking takes precedence over the password
+	assert.Equal(t, "!", *options.Users["root"].Password)
+
+	// password aging is not supported by the stage
+	assert.Equal(t, UsersStageOptionsUser{
+		ExpireDate:         common.ToPtr(20089),
+		ForcePasswordReset: common.ToPtr(true),
+	}, options.Users["bart"])
+
+	stage, err := GenUsersStage(users, false)
+	require.NoError(t, err)
+	assert.Equal(t, options, stage.Options)
+}
@@ -0,0 +1,62 @@
+package osbuild
+
+import "fmt"
+
+type ZstdStageOptions struct {
+	// Filename for the zstd compressed file
+	Filename string `json:"filename"`
+
+	// Compression level from 1 to 19, the zstd default is used if unset
+	Level int `json:"level,omitempty"`
+
+	// Number of worker threads, zstd compresses in a single thread if unset
+	Threads int `json:"threads,omitempty"`
+}
+
+func (ZstdStageOptions) isStageOptions() {}
+
+func (o *ZstdStageOptions) validate() error {
+	if o.Level < 0 || o.Level > 19 {
+		return fmt.Errorf("zstd compression level %d is not between 1 and 19", o.Level)
+	}
+	if o.Threads < 0 {
+		return fmt.Errorf("zstd thread count %d is negative", o.Threads)
+	}
+	return nil
+}
+
+func NewZstdStageOptions(filename string) *ZstdStageOptions {
+	return &ZstdStageOptions{
+		Filename: filename,
+	}
+}
+
+type ZstdStageInputs struct {
+	File *FilesInput `json:"file"`
+}
+
+func (*ZstdStageInputs) isStageInputs() {}
+
+func NewZstdStageInputs(references FilesInputRef) *ZstdStageInputs {
+	return &ZstdStageInputs{
+		File: NewFilesInput(references),
+	}
+}
+
+// Compresses a file with zstd.
+func NewZstdStage(options *ZstdStageOptions, inputs *ZstdStageInputs) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+
+	var stageInputs Inputs
+	if inputs != nil {
+		stageInputs = inputs
+	}
+
+	return &Stage{
+		Type:    "org.osbuild.zstd",
+		Options: options,
+		Inputs:  stageInputs,
+	}
+}
@@ -0,0 +1,43 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewZstdStage(t *testing.T) {
+	options := NewZstdStageOptions("image.raw.zst")
+	options.Level = 19
+	options.Threads = 4
+	inputs := NewZstdStageInputs(NewFilesInputPipelineObjectRef("image", "image.raw", nil))
+
+	expectedStage := &Stage{
+		Type:    "org.osbuild.zstd",
+		Options: options,
+		Inputs:  inputs,
+	}
+	actualStage := NewZstdStage(options, inputs)
+	assert.Equal(t, expectedStage, actualStage)
+
+	data, err := json.Marshal(actualStage.Options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"filename": "image.raw.zst", "level": 19, "threads": 4}`, string(data))
+}
+
+func TestNewZstdStageDefaults(t *testing.T) {
+	data, err := json.Marshal(NewZstdStage(NewZstdStageOptions("image.raw.zst"), nil).Options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"filename": "image.raw.zst"}`, string(data))
+}
+
+func TestNewZstdStageInvalid(t *testing.T) {
+	assert.PanicsWithError(t, "zstd compression level 20 is not between 1 and 19", func() {
+		NewZstdStage(&ZstdStageOptions{Filename: "image.raw.zst", Level: 20}, nil)
+	})
+	assert.PanicsWithError(t, "zstd thread count -1 is negative", func() {
+		NewZstdStage(&ZstdStageOptions{Filename: "image.raw.zst", Threads: -1}, nil)
+	})
+}
@@ -19,6 +19,7 @@ const ( // image format enum
 	FORMAT_VHD
 	FORMAT_GCE
 	FORMAT_OVA
+	FORMAT_VHDX
 )
 
 func (a Arch) String() string {
@@ -52,6 +53,8 @@ func (f ImageFormat) String() string {
 		return "gce"
 	case FORMAT_OVA:
 		return "ova"
+	case FORMAT_VHDX:
+		return "vhdx"
 	default:
 		panic("invalid image format")
 	}
@@ -168,6 +168,7 @@ type PackageSpec struct {
 	Secrets        string `json:"secrets,omitempty"`
 	CheckGPG       bool   `json:"check_gpg,omitempty"`
 	IgnoreSSL      bool   `json:"ignore_ssl,omitempty"`
+	InstalledSize  uint64 `json:"installed_size,omitempty"` // from the repository metadata
 }
 
 type PackageSource struct {
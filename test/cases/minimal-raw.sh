@@ -51,7 +51,8 @@ IMAGE_KEY="minimal-raw-${TEST_UUID}"
 UEFI_GUEST_ADDRESS=192.168.100.51
 MINIMAL_RAW_TYPE=minimal-raw
 MINIMAL_RAW_DECOMPRESSED=raw.img
-MINIMAL_RAW_FILENAME=raw.img.xz
+MINIMAL_RAW_FILENAME=raw.img.zst
+MINIMAL_RAW_DECOMPRESS="zstd -d --rm"
 BOOT_ARGS="uefi"
 
 # Set up temporary files.
@@ -69,12 +70,16 @@ EDGE_USER_PASSWORD=foobar
 
 case "${ID}-${VERSION_ID}" in
     "rhel-8.9")
+        MINIMAL_RAW_FILENAME=raw.img.xz
+        MINIMAL_RAW_DECOMPRESS="xz -d"
         OS_VARIANT="rhel8-unknown"
         ;;
     "rhel-9.3")
         OS_VARIANT="rhel9-unknown"
         ;;
     "centos-8")
+        MINIMAL_RAW_FILENAME=raw.img.xz
+        MINIMAL_RAW_DECOMPRESS="xz -d"
         OS_VARIANT="centos-stream8"
         ;;
     "centos-9")
@@ -257,7 +262,8 @@ sudo composer-cli compose image "${COMPOSE_ID}" > /dev/null
 
 greenprint "Extracting and converting the raw image to a qcow2 file"
 MINIMAL_RAW_FILENAME="${COMPOSE_ID}-${MINIMAL_RAW_FILENAME}"
-sudo xz -d "${MINIMAL_RAW_FILENAME}"
+# shellcheck disable=SC2086 # the decompress command has arguments
+sudo ${MINIMAL_RAW_DECOMPRESS} "${MINIMAL_RAW_FILENAME}"
 LIBVIRT_IMAGE_PATH_UEFI=/var/lib/libvirt/images/"${IMAGE_KEY}-uefi.qcow2"
 sudo qemu-img convert -f raw "${COMPOSE_ID}-${MINIMAL_RAW_DECOMPRESSED}" -O qcow2 "$LIBVIRT_IMAGE_PATH_UEFI"
 # Remove raw file